@@ -28,12 +28,10 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/params"
-	"github.com/ethereum/go-ethereum/trie"
 	"github.com/urfave/cli/v2"
 )
 
@@ -224,7 +222,7 @@ func verify(ctx *cli.Context) error {
 				return fmt.Errorf("invalid root %s: got %s, want %s", name, got, want)
 			}
 			// Recompute accumulator.
-			if err := checkAccumulator(e); err != nil {
+			if err := e.Verify(); err != nil {
 				return fmt.Errorf("error verify era1 file %s: %w", name, err)
 			}
 			// Give the user some feedback that something is happening.
@@ -242,69 +240,6 @@ func verify(ctx *cli.Context) error {
 	return nil
 }
 
-// checkAccumulator verifies the accumulator matches the data in the Era.
-func checkAccumulator(e *era.Era) error {
-	var (
-		err    error
-		want   common.Hash
-		td     *big.Int
-		tds    = make([]*big.Int, 0)
-		hashes = make([]common.Hash, 0)
-	)
-	if want, err = e.Accumulator(); err != nil {
-		return fmt.Errorf("error reading accumulator: %w", err)
-	}
-	if td, err = e.InitialTD(); err != nil {
-		return fmt.Errorf("error reading total difficulty: %w", err)
-	}
-	it, err := era.NewIterator(e)
-	if err != nil {
-		return fmt.Errorf("error making era iterator: %w", err)
-	}
-	// To fully verify an era the following attributes must be checked:
-	//   1) the block index is constructed correctly
-	//   2) the tx root matches the value in the block
-	//   3) the receipts root matches the value in the block
-	//   4) the starting total difficulty value is correct
-	//   5) the accumulator is correct by recomputing it locally, which verifies
-	//      the blocks are all correct (via hash)
-	//
-	// The attributes 1), 2), and 3) are checked for each block. 4) and 5) require
-	// accumulation across the entire set and are verified at the end.
-	for it.Next() {
-		// 1) next() walks the block index, so we're able to implicitly verify it.
-		if it.Error() != nil {
-			return fmt.Errorf("error reading block %d: %w", it.Number(), err)
-		}
-		block, receipts, err := it.BlockAndReceipts()
-		if it.Error() != nil {
-			return fmt.Errorf("error reading block %d: %w", it.Number(), err)
-		}
-		// 2) recompute tx root and verify against header.
-		tr := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil))
-		if tr != block.TxHash() {
-			return fmt.Errorf("tx root in block %d mismatch: want %s, got %s", block.NumberU64(), block.TxHash(), tr)
-		}
-		// 3) recompute receipt root and check value against block.
-		rr := types.DeriveSha(receipts, trie.NewStackTrie(nil))
-		if rr != block.ReceiptHash() {
-			return fmt.Errorf("receipt root in block %d mismatch: want %s, got %s", block.NumberU64(), block.ReceiptHash(), rr)
-		}
-		hashes = append(hashes, block.Hash())
-		td.Add(td, block.Difficulty())
-		tds = append(tds, new(big.Int).Set(td))
-	}
-	// 4+5) Verify accumulator and total difficulty.
-	got, err := era.ComputeAccumulator(hashes, tds)
-	if err != nil {
-		return fmt.Errorf("error computing accumulator: %w", err)
-	}
-	if got != want {
-		return fmt.Errorf("expected accumulator root does not match calculated: got %s, want %s", got, want)
-	}
-	return nil
-}
-
 // readHashes reads a file of newline-delimited hashes.
 func readHashes(f string) ([]common.Hash, error) {
 	b, err := os.ReadFile(f)
@@ -0,0 +1,79 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	InitcodeFlag = &cli.BoolFlag{
+		Name:  "initcode",
+		Usage: "validate the containers as initcode",
+	}
+	eofParseCommand = &cli.Command{
+		Action: eofParseCmd,
+		Name:   "eofparse",
+		Usage:  "Parses and validates hex-encoded EOF containers, read from --input or line by line from stdin",
+		Flags:  []cli.Flag{InitcodeFlag},
+	}
+)
+
+func eofParseCmd(ctx *cli.Context) error {
+	isInitCode := ctx.Bool(InitcodeFlag.Name)
+	if ctx.IsSet(InputFlag.Name) {
+		fmt.Println(eofParse(ctx.String(InputFlag.Name), isInitCode))
+		return nil
+	}
+	return eofParseAll(os.Stdin, os.Stdout, isInitCode)
+}
+
+// eofParseAll validates every line of the input as a container, writing the
+// results line by line. Empty lines and comments are skipped.
+func eofParseAll(r io.Reader, w io.Writer, isInitCode bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Fprintln(w, eofParse(line, isInitCode))
+	}
+	return scanner.Err()
+}
+
+// eofParse validates a single hex-encoded container.
+func eofParse(hexcode string, isInitCode bool) string {
+	code, err := hex.DecodeString(strings.TrimPrefix(hexcode, "0x"))
+	if err != nil {
+		return fmt.Sprintf("err: invalid hex: %v", err)
+	}
+	c, err := vm.ParseAndValidateEOF(code, isInitCode)
+	if err != nil {
+		return fmt.Sprintf("err: %v", err)
+	}
+	return fmt.Sprintf("OK sections=%d containers=%d data=%d", c.CodeSectionCount(), c.SubContainerCount(), len(c.Data()))
+}
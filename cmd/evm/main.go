@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/urfave/cli/v2"
@@ -116,6 +117,16 @@ var (
 		Usage:    "The transaction receiver (execution context)",
 		Category: flags.VMCategory,
 	}
+	InterpreterFlag = &cli.StringFlag{
+		Name:     "interpreter",
+		Usage:    "The interpreter implementation to execute with",
+		Category: flags.VMCategory,
+	}
+	ShadowInterpreterFlag = &cli.StringFlag{
+		Name:     "shadow",
+		Usage:    "The interpreter implementation to cross-check every call against",
+		Category: flags.VMCategory,
+	}
 	DisableMemoryFlag = &cli.BoolFlag{
 		Name:     "nomemory",
 		Value:    true,
@@ -207,6 +218,8 @@ var vmFlags = []cli.Flag{
 	GenesisFlag,
 	SenderFlag,
 	ReceiverFlag,
+	InterpreterFlag,
+	ShadowInterpreterFlag,
 }
 
 // traceFlags contains flags that configure tracing output.
@@ -229,6 +242,7 @@ func init() {
 	app.Commands = []*cli.Command{
 		compileCommand,
 		disasmCommand,
+		eofParseCommand,
 		runCommand,
 		blockTestCommand,
 		stateTestCommand,
@@ -246,6 +260,19 @@ func init() {
 	}
 }
 
+// setInterpreters configures the interpreter implementations selected on the
+// command line, rejecting unknown ones.
+func setInterpreters(ctx *cli.Context, cfg *vm.Config) error {
+	for _, name := range []string{ctx.String(InterpreterFlag.Name), ctx.String(ShadowInterpreterFlag.Name)} {
+		if !vm.IsInterpreterRegistered(name) {
+			return fmt.Errorf("unknown interpreter %q", name)
+		}
+	}
+	cfg.InterpreterImpl = ctx.String(InterpreterFlag.Name)
+	cfg.ShadowInterpreterImpl = ctx.String(ShadowInterpreterFlag.Name)
+	return nil
+}
+
 func main() {
 	if err := app.Run(os.Args); err != nil {
 		code := 1
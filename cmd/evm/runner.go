@@ -228,12 +228,19 @@ func runCmd(ctx *cli.Context) error {
 			Tracer: tracer,
 		},
 	}
+	if err := setInterpreters(ctx, &runtimeConfig.EVMConfig); err != nil {
+		return err
+	}
 
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	} else {
 		runtimeConfig.ChainConfig = params.AllEthashProtocolChanges
 	}
+	if runtimeConfig.ChainConfig.IsOsaka(runtimeConfig.BlockNumber, runtimeConfig.Time) {
+		// EOF is only enabled post-merge, which the random value signals
+		runtimeConfig.Random = &genesisConfig.Mixhash
+	}
 
 	var hexInput []byte
 	if inputFileFlag := ctx.String(InputFileFlag.Name); inputFileFlag != "" {
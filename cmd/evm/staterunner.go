@@ -19,8 +19,10 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -65,6 +67,9 @@ func stateTestCmd(ctx *cli.Context) error {
 	case ctx.Bool(DebugFlag.Name):
 		cfg.Tracer = logger.NewStructLogger(config).Hooks()
 	}
+	if err := setInterpreters(ctx, &cfg); err != nil {
+		return err
+	}
 	// Load the test content from the input file
 	if len(ctx.Args().First()) != 0 {
 		return runStateTest(ctx.Args().First(), cfg, ctx.Bool(DumpFlag.Name))
@@ -100,6 +105,13 @@ func runStateTest(fname string, cfg vm.Config, dump bool) error {
 		for _, st := range test.Subtests() {
 			// Run the test and aggregate the result
 			result := &StatetestResult{Name: key, Fork: st.Fork, Pass: true}
+
+			// Divergences of the shadow interpreter fail the test, even if the
+			// post state of the primary one is correct.
+			var divergences []string
+			cfg.OnShadowDivergence = func(d *vm.ShadowDivergence) {
+				divergences = append(divergences, d.String())
+			}
 			test.Run(st, cfg, false, rawdb.HashScheme, func(err error, tstate *tests.StateTestState) {
 				var root common.Hash
 				if tstate.StateDB != nil {
@@ -112,6 +124,9 @@ func runStateTest(fname string, cfg vm.Config, dump bool) error {
 						result.State = &dump
 					}
 				}
+				if err == nil && len(divergences) > 0 {
+					err = errors.New(strings.Join(divergences, "; "))
+				}
 				if err != nil {
 					// Test failed, mark as so
 					result.Pass, result.Error = false, err.Error()
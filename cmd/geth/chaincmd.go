@@ -101,6 +101,8 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.TxLookupLimitFlag,
 			utils.VMTraceFlag,
 			utils.VMTraceJsonConfigFlag,
+			utils.VMInterpreterFlag,
+			utils.VMShadowInterpreterFlag,
 			utils.TransactionHistoryFlag,
 			utils.StateHistoryFlag,
 		}, utils.DatabaseFlags),
@@ -517,36 +519,12 @@ func importPreimages(ctx *cli.Context) error {
 }
 
 func parseDumpConfig(ctx *cli.Context, stack *node.Node, db ethdb.Database) (*state.DumpConfig, common.Hash, error) {
-	var header *types.Header
 	if ctx.NArg() > 1 {
 		return nil, common.Hash{}, fmt.Errorf("expected 1 argument (number or hash), got %d", ctx.NArg())
 	}
-	if ctx.NArg() == 1 {
-		arg := ctx.Args().First()
-		if hashish(arg) {
-			hash := common.HexToHash(arg)
-			if number := rawdb.ReadHeaderNumber(db, hash); number != nil {
-				header = rawdb.ReadHeader(db, hash, *number)
-			} else {
-				return nil, common.Hash{}, fmt.Errorf("block %x not found", hash)
-			}
-		} else {
-			number, err := strconv.ParseUint(arg, 10, 64)
-			if err != nil {
-				return nil, common.Hash{}, err
-			}
-			if hash := rawdb.ReadCanonicalHash(db, number); hash != (common.Hash{}) {
-				header = rawdb.ReadHeader(db, hash, number)
-			} else {
-				return nil, common.Hash{}, fmt.Errorf("header for block %d not found", number)
-			}
-		}
-	} else {
-		// Use latest
-		header = rawdb.ReadHeadHeader(db)
-	}
-	if header == nil {
-		return nil, common.Hash{}, errors.New("no head block found")
+	header, err := parseBlockHeader(db, ctx.Args().First())
+	if err != nil {
+		return nil, common.Hash{}, err
 	}
 	startArg := common.FromHex(ctx.String(utils.StartKeyFlag.Name))
 	var start common.Hash
@@ -573,6 +551,39 @@ func parseDumpConfig(ctx *cli.Context, stack *node.Node, db ethdb.Database) (*st
 	return conf, header.Root, nil
 }
 
+// parseBlockHeader resolves the header of the block specified by number or
+// hash, or the head header if none is specified.
+func parseBlockHeader(db ethdb.Reader, arg string) (*types.Header, error) {
+	var header *types.Header
+	if arg != "" {
+		if hashish(arg) {
+			hash := common.HexToHash(arg)
+			if number := rawdb.ReadHeaderNumber(db, hash); number != nil {
+				header = rawdb.ReadHeader(db, hash, *number)
+			} else {
+				return nil, fmt.Errorf("block %x not found", hash)
+			}
+		} else {
+			number, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if hash := rawdb.ReadCanonicalHash(db, number); hash != (common.Hash{}) {
+				header = rawdb.ReadHeader(db, hash, number)
+			} else {
+				return nil, fmt.Errorf("header for block %d not found", number)
+			}
+		}
+	} else {
+		// Use latest
+		header = rawdb.ReadHeadHeader(db)
+	}
+	if header == nil {
+		return nil, errors.New("no head block found")
+	}
+	return header, nil
+}
+
 func dump(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
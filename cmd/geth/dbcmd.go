@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -31,7 +32,9 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -54,6 +57,20 @@ var (
 		Name:  "remove.chain",
 		Usage: "If set, selects the state data for removal",
 	}
+	freezerCodecFlag = &cli.StringFlag{
+		Name:  "codec",
+		Usage: "Compression codec of the re-encoded freezer table (snappy, zstd)",
+		Value: "zstd",
+	}
+	freezerDictSizeFlag = &cli.IntFlag{
+		Name:  "dict.size",
+		Usage: "Size of the zstd dictionary trained from the freezer table items, 0 disables the dictionary",
+		Value: 110 * 1024,
+	}
+	verifyChainRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Rewrite or delete the inconsistent entries of the key-value store",
+	}
 
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
@@ -78,10 +95,13 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbReencodeFreezerCmd,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
+			dbCheckpointCmd,
 			dbCheckStateContentCmd,
+			dbVerifyChainCmd,
 			dbInspectHistoryCmd,
 		},
 	}
@@ -178,6 +198,24 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbReencodeFreezerCmd = &cli.Command{
+		Action:    freezerReencode,
+		Name:      "freezer-reencode",
+		Usage:     "Re-encode the items of a specific freezer table with another compression codec",
+		ArgsUsage: "<freezer-type> <table-type>",
+		Flags: flags.Merge([]cli.Flag{
+			freezerCodecFlag,
+			freezerDictSizeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command re-encodes all the items of the given freezer table offline, e.g.
+
+    geth db freezer-reencode --codec zstd chain receipts
+
+The zstd codec compresses the items with a dictionary trained from a sample of
+the table, which is most effective on the repetitive receipts and bodies. The
+codec is recorded in the table metadata, and newly frozen items are encoded
+with it as well. The node must not be running while the table is re-encoded.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -207,6 +245,42 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbCheckpointCmd = &cli.Command{
+		Action:    checkpointDB,
+		Name:      "checkpoint",
+		Usage:     "Create a consistent copy of the chain database",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command creates a consistent copy of the chain database in the given
+directory, which must not exist yet. If the node is running, the copy is taken by
+the node itself through its IPC endpoint (or the node given with --remotedb),
+otherwise the database is opened directly.
+
+The key-value store is copied using the checkpoint or snapshot facilities of the
+database engine, the sealed files of the chain freezer are hard-linked where
+possible. The head block of the copy is recorded in checkpoint.json, the copy is
+restored by running geth with the directory as --datadir.`,
+	}
+	dbVerifyChainCmd = &cli.Command{
+		Action:    verifyChain,
+		Name:      "verify-chain",
+		ArgsUsage: "[<start> [<end>]]",
+		Flags:     flags.Merge([]cli.Flag{verifyChainRepairFlag}, utils.NetworkFlags, utils.DatabaseFlags),
+		Usage:     "Verify the consistency of the chain data in the key-value store and the freezer",
+		Description: `This command walks the canonical chain from the start block (default genesis)
+to the end block (default head block), checking that the headers, canonical hashes,
+total difficulties, bodies, receipts and transaction lookup entries agree with each
+other: the headers must link to their canonical parents, the transaction, uncle,
+withdrawal and receipt roots of the headers must match the stored bodies and
+receipts, and the transactions of the indexed blocks must be looked up to them.
+
+With --repair, the inconsistent entries of the key-value store are rewritten if
+they can be derived from the rest of the chain data, or deleted otherwise, so that
+they are fetched again from the network. The head block is rewound below the first
+block with deleted data for that. The freezer is never modified.`,
+	}
 	dbInspectHistoryCmd = &cli.Command{
 		Action:    inspectHistory,
 		Name:      "inspect-history",
@@ -407,6 +481,53 @@ func checkStateContent(ctx *cli.Context) error {
 	return nil
 }
 
+func verifyChain(ctx *cli.Context) error {
+	if ctx.NArg() > 2 {
+		return fmt.Errorf("max 2 arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	repair := ctx.Bool(verifyChainRepairFlag.Name)
+	db := utils.MakeChainDatabase(ctx, stack, !repair)
+	defer db.Close()
+
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	config := core.ChainVerifyConfig{To: head.NumberU64(), Repair: repair}
+	if ctx.NArg() > 0 {
+		number, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid start block: %v", err)
+		}
+		config.From = number
+	}
+	if ctx.NArg() > 1 {
+		number, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid end block: %v", err)
+		}
+		config.To = number
+	}
+	if config.From > config.To {
+		return fmt.Errorf("start block %d above end block %d", config.From, config.To)
+	}
+	report, err := core.VerifyChainData(db, config)
+	if err != nil {
+		return err
+	}
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+	fmt.Printf("Verified %d blocks: %d issues, %d repaired\n", report.Blocks, len(report.Issues), report.Repaired)
+	if left := len(report.Issues) - report.Repaired; left > 0 {
+		return fmt.Errorf("%d unrepaired chain data issues", left)
+	}
+	return nil
+}
+
 func showLeveldbStats(db ethdb.KeyValueStater) {
 	if stats, err := db.Stat("leveldb.stats"); err != nil {
 		log.Warn("Failed to read database stats", "error", err)
@@ -630,6 +751,73 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func checkpointDB(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	dir := ctx.Args().Get(0)
+
+	// Take the checkpoint through the running node if there is one
+	endpoint := ctx.String(utils.RemoteDBFlag.Name)
+	if endpoint == "" {
+		cfg := loadBaseConfig(ctx)
+		if path := cfg.Node.IPCEndpoint(); path != "" && common.FileExist(path) {
+			endpoint = path
+		}
+		// The local node resolves relative paths from its own working directory
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		dir = abs
+	}
+	var info *rawdb.CheckpointInfo
+	if endpoint != "" {
+		client, err := utils.DialRPCWithHeaders(endpoint, ctx.StringSlice(utils.HttpHeaderFlag.Name))
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if err := client.Call(&info, "admin_checkpoint", dir); err != nil {
+			return err
+		}
+	} else {
+		stack, _ := makeConfigNode(ctx)
+		defer stack.Close()
+
+		db := utils.MakeChainDatabase(ctx, stack, false)
+		defer db.Close()
+
+		chaindata, err := filepath.Rel(stack.DataDir(), stack.ResolvePath("chaindata"))
+		if err != nil {
+			return err
+		}
+		if info, err = rawdb.CheckpointDatabase(db, dir, chaindata, nil); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Created checkpoint in %s, head block %d [%x], %d ancient blocks\n", dir, info.Number, info.Hash, info.Ancients)
+	return nil
+}
+
+func freezerReencode(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		freezer = ctx.Args().Get(0)
+		table   = ctx.Args().Get(1)
+	)
+	// Keep the node open during the re-encoding, its datadir lock prevents
+	// the database from being used concurrently.
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	return rawdb.ReencodeFreezerTable(ancient, freezer, table, ctx.String(freezerCodecFlag.Name), ctx.Int(freezerDictSizeFlag.Name))
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -775,6 +963,7 @@ func showMetaData(ctx *cli.Context) error {
 	data := rawdb.ReadChainMetadata(db)
 	data = append(data, []string{"frozen", fmt.Sprintf("%d items", ancients)})
 	data = append(data, []string{"snapshotGenerator", snapshot.ParseGeneratorStatus(rawdb.ReadSnapshotGenerator(db))})
+	data = append(data, []string{"onlinePruning", pruner.ParseOnlineStatus(rawdb.ReadOnlinePruningStatus(db))})
 	if b := rawdb.ReadHeadBlock(db); b != nil {
 		data = append(data, []string{"headBlock.Hash", fmt.Sprintf("%v", b.Hash())})
 		data = append(data, []string{"headBlock.Root", fmt.Sprintf("%v", b.Root())})
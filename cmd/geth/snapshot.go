@@ -148,6 +148,43 @@ as the backend data source, making this command a lot faster.
 
 The argument is interpreted as block number or hash. If none is provided, the latest
 block is used.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state of a block into a binary state export file",
+				ArgsUsage: "<filename> [<blockHash> | <blockNum>]",
+				Action:    exportSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth snapshot export <filename> [<blockHash> | <blockNum>]
+
+This command exports the state of the specified block into a compact binary
+file, which can be imported by 'geth snapshot import' to bootstrap a node
+without syncing the state from the network. The accounts and storage are read
+from the snapshot in chunks, each proven against the state root of the block.
+
+The block is interpreted as block number or hash. If none is provided, the
+latest block is used. The snapshot of the state must be fully generated.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the state from a binary state export file",
+				ArgsUsage: "<filename>",
+				Action:    importSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth snapshot import <filename>
+
+This command imports the state exported by 'geth snapshot export', verifying
+every chunk against the state root of the exported block. The tries are
+regenerated from the imported data, and the existing persistent state and
+snapshot are replaced.
+
+If the exported block is available locally, it becomes the head block once
+the state is imported. Otherwise the chain must be imported up to the block
+for the state to be used.
 `,
 			},
 			{
@@ -619,6 +656,73 @@ func dumpState(ctx *cli.Context) error {
 	return nil
 }
 
+// exportSnapshot exports the state of the specified block into a state export
+// file.
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("expected the export file and an optional block number or hash")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	header, err := parseBlockHeader(db, ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	triedb := utils.MakeTrieDatabase(ctx, db, false, true, false)
+	defer triedb.Close()
+
+	snapConfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapConfig, db, triedb, header.Root)
+	if err != nil {
+		return err
+	}
+	return utils.ExportSnapshot(snaptree, header, ctx.Args().First())
+}
+
+// importSnapshot imports the state from a state export file, making the
+// exported block the head block if it's available.
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the import file")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	triedb := utils.MakeTrieDatabase(ctx, db, false, false, false)
+	defer triedb.Close()
+
+	header, err := utils.ImportSnapshot(db, triedb, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var (
+		number = header.Number.Uint64()
+		hash   = header.Hash()
+	)
+	if rawdb.ReadCanonicalHash(db, number) != hash || !rawdb.HasBody(db, hash, number) {
+		log.Warn("Exported block not available, import the chain up to it to use the state", "number", number, "hash", hash)
+		return nil
+	}
+	if head := rawdb.ReadHeadBlock(db); head == nil || head.NumberU64() < number {
+		rawdb.WriteHeadBlockHash(db, hash)
+		rawdb.WriteHeadFastBlockHash(db, hash)
+		log.Info("Updated head block to the exported one", "number", number, "hash", hash)
+	}
+	return nil
+}
+
 // snapshotExportPreimages dumps the preimage data to a flat file.
 func snapshotExportPreimages(ctx *cli.Context) error {
 	if ctx.NArg() < 1 {
@@ -22,10 +22,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/converter"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/gballet/go-verkle"
@@ -35,6 +40,17 @@ import (
 var (
 	zero [32]byte
 
+	verkleOutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "Database directory of the converted verkle tree, relative to the data directory if not absolute",
+		Value: "verkledata",
+	}
+	verkleCheckpointFlag = &cli.IntFlag{
+		Name:  "checkpoint",
+		Usage: "Number of accounts and storage slots converted between two checkpoints",
+		Value: 1_000_000,
+	}
+
 	verkleCommand = &cli.Command{
 		Name:        "verkle",
 		Usage:       "A set of experimental verkle tree management commands",
@@ -61,6 +77,24 @@ This command takes a root commitment and attempts to rebuild the tree.
 geth verkle dump <state-root> <key 1> [<key 2> ...]
 This command will produce a dot file representing the tree, rooted at <root>.
 in which key1, key2, ... are expanded.
+ `,
+			},
+			{
+				Name:      "convert",
+				Usage:     "Convert the Merkle-Patricia state into a verkle tree",
+				ArgsUsage: "[<root>]",
+				Action:    convertVerkle,
+				Flags:     flags.Merge([]cli.Flag{verkleOutputFlag, verkleCheckpointFlag}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth verkle convert [--output <dir>] [<state-root>]
+This command walks the state with the given root, or the head state if none is
+provided, and writes the equivalent verkle tree into a new database. The state
+is read from the snapshot if available and from the tries otherwise, and the
+preimages of all the account and storage keys must be recorded (--cache.preimages).
+
+The conversion is checkpointed, it's resumed from the last checkpoint if the
+command is interrupted and run again with the same output. The root of the
+verkle tree and the conversion statistics are reported once finished.
  `,
 			},
 		},
@@ -212,3 +246,82 @@ func expandVerkle(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+func convertVerkle(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	var root common.Hash
+	if ctx.NArg() == 1 {
+		var err error
+		if root, err = parseRoot(ctx.Args().First()); err != nil {
+			log.Error("Failed to resolve state root", "error", err)
+			return err
+		}
+	} else {
+		headBlock := rawdb.ReadHeadBlock(chaindb)
+		if headBlock == nil {
+			log.Error("Failed to load head block")
+			return errors.New("no head block")
+		}
+		root = headBlock.Root()
+		log.Info("Converting the head state", "number", headBlock.NumberU64(), "root", root)
+	}
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, false, true, false)
+	defer triedb.Close()
+
+	snapConfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapConfig, chaindb, triedb, root)
+	if err != nil {
+		log.Info("Snapshot unavailable, converting from tries", "err", err)
+		snaptree = nil
+	}
+	dst, err := stack.OpenDatabase(ctx.String(verkleOutputFlag.Name), 512, utils.MakeDatabaseHandles(0), "eth/db/verkle/", false)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	conv, err := converter.New(chaindb, triedb, snaptree, root, dst, converter.Config{
+		Checkpoint: ctx.Int(verkleCheckpointFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during verkle conversion, stopping at next checkpoint")
+		}
+		close(stop)
+	}()
+	status, err := conv.Run(stop)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Source root:   %#x\n", status.Source)
+	fmt.Printf("Verkle root:   %#x\n", status.Root)
+	fmt.Printf("Accounts:      %d\n", status.Accounts)
+	fmt.Printf("Storage slots: %d\n", status.Slots)
+	fmt.Printf("Codes:         %d (%v)\n", status.Codes, common.StorageSize(status.CodeBytes))
+	fmt.Printf("Tree nodes:    %d (%v)\n", status.Nodes, common.StorageSize(status.NodeBytes))
+	fmt.Printf("Elapsed:       %v\n", common.PrettyDuration(time.Duration(status.Elapsed)))
+	return nil
+}
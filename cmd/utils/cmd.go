@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/urfave/cli/v2"
 )
 
@@ -661,6 +662,39 @@ func ExportSnapshotPreimages(chaindb ethdb.Database, snaptree *snapshot.Tree, fn
 	return nil
 }
 
+// ExportSnapshot exports the state of the given block from the snapshot into
+// a state export file.
+func ExportSnapshot(snaptree *snapshot.Tree, header *types.Header, fn string) error {
+	log.Info("Exporting state snapshot", "file", fn, "number", header.Number, "hash", header.Hash(), "root", header.Root)
+
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	// The chunks are already compressed, only buffer the writes
+	writer := bufio.NewWriter(fh)
+	if err := snapshot.Export(writer, snaptree, header); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// ImportSnapshot imports the state from a state export file, replacing the
+// persistent state and snapshot. The header of the exported block is returned.
+func ImportSnapshot(db ethdb.Database, triedb *triedb.Database, fn string) (*types.Header, error) {
+	log.Info("Importing state snapshot", "file", fn)
+
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return snapshot.Import(bufio.NewReader(fh), db, triedb)
+}
+
 // exportHeader is used in the export/import flow. When we do an export,
 // the first element we output is the exportHeader.
 // Whenever a backwards-incompatible change is made, the Version header
@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -283,6 +284,39 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	HistoryExpiryFlag = &cli.Uint64Flag{
+		Name:     "history.expiry",
+		Usage:    "Number of the first block whose body and receipts are retained locally, older ones are served from the era1 archive (0 = entire chain, 15537394 = mainnet merge)",
+		Category: flags.StateCategory,
+	}
+	StatePruneOnlineFlag = &cli.BoolFlag{
+		Name:     "state.prune.online",
+		Usage:    "Delete the stale state trie nodes while the node runs, only relevant in state.scheme=hash",
+		Category: flags.StateCategory,
+	}
+	StatePruneBloomSizeFlag = &cli.Uint64Flag{
+		Name:     "state.prune.bloomsize",
+		Usage:    "Megabytes of memory allocated to bloom-filter for online pruning",
+		Value:    ethconfig.Defaults.StatePruneBloomSize,
+		Category: flags.StateCategory,
+	}
+	StatePruneRateFlag = &cli.IntFlag{
+		Name:     "state.prune.rate",
+		Usage:    "Maximum number of stale trie nodes deleted per second by online pruning (0 = unlimited)",
+		Value:    ethconfig.Defaults.StatePruneRate,
+		Category: flags.StateCategory,
+	}
+	StatePruneIntervalFlag = &cli.DurationFlag{
+		Name:     "state.prune.interval",
+		Usage:    "Time between the end of an online pruning cycle and the start of the next one",
+		Value:    ethconfig.Defaults.StatePruneInterval,
+		Category: flags.StateCategory,
+	}
+	HistoryEraFlag = &flags.DirectoryFlag{
+		Name:     "history.era",
+		Usage:    "Directory of era1 files serving the expired chain history",
+		Category: flags.StateCategory,
+	}
 	// Beacon client light sync settings
 	BeaconApiFlag = &cli.StringSliceFlag{
 		Name:     "beacon.api",
@@ -358,6 +392,18 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolSnapshotFlag = &cli.StringFlag{
+		Name:     "txpool.snapshot",
+		Usage:    "Disk snapshot of all pooled transactions to survive node restarts (empty = disabled)",
+		Value:    ethconfig.Defaults.TxPool.Snapshot,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolResnapshotFlag = &cli.DurationFlag{
+		Name:     "txpool.resnapshot",
+		Usage:    "Time interval to regenerate the transaction pool snapshot",
+		Value:    ethconfig.Defaults.TxPool.Resnapshot,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price tip to enforce for acceptance into the pool",
@@ -400,6 +446,17 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolConditionalFlag = &cli.BoolFlag{
+		Name:     "txpool.conditional",
+		Usage:    "Accept conditional transactions over the unauthenticated eth_sendRawTransactionConditional API",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolConditionalLifetimeFlag = &cli.DurationFlag{
+		Name:     "txpool.conditional.lifetime",
+		Usage:    "Maximum amount of time conditional transactions are kept, reserving their senders",
+		Value:    ethconfig.Defaults.ConditionalPool.Lifetime,
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -419,6 +476,11 @@ var (
 		Value:    ethconfig.Defaults.BlobPool.PriceBump,
 		Category: flags.BlobPoolCategory,
 	}
+	BlobPoolSnapshotFlag = &cli.BoolFlag{
+		Name:     "blobpool.snapshot",
+		Usage:    "Snapshot the blob pool metadata to speed up node restarts",
+		Category: flags.BlobPoolCategory,
+	}
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
 		Name:     "cache",
@@ -507,6 +569,22 @@ var (
 		Usage:    "0x prefixed public address for the pending block producer (not used for actual block production)",
 		Category: flags.MinerCategory,
 	}
+	MinerOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    "Transaction ordering strategy of the built blocks (price, fifo, roundrobin, priority)",
+		Value:    miner.OrderingPrice,
+		Category: flags.MinerCategory,
+	}
+	MinerPrioritySendersFlag = &cli.StringFlag{
+		Name:     "miner.prioritysenders",
+		Usage:    "Comma separated sender addresses included first by the priority ordering, in order",
+		Category: flags.MinerCategory,
+	}
+	MinerBundlesFlag = &cli.BoolFlag{
+		Name:     "miner.bundles",
+		Usage:    "Accept transaction bundles over the unauthenticated eth_sendBundle and eth_callBundle APIs",
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -549,6 +627,16 @@ var (
 		Usage:    "Tracer configuration (JSON)",
 		Category: flags.VMCategory,
 	}
+	VMInterpreterFlag = &cli.StringFlag{
+		Name:     "vminterpreter",
+		Usage:    "Name of the interpreter implementation executing transactions",
+		Category: flags.VMCategory,
+	}
+	VMShadowInterpreterFlag = &cli.StringFlag{
+		Name:     "vmshadow",
+		Usage:    "Name of an interpreter implementation to cross-check every call against, reporting divergences (costly)",
+		Category: flags.VMCategory,
+	}
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
 		Name:     "rpc.gascap",
@@ -1522,6 +1610,12 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.String(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.IsSet(TxPoolResnapshotFlag.Name) {
+		cfg.Resnapshot = ctx.Duration(TxPoolResnapshotFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1545,6 +1639,21 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	}
 }
 
+func setBlobPool(ctx *cli.Context, cfg *blobpool.Config) {
+	if ctx.IsSet(BlobPoolDataDirFlag.Name) {
+		cfg.Datadir = ctx.String(BlobPoolDataDirFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolDataCapFlag.Name) {
+		cfg.Datacap = ctx.Uint64(BlobPoolDataCapFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.Uint64(BlobPoolPriceBumpFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.Bool(BlobPoolSnapshotFlag.Name)
+	}
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.Bool(MiningEnabledFlag.Name) {
 		log.Warn("The flag --mine is deprecated and will be removed")
@@ -1565,6 +1674,21 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		log.Warn("The flag --miner.newpayload-timeout is deprecated and will be removed, please use --miner.recommit")
 		cfg.Recommit = ctx.Duration(MinerNewPayloadTimeoutFlag.Name)
 	}
+	if ctx.IsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.String(MinerOrderingFlag.Name)
+	}
+	if ctx.IsSet(MinerPrioritySendersFlag.Name) {
+		cfg.PrioritySenders = nil
+		for _, account := range SplitAndTrim(ctx.String(MinerPrioritySendersFlag.Name)) {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid priority sender: %s", account)
+			}
+			cfg.PrioritySenders = append(cfg.PrioritySenders, common.HexToAddress(account))
+		}
+	}
+	if _, err := miner.NewOrdering(cfg.Ordering, cfg.PrioritySenders); err != nil {
+		Fatalf("Invalid miner configuration: %v", err)
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1646,7 +1770,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setBlobPool(ctx, &cfg.BlobPool)
+	if ctx.IsSet(TxPoolConditionalFlag.Name) {
+		cfg.ConditionalTxs = ctx.Bool(TxPoolConditionalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolConditionalLifetimeFlag.Name) {
+		cfg.ConditionalPool.Lifetime = ctx.Duration(TxPoolConditionalLifetimeFlag.Name)
+	}
 	setMiner(ctx, &cfg.Miner)
+	if ctx.IsSet(MinerBundlesFlag.Name) {
+		cfg.Bundles = ctx.Bool(MinerBundlesFlag.Name)
+	}
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
 
@@ -1707,6 +1841,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
+	if ctx.IsSet(StatePruneOnlineFlag.Name) {
+		cfg.StatePruneOnline = ctx.Bool(StatePruneOnlineFlag.Name)
+	}
+	if ctx.IsSet(StatePruneBloomSizeFlag.Name) {
+		cfg.StatePruneBloomSize = ctx.Uint64(StatePruneBloomSizeFlag.Name)
+	}
+	if ctx.IsSet(StatePruneRateFlag.Name) {
+		cfg.StatePruneRate = ctx.Int(StatePruneRateFlag.Name)
+	}
+	if ctx.IsSet(StatePruneIntervalFlag.Name) {
+		cfg.StatePruneInterval = ctx.Duration(StatePruneIntervalFlag.Name)
+	}
+	if ctx.IsSet(HistoryExpiryFlag.Name) {
+		cfg.HistoryExpiry = ctx.Uint64(HistoryExpiryFlag.Name)
+	}
+	if ctx.IsSet(HistoryEraFlag.Name) {
+		cfg.HistoryEraDir = ctx.String(HistoryEraFlag.Name)
+	}
 	// Parse transaction history flag, if user is still using legacy config
 	// file with 'TxLookupLimit' configured, copy the value to 'TransactionHistory'.
 	if cfg.TransactionHistory == ethconfig.Defaults.TransactionHistory && cfg.TxLookupLimit != ethconfig.Defaults.TxLookupLimit {
@@ -1913,6 +2065,23 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			cfg.VMTraceJsonConfig = config
 		}
 	}
+	// VM interpreter config.
+	cfg.VMInterpreter, cfg.VMShadowInterpreter = interpreterFlags(ctx)
+}
+
+// interpreterFlags returns the interpreter implementations selected on the
+// command line, failing on unknown ones.
+func interpreterFlags(ctx *cli.Context) (string, string) {
+	primary, shadow := ctx.String(VMInterpreterFlag.Name), ctx.String(VMShadowInterpreterFlag.Name)
+	for _, name := range []string{primary, shadow} {
+		if !vm.IsInterpreterRegistered(name) {
+			Fatalf("Unknown interpreter implementation %q", name)
+		}
+	}
+	if shadow != "" {
+		log.Warn("Cross-checking execution against shadow interpreter", "primary", primary, "shadow", shadow)
+	}
+	return primary, shadow
 }
 
 // SetDNSDiscoveryDefaults configures DNS discovery with the given URL if
@@ -2197,13 +2366,14 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 			if ctx.IsSet(VMTraceJsonConfigFlag.Name) {
 				config = json.RawMessage(ctx.String(VMTraceJsonConfigFlag.Name))
 			}
-			t, err := tracers.LiveDirectory.New(name, config)
+			t, err := tracers.LiveDirectory.NewWithDB(name, config, chainDb)
 			if err != nil {
 				Fatalf("Failed to create tracer %q: %v", name, err)
 			}
 			vmcfg.Tracer = t
 		}
 	}
+	vmcfg.InterpreterImpl, vmcfg.ShadowInterpreterImpl = interpreterFlags(ctx)
 	// Disable transaction indexing/unindexing by default.
 	chain, err := core.NewBlockChain(chainDb, cache, gspec, nil, engine, vmcfg, nil, nil)
 	if err != nil {
@@ -568,10 +568,10 @@ var (
 	u256_32 = uint256.NewInt(32)
 )
 
-// accumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, stateDB *state.StateDB, header *types.Header, uncles []*types.Header) {
+// BlockRewards returns the mining rewards of the given block, the reward of its
+// coinbase including the static block reward and the rewards for the included
+// uncles, and the reward of the coinbase of each uncle block.
+func BlockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*uint256.Int, []*uint256.Int) {
 	// Select the correct block reward based on chain progression
 	blockReward := FrontierBlockReward
 	if config.IsByzantium(header.Number) {
@@ -581,19 +581,32 @@ func accumulateRewards(config *params.ChainConfig, stateDB *state.StateDB, heade
 		blockReward = ConstantinopleBlockReward
 	}
 	// Accumulate the rewards for the miner and any included uncles
-	reward := new(uint256.Int).Set(blockReward)
-	r := new(uint256.Int)
+	var (
+		reward       = new(uint256.Int).Set(blockReward)
+		uncleRewards = make([]*uint256.Int, len(uncles))
+	)
 	hNum, _ := uint256.FromBig(header.Number)
-	for _, uncle := range uncles {
+	for i, uncle := range uncles {
+		r := new(uint256.Int)
 		uNum, _ := uint256.FromBig(uncle.Number)
 		r.AddUint64(uNum, 8)
 		r.Sub(r, hNum)
 		r.Mul(r, blockReward)
 		r.Div(r, u256_8)
-		stateDB.AddBalance(uncle.Coinbase, r, tracing.BalanceIncreaseRewardMineUncle)
+		uncleRewards[i] = r
 
-		r.Div(blockReward, u256_32)
-		reward.Add(reward, r)
+		reward.Add(reward, new(uint256.Int).Div(blockReward, u256_32))
+	}
+	return reward, uncleRewards
+}
+
+// accumulateRewards credits the coinbase of the given block with the mining
+// reward. The total reward consists of the static block reward and rewards for
+// included uncles. The coinbase of each uncle block is also rewarded.
+func accumulateRewards(config *params.ChainConfig, stateDB *state.StateDB, header *types.Header, uncles []*types.Header) {
+	reward, uncleRewards := BlockRewards(config, header, uncles)
+	for i, uncle := range uncles {
+		stateDB.AddBalance(uncle.Coinbase, uncleRewards[i], tracing.BalanceIncreaseRewardMineUncle)
 	}
 	stateDB.AddBalance(header.Coinbase, reward, tracing.BalanceIncreaseRewardMineBlock)
 }
@@ -355,8 +355,19 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 }
 
 // StateAt returns a new mutable state based on a particular point in time.
+//
+// In path scheme, states no longer kept by the trie database are reconstructed
+// from the retained state histories. Such historical states are read-only.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache, bc.snaps)
+	statedb, err := state.New(root, bc.stateCache, bc.snaps)
+	if err == nil || bc.historicState == nil {
+		return statedb, err
+	}
+	historic, herr := state.New(root, bc.historicState, nil)
+	if herr != nil {
+		return nil, err
+	}
+	return historic, nil
 }
 
 // Config retrieves the chain's fork configuration.
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -4221,3 +4223,202 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// TestEIP7702 deploys two delegation designations and calls them. It writes one
+// value to storage which is verified after.
+func TestEIP7702(t *testing.T) {
+	var (
+		config  = *params.AllEthashProtocolChanges
+		engine  = beacon.NewFaker()
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		aa      = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bb      = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		funds   = new(big.Int).Mul(common.Big1, big.NewInt(params.Ether))
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.TerminalTotalDifficultyPassed = true
+	config.ShanghaiTime = u64(0)
+	config.CancunTime = u64(0)
+	config.PragueTime = u64(0)
+	signer := types.LatestSigner(&config)
+
+	// 0xAAAA calls into addr2, which delegates to 0xBBBB.
+	aaCode := []byte{
+		byte(vm.PUSH1), 0x00, // out size
+		byte(vm.DUP1), // out offset
+		byte(vm.DUP1), // in size
+		byte(vm.DUP1), // in offset
+		byte(vm.DUP1), // value
+		byte(vm.PUSH20),
+	}
+	aaCode = append(aaCode, addr2.Bytes()...)
+	aaCode = append(aaCode, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	// 0xBBBB stores 0x42 into slot 0x42.
+	bbCode := []byte{byte(vm.PUSH1), 0x42, byte(vm.DUP1), byte(vm.SSTORE), byte(vm.STOP)}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			addr1: {Balance: funds},
+			addr2: {Balance: funds},
+			aa:    {Code: aaCode, Balance: common.Big0},
+			bb:    {Code: bbCode, Balance: common.Big0},
+		},
+	}
+	// Sign authorizations: addr1 delegates to 0xAAAA and addr2 to 0xBBBB. As
+	// addr1 is the sender as well, its nonce is already bumped when the
+	// authorization is applied.
+	auth1, _ := types.SignSetCode(key1, types.SetCodeAuthorization{
+		ChainID: *uint256.MustFromBig(config.ChainID),
+		Address: aa,
+		Nonce:   1,
+	})
+	auth2, _ := types.SignSetCode(key2, types.SetCodeAuthorization{
+		Address: bb,
+		Nonce:   0,
+	})
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		b.SetCoinbase(aa)
+		if i == 1 {
+			// Delegated accounts are still allowed to send transactions
+			b.AddTx(types.MustSignNewTx(key2, signer, &types.DynamicFeeTx{
+				ChainID:   config.ChainID,
+				Nonce:     1,
+				To:        &bb,
+				Gas:       100000,
+				GasFeeCap: newGwei(5),
+				GasTipCap: big.NewInt(2),
+			}))
+			return
+		}
+		txdata := &types.SetCodeTx{
+			ChainID:   uint256.MustFromBig(config.ChainID),
+			Nonce:     0,
+			To:        addr1,
+			Gas:       500000,
+			GasFeeCap: uint256.MustFromBig(newGwei(5)),
+			GasTipCap: uint256.NewInt(2),
+			AuthList:  []types.SetCodeAuthorization{auth1, auth2},
+		}
+		tx := types.MustSignNewTx(key1, signer, txdata)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+
+	// Verify delegation designations were deployed.
+	state, _ := chain.State()
+	code, want := state.GetCode(addr1), types.AddressToDelegation(aa)
+	if !bytes.Equal(code, want) {
+		t.Fatalf("addr1 code incorrect: got %s, want %s", common.Bytes2Hex(code), common.Bytes2Hex(want))
+	}
+	code, want = state.GetCode(addr2), types.AddressToDelegation(bb)
+	if !bytes.Equal(code, want) {
+		t.Fatalf("addr2 code incorrect: got %s, want %s", common.Bytes2Hex(code), common.Bytes2Hex(want))
+	}
+	if nonce := state.GetNonce(addr1); nonce != 2 {
+		t.Fatalf("addr1 nonce incorrect: got %d, want 2", nonce)
+	}
+	if nonce := state.GetNonce(addr2); nonce != 2 {
+		t.Fatalf("addr2 nonce incorrect: got %d, want 2", nonce)
+	}
+	// Verify delegation executed the correct code.
+	var (
+		fortyTwo = common.BytesToHash([]byte{0x42})
+		actual   = state.GetState(addr2, fortyTwo)
+	)
+	if actual.Cmp(fortyTwo) != 0 {
+		t.Fatalf("addr2 storage wrong: expected %d, got %d", fortyTwo, actual)
+	}
+}
+
+// Tests that in path scheme the states beyond the in-memory layers are served
+// from the state histories.
+func TestHistoricalStateAt(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address   = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.Address{0xaa}
+		// Contract storing the block number into slot 0
+		contract = common.Address{0xbb}
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				address:  {Balance: big.NewInt(params.Ether)},
+				contract: {Code: []byte{byte(vm.NUMBER), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		height = 2 * state.TriesInMemory
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), height, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), recipient, big.NewInt(1), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(address), contract, nil, 50000, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	chain, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.PathScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	for _, number := range []int{0, 1, state.TriesInMemory / 2, height - state.TriesInMemory - 1, height} {
+		root := chain.genesisBlock.Root()
+		if number > 0 {
+			root = blocks[number-1].Root()
+		}
+		statedb, err := chain.StateAt(root)
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", number, err)
+		}
+		if balance := statedb.GetBalance(recipient); balance.Uint64() != uint64(number) {
+			t.Errorf("block %d: recipient balance mismatch: have %v, want %d", number, balance, number)
+		}
+		if nonce := statedb.GetNonce(address); nonce != uint64(2*number) {
+			t.Errorf("block %d: sender nonce mismatch: have %d, want %d", number, nonce, 2*number)
+		}
+		if slot := statedb.GetState(contract, common.Hash{}); slot.Big().Int64() != int64(number) {
+			t.Errorf("block %d: contract storage mismatch: have %x, want %d", number, slot, number)
+		}
+	}
+	// The historical states must not be served by the live state database
+	if _, err := state.New(blocks[0].Root(), chain.stateCache, nil); err == nil {
+		t.Fatal("historical state served by the live state database")
+	}
+	// Historical states can be hashed while unmodified, but hashing or
+	// committing them fails once mutated
+	statedb, err := chain.StateAt(blocks[0].Root())
+	if err != nil {
+		t.Fatalf("failed to open historical state: %v", err)
+	}
+	if root := statedb.IntermediateRoot(true); root != blocks[0].Root() {
+		t.Fatalf("unmodified historical state root mismatch: have %x, want %x", root, blocks[0].Root())
+	}
+	statedb.AddBalance(recipient, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	if root := statedb.IntermediateRoot(true); root != (common.Hash{}) || statedb.Error() == nil {
+		t.Fatalf("mutated historical state hashed: root %x, err %v", root, statedb.Error())
+	}
+	if _, err := statedb.Commit(1, true); err == nil {
+		t.Fatal("mutated historical state committed")
+	}
+}
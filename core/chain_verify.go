@@ -0,0 +1,430 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ChainIssueKind is the kind of chain data found inconsistent.
+type ChainIssueKind string
+
+const (
+	IssueCanonical ChainIssueKind = "canonical" // Missing canonical hash
+	IssueHeader    ChainIssueKind = "header"    // Missing or corrupted header
+	IssueLink      ChainIssueKind = "link"      // Header not linked to the canonical parent
+	IssueNumber    ChainIssueKind = "number"    // Missing or wrong hash to number mapping
+	IssueTd        ChainIssueKind = "td"        // Missing or wrong total difficulty
+	IssueBody      ChainIssueKind = "body"      // Missing body or not matching the header
+	IssueReceipts  ChainIssueKind = "receipts"  // Missing receipts or not matching the header
+	IssueTxLookup  ChainIssueKind = "txlookup"  // Missing or wrong transaction lookup entry
+	IssueHead      ChainIssueKind = "head"      // Head marker pointing to a non-canonical block
+)
+
+// ChainIssue is an inconsistency of the chain data found by VerifyChainData.
+type ChainIssue struct {
+	Number   uint64
+	Hash     common.Hash
+	Kind     ChainIssueKind
+	Detail   string
+	Frozen   bool // Whether the data is stored in the freezer
+	Repaired bool // Whether the bad entry is rewritten or deleted
+}
+
+// String implements fmt.Stringer.
+func (issue *ChainIssue) String() string {
+	var suffix string
+	switch {
+	case issue.Repaired:
+		suffix = " (repaired)"
+	case issue.Frozen:
+		suffix = " (frozen)"
+	}
+	return fmt.Sprintf("#%d [%x..] %s: %s%s", issue.Number, issue.Hash[:4], issue.Kind, issue.Detail, suffix)
+}
+
+// ChainVerifyConfig is the configuration of the chain data verification.
+type ChainVerifyConfig struct {
+	From   uint64 // First block to verify
+	To     uint64 // Last block to verify
+	Repair bool   // Whether to rewrite or delete the bad entries in the key-value store
+}
+
+// ChainVerifyReport is the outcome of the chain data verification.
+type ChainVerifyReport struct {
+	Blocks   uint64        // Number of blocks verified
+	Issues   []*ChainIssue // Inconsistencies found
+	Repaired int           // Number of issues repaired
+}
+
+// chainVerifier checks the chain data of a range of blocks.
+type chainVerifier struct {
+	db      ethdb.Database
+	batch   ethdb.Batch
+	config  ChainVerifyConfig
+	frozen  uint64  // Number of blocks in the freezer
+	txTail  *uint64 // First block with indexed transactions, nil if not indexed
+	report  *ChainVerifyReport
+	hasher  *trie.StackTrie
+	parent  common.Hash // Canonical hash of the previous block
+	prevTd  *big.Int    // Total difficulty of the previous block
+	current uint64      // Block being verified
+	deleted *uint64     // Lowest block with its body or receipts deleted by a repair
+}
+
+// issue records an inconsistency, running the repair function if repairing is
+// enabled. The repair function is nil if the issue is not repairable.
+func (v *chainVerifier) issue(hash common.Hash, kind ChainIssueKind, frozen bool, repair func(ethdb.KeyValueWriter), format string, args ...interface{}) {
+	issue := &ChainIssue{
+		Number: v.current,
+		Hash:   hash,
+		Kind:   kind,
+		Detail: fmt.Sprintf(format, args...),
+		Frozen: frozen,
+	}
+	if v.config.Repair && repair != nil {
+		repair(v.batch)
+		issue.Repaired = true
+		v.report.Repaired++
+	}
+	log.Warn("Inconsistent chain data", "number", issue.Number, "hash", hash, "kind", kind, "detail", issue.Detail, "frozen", frozen, "repaired", issue.Repaired)
+	v.report.Issues = append(v.report.Issues, issue)
+}
+
+// deriveSha computes the root of the list.
+func (v *chainVerifier) deriveSha(list types.DerivableList) common.Hash {
+	v.hasher.Reset()
+	return types.DeriveSha(list, v.hasher)
+}
+
+// VerifyChainData walks the canonical chain in the configured range, checking
+// that the headers, canonical hashes, bodies, receipts, total difficulties and
+// transaction lookup entries agree with each other, both in the key-value store
+// and the freezer. If repairing is enabled, the bad entries in the key-value
+// store are rewritten if they can be derived from the rest of the chain data,
+// or deleted otherwise. The head block is rewound below the blocks with deleted
+// data, so they are fetched again. Frozen data is never modified.
+func VerifyChainData(db ethdb.Database, config ChainVerifyConfig) (*ChainVerifyReport, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		frozen = 0 // database without a freezer
+	}
+	v := &chainVerifier{
+		db:     db,
+		batch:  db.NewBatch(),
+		config: config,
+		frozen: frozen,
+		txTail: rawdb.ReadTxIndexTail(db),
+		report: new(ChainVerifyReport),
+		hasher: trie.NewStackTrie(nil),
+	}
+	if config.From > 0 {
+		v.parent = rawdb.ReadCanonicalHash(db, config.From-1)
+		if v.parent != (common.Hash{}) {
+			v.prevTd = rawdb.ReadTd(db, v.parent, config.From-1)
+		}
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := config.From; number <= config.To; number++ {
+		v.current = number
+		v.verifyBlock(number)
+		v.report.Blocks++
+
+		if v.batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := v.batch.Write(); err != nil {
+				return nil, err
+			}
+			v.batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain data", "number", number, "issues", len(v.report.Issues), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		if number == config.To {
+			break // prevent overflow
+		}
+	}
+	v.verifyHeads()
+	v.rewindHeads()
+
+	if err := v.batch.Write(); err != nil {
+		return nil, err
+	}
+	log.Info("Verified chain data", "blocks", v.report.Blocks, "issues", len(v.report.Issues), "repaired", v.report.Repaired, "elapsed", common.PrettyDuration(time.Since(start)))
+	return v.report, nil
+}
+
+// verifyBlock checks the chain data of the canonical block with the number.
+func (v *chainVerifier) verifyBlock(number uint64) {
+	var (
+		frozen = number < v.frozen
+		parent = v.parent
+		prevTd = v.prevTd
+	)
+	// Reset the parent tracking, so that a broken block doesn't cause issues
+	// to be reported for the next one.
+	v.parent, v.prevTd = common.Hash{}, nil
+
+	hash := rawdb.ReadCanonicalHash(v.db, number)
+	if hash == (common.Hash{}) {
+		v.issue(hash, IssueCanonical, frozen, nil, "missing canonical hash")
+		return
+	}
+	header := rawdb.ReadHeader(v.db, hash, number)
+	if header == nil {
+		v.issue(hash, IssueHeader, frozen, nil, "missing header")
+		return
+	}
+	if have := header.Hash(); have != hash {
+		v.issue(hash, IssueHeader, frozen, nil, "header hash mismatch, have %x", have)
+		return
+	}
+	if header.Number == nil || header.Number.Uint64() != number {
+		v.issue(hash, IssueHeader, frozen, nil, "header number mismatch, have %v", header.Number)
+		return
+	}
+	v.parent = hash
+
+	if number > 0 && parent != (common.Hash{}) && header.ParentHash != parent {
+		v.issue(hash, IssueLink, frozen, nil, "parent hash %x not canonical, want %x", header.ParentHash, parent)
+	}
+	// The hash to number mapping is always kept in the key-value store
+	if stored := rawdb.ReadHeaderNumber(v.db, hash); stored == nil || *stored != number {
+		repair := func(w ethdb.KeyValueWriter) { rawdb.WriteHeaderNumber(w, hash, number) }
+		if stored == nil {
+			v.issue(hash, IssueNumber, false, repair, "missing hash to number mapping")
+		} else {
+			v.issue(hash, IssueNumber, false, repair, "hash mapped to number %d", *stored)
+		}
+	}
+	v.verifyTd(header, prevTd, frozen)
+
+	body := rawdb.ReadBody(v.db, hash, number)
+	if body == nil {
+		v.issue(hash, IssueBody, frozen, nil, "missing body")
+		return
+	}
+	if !v.verifyBody(header, body, frozen) {
+		return
+	}
+	v.verifyReceipts(header, body, frozen)
+	v.verifyTxLookups(header, body)
+}
+
+// verifyTd checks the total difficulty of the block against the one of the
+// parent block.
+func (v *chainVerifier) verifyTd(header *types.Header, prevTd *big.Int, frozen bool) {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		want   *big.Int
+	)
+	if number == 0 {
+		want = new(big.Int).Set(header.Difficulty)
+	} else if prevTd != nil {
+		want = new(big.Int).Add(prevTd, header.Difficulty)
+	}
+	td := rawdb.ReadTd(v.db, hash, number)
+	switch {
+	case td == nil && want == nil:
+		v.issue(hash, IssueTd, frozen, nil, "missing total difficulty")
+	case td == nil:
+		v.issue(hash, IssueTd, frozen, v.repairable(frozen, func(w ethdb.KeyValueWriter) { rawdb.WriteTd(w, hash, number, want) }), "missing total difficulty")
+		v.prevTd = want
+	case want != nil && td.Cmp(want) != 0:
+		v.issue(hash, IssueTd, frozen, v.repairable(frozen, func(w ethdb.KeyValueWriter) { rawdb.WriteTd(w, hash, number, want) }), "total difficulty %v, want %v", td, want)
+		v.prevTd = want
+	default:
+		v.prevTd = td
+	}
+}
+
+// repairable returns the repair function if the data is in the key-value store,
+// nil otherwise.
+func (v *chainVerifier) repairable(frozen bool, repair func(ethdb.KeyValueWriter)) func(ethdb.KeyValueWriter) {
+	if frozen {
+		return nil
+	}
+	return repair
+}
+
+// deletion returns the repair function deleting the data of the block if it's
+// in the key-value store, nil otherwise. The data of the genesis block is never
+// deleted as it cannot be fetched again.
+func (v *chainVerifier) deletion(frozen bool, number uint64, del func(ethdb.KeyValueWriter)) func(ethdb.KeyValueWriter) {
+	if frozen || number == 0 {
+		return nil
+	}
+	return func(w ethdb.KeyValueWriter) {
+		del(w)
+		if v.deleted == nil || number < *v.deleted {
+			v.deleted = &number
+		}
+	}
+}
+
+// verifyBody checks the body against the roots in the header, reporting
+// whether it's valid.
+func (v *chainVerifier) verifyBody(header *types.Header, body *types.Body, frozen bool) bool {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		detail string
+	)
+	switch {
+	case v.deriveSha(types.Transactions(body.Transactions)) != header.TxHash:
+		detail = "transaction root mismatch"
+	case types.CalcUncleHash(body.Uncles) != header.UncleHash:
+		detail = "uncle hash mismatch"
+	case header.WithdrawalsHash == nil && body.Withdrawals != nil:
+		detail = "unexpected withdrawals"
+	case header.WithdrawalsHash != nil && body.Withdrawals == nil:
+		detail = "missing withdrawals"
+	case header.WithdrawalsHash != nil && v.deriveSha(types.Withdrawals(body.Withdrawals)) != *header.WithdrawalsHash:
+		detail = "withdrawal root mismatch"
+	default:
+		return true
+	}
+	v.issue(hash, IssueBody, frozen, v.deletion(frozen, number, func(w ethdb.KeyValueWriter) { rawdb.DeleteBody(w, hash, number) }), detail)
+	return false
+}
+
+// verifyReceipts checks the receipts against the transactions and the roots in
+// the header.
+func (v *chainVerifier) verifyReceipts(header *types.Header, body *types.Body, frozen bool) {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		repair = v.deletion(frozen, number, func(w ethdb.KeyValueWriter) { rawdb.DeleteReceipts(w, hash, number) })
+	)
+	if len(rawdb.ReadReceiptsRLP(v.db, hash, number)) == 0 {
+		v.issue(hash, IssueReceipts, frozen, nil, "missing receipts")
+		return
+	}
+	receipts := rawdb.ReadRawReceipts(v.db, hash, number)
+	if receipts == nil {
+		v.issue(hash, IssueReceipts, frozen, repair, "undecodable receipts")
+		return
+	}
+	if len(receipts) != len(body.Transactions) {
+		v.issue(hash, IssueReceipts, frozen, repair, "%d receipts for %d transactions", len(receipts), len(body.Transactions))
+		return
+	}
+	// The receipt types are not stored, they are needed for the encoding
+	for i, tx := range body.Transactions {
+		receipts[i].Type = tx.Type()
+	}
+	if root := v.deriveSha(receipts); root != header.ReceiptHash {
+		v.issue(hash, IssueReceipts, frozen, repair, "receipt root mismatch, have %x", root)
+		return
+	}
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		v.issue(hash, IssueReceipts, frozen, repair, "logs bloom mismatch")
+	}
+}
+
+// verifyTxLookups checks the lookup entries of the transactions, if the block
+// is in the indexed range.
+func (v *chainVerifier) verifyTxLookups(header *types.Header, body *types.Body) {
+	number := header.Number.Uint64()
+	if v.txTail == nil || number < *v.txTail {
+		return
+	}
+	for _, tx := range body.Transactions {
+		txHash := tx.Hash()
+		repair := func(w ethdb.KeyValueWriter) { rawdb.WriteTxLookupEntries(w, number, []common.Hash{txHash}) }
+
+		if stored := rawdb.ReadTxLookupEntry(v.db, txHash); stored == nil {
+			v.issue(header.Hash(), IssueTxLookup, false, repair, "missing lookup entry of transaction %x", txHash)
+		} else if *stored != number {
+			v.issue(header.Hash(), IssueTxLookup, false, repair, "transaction %x mapped to block %d", txHash, *stored)
+		}
+	}
+}
+
+// verifyHeads checks that the head markers point to canonical blocks.
+func (v *chainVerifier) verifyHeads() {
+	v.current = 0
+	for _, head := range []struct {
+		name string
+		hash common.Hash
+	}{
+		{"header", rawdb.ReadHeadHeaderHash(v.db)},
+		{"block", rawdb.ReadHeadBlockHash(v.db)},
+		{"snap block", rawdb.ReadHeadFastBlockHash(v.db)},
+	} {
+		if head.hash == (common.Hash{}) {
+			continue
+		}
+		number := rawdb.ReadHeaderNumber(v.db, head.hash)
+		if number == nil {
+			v.issue(head.hash, IssueHead, false, nil, "head %s unknown", head.name)
+			continue
+		}
+		v.current = *number
+		if rawdb.ReadCanonicalHash(v.db, *number) != head.hash {
+			v.issue(head.hash, IssueHead, false, nil, "head %s not canonical", head.name)
+		}
+	}
+}
+
+// rewindHeads moves the head block markers below the lowest block with deleted
+// data, as the chain is only synced above the head block and the deleted data
+// would never be fetched again otherwise.
+func (v *chainVerifier) rewindHeads() {
+	if v.deleted == nil {
+		return
+	}
+	var (
+		number = *v.deleted - 1
+		hash   = rawdb.ReadCanonicalHash(v.db, number)
+	)
+	if hash == (common.Hash{}) {
+		return // missing canonical hash already reported
+	}
+	v.current = number
+	for _, head := range []struct {
+		name  string
+		hash  common.Hash
+		write func(ethdb.KeyValueWriter, common.Hash)
+	}{
+		{"block", rawdb.ReadHeadBlockHash(v.db), rawdb.WriteHeadBlockHash},
+		{"snap block", rawdb.ReadHeadFastBlockHash(v.db), rawdb.WriteHeadFastBlockHash},
+	} {
+		if head.hash == (common.Hash{}) {
+			continue
+		}
+		current := rawdb.ReadHeaderNumber(v.db, head.hash)
+		if current == nil || *current <= number {
+			continue
+		}
+		write := head.write
+		v.issue(hash, IssueHead, false, func(w ethdb.KeyValueWriter) { write(w, hash) }, "head %s #%d above deleted block data, rewound", head.name, *current)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+// newVerifyTestChain writes a canonical chain with a transaction in every
+// block into a database.
+func newVerifyTestChain(t *testing.T, n int) (ethdb.Database, []*types.Block) {
+	t.Helper()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, gen *BlockGen) {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(addr),
+			To:       &common.Address{0xaa},
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: gen.header.BaseFee,
+		})
+		gen.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(db, triedb.NewDatabase(db, triedb.HashDefaults))
+
+	td := new(big.Int).Set(genesis.Difficulty())
+	for i, block := range blocks {
+		td.Add(td, block.Difficulty())
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		rawdb.WriteTd(db, block.Hash(), block.NumberU64(), td)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteTxLookupEntriesByBlock(db, block)
+	}
+	rawdb.WriteTxIndexTail(db, 0)
+	rawdb.WriteHeadHeaderHash(db, blocks[n-1].Hash())
+	rawdb.WriteHeadBlockHash(db, blocks[n-1].Hash())
+	return db, blocks
+}
+
+func TestVerifyChainData(t *testing.T) {
+	db, blocks := newVerifyTestChain(t, 10)
+	config := ChainVerifyConfig{To: 10}
+
+	report, err := VerifyChainData(db, config)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	if report.Blocks != 11 || len(report.Issues) != 0 {
+		t.Fatalf("unexpected report of intact chain: %d blocks, issues %v", report.Blocks, report.Issues)
+	}
+	// Corrupt the chain data in various ways
+	rawdb.DeleteReceipts(db, blocks[2].Hash(), 3)
+	rawdb.WriteReceipts(db, blocks[3].Hash(), 4, types.Receipts{})
+	rawdb.DeleteTxLookupEntry(db, blocks[5].Transactions()[0].Hash())
+	rawdb.WriteTd(db, blocks[6].Hash(), 7, big.NewInt(1))
+	rawdb.DeleteHeaderNumber(db, blocks[8].Hash())
+
+	want := []struct {
+		number     uint64
+		kind       ChainIssueKind
+		repairable bool
+	}{
+		{3, IssueReceipts, false},
+		{4, IssueReceipts, true},
+		{6, IssueTxLookup, true},
+		{7, IssueTd, true},
+		{9, IssueNumber, true},
+	}
+	check := func(issues []*ChainIssue, repair bool) {
+		t.Helper()
+		if len(issues) != len(want) {
+			t.Fatalf("issue count mismatch: have %v, want %d", issues, len(want))
+		}
+		for i, issue := range issues {
+			if issue.Number != want[i].number || issue.Kind != want[i].kind || issue.Repaired != (repair && want[i].repairable) {
+				t.Fatalf("issue %d mismatch: have %v, want %+v", i, issue, want[i])
+			}
+		}
+	}
+	report, err = VerifyChainData(db, config)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	check(report.Issues, false)
+
+	// Verifying without repair must leave the database untouched
+	if report, _ = VerifyChainData(db, config); len(report.Issues) != len(want) {
+		t.Fatalf("database modified without repair: %v", report.Issues)
+	}
+	config.Repair = true
+	report, err = VerifyChainData(db, config)
+	if err != nil {
+		t.Fatalf("failed to repair chain: %v", err)
+	}
+	// Deleting the receipts of block 4 rewinds the head block below it
+	check(report.Issues[:len(report.Issues)-1], true)
+	if head := report.Issues[len(report.Issues)-1]; head.Kind != IssueHead || head.Number != 3 || !head.Repaired {
+		t.Fatalf("head rewind issue mismatch: %v", head)
+	}
+	if report.Repaired != 5 {
+		t.Fatalf("repaired count mismatch: have %d, want 5", report.Repaired)
+	}
+	if head := rawdb.ReadHeadBlockHash(db); head != blocks[2].Hash() {
+		t.Fatalf("head block not rewound: have %x, want %x", head, blocks[2].Hash())
+	}
+	if head := rawdb.ReadHeadHeaderHash(db); head != blocks[9].Hash() {
+		t.Fatalf("head header rewound: have %x, want %x", head, blocks[9].Hash())
+	}
+	// The only issues left are the receipts which cannot be derived
+	config.Repair = false
+	if report, _ = VerifyChainData(db, config); len(report.Issues) != 2 {
+		t.Fatalf("unexpected issues after repair: %v", report.Issues)
+	}
+	for _, issue := range report.Issues {
+		if issue.Kind != IssueReceipts || issue.Detail != "missing receipts" {
+			t.Fatalf("unexpected issue after repair: %v", issue)
+		}
+	}
+	if td := rawdb.ReadTd(db, blocks[6].Hash(), 7); td.Cmp(rawdb.ReadTd(db, blocks[7].Hash(), 8)) >= 0 {
+		t.Fatalf("total difficulty not repaired: %v", td)
+	}
+}
+
+func TestVerifyChainDataHeads(t *testing.T) {
+	db, blocks := newVerifyTestChain(t, 4)
+
+	// Point the head to a block which is not canonical anymore
+	rawdb.WriteCanonicalHash(db, blocks[1].Hash(), 4)
+	report, err := VerifyChainData(db, ChainVerifyConfig{To: 4})
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	var heads int
+	for _, issue := range report.Issues {
+		if issue.Kind == IssueHead {
+			heads++
+		}
+	}
+	if heads != 2 {
+		t.Fatalf("head issue count mismatch: have %d, want 2, issues %v", heads, report.Issues)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	accountHistoryIndexKeyLength = 2 + common.AddressLength + 8
+	storageHistoryIndexKeyLength = 2 + common.AddressLength + common.HashLength + 8
+)
+
+// ReadStateHistoryIndexHead retrieves the id of the last indexed state history.
+func ReadStateHistoryIndexHead(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(headStateHistoryIndexKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateHistoryIndexHead stores the id of the last indexed state history.
+func WriteStateHistoryIndexHead(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Put(headStateHistoryIndexKey, encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store the state history index head", "err", err)
+	}
+}
+
+// DeleteStateHistoryIndexHead removes the id of the last indexed state history.
+func DeleteStateHistoryIndexHead(db ethdb.KeyValueWriter) {
+	if err := db.Delete(headStateHistoryIndexKey); err != nil {
+		log.Crit("Failed to delete the state history index head", "err", err)
+	}
+}
+
+// ReadAccountHistoryIndex retrieves the chunk of state history ids in which
+// the account was modified.
+func ReadAccountHistoryIndex(db ethdb.KeyValueReader, address common.Address, chunk uint64) []byte {
+	data, _ := db.Get(accountHistoryIndexKey(address, chunk))
+	return data
+}
+
+// WriteAccountHistoryIndex stores the chunk of state history ids in which the
+// account was modified.
+func WriteAccountHistoryIndex(db ethdb.KeyValueWriter, address common.Address, chunk uint64, data []byte) {
+	if err := db.Put(accountHistoryIndexKey(address, chunk), data); err != nil {
+		log.Crit("Failed to store account history index", "err", err)
+	}
+}
+
+// DeleteAccountHistoryIndex removes the chunk of state history ids in which
+// the account was modified.
+func DeleteAccountHistoryIndex(db ethdb.KeyValueWriter, address common.Address, chunk uint64) {
+	if err := db.Delete(accountHistoryIndexKey(address, chunk)); err != nil {
+		log.Crit("Failed to delete account history index", "err", err)
+	}
+}
+
+// IterateAccountHistoryIndex iterates the chunks of the account history index,
+// starting from the chunk with the given id, until the callback returns false.
+func IterateAccountHistoryIndex(db ethdb.Iteratee, address common.Address, from uint64, fn func(chunk uint64, data []byte) bool) {
+	prefix := append(append([]byte{}, StateHistoryAccountIndexPrefix...), address.Bytes()...)
+	iterateHistoryIndex(db, prefix, from, fn)
+}
+
+// ReadStorageHistoryIndex retrieves the chunk of state history ids in which
+// the storage slot was modified.
+func ReadStorageHistoryIndex(db ethdb.KeyValueReader, address common.Address, slot common.Hash, chunk uint64) []byte {
+	data, _ := db.Get(storageHistoryIndexKey(address, slot, chunk))
+	return data
+}
+
+// WriteStorageHistoryIndex stores the chunk of state history ids in which the
+// storage slot was modified.
+func WriteStorageHistoryIndex(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, chunk uint64, data []byte) {
+	if err := db.Put(storageHistoryIndexKey(address, slot, chunk), data); err != nil {
+		log.Crit("Failed to store storage history index", "err", err)
+	}
+}
+
+// DeleteStorageHistoryIndex removes the chunk of state history ids in which
+// the storage slot was modified.
+func DeleteStorageHistoryIndex(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, chunk uint64) {
+	if err := db.Delete(storageHistoryIndexKey(address, slot, chunk)); err != nil {
+		log.Crit("Failed to delete storage history index", "err", err)
+	}
+}
+
+// IterateStorageHistoryIndex iterates the chunks of the storage slot history
+// index, starting from the chunk with the given id, until the callback returns
+// false.
+func IterateStorageHistoryIndex(db ethdb.Iteratee, address common.Address, slot common.Hash, from uint64, fn func(chunk uint64, data []byte) bool) {
+	prefix := append(append([]byte{}, StateHistoryStorageIndexPrefix...), address.Bytes()...)
+	prefix = append(prefix, slot.Bytes()...)
+	iterateHistoryIndex(db, prefix, from, fn)
+}
+
+// iterateHistoryIndex iterates the index chunks stored under the given prefix.
+func iterateHistoryIndex(db ethdb.Iteratee, prefix []byte, from uint64, fn func(chunk uint64, data []byte) bool) {
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8 {
+			continue
+		}
+		if !fn(binary.BigEndian.Uint64(key[len(prefix):]), it.Value()) {
+			return
+		}
+	}
+}
+
+// DeleteStateHistoryIndex removes the entire state history index.
+func DeleteStateHistoryIndex(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	for _, item := range []struct {
+		prefix []byte
+		length int
+	}{
+		{StateHistoryAccountIndexPrefix, accountHistoryIndexKeyLength},
+		{StateHistoryStorageIndexPrefix, storageHistoryIndexKeyLength},
+	} {
+		it := db.NewIterator(item.prefix, nil)
+		for it.Next() {
+			if len(it.Key()) != item.length {
+				continue
+			}
+			if err := batch.Delete(it.Key()); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+	}
+	DeleteStateHistoryIndexHead(batch)
+	return batch.Write()
+}
@@ -160,6 +160,36 @@ func DeleteTrieJournal(db ethdb.KeyValueWriter) {
 	}
 }
 
+// ReadOnlinePruningStatus retrieves the serialized progress of the online state
+// pruning.
+func ReadOnlinePruningStatus(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(onlinePruningStatusKey)
+	return data
+}
+
+// WriteOnlinePruningStatus stores the serialized progress of the online state
+// pruning.
+func WriteOnlinePruningStatus(db ethdb.KeyValueWriter, status []byte) {
+	if err := db.Put(onlinePruningStatusKey, status); err != nil {
+		log.Crit("Failed to store online pruning status", "err", err)
+	}
+}
+
+// ReadVerkleConversionStatus retrieves the serialized progress of the verkle
+// tree conversion.
+func ReadVerkleConversionStatus(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(verkleConversionStatusKey)
+	return data
+}
+
+// WriteVerkleConversionStatus stores the serialized progress of the verkle
+// tree conversion.
+func WriteVerkleConversionStatus(db ethdb.KeyValueWriter, status []byte) {
+	if err := db.Put(verkleConversionStatusKey, status); err != nil {
+		log.Crit("Failed to store verkle conversion status", "err", err)
+	}
+}
+
 // ReadStateHistoryMeta retrieves the metadata corresponding to the specified
 // state history. Compute the position of state history in freezer by minus
 // one since the id of first state history starts from one(zero for initial
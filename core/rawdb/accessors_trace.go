@@ -0,0 +1,134 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CallTrace is the persisted call trace of a single transaction, produced
+// during block import.
+type CallTrace struct {
+	Config []byte // JSON encoded config of the tracer
+	Result []byte // JSON encoded result of the tracer
+}
+
+// CallTraceBlock is the index entry of a block whose transactions have been
+// traced, used to prune the traces once the block is reorged away.
+type CallTraceBlock struct {
+	TxHashes []common.Hash
+}
+
+// ReadCallTrace retrieves the call trace of a transaction traced in the block
+// with the given hash.
+func ReadCallTrace(db ethdb.KeyValueReader, blockHash common.Hash, txHash common.Hash) *CallTrace {
+	data, _ := db.Get(callTraceKey(blockHash, txHash))
+	if len(data) == 0 {
+		return nil
+	}
+	var trace CallTrace
+	if err := rlp.DecodeBytes(data, &trace); err != nil {
+		log.Error("Invalid call trace RLP", "block", blockHash, "tx", txHash, "err", err)
+		return nil
+	}
+	return &trace
+}
+
+// WriteCallTrace stores the call trace of a transaction traced in the block with
+// the given hash.
+func WriteCallTrace(db ethdb.KeyValueWriter, blockHash common.Hash, txHash common.Hash, trace *CallTrace) {
+	data, err := rlp.EncodeToBytes(trace)
+	if err != nil {
+		log.Crit("Failed to RLP encode call trace", "err", err)
+	}
+	if err := db.Put(callTraceKey(blockHash, txHash), data); err != nil {
+		log.Crit("Failed to store call trace", "err", err)
+	}
+}
+
+// DeleteCallTrace removes the call trace of a transaction traced in the block
+// with the given hash.
+func DeleteCallTrace(db ethdb.KeyValueWriter, blockHash common.Hash, txHash common.Hash) {
+	if err := db.Delete(callTraceKey(blockHash, txHash)); err != nil {
+		log.Crit("Failed to delete call trace", "err", err)
+	}
+}
+
+// ReadCallTraceBlock retrieves the index entry of the traced block with the
+// given number and hash.
+func ReadCallTraceBlock(db ethdb.KeyValueReader, number uint64, hash common.Hash) *CallTraceBlock {
+	data, _ := db.Get(callTraceBlockKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var block CallTraceBlock
+	if err := rlp.DecodeBytes(data, &block); err != nil {
+		log.Error("Invalid call trace block RLP", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return &block
+}
+
+// WriteCallTraceBlock stores the index entry of the traced block with the given
+// number and hash.
+func WriteCallTraceBlock(db ethdb.KeyValueWriter, number uint64, hash common.Hash, block *CallTraceBlock) {
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Crit("Failed to RLP encode call trace block", "err", err)
+	}
+	if err := db.Put(callTraceBlockKey(number, hash), data); err != nil {
+		log.Crit("Failed to store call trace block", "err", err)
+	}
+}
+
+// DeleteCallTraceBlock removes the index entry of the traced block with the
+// given number and hash.
+func DeleteCallTraceBlock(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Delete(callTraceBlockKey(number, hash)); err != nil {
+		log.Crit("Failed to delete call trace block", "err", err)
+	}
+}
+
+// ReadCallTraceBlockHashes returns the numbers and hashes of all indexed traced
+// blocks up to and including the given height, in ascending order.
+func ReadCallTraceBlockHashes(db ethdb.Iteratee, limit uint64) ([]uint64, []common.Hash) {
+	it := db.NewIterator(callTraceBlockPrefix, nil)
+	defer it.Release()
+
+	var (
+		numbers []uint64
+		hashes  []common.Hash
+	)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(callTraceBlockPrefix)+8+common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(callTraceBlockPrefix):])
+		if number > limit {
+			break
+		}
+		numbers = append(numbers, number)
+		hashes = append(hashes, common.BytesToHash(key[len(callTraceBlockPrefix)+8:]))
+	}
+	return numbers, hashes
+}
@@ -17,11 +17,17 @@
 package rawdb
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type tableSize struct {
@@ -51,7 +57,7 @@ func (info *freezerInfo) size() common.StorageSize {
 	return total
 }
 
-func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
+func inspect(name string, order map[string]freezerTableConfig, reader ethdb.AncientReader) (freezerInfo, error) {
 	info := freezerInfo{name: name}
 	for t := range order {
 		size, err := reader.AncientSize(t)
@@ -82,7 +88,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	for _, freezer := range freezers {
 		switch freezer {
 		case ChainFreezerName:
-			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db)
+			info, err := inspect(ChainFreezerName, chainFreezerTableConfigs, db)
 			if err != nil {
 				return nil, err
 			}
@@ -91,7 +97,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 		case StateFreezerName:
 			datadir, err := db.AncientDatadir()
 			if err != nil {
-				return nil, err
+				continue // the ancient directory is not available, e.g. for remote databases
 			}
 			f, err := NewStateFreezer(datadir, true)
 			if err != nil {
@@ -99,7 +105,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			}
 			defer f.Close()
 
-			info, err := inspect(freezer, stateFreezerNoSnappy, f)
+			info, err := inspect(freezer, stateFreezerTableConfigs, f)
 			if err != nil {
 				return nil, err
 			}
@@ -112,35 +118,257 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	return infos, nil
 }
 
-// InspectFreezerTable dumps out the index of a specific freezer table. The passed
-// ancient indicates the path of root ancient directory where the chain freezer can
-// be opened. Start and end specify the range for dumping out indexes.
-// Note this function can only be used for debugging purposes.
-func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+// resolveFreezerTable resolves the directory and the configuration of a
+// specific freezer table. The passed ancient indicates the path of root
+// ancient directory where the chain freezer can be opened.
+func resolveFreezerTable(ancient string, freezerName string, tableName string) (string, freezerTableConfig, error) {
 	var (
 		path   string
-		tables map[string]bool
+		tables map[string]freezerTableConfig
 	)
 	switch freezerName {
 	case ChainFreezerName:
-		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+		path, tables = resolveChainFreezerDir(ancient), chainFreezerTableConfigs
 	case StateFreezerName:
-		path, tables = filepath.Join(ancient, freezerName), stateFreezerNoSnappy
+		path, tables = filepath.Join(ancient, freezerName), stateFreezerTableConfigs
 	default:
-		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
+		return "", freezerTableConfig{}, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
-	noSnappy, exist := tables[tableName]
+	config, exist := tables[tableName]
 	if !exist {
 		var names []string
 		for name := range tables {
 			names = append(names, name)
 		}
-		return fmt.Errorf("unknown table, supported ones: %v", names)
+		return "", freezerTableConfig{}, fmt.Errorf("unknown table, supported ones: %v", names)
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
+	return path, config, nil
+}
+
+// InspectFreezerTable dumps out the index of a specific freezer table. The passed
+// ancient indicates the path of root ancient directory where the chain freezer can
+// be opened. Start and end specify the range for dumping out indexes.
+// Note this function can only be used for debugging purposes.
+func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+	path, config, err := resolveFreezerTable(ancient, freezerName, tableName)
+	if err != nil {
+		return err
+	}
+	table, err := newFreezerTable(path, tableName, config, true)
 	if err != nil {
 		return err
 	}
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// ReencodeFreezerTable re-encodes all the items of a specific freezer table
+// with the given codec. If zstd is requested with a non-zero dictionary size,
+// a dictionary is trained from a sample of the items first. The passed ancient
+// indicates the path of root ancient directory where the chain freezer can be
+// opened.
+//
+// Note the table files are replaced at the end of the process, so the database
+// must not be in use. If the replacement is interrupted, the table is unusable
+// until the re-encoding of it is run again, finishing the replacement.
+func ReencodeFreezerTable(ancient string, freezerName string, tableName string, codecName string, dictSize int) error {
+	path, config, err := resolveFreezerTable(ancient, freezerName, tableName)
+	if err != nil {
+		return err
+	}
+	// Finish an interrupted replacement first, the old table files might already
+	// be partially overwritten, so the table must not be opened before.
+	reencodePath := filepath.Join(path, "reencode")
+	if blob, err := os.ReadFile(filepath.Join(reencodePath, reencodeMarker)); err == nil {
+		files := strings.Fields(string(blob))
+		if len(files) == 0 || files[0] != tableName {
+			return fmt.Errorf("interrupted re-encoding of another table pending, rerun it first: %v", files)
+		}
+		log.Warn("Finishing interrupted replacement of re-encoded table", "table", tableName)
+		return replaceReencodedTable(path, reencodePath, tableName, files[1:])
+	}
+	if config.noSnappy {
+		return errors.New("table is not compressed")
+	}
+	codec, err := parseFreezerCodec(codecName)
+	if err != nil {
+		return err
+	}
+	table, err := newFreezerTable(path, tableName, config, false)
+	if err != nil {
+		return err
+	}
+	defer table.Close()
+
+	var (
+		start = time.Now()
+		tail  = table.itemHidden.Load()
+		head  = table.items.Load()
+		dict  []byte
+	)
+	if codec == codecZstd && dictSize > 0 {
+		dict, err = trainTableDict(table, tail, head, dictSize)
+		if err != nil {
+			return fmt.Errorf("failed to train dictionary: %w", err)
+		}
+		log.Info("Trained compression dictionary", "table", tableName, "size", len(dict), "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	// Set up the new table in a temporary directory, with the deleted items
+	// recorded in its index, the content of which we'll at the end move over
+	// to the ancients dir.
+	if err := os.RemoveAll(reencodePath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(reencodePath, 0755); err != nil {
+		return err
+	}
+	first := indexEntry{filenum: 0, offset: uint32(tail)}
+	if err := os.WriteFile(filepath.Join(reencodePath, fmt.Sprintf("%s.cidx", tableName)), first.append(nil), 0644); err != nil {
+		return err
+	}
+	newTable, err := newFreezerTable(reencodePath, tableName, config, false)
+	if err != nil {
+		return err
+	}
+	defer newTable.Close()
+
+	if newTable.items.Load() != tail {
+		return fmt.Errorf("unexpected item offset of re-encoded table, have: %d, want: %d", newTable.items.Load(), tail)
+	}
+	if err := newTable.setCodec(codec, dict); err != nil {
+		return err
+	}
+	var (
+		batch     = newTable.newBatch()
+		batchSize = uint64(1024)
+		maxBytes  = uint64(1024 * 1024)
+		logged    = time.Now()
+	)
+	for i := tail; i < head; {
+		items, err := table.RetrieveItems(i, min(batchSize, head-i), maxBytes)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := batch.AppendRaw(i, item); err != nil {
+				return err
+			}
+			i++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Re-encoding freezer table", "table", tableName, "processed", i-tail, "total", head-tail, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.commit(); err != nil {
+		return err
+	}
+	oldSize, err := table.size()
+	if err != nil {
+		return err
+	}
+	newSize, err := newTable.size()
+	if err != nil {
+		return err
+	}
+	log.Info("Replacing old table files with re-encoded ones", "table", tableName, "old", common.StorageSize(oldSize), "new", common.StorageSize(newSize), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := table.Close(); err != nil {
+		return err
+	}
+	if err := newTable.Close(); err != nil {
+		return err
+	}
+	// Record the files of the complete new table, so an interrupted replacement
+	// can be finished by a rerun. From here on the old table is overwritten.
+	entries, err := os.ReadDir(reencodePath)
+	if err != nil {
+		return err
+	}
+	files := []string{tableName}
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	if err := writeFileSync(filepath.Join(reencodePath, reencodeMarker), []byte(strings.Join(files, "\n"))); err != nil {
+		return err
+	}
+	return replaceReencodedTable(path, reencodePath, tableName, files[1:])
+}
+
+// reencodeMarker is the name of the file marking a complete re-encoded table in
+// the temporary directory. It lists the table name and the files of the table.
+const reencodeMarker = "REENCODED"
+
+// replaceReencodedTable moves the files of the re-encoded table over the files of
+// the old table, then deletes the old data files not overwritten. Files already
+// moved by an interrupted run are skipped.
+func replaceReencodedTable(path string, reencodePath string, tableName string, files []string) error {
+	keep := make(map[string]bool)
+	for _, name := range files {
+		keep[name] = true
+
+		src := filepath.Join(reencodePath, name)
+		if !common.FileExist(src) {
+			continue
+		}
+		if err := os.Rename(src, filepath.Join(path, name)); err != nil {
+			return err
+		}
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	data := regexp.MustCompile("^" + regexp.QuoteMeta(tableName) + `\.[0-9]{4}\.[rc]dat$`)
+	for _, entry := range entries {
+		if keep[entry.Name()] || !data.MatchString(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(reencodePath)
+}
+
+// writeFileSync writes the data to the named file and flushes it to disk.
+func writeFileSync(name string, data []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// trainTableDict trains a zstd dictionary of the given size from a sample of
+// the items in the range [tail, head) of the table. The samples are spread
+// evenly across the range and capped in total size.
+func trainTableDict(t *freezerTable, tail, head uint64, size int) ([]byte, error) {
+	const maxSamples = 16384
+	var (
+		step     = max((head-tail)/maxSamples, 1)
+		samples  [][]byte
+		total    int
+		maxTotal = 100 * size
+	)
+	for i := tail; i < head && total < maxTotal; i += step {
+		items, err := t.RetrieveItems(i, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, items[0])
+		total += len(items[0])
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("no items to sample")
+	}
+	return trainZstdDict(samples, size)
+}
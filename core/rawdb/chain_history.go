@@ -0,0 +1,264 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// isExpirableKind reports whether the items of the given chain freezer table
+// are subject to history expiry.
+func isExpirableKind(kind string) bool {
+	config, ok := chainFreezerTableConfigs[kind]
+	return ok && config.prunable
+}
+
+// historyExpiryDB is a wrapper of the chain database which expires the block
+// bodies and receipts below a cutoff block from the chain freezer. The expired
+// items are transparently served from a history archive instead, such as a
+// directory of era1 files.
+type historyExpiryDB struct {
+	ethdb.Database
+
+	cutoff  uint64                // Number of the first block retained locally
+	archive ethdb.AncientReaderOp // Archive serving the expired chain history
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewHistoryExpiryDatabase wraps the given chain database, pruning the block
+// bodies and receipts below the cutoff block from the chain freezer in the
+// background. The pruned items are served from the archive afterwards, which
+// is closed together with the database if it's closable.
+func NewHistoryExpiryDatabase(db ethdb.Database, cutoff uint64, archive ethdb.AncientReaderOp) ethdb.Database {
+	edb := &historyExpiryDB{
+		Database: db,
+		cutoff:   cutoff,
+		archive:  archive,
+		quit:     make(chan struct{}),
+	}
+	edb.wg.Add(1)
+	go edb.loop()
+	return edb
+}
+
+// loop periodically prunes the chain history which has been moved into the
+// chain freezer since the last run.
+func (db *historyExpiryDB) loop() {
+	defer db.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := db.expire(); err != nil {
+				log.Error("Failed to expire chain history", "err", err)
+			}
+			timer.Reset(freezerRecheckInterval)
+		case <-db.quit:
+			return
+		}
+	}
+}
+
+// expire truncates the tail of the chain freezer up to the cutoff, as long as
+// the pruned items are available in the archive.
+func (db *historyExpiryDB) expire() error {
+	frozen, err := db.Database.Ancients()
+	if err != nil {
+		return err
+	}
+	tail, err := db.Database.Tail()
+	if err != nil {
+		return err
+	}
+	target := min(db.cutoff, frozen)
+	if target <= tail {
+		return nil
+	}
+	// Never prune history which can't be served afterwards
+	start, err := db.archive.Tail()
+	if err != nil {
+		return err
+	}
+	end, err := db.archive.Ancients()
+	if err != nil {
+		return err
+	}
+	if start > tail {
+		log.Warn("Chain history is not archived", "tail", tail, "archived", start)
+		return nil
+	}
+	target = min(target, end)
+	if target <= tail {
+		return nil
+	}
+	if _, err := db.Database.TruncateTail(target); err != nil {
+		return err
+	}
+	log.Info("Expired chain history", "from", tail, "to", target, "cutoff", db.cutoff)
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists,
+// either in the chain freezer or in the archive.
+func (db *historyExpiryDB) HasAncient(kind string, number uint64) (bool, error) {
+	return db.reader(db.Database).HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob from the chain freezer, or from the
+// archive if it has been expired.
+func (db *historyExpiryDB) Ancient(kind string, number uint64) ([]byte, error) {
+	return db.reader(db.Database).Ancient(kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start', the expired ones being read from the archive.
+func (db *historyExpiryDB) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return db.reader(db.Database).AncientRange(kind, start, count, maxBytes)
+}
+
+// ReadAncients runs the given read operation while ensuring that no writes take
+// place on the underlying chain freezer, falling back to the archive for the
+// expired items.
+func (db *historyExpiryDB) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return db.Database.ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		return fn(db.reader(reader))
+	})
+}
+
+// Checkpoint creates a consistent copy of the underlying database in the given
+// directory. The archive is not copied, it has to be provided to the restored
+// node again.
+func (db *historyExpiryDB) Checkpoint(dir string) error {
+	cp, ok := db.Database.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	return cp.Checkpoint(dir)
+}
+
+// Close stops the background pruning, and closes the archive along with the
+// underlying database.
+func (db *historyExpiryDB) Close() error {
+	var errs []error
+	db.closeOnce.Do(func() {
+		close(db.quit)
+		db.wg.Wait()
+
+		if closer, ok := db.archive.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := db.Database.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// reader returns an ancient reader falling back to the archive for the expired
+// items below the tail of the given one.
+func (db *historyExpiryDB) reader(reader ethdb.AncientReaderOp) *historyReader {
+	return &historyReader{AncientReaderOp: reader, archive: db.archive}
+}
+
+// historyReader is an ancient reader serving the expired chain history from
+// the archive.
+type historyReader struct {
+	ethdb.AncientReaderOp
+	archive ethdb.AncientReaderOp
+}
+
+// expired reports whether the given item has been pruned from the local store.
+func (r *historyReader) expired(kind string, number uint64) bool {
+	if !isExpirableKind(kind) {
+		return false
+	}
+	tail, err := r.AncientReaderOp.Tail()
+	return err == nil && number < tail
+}
+
+// HasAncient implements ethdb.AncientReaderOp.
+func (r *historyReader) HasAncient(kind string, number uint64) (bool, error) {
+	if r.expired(kind, number) {
+		return r.archive.HasAncient(kind, number)
+	}
+	return r.AncientReaderOp.HasAncient(kind, number)
+}
+
+// Ancient implements ethdb.AncientReaderOp.
+func (r *historyReader) Ancient(kind string, number uint64) ([]byte, error) {
+	if r.expired(kind, number) {
+		return r.archive.Ancient(kind, number)
+	}
+	return r.AncientReaderOp.Ancient(kind, number)
+}
+
+// AncientRange implements ethdb.AncientReaderOp. The expired part of the range
+// is read from the archive, and the remainder from the local store.
+func (r *historyReader) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if count == 0 || !r.expired(kind, start) {
+		return r.AncientReaderOp.AncientRange(kind, start, count, maxBytes)
+	}
+	tail, err := r.AncientReaderOp.Tail()
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.archive.AncientRange(kind, start, min(count, tail-start), maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	// Return the archived items only if the range or the size limit is
+	// exhausted, or if the archive couldn't deliver the full expired range.
+	var size uint64
+	for _, item := range items {
+		size += uint64(len(item))
+	}
+	if uint64(len(items)) == count || start+uint64(len(items)) < tail || (maxBytes != 0 && size >= maxBytes) {
+		return items, nil
+	}
+	// Continue with the local store, unless there's nothing past the tail in
+	// it, same as a range reaching beyond the head of a plain freezer
+	head, err := r.AncientReaderOp.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	if head <= tail {
+		return items, nil
+	}
+	var limit uint64
+	if maxBytes != 0 {
+		limit = maxBytes - size
+	}
+	rest, err := r.AncientReaderOp.AncientRange(kind, tail, count-uint64(len(items)), limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, rest...), nil
+}
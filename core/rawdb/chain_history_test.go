@@ -0,0 +1,141 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var errRangeFailure = errors.New("range failure")
+
+// failingRangeReader is an ancient reader failing all range reads.
+type failingRangeReader struct {
+	ethdb.AncientReaderOp
+}
+
+func (r failingRangeReader) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return nil, errRangeFailure
+}
+
+func TestHistoryExpiry(t *testing.T) {
+	var (
+		blocks   = makeTestBlocks(100, 2)
+		receipts = make([]types.Receipts, len(blocks))
+	)
+	for i := range receipts {
+		for j := 0; j < 2; j++ {
+			receipts[i] = append(receipts[i], &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(i*2 + j),
+				Logs:              []*types.Log{{Address: common.Address{byte(i)}, Data: []byte{byte(j)}}},
+			})
+		}
+	}
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	// Archive the first half of the chain, which is expired
+	archive := NewMemoryFreezer(false, chainFreezerTableConfigs)
+	if _, err := WriteAncientBlocks(archive, blocks[:50], receipts[:50], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	var (
+		wantBodies   = make([][]byte, len(blocks))
+		wantReceipts = make([][]byte, len(blocks))
+	)
+	for i, block := range blocks {
+		wantBodies[i] = ReadBodyRLP(db, block.Hash(), block.NumberU64())
+		wantReceipts[i] = ReadReceiptsRLP(db, block.Hash(), block.NumberU64())
+	}
+	edb := NewHistoryExpiryDatabase(db, 80, archive).(*historyExpiryDB)
+	defer edb.Close()
+
+	// The expiry must not go beyond the archived range
+	if err := edb.expire(); err != nil {
+		t.Fatalf("failed to expire history: %v", err)
+	}
+	if tail, _ := db.Tail(); tail != 50 {
+		t.Fatalf("unexpected tail: %d", tail)
+	}
+	for i, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		if ReadHeaderRLP(db, hash, number) == nil {
+			t.Fatalf("header %d is pruned", i)
+		}
+		if have := ReadBodyRLP(edb, hash, number); !bytes.Equal(have, wantBodies[i]) {
+			t.Fatalf("unexpected body %d: %x", i, have)
+		}
+		if have := ReadCanonicalBodyRLP(edb, number); !bytes.Equal(have, wantBodies[i]) {
+			t.Fatalf("unexpected canonical body %d: %x", i, have)
+		}
+		if have := ReadReceiptsRLP(edb, hash, number); !bytes.Equal(have, wantReceipts[i]) {
+			t.Fatalf("unexpected receipts %d: %x", i, have)
+		}
+		if i < 50 && ReadBodyRLP(db, hash, number) != nil {
+			t.Fatalf("body %d is not pruned", i)
+		}
+	}
+	// Ranges across the tail must be served from both stores
+	items, err := edb.AncientRange(ChainFreezerBodiesTable, 40, 20, 0)
+	if err != nil {
+		t.Fatalf("failed to read range: %v", err)
+	}
+	if len(items) != 20 {
+		t.Fatalf("unexpected range length: %d", len(items))
+	}
+	for i, item := range items {
+		if !bytes.Equal(item, wantBodies[40+i]) {
+			t.Fatalf("unexpected body %d in range: %x", 40+i, item)
+		}
+	}
+	// Failures reading the local part of the range must not be swallowed
+	reader := &historyReader{AncientReaderOp: failingRangeReader{db}, archive: archive}
+	if _, err := reader.AncientRange(ChainFreezerBodiesTable, 40, 20, 0); !errors.Is(err, errRangeFailure) {
+		t.Fatalf("unexpected range error: have %v, want %v", err, errRangeFailure)
+	}
+	if ok, _ := edb.HasAncient(ChainFreezerReceiptTable, 10); !ok {
+		t.Fatal("expired receipts are not available")
+	}
+	// Once the archive covers the cutoff, the history is expired up to it
+	if _, err := WriteAncientBlocks(archive, blocks[50:], receipts[50:], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	if err := edb.expire(); err != nil {
+		t.Fatalf("failed to expire history: %v", err)
+	}
+	if tail, _ := db.Tail(); tail != 80 {
+		t.Fatalf("unexpected tail: %d", tail)
+	}
+	block := blocks[60]
+	if have := ReadBody(edb, block.Hash(), block.NumberU64()); have == nil || len(have.Transactions) != 2 {
+		t.Fatal("expired body is not served")
+	}
+	if have := ReadRawReceipts(edb, block.Hash(), block.NumberU64()); len(have) != 2 {
+		t.Fatal("expired receipts are not served")
+	}
+}
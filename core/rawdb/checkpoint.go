@@ -0,0 +1,216 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// CheckpointInfoFile is the name of the file recording the metadata of a
+// checkpoint, located in the root of the checkpoint directory.
+const CheckpointInfoFile = "checkpoint.json"
+
+// CheckpointInfo is the metadata of a database checkpoint.
+type CheckpointInfo struct {
+	Time     time.Time   `json:"time"`     // Time the checkpoint was taken at
+	Number   uint64      `json:"number"`   // Number of the head block in the checkpoint
+	Hash     common.Hash `json:"hash"`     // Hash of the head block in the checkpoint
+	Ancients uint64      `json:"ancients"` // Number of blocks in the chain freezer of the checkpoint
+}
+
+// Checkpoint creates a consistent copy of the database in the given directory,
+// which must not exist yet. The key-value store is copied first, the chain
+// freezer afterwards into the default ancient location of the copy. As items
+// are only deleted from the key-value store once frozen, the copied freezer
+// covers everything the key-value store refers to, but it might be ahead of the
+// head of the copy, see clipCheckpointFreezer.
+func (frdb *freezerdb) Checkpoint(dir string) error {
+	kvdb, ok := frdb.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	freezer, ok := frdb.chainFreezer.AncientStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	if err := kvdb.Checkpoint(dir); err != nil {
+		return err
+	}
+	return freezer.Checkpoint(filepath.Join(dir, "ancient", ChainFreezerName))
+}
+
+// Checkpoint creates a consistent copy of the key-value store in the given
+// directory, which must not exist yet.
+func (db *nofreezedb) Checkpoint(dir string) error {
+	kvdb, ok := db.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	return kvdb.Checkpoint(dir)
+}
+
+// CheckpointDatabase creates a consistent copy of the chain database, which can
+// be used as the data directory of a new node. The database is copied at the
+// given path relative to the data directory, and the metadata of the copy is
+// recorded in its root. The data directory must not exist yet.
+//
+// The state history of the path scheme is kept in a separate freezer, owned by
+// the trie database while the node runs. If the trie database is open, its
+// history checkpoint function must be passed, otherwise the state freezer is
+// opened from disk, if present.
+func CheckpointDatabase(db ethdb.Database, datadir string, chaindata string, checkpointHistory func(dir string) error) (*CheckpointInfo, error) {
+	if _, err := os.Stat(datadir); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("checkpoint directory %s already exists", datadir)
+	}
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		return nil, errors.New("database doesn't support checkpoints")
+	}
+	var (
+		start = time.Now()
+		path  = filepath.Join(datadir, chaindata)
+	)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := cp.Checkpoint(path); err != nil {
+		return nil, err
+	}
+	if err := checkpointStateFreezer(db, path, checkpointHistory); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint state history: %w", err)
+	}
+	options := OpenOptions{Directory: path, ReadOnly: true}
+	if ancient := filepath.Join(path, "ancient"); common.FileExist(ancient) {
+		options.AncientsDirectory = ancient
+		if err := clipCheckpointFreezer(options); err != nil {
+			return nil, fmt.Errorf("failed to clip checkpoint freezer: %w", err)
+		}
+	}
+	// Retrieve the head from the copy, which is opened to make sure that it's
+	// usable. Note the head state might lag behind the head block, as only the
+	// persisted state is copied; the node rewinds to it when restored.
+	cdb, err := Open(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	defer cdb.Close()
+
+	info := &CheckpointInfo{Time: start.UTC()}
+	if hash := ReadHeadBlockHash(cdb); hash != (common.Hash{}) {
+		number := ReadHeaderNumber(cdb, hash)
+		if number == nil {
+			return nil, fmt.Errorf("missing head block %x in checkpoint", hash)
+		}
+		info.Number, info.Hash = *number, hash
+	}
+	if options.AncientsDirectory != "" {
+		if info.Ancients, err = cdb.Ancients(); err != nil {
+			return nil, err
+		}
+	}
+	blob, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(datadir, CheckpointInfoFile), blob, 0644); err != nil {
+		return nil, err
+	}
+	log.Info("Created database checkpoint", "path", datadir, "number", info.Number, "hash", info.Hash, "ancients", info.Ancients, "elapsed", common.PrettyDuration(time.Since(start)))
+	return info, nil
+}
+
+// checkpointStateFreezer copies the state history freezer into the default
+// ancient location of the checkpoint at path. Same as the chain freezer, it's
+// copied after the key-value store, so it might be ahead of the persisted state
+// of the copy, which the restored node truncates on startup.
+func checkpointStateFreezer(db ethdb.Database, path string, checkpointHistory func(dir string) error) error {
+	dir := filepath.Join(path, "ancient", StateFreezerName)
+	if checkpointHistory != nil {
+		return checkpointHistory(dir)
+	}
+	ancient, err := db.AncientDatadir()
+	if err != nil || !common.FileExist(filepath.Join(ancient, StateFreezerName)) {
+		return nil // No state history to copy
+	}
+	freezer, err := NewStateFreezer(ancient, true)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	cp, ok := freezer.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	return cp.Checkpoint(dir)
+}
+
+// clipCheckpointFreezer truncates the chain freezer of a checkpoint to the head
+// blocks of its key-value store. Blocks might get frozen while the database is
+// copied, so the copied freezer can be ahead of the copied key-value store, which
+// the restored node would truncate on startup anyway.
+func clipCheckpointFreezer(options OpenOptions) error {
+	db, err := Open(options)
+	if err != nil {
+		return err
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		db.Close()
+		return err
+	}
+	// Same as the node on startup, keep everything up to the lowest of the head
+	// block and the snap sync head block
+	var (
+		limit = frozen
+		heads = []common.Hash{ReadHeadBlockHash(db), ReadHeadFastBlockHash(db)}
+	)
+	for i, hash := range heads {
+		number := ReadHeaderNumber(db, hash)
+		if number == nil || (i == 0 && *number == 0) {
+			continue // Missing head or the genesis head block, nothing to clip to
+		}
+		if *number+1 < limit {
+			limit = *number + 1
+		}
+	}
+	db.Close()
+
+	if limit == frozen {
+		return nil
+	}
+	freezer, err := newChainFreezer(resolveChainFreezerDir(options.AncientsDirectory), "", false)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	if _, err := freezer.TruncateHead(limit); err != nil {
+		return err
+	}
+	log.Info("Clipped checkpoint freezer to head", "ancients", frozen, "limit", limit)
+	return nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestFreezerCheckpoint(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		tables = map[string]freezerTableConfig{"a": {noSnappy: true}, "b": {}}
+	)
+	// Use tiny data files, so that most of them are sealed and hard-linked
+	f, err := NewFreezer(filepath.Join(dir, "freezer"), "", false, 64, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { f.Close() }()
+
+	write := func(from, to int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("a", uint64(i), getChunk(20, i)); err != nil {
+					return err
+				}
+				if err := op.AppendRaw("b", uint64(i), getChunk(20, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(0, 50)
+	if err := f.Checkpoint(filepath.Join(dir, "checkpoint")); err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	// Writes after the checkpoint must not be included in it
+	write(50, 60)
+
+	cp, err := NewFreezer(filepath.Join(dir, "checkpoint"), "", false, 64, tables)
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+	defer cp.Close()
+
+	if frozen, _ := cp.Ancients(); frozen != 50 {
+		t.Fatalf("unexpected items in checkpoint: %d", frozen)
+	}
+	for i := 0; i < 50; i++ {
+		for _, kind := range []string{"a", "b"} {
+			blob, err := cp.Ancient(kind, uint64(i))
+			if err != nil || !bytes.Equal(blob, getChunk(20, i)) {
+				t.Fatalf("unexpected item %d in table %s: %x, %v", i, kind, blob, err)
+			}
+		}
+	}
+	// The checkpoint must remain usable independently
+	_, err = cp.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		if err := op.AppendRaw("a", 50, []byte{0x1}); err != nil {
+			return err
+		}
+		return op.AppendRaw("b", 50, []byte{0x1})
+	})
+	if err != nil {
+		t.Fatalf("failed to write into checkpoint: %v", err)
+	}
+	if blob, _ := f.Ancient("a", 50); !bytes.Equal(blob, getChunk(20, 50)) {
+		t.Fatalf("checkpoint write affected the original freezer: %x", blob)
+	}
+	// Truncating the checkpoint back into the linked data files and refilling it
+	// must not affect the original freezer
+	if _, err := cp.TruncateHead(5); err != nil {
+		t.Fatalf("failed to truncate checkpoint: %v", err)
+	}
+	_, err = cp.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 5; i < 30; i++ {
+			if err := op.AppendRaw("a", uint64(i), []byte{0x2}); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", uint64(i), []byte{0x2}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to refill checkpoint: %v", err)
+	}
+	if frozen, _ := f.Ancients(); frozen != 60 {
+		t.Fatalf("unexpected items in original freezer: %d", frozen)
+	}
+	for i := 0; i < 60; i++ {
+		for _, kind := range []string{"a", "b"} {
+			blob, err := f.Ancient(kind, uint64(i))
+			if err != nil || !bytes.Equal(blob, getChunk(20, i)) {
+				t.Fatalf("checkpoint truncation affected item %d in table %s: %x, %v", i, kind, blob, err)
+			}
+		}
+	}
+	// The original freezer must survive a restart as well, repairing the files
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = NewFreezer(filepath.Join(dir, "freezer"), "", false, 64, tables); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	if frozen, _ := f.Ancients(); frozen != 60 {
+		t.Fatalf("unexpected items in reopened freezer: %d", frozen)
+	}
+	for i := 0; i < 60; i++ {
+		if blob, err := f.Ancient("a", uint64(i)); err != nil || !bytes.Equal(blob, getChunk(20, i)) {
+			t.Fatalf("unexpected item %d in reopened freezer: %x, %v", i, blob, err)
+		}
+	}
+}
+
+func TestCheckpointDatabase(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(OpenOptions{
+		Type:              dbPebble,
+		Directory:         filepath.Join(dir, "chaindata"),
+		AncientsDirectory: filepath.Join(dir, "chaindata", "ancient"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		blocks   = makeTestBlocks(20, 1)
+		receipts = make([]types.Receipts, len(blocks))
+	)
+	if _, err := WriteAncientBlocks(db, blocks[:10], receipts[:10], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[10:] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	head := blocks[len(blocks)-1]
+	WriteHeadBlockHash(db, head.Hash())
+
+	checkpoint := filepath.Join(dir, "checkpoint")
+	info, err := CheckpointDatabase(db, checkpoint, filepath.Join("geth", "chaindata"), nil)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	if info.Number != head.NumberU64() || info.Hash != head.Hash() || info.Ancients != 10 {
+		t.Fatalf("unexpected checkpoint info: %+v", info)
+	}
+	if _, err := CheckpointDatabase(db, checkpoint, filepath.Join("geth", "chaindata"), nil); err == nil {
+		t.Fatal("overwrote existing checkpoint")
+	}
+	// The metadata must be recorded in the root of the checkpoint
+	blob, err := os.ReadFile(filepath.Join(checkpoint, CheckpointInfoFile))
+	if err != nil {
+		t.Fatalf("failed to read checkpoint metadata: %v", err)
+	}
+	var stored CheckpointInfo
+	if err := json.Unmarshal(blob, &stored); err != nil {
+		t.Fatalf("failed to decode checkpoint metadata: %v", err)
+	}
+	if stored.Number != info.Number || stored.Hash != info.Hash || stored.Ancients != info.Ancients {
+		t.Fatalf("unexpected stored checkpoint metadata: %+v", stored)
+	}
+	// The copy must be openable in the default layout of a datadir
+	path := filepath.Join(checkpoint, "geth", "chaindata")
+	cdb, err := Open(OpenOptions{Directory: path, AncientsDirectory: filepath.Join(path, "ancient")})
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+	defer cdb.Close()
+
+	for _, block := range blocks {
+		if ReadCanonicalHash(cdb, block.NumberU64()) != block.Hash() {
+			t.Fatalf("missing canonical hash %d", block.NumberU64())
+		}
+		if ReadBodyRLP(cdb, block.Hash(), block.NumberU64()) == nil {
+			t.Fatalf("missing body %d", block.NumberU64())
+		}
+	}
+	if frozen, _ := cdb.Ancients(); frozen != 10 {
+		t.Fatalf("unexpected ancients in checkpoint: %d", frozen)
+	}
+}
+
+// Tests that the freezer of a checkpoint is clipped to the head of its key-value
+// store, without affecting the original freezer.
+func TestCheckpointDatabaseClip(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(OpenOptions{
+		Type:              dbPebble,
+		Directory:         filepath.Join(dir, "chaindata"),
+		AncientsDirectory: filepath.Join(dir, "chaindata", "ancient"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		blocks   = makeTestBlocks(20, 1)
+		receipts = make([]types.Receipts, len(blocks))
+	)
+	if _, err := WriteAncientBlocks(db, blocks[:10], receipts[:10], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[:10] {
+		WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+	}
+	for _, block := range blocks[10:] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	// Simulate blocks frozen beyond the head of the key-value store
+	head := blocks[5]
+	WriteHeadBlockHash(db, head.Hash())
+
+	checkpoint := filepath.Join(dir, "checkpoint")
+	info, err := CheckpointDatabase(db, checkpoint, filepath.Join("geth", "chaindata"), nil)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	if info.Number != head.NumberU64() || info.Ancients != head.NumberU64()+1 {
+		t.Fatalf("unexpected checkpoint info: %+v", info)
+	}
+	if frozen, _ := db.Ancients(); frozen != 10 {
+		t.Fatalf("unexpected ancients in original database: %d", frozen)
+	}
+	for _, block := range blocks[:10] {
+		if ReadBodyRLP(db, block.Hash(), block.NumberU64()) == nil {
+			t.Fatalf("missing body %d in original database", block.NumberU64())
+		}
+	}
+}
+
+// Tests that the state history freezer is copied along with the database, both
+// from disk and through the trie database holding it open.
+func TestCheckpointStateFreezer(t *testing.T) {
+	dir := t.TempDir()
+	ancient := filepath.Join(dir, "chaindata", "ancient")
+	db, err := Open(OpenOptions{
+		Type:              dbPebble,
+		Directory:         filepath.Join(dir, "chaindata"),
+		AncientsDirectory: ancient,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	freezer, err := NewStateFreezer(ancient, false)
+	if err != nil {
+		t.Fatalf("failed to open state freezer: %v", err)
+	}
+	_, err = freezer.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for kind := range stateFreezerTableConfigs {
+			if err := op.AppendRaw(kind, 0, []byte(kind)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to write state history: %v", err)
+	}
+	check := func(checkpoint string) {
+		t.Helper()
+
+		copied, err := NewStateFreezer(filepath.Join(checkpoint, "geth", "chaindata", "ancient"), true)
+		if err != nil {
+			t.Fatalf("failed to open copied state freezer: %v", err)
+		}
+		defer copied.Close()
+
+		if blob, _ := copied.Ancient(stateHistoryMeta, 0); !bytes.Equal(blob, []byte(stateHistoryMeta)) {
+			t.Fatalf("state history mismatch: have %x", blob)
+		}
+	}
+	// While held open, the state freezer must be copied through its owner
+	history := func(dir string) error { return freezer.(ethdb.Checkpointer).Checkpoint(dir) }
+	live := filepath.Join(dir, "live")
+	if _, err := CheckpointDatabase(db, live, filepath.Join("geth", "chaindata"), history); err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	check(live)
+
+	// Once closed, the state freezer is copied from disk
+	freezer.Close()
+	offline := filepath.Join(dir, "offline")
+	if _, err := CheckpointDatabase(db, offline, filepath.Join("geth", "chaindata"), nil); err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	check(offline)
+}
@@ -79,9 +79,8 @@ type Freezer struct {
 // NewFreezer creates a freezer instance for maintaining immutable ordered
 // data according to the given parameters.
 //
-// The 'tables' argument defines the data tables. If the value of a map
-// entry is true, snappy compression is disabled for the table.
-func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+// The 'tables' argument defines the data tables along with their settings.
+func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -119,8 +118,8 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	}
 
 	// Create the tables.
-	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+	for name, config := range tables {
+		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, config, readonly)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -295,6 +294,7 @@ func (f *Freezer) TruncateHead(items uint64) (uint64, error) {
 }
 
 // TruncateTail discards any recent data below the provided threshold number.
+// Only the prunable tables are truncated, the others retain all their items.
 func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
 	if f.readonly {
 		return 0, errReadOnly
@@ -307,6 +307,9 @@ func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
 		return old, nil
 	}
 	for _, table := range f.tables {
+		if !table.config.prunable {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return 0, err
 		}
@@ -329,31 +332,64 @@ func (f *Freezer) Sync() error {
 	return nil
 }
 
-// validate checks that every table has the same boundary.
-// Used instead of `repair` in readonly mode.
+// Checkpoint creates a consistent copy of the freezer in the given directory,
+// which must not exist yet. The freezer is synced first, and no writes happen
+// while the tables are copied.
+func (f *Freezer) Checkpoint(dir string) error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	if !f.readonly {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, table := range f.tables {
+		if err := table.checkpoint(dir); err != nil {
+			return fmt.Errorf("failed to checkpoint table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that every table has the same boundary, the tail only being
+// shared by the prunable tables. Used instead of `repair` in readonly mode.
 func (f *Freezer) validate() error {
 	if len(f.tables) == 0 {
 		return nil
 	}
 	var (
-		head uint64
-		tail uint64
-		name string
+		head     uint64
+		tail     uint64
+		name     string
+		tailName string
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
 		head = table.items.Load()
-		tail = table.itemHidden.Load()
 		name = kind
 		break
 	}
+	for kind, table := range f.tables {
+		if table.config.prunable {
+			tail = table.itemHidden.Load()
+			tailName = kind
+			break
+		}
+	}
 	// Now check every table against those boundaries.
 	for kind, table := range f.tables {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
-		if tail != table.itemHidden.Load() {
-			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
+		if table.config.prunable && tail != table.itemHidden.Load() {
+			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, tailName, table.itemHidden.Load(), tail)
 		}
 	}
 	f.frozen.Store(head)
@@ -361,7 +397,8 @@ func (f *Freezer) validate() error {
 	return nil
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length, and the prunable ones
+// to the same tail.
 func (f *Freezer) repair() error {
 	var (
 		head = uint64(math.MaxUint64)
@@ -372,6 +409,9 @@ func (f *Freezer) repair() error {
 		if head > items {
 			head = items
 		}
+		if !table.config.prunable {
+			continue
+		}
 		hidden := table.itemHidden.Load()
 		if hidden > tail {
 			tail = hidden
@@ -381,6 +421,9 @@ func (f *Freezer) repair() error {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !table.config.prunable {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -442,7 +485,7 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+	newTable, err := newFreezerTable(migrationPath, kind, table.config, false)
 	if err != nil {
 		return err
 	}
@@ -84,7 +84,7 @@ func (batch *freezerBatch) commit() (item uint64, writeSize int64, err error) {
 type freezerTableBatch struct {
 	t *freezerTable
 
-	sb          *snappyBuffer
+	comp        itemCompressor
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,8 +95,8 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
-		batch.sb = new(snappyBuffer)
+	if t.compressor != nil {
+		batch.comp = t.compressor.newCompressor()
 	}
 	batch.reset()
 	return batch
@@ -124,8 +124,8 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 		return err
 	}
 	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
+	if batch.comp != nil {
+		encItem = batch.comp.compress(encItem)
 	}
 	return batch.appendItem(encItem)
 }
@@ -139,8 +139,8 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 	}
 
 	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	if batch.comp != nil {
+		encItem = batch.comp.compress(blob)
 	}
 	return batch.appendItem(encItem)
 }
@@ -0,0 +1,204 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// freezerCodec is the compression algorithm of the items in a freezer table.
+type freezerCodec uint8
+
+const (
+	// codecDefault compresses the items with snappy, or leaves them
+	// uncompressed if the table is configured with noSnappy.
+	codecDefault freezerCodec = iota
+
+	// codecZstd compresses the items with zstd, optionally using a
+	// dictionary trained from the items of the table.
+	codecZstd
+)
+
+// String implements fmt.Stringer.
+func (c freezerCodec) String() string {
+	switch c {
+	case codecDefault:
+		return "snappy"
+	case codecZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// parseFreezerCodec parses the codec name.
+func parseFreezerCodec(name string) (freezerCodec, error) {
+	switch name {
+	case "snappy":
+		return codecDefault, nil
+	case "zstd":
+		return codecZstd, nil
+	default:
+		return 0, fmt.Errorf("unknown freezer codec %q, supported ones: snappy, zstd", name)
+	}
+}
+
+// itemCompressor compresses the items appended to a freezer table. The slice
+// returned is only valid until the next call.
+type itemCompressor interface {
+	compress(data []byte) []byte
+}
+
+// itemCodec compresses and decompresses the items of a freezer table. The
+// decompression is safe for concurrent use.
+type itemCodec interface {
+	// newCompressor creates a compressor for appending items, which can't be
+	// used concurrently.
+	newCompressor() itemCompressor
+
+	// decompress decompresses the item.
+	decompress(data []byte) ([]byte, error)
+
+	// decompressedLen returns the length of the item once decompressed.
+	decompressedLen(data []byte) (int, error)
+
+	// close releases the resources held by the codec.
+	close()
+}
+
+// newItemCodec creates the item codec for a table with the given settings and
+// the codec recorded in its metadata. Nil is returned for uncompressed tables.
+func newItemCodec(config freezerTableConfig, codec freezerCodec, dict []byte) (itemCodec, error) {
+	switch codec {
+	case codecDefault:
+		if config.noSnappy {
+			return nil, nil
+		}
+		return snappyCodec{}, nil
+	case codecZstd:
+		if config.noSnappy {
+			return nil, errors.New("zstd codec recorded for uncompressed table")
+		}
+		return newZstdCodec(dict)
+	default:
+		return nil, fmt.Errorf("unknown freezer codec %d", codec)
+	}
+}
+
+// snappyCodec compresses the items with snappy in block format.
+type snappyCodec struct{}
+
+func (snappyCodec) newCompressor() itemCompressor { return new(snappyBuffer) }
+
+func (snappyCodec) decompress(data []byte) ([]byte, error) { return snappy.Decode(nil, data) }
+
+func (snappyCodec) decompressedLen(data []byte) (int, error) { return snappy.DecodedLen(data) }
+
+func (snappyCodec) close() {}
+
+// zstdCodec compresses the items with zstd, each item being a standalone
+// frame.
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// newZstdCodec creates a zstd codec with the optional dictionary.
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	// The items are encoded as single segment frames, so that their content
+	// size is always recorded in the frame header. The checksum is omitted,
+	// it's overhead on small items and the frames are already verified by
+	// the decompression.
+	var (
+		eopts = []zstd.EOption{zstd.WithEncoderConcurrency(1), zstd.WithSingleSegment(true), zstd.WithEncoderCRC(false)}
+		dopts = []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+	)
+	if len(dict) > 0 {
+		eopts = append(eopts, zstd.WithEncoderDict(dict))
+		dopts = append(dopts, zstd.WithDecoderDicts(dict))
+	}
+	encoder, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (z *zstdCodec) newCompressor() itemCompressor {
+	return &zstdBuffer{encoder: z.encoder}
+}
+
+func (z *zstdCodec) decompress(data []byte) ([]byte, error) {
+	// Empty items are stored without a frame.
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+	return z.decoder.DecodeAll(data, nil)
+}
+
+// decompressedLen returns the length of the item as recorded in the header
+// of its frame.
+func (z *zstdCodec) decompressedLen(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	var header zstd.Header
+	if err := header.Decode(data); err != nil {
+		return 0, err
+	}
+	if !header.HasFCS {
+		return 0, errors.New("missing zstd frame content size")
+	}
+	return int(header.FrameContentSize), nil
+}
+
+func (z *zstdCodec) close() {
+	z.encoder.Close()
+	z.decoder.Close()
+}
+
+// zstdBuffer writes zstd frames, and can be reused.
+type zstdBuffer struct {
+	encoder *zstd.Encoder
+	dst     []byte
+}
+
+// compress zstd-compresses the data.
+func (z *zstdBuffer) compress(data []byte) []byte {
+	z.dst = z.encoder.EncodeAll(data, z.dst[:0])
+	return z.dst
+}
+
+// trainZstdDict trains a zstd dictionary of at most the given size from the
+// sample items.
+func trainZstdDict(samples [][]byte, size int) ([]byte, error) {
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize:    size,
+		HashBytes:      6,
+		ZstdDictCompat: true,
+	})
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// makeCodecTestItem creates a repetitive item, resembling the receipts.
+func makeCodecTestItem(i int) []byte {
+	var logs [][]byte
+	for j := 0; j < i%8; j++ {
+		logs = append(logs, []byte(fmt.Sprintf("Transfer(address,address,uint256) %d %d", i, j)))
+	}
+	blob, _ := rlp.EncodeToBytes(logs)
+	return blob
+}
+
+func TestFreezerTableCodecs(t *testing.T) {
+	samples := make([][]byte, 256)
+	for i := range samples {
+		samples[i] = makeCodecTestItem(i)
+	}
+	dict, err := trainZstdDict(samples, 2048)
+	if err != nil {
+		t.Fatalf("failed to train dictionary: %v", err)
+	}
+	tests := []struct {
+		codec freezerCodec
+		dict  []byte
+	}{
+		{codecDefault, nil},
+		{codecZstd, nil},
+		{codecZstd, dict},
+	}
+	for _, test := range tests {
+		fname := fmt.Sprintf("codec-%v-%d", test.codec, len(test.dict))
+		dir := t.TempDir()
+
+		f, err := newTable(dir, fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 200, freezerTableConfig{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.setCodec(test.codec, test.dict); err != nil {
+			t.Fatalf("%s: failed to set codec: %v", fname, err)
+		}
+		batch := f.newBatch()
+		for i := 0; i < 100; i++ {
+			if err := batch.AppendRaw(uint64(i), makeCodecTestItem(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Empty items must be supported too
+		if err := batch.AppendRaw(100, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.setCodec(codecDefault, nil); err == nil {
+			t.Fatalf("%s: changed codec of non-empty table", fname)
+		}
+		f.Close()
+
+		// Reopen the table, the codec must be loaded from the metadata
+		f, err = newTable(dir, fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 200, freezerTableConfig{}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.codec != test.codec || !bytes.Equal(f.dict, test.dict) {
+			t.Fatalf("%s: unexpected codec loaded: %v, %d bytes dict", fname, f.codec, len(f.dict))
+		}
+		for i := 0; i < 100; i++ {
+			checkRetrieve(t, f, map[uint64][]byte{uint64(i): makeCodecTestItem(i)})
+		}
+		if item, err := f.Retrieve(100); err != nil || len(item) != 0 {
+			t.Fatalf("%s: unexpected empty item: %x, %v", fname, item, err)
+		}
+		items, err := f.RetrieveItems(0, 101, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 101 {
+			t.Fatalf("%s: unexpected number of items: %d", fname, len(items))
+		}
+		for i := 0; i < 100; i++ {
+			if !bytes.Equal(items[i], makeCodecTestItem(i)) {
+				t.Fatalf("%s: unexpected item %d in range: %x", fname, i, items[i])
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestFreezerTableCodecMismatch(t *testing.T) {
+	dir := t.TempDir()
+	f, err := newFreezerTable(dir, "raw", freezerTableConfig{noSnappy: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := f.setCodec(codecZstd, nil); err == nil {
+		t.Fatal("set zstd codec on uncompressed table")
+	}
+}
+
+// makeReencodeTestFreezer creates a chain freezer in the directory with a
+// compressed receipt table of 1000 items, the first 100 of them deleted, and
+// returns the size of the table.
+func makeReencodeTestFreezer(t *testing.T, dir string, tables map[string]freezerTableConfig) uint64 {
+	f, err := NewFreezer(resolveChainFreezerDir(dir), "", false, 2048, tables)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer f.Close()
+
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 1000; i++ {
+			if err := op.AppendRaw(ChainFreezerReceiptTable, uint64(i), makeCodecTestItem(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+	// Delete some items at the tail, leaving some of them hidden in the
+	// remaining data files.
+	if _, err := f.TruncateTail(100); err != nil {
+		t.Fatalf("failed to truncate tail: %v", err)
+	}
+	size, _ := f.AncientSize(ChainFreezerReceiptTable)
+	return size
+}
+
+func TestReencodeFreezerTable(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		path   = resolveChainFreezerDir(dir)
+		tables = map[string]freezerTableConfig{ChainFreezerReceiptTable: {prunable: true}}
+		before = makeReencodeTestFreezer(t, dir, tables)
+	)
+
+	check := func(codec freezerCodec, dict bool) {
+		t.Helper()
+
+		f, err := NewFreezer(path, "", false, 2048, tables)
+		if err != nil {
+			t.Fatalf("failed to open freezer: %v", err)
+		}
+		defer f.Close()
+
+		table := f.tables[ChainFreezerReceiptTable]
+		if table.codec != codec || (len(table.dict) > 0) != dict {
+			t.Fatalf("unexpected codec: %v, %d bytes dict", table.codec, len(table.dict))
+		}
+		if tail, _ := f.Tail(); tail != 100 {
+			t.Fatalf("unexpected tail: %d", tail)
+		}
+		if head, _ := f.Ancients(); head != 1000 {
+			t.Fatalf("unexpected head: %d", head)
+		}
+		if _, err := f.Ancient(ChainFreezerReceiptTable, 99); err == nil {
+			t.Fatal("retrieved deleted item")
+		}
+		for i := 100; i < 1000; i++ {
+			item, err := f.Ancient(ChainFreezerReceiptTable, uint64(i))
+			if err != nil || !bytes.Equal(item, makeCodecTestItem(i)) {
+				t.Fatalf("unexpected item %d: %x, %v", i, item, err)
+			}
+		}
+	}
+	if err := ReencodeFreezerTable(dir, ChainFreezerName, ChainFreezerReceiptTable, "zstd", 1024); err != nil {
+		t.Fatalf("failed to re-encode table: %v", err)
+	}
+	check(codecZstd, true)
+
+	if _, err := os.Stat(filepath.Join(path, "reencode")); !os.IsNotExist(err) {
+		t.Fatalf("temporary directory is not removed: %v", err)
+	}
+	f, err := NewFreezer(path, "", true, 2048, tables)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	after, _ := f.AncientSize(ChainFreezerReceiptTable)
+	f.Close()
+	if after >= before {
+		t.Fatalf("re-encoded table is not smaller, before: %d, after: %d", before, after)
+	}
+	// Re-encode back to the default codec
+	if err := ReencodeFreezerTable(dir, ChainFreezerName, ChainFreezerReceiptTable, "snappy", 0); err != nil {
+		t.Fatalf("failed to re-encode table: %v", err)
+	}
+	check(codecDefault, false)
+
+	if err := ReencodeFreezerTable(dir, ChainFreezerName, ChainFreezerReceiptTable, "lz4", 0); err == nil {
+		t.Fatal("re-encoded table with unknown codec")
+	}
+}
+
+// Tests that an interrupted replacement of the table files is finished by
+// running the re-encoding again.
+func TestReencodeFreezerTableInterrupted(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		path   = resolveChainFreezerDir(dir)
+		tables = map[string]freezerTableConfig{ChainFreezerReceiptTable: {prunable: true}}
+	)
+	makeReencodeTestFreezer(t, dir, tables)
+
+	// Re-encode the same table elsewhere, and set it up as if the replacement
+	// was interrupted after moving the index file over
+	other := t.TempDir()
+	makeReencodeTestFreezer(t, other, tables)
+	if err := ReencodeFreezerTable(other, ChainFreezerName, ChainFreezerReceiptTable, "zstd", 0); err != nil {
+		t.Fatalf("failed to re-encode table: %v", err)
+	}
+	reencodePath := filepath.Join(path, "reencode")
+	if err := os.MkdirAll(reencodePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(resolveChainFreezerDir(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{ChainFreezerReceiptTable}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ChainFreezerReceiptTable+".") {
+			continue
+		}
+		blob, err := os.ReadFile(filepath.Join(resolveChainFreezerDir(other), entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(reencodePath, entry.Name())
+		if strings.HasSuffix(entry.Name(), "idx") {
+			dst = filepath.Join(path, entry.Name())
+		}
+		if err := os.WriteFile(dst, blob, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, entry.Name())
+	}
+	if err := os.WriteFile(filepath.Join(reencodePath, reencodeMarker), []byte(strings.Join(files, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Re-encoding another table must not touch the pending replacement
+	if err := ReencodeFreezerTable(dir, ChainFreezerName, ChainFreezerBodiesTable, "zstd", 0); err == nil || !strings.Contains(err.Error(), "another table") {
+		t.Fatalf("re-encoded table with pending replacement of another one: %v", err)
+	}
+	if err := ReencodeFreezerTable(dir, ChainFreezerName, ChainFreezerReceiptTable, "zstd", 0); err != nil {
+		t.Fatalf("failed to finish replacement: %v", err)
+	}
+	if _, err := os.Stat(reencodePath); !os.IsNotExist(err) {
+		t.Fatalf("temporary directory is not removed: %v", err)
+	}
+	f, err := NewFreezer(path, "", true, 2048, tables)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	defer f.Close()
+
+	if codec := f.tables[ChainFreezerReceiptTable].codec; codec != codecZstd {
+		t.Fatalf("unexpected codec: %v", codec)
+	}
+	for i := 100; i < 1000; i++ {
+		item, err := f.Ancient(ChainFreezerReceiptTable, uint64(i))
+		if err != nil || !bytes.Equal(item, makeCodecTestItem(i)) {
+			t.Fatalf("unexpected item %d: %x, %v", i, item, err)
+		}
+	}
+}
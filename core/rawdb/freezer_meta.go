@@ -24,7 +24,10 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-const freezerVersion = 1 // The initial version tag of freezer table metadata
+const (
+	freezerVersion      = 1 // The initial version tag of freezer table metadata
+	freezerCodecVersion = 2 // The version tag of freezer table metadata with item codec
+)
 
 // freezerTableMeta wraps all the metadata of the freezer table.
 type freezerTableMeta struct {
@@ -36,13 +39,29 @@ type freezerTableMeta struct {
 	// plus the number of items hidden in the table, so it should never
 	// be lower than the "actual tail".
 	VirtualTail uint64
+
+	// Codec is the compression algorithm of the items in the table. It's
+	// only recorded since version 2, the tables of the initial version are
+	// snappy compressed, or not compressed at all if configured so.
+	Codec freezerCodec `rlp:"optional"`
+
+	// Dict is the dictionary the items are compressed with, in the format
+	// of the codec. It's empty if no dictionary is used.
+	Dict []byte `rlp:"optional"`
 }
 
-// newMetadata initializes the metadata object with the given virtual tail.
-func newMetadata(tail uint64) *freezerTableMeta {
+// newMetadata initializes the metadata object with the given virtual tail
+// and item codec.
+func newMetadata(tail uint64, codec freezerCodec, dict []byte) *freezerTableMeta {
+	version := uint16(freezerVersion)
+	if codec != codecDefault {
+		version = freezerCodecVersion
+	}
 	return &freezerTableMeta{
-		Version:     freezerVersion,
+		Version:     version,
 		VirtualTail: tail,
+		Codec:       codec,
+		Dict:        dict,
 	}
 }
 
@@ -85,7 +104,7 @@ func loadMetadata(file *os.File, tail uint64) (*freezerTableMeta, error) {
 	// In both cases, write the meta into the file with the actual tail
 	// as the virtual tail.
 	if stat.Size() == 0 {
-		m := newMetadata(tail)
+		m := newMetadata(tail, codecDefault, nil)
 		if err := writeMetadata(file, m); err != nil {
 			return nil, err
 		}
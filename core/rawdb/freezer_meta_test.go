@@ -17,8 +17,11 @@
 package rawdb
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func TestReadWriteFreezerTableMeta(t *testing.T) {
@@ -26,7 +29,7 @@ func TestReadWriteFreezerTableMeta(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create file %v", err)
 	}
-	err = writeMetadata(f, newMetadata(100))
+	err = writeMetadata(f, newMetadata(100, codecDefault, nil))
 	if err != nil {
 		t.Fatalf("Failed to write metadata %v", err)
 	}
@@ -58,3 +61,44 @@ func TestInitializeFreezerTableMeta(t *testing.T) {
 		t.Fatalf("Unexpected virtual tail field")
 	}
 }
+
+func TestFreezerTableMetaCodec(t *testing.T) {
+	f, err := os.CreateTemp(os.TempDir(), "*")
+	if err != nil {
+		t.Fatalf("Failed to create file %v", err)
+	}
+	// The legacy metadata must be decoded with the default codec
+	if err := rlp.Encode(f, []uint64{freezerVersion, 100}); err != nil {
+		t.Fatalf("Failed to write legacy metadata %v", err)
+	}
+	meta, err := readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Codec != codecDefault || meta.Dict != nil {
+		t.Fatalf("Unexpected codec fields")
+	}
+	err = writeMetadata(f, newMetadata(100, codecZstd, []byte{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err = readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Version != freezerCodecVersion || meta.Codec != codecZstd || !bytes.Equal(meta.Dict, []byte{1, 2, 3}) {
+		t.Fatalf("Unexpected codec fields")
+	}
+	// Overwriting with shorter metadata must not leave the stale codec behind
+	err = writeMetadata(f, newMetadata(200, codecDefault, nil))
+	if err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err = readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Version != freezerVersion || meta.VirtualTail != 200 || meta.Codec != codecDefault {
+		t.Fatalf("Unexpected metadata")
+	}
+}
@@ -48,7 +48,7 @@ type resettableFreezer struct {
 //
 // The reset function will delete directory atomically and re-create the
 // freezer from scratch.
-func newResettableFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*resettableFreezer, error) {
+func newResettableFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig) (*resettableFreezer, error) {
 	if err := cleanup(datadir); err != nil {
 		return nil, err
 	}
@@ -189,6 +189,15 @@ func (f *resettableFreezer) TruncateTail(tail uint64) (uint64, error) {
 	return f.freezer.TruncateTail(tail)
 }
 
+// Checkpoint creates a consistent copy of the freezer in the given directory,
+// which must not exist yet.
+func (f *resettableFreezer) Checkpoint(dir string) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.freezer.Checkpoint(dir)
+}
+
 // Sync flushes all data tables to disk.
 func (f *resettableFreezer) Sync() error {
 	f.lock.RLock()
@@ -30,7 +30,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -98,11 +97,14 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
-	readonly      bool
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	config      freezerTableConfig // if noSnappy is set, disables snappy compression. Note: does not work retroactively
+	codec       freezerCodec       // Compression algorithm of the items, as recorded in the metadata
+	dict        []byte             // Compression dictionary of the items, as recorded in the metadata
+	compressor  itemCodec          // Codec of the items, nil if the items are not compressed
+	readonly    bool
+	maxFileSize uint32 // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -121,20 +123,20 @@ type freezerTable struct {
 }
 
 // newFreezerTable opens the given path as a freezer table.
-func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerTable, error) {
-	return newTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, disableSnappy, readonly)
+func newFreezerTable(path, name string, config freezerTableConfig, readonly bool) (*freezerTable, error) {
+	return newTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, config, readonly)
 }
 
 // newTable opens a freezer table, creating the data and index files if they are
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
-func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, config freezerTableConfig, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
 	var idxName string
-	if noCompression {
+	if config.noSnappy {
 		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
 	} else {
 		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
@@ -166,18 +168,18 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         index,
-		meta:          meta,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		readonly:      readonly,
-		maxFileSize:   maxFilesize,
+		index:       index,
+		meta:        meta,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		config:      config,
+		readonly:    readonly,
+		maxFileSize: maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -252,6 +254,11 @@ func (t *freezerTable) repair() error {
 	}
 	t.itemHidden.Store(meta.VirtualTail)
 
+	// Set up the item codec recorded in the metadata
+	if err := t.setupCodec(meta.Codec, meta.Dict); err != nil {
+		return err
+	}
+
 	// Read the last index, use the default value in case the freezer is empty
 	if offsetsSize == indexEntrySize {
 		lastIndex = indexEntry{filenum: t.tailId, offset: 0}
@@ -517,7 +524,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	}
 	// Update the virtual tail marker and hidden these entries in table.
 	t.itemHidden.Store(items)
-	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+	if err := writeMetadata(t.meta, newMetadata(items, t.codec, t.dict)); err != nil {
 		return err
 	}
 	// Hidden items still fall in the current tail file, no data file
@@ -625,18 +632,95 @@ func (t *freezerTable) Close() error {
 	t.meta = nil
 	t.head = nil
 
+	if t.compressor != nil {
+		t.compressor.close()
+		t.compressor = nil
+	}
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
 	}
 	return nil
 }
 
+// setupCodec creates the item codec of the table. It assumes that the
+// write-lock is held by the caller.
+func (t *freezerTable) setupCodec(codec freezerCodec, dict []byte) error {
+	compressor, err := newItemCodec(t.config, codec, dict)
+	if err != nil {
+		return err
+	}
+	if t.compressor != nil {
+		t.compressor.close()
+	}
+	t.codec, t.dict, t.compressor = codec, dict, compressor
+	return nil
+}
+
+// setCodec changes the compression of the table and records it in the
+// metadata. As the items are not re-encoded, it's only allowed on an empty
+// table.
+func (t *freezerTable) setCodec(codec freezerCodec, dict []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if t.items.Load() != t.itemOffset.Load() {
+		return errors.New("codec can only be changed on an empty table")
+	}
+	if err := t.setupCodec(codec, dict); err != nil {
+		return err
+	}
+	if err := writeMetadata(t.meta, newMetadata(t.itemHidden.Load(), codec, dict)); err != nil {
+		return err
+	}
+	return t.meta.Sync()
+}
+
+// checkpoint copies the table with the items stored at the moment into the
+// given directory. The sealed data files are hard-linked if possible, whereas
+// the head data file, the index and the metadata are copied as they are still
+// mutated.
+//
+// The linked files are shared with the live table until either side modifies
+// them, e.g. truncating its head back into them, which unshares them first.
+func (t *freezerTable) checkpoint(dir string) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	for num, f := range t.files {
+		dst := filepath.Join(dir, filepath.Base(f.Name()))
+		if num == t.headId {
+			if err := copyFilePrefix(f.Name(), dst, t.headBytes); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := linkOrCopyFile(f.Name(), dst); err != nil {
+			return err
+		}
+	}
+	size := int64(t.items.Load()-t.itemOffset.Load()+1) * indexEntrySize
+	if err := copyFilePrefix(t.index.Name(), filepath.Join(dir, filepath.Base(t.index.Name())), size); err != nil {
+		return err
+	}
+	stat, err := t.meta.Stat()
+	if err != nil {
+		return err
+	}
+	return copyFilePrefix(t.meta.Name(), filepath.Join(dir, filepath.Base(t.meta.Name())), stat.Size())
+}
+
 // openFile assumes that the write-lock is held by the caller
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
 		var name string
-		if t.noCompression {
+		if t.config.noSnappy {
 			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
 		} else {
 			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
@@ -752,14 +836,14 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
 		decompressedSize := diskSize
-		if !t.noCompression {
-			decompressedSize, _ = snappy.DecodedLen(item)
+		if t.compressor != nil {
+			decompressedSize, _ = t.compressor.decompressedLen(item)
 		}
 		if i > 0 && maxBytes != 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
-			data, err := snappy.Decode(nil, item)
+		if t.compressor != nil {
+			data, err := t.compressor.decompress(item)
 			if err != nil {
 				return nil, err
 			}
@@ -967,8 +1051,8 @@ func (t *freezerTable) dumpIndex(w io.Writer, start, stop int64) {
 		fmt.Fprintf(w, "Failed to decode freezer table %v\n", err)
 		return
 	}
-	fmt.Fprintf(w, "Version %d count %d, deleted %d, hidden %d\n", meta.Version,
-		t.items.Load(), t.itemOffset.Load(), t.itemHidden.Load())
+	fmt.Fprintf(w, "Version %d count %d, deleted %d, hidden %d, codec %v, dict %d bytes\n", meta.Version,
+		t.items.Load(), t.itemOffset.Load(), t.itemHidden.Load(), t.codec, len(t.dict))
 
 	buf := make([]byte, indexEntrySize)
 
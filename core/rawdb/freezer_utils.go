@@ -76,8 +76,73 @@ func copyFrom(srcPath, destPath string, offset uint64, before func(f *os.File) e
 	return os.Rename(fname, destPath)
 }
 
+// copyFilePrefix copies the first 'size' bytes of 'srcPath' into 'destPath',
+// which must not exist yet. The copy is synced to disk.
+func copyFilePrefix(srcPath, destPath string, size int64) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(dst, src, size); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// linkOrCopyFile hard-links 'srcPath' to 'destPath', falling back to copying
+// the file if hard links are not supported, e.g. across filesystems.
+//
+// Linked files are shared between the freezers until one of them modifies the
+// file, which unshares it first, see unshareFile.
+func linkOrCopyFile(srcPath, destPath string) error {
+	if err := linkFile(srcPath, destPath); err == nil {
+		return nil
+	}
+	stat, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	return copyFilePrefix(srcPath, destPath, stat.Size())
+}
+
+// unshareFile replaces a file hard-linked to multiple paths, e.g. a data file
+// shared with a freezer checkpoint, by a private copy of it. Files are unshared
+// before being opened for modification, so that appending to or truncating them
+// doesn't change the data of the other freezer.
+func unshareFile(filename string) error {
+	stat, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isLinked(stat) {
+		return nil
+	}
+	if err := copyFilePrefix(filename, filename+".unshare", stat.Size()); err != nil {
+		os.Remove(filename + ".unshare")
+		return err
+	}
+	return os.Rename(filename+".unshare", filename)
+}
+
 // openFreezerFileForAppend opens a freezer table file and seeks to the end
 func openFreezerFileForAppend(filename string) (*os.File, error) {
+	if err := unshareFile(filename); err != nil {
+		return nil, err
+	}
 	// Open the file without the O_APPEND flag
 	// because it has differing behaviour during Truncate operations
 	// on different OS's
@@ -99,6 +164,12 @@ func openFreezerFileForReadOnly(filename string) (*os.File, error) {
 
 // openFreezerFileTruncated opens a freezer table making sure it is truncated
 func openFreezerFileTruncated(filename string) (*os.File, error) {
+	// Unlink a shared file instead of truncating the data of the other freezer
+	if stat, err := os.Stat(filename); err == nil && isLinked(stat) {
+		if err := os.Remove(filename); err != nil {
+			return nil, err
+		}
+	}
 	return os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 }
 
@@ -0,0 +1,37 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !unix
+
+package rawdb
+
+import (
+	"errors"
+	"os"
+)
+
+// linkFile always fails, as the link count of files can't be checked on this
+// platform to detect files shared with a checkpoint. Freezer files are always
+// copied instead.
+func linkFile(srcPath, destPath string) error {
+	return errors.ErrUnsupported
+}
+
+// isLinked reports whether the file is hard-linked to multiple paths, which is
+// never the case as freezer files are never linked on this platform.
+func isLinked(stat os.FileInfo) bool {
+	return false
+}
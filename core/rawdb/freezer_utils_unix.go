@@ -0,0 +1,37 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package rawdb
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkFile hard-links 'srcPath' to 'destPath'.
+func linkFile(srcPath, destPath string) error {
+	return os.Link(srcPath, destPath)
+}
+
+// isLinked reports whether the file is hard-linked to multiple paths.
+func isLinked(stat os.FileInfo) bool {
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return sys.Nlink > 1
+	}
+	return false
+}
@@ -76,6 +76,17 @@ var (
 	// trieJournalKey tracks the in-memory trie node layers across restarts.
 	trieJournalKey = []byte("TrieJournal")
 
+	// headStateHistoryIndexKey tracks the id of the last indexed state history.
+	headStateHistoryIndexKey = []byte("LastStateHistoryIndex")
+
+	// onlinePruningStatusKey tracks the progress of the online state pruning
+	// across restarts.
+	onlinePruningStatusKey = []byte("OnlinePruningStatus")
+
+	// verkleConversionStatusKey tracks the progress of the offline conversion
+	// of the state into a verkle tree.
+	verkleConversionStatusKey = []byte("VerkleConversionStatus")
+
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
@@ -117,6 +128,12 @@ var (
 	TrieNodeStoragePrefix = []byte("O") // TrieNodeStoragePrefix + accountHash + hexPath -> trie node
 	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
 
+	StateHistoryAccountIndexPrefix = []byte("ma") // StateHistoryAccountIndexPrefix + address + chunk id -> state history ids
+	StateHistoryStorageIndexPrefix = []byte("ms") // StateHistoryStorageIndexPrefix + address + slot hash + chunk id -> state history ids
+
+	callTracePrefix      = []byte("x") // callTracePrefix + block hash + tx hash -> call trace
+	callTraceBlockPrefix = []byte("X") // callTraceBlockPrefix + num (uint64 big endian) + hash -> traced block
+
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
@@ -265,6 +282,29 @@ func stateIDKey(root common.Hash) []byte {
 	return append(stateIDPrefix, root.Bytes()...)
 }
 
+// accountHistoryIndexKey = StateHistoryAccountIndexPrefix + address + chunk id (uint64 big endian)
+func accountHistoryIndexKey(address common.Address, chunk uint64) []byte {
+	key := append(append([]byte{}, StateHistoryAccountIndexPrefix...), address.Bytes()...)
+	return binary.BigEndian.AppendUint64(key, chunk)
+}
+
+// storageHistoryIndexKey = StateHistoryStorageIndexPrefix + address + slot hash + chunk id (uint64 big endian)
+func storageHistoryIndexKey(address common.Address, slot common.Hash, chunk uint64) []byte {
+	key := append(append([]byte{}, StateHistoryStorageIndexPrefix...), address.Bytes()...)
+	key = append(key, slot.Bytes()...)
+	return binary.BigEndian.AppendUint64(key, chunk)
+}
+
+// callTraceKey = callTracePrefix + block hash + tx hash
+func callTraceKey(blockHash common.Hash, txHash common.Hash) []byte {
+	return append(append(callTracePrefix, blockHash.Bytes()...), txHash.Bytes()...)
+}
+
+// callTraceBlockKey = callTraceBlockPrefix + num (uint64 big endian) + hash
+func callTraceBlockKey(number uint64, hash common.Hash) []byte {
+	return append(append(callTraceBlockPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // accountTrieNodeKey = TrieNodeAccountPrefix + nodePath.
 func accountTrieNodeKey(path []byte) []byte {
 	return append(TrieNodeAccountPrefix, path...)
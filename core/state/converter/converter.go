@@ -0,0 +1,471 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package converter implements the offline conversion of the Merkle-Patricia
+// state into the equivalent verkle tree.
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+const (
+	// defaultCheckpoint is the default number of converted leaves between two
+	// checkpoints.
+	defaultCheckpoint = 1_000_000
+
+	// pointCacheItems is the number of address points to cache.
+	pointCacheItems = 4096
+)
+
+// ErrInterrupted is returned if the conversion is interrupted, its progress
+// is checkpointed and can be resumed.
+var ErrInterrupted = errors.New("conversion interrupted")
+
+// Config includes the configurations of the conversion.
+type Config struct {
+	// Checkpoint is the number of converted accounts and storage slots
+	// between two checkpoints. The verkle tree nodes are held in memory
+	// until they are flushed in a checkpoint.
+	Checkpoint int
+}
+
+// Status is the progress of the conversion, persisted in the database of the
+// verkle tree at each checkpoint.
+type Status struct {
+	Source common.Hash // Root of the Merkle-Patricia state being converted
+	Root   common.Hash // Root of the verkle tree converted so far
+	Marker []byte      // Last converted account hash (and slot hash), empty if not started
+	Done   bool        // Flag whether the conversion is finished
+
+	Accounts  uint64 // Number of converted accounts
+	Slots     uint64 // Number of converted storage slots
+	Codes     uint64 // Number of converted contract codes
+	CodeBytes uint64 // Total size of the converted contract codes
+	Nodes     uint64 // Number of verkle tree nodes stored, set once finished
+	NodeBytes uint64 // Total size of the verkle tree nodes stored, set once finished
+	Elapsed   uint64 // Time spent converting, in nanoseconds
+}
+
+// ReadStatus retrieves the conversion progress from the database of the verkle
+// tree, nil is returned if no conversion is recorded.
+func ReadStatus(db ethdb.KeyValueReader) (*Status, error) {
+	blob := rawdb.ReadVerkleConversionStatus(db)
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	var status Status
+	if err := rlp.DecodeBytes(blob, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// nodeDatabase is the node reader of the verkle tree being converted, which
+// resolves the nodes from the database by path. Only the latest version of
+// the tree is stored, so the state root is ignored.
+type nodeDatabase struct {
+	db ethdb.KeyValueReader
+}
+
+// Reader implements database.Database, returning the reader of the tree.
+func (db *nodeDatabase) Reader(root common.Hash) (database.Reader, error) {
+	return db, nil
+}
+
+// Node implements database.Reader, retrieving the tree node at the path.
+func (db *nodeDatabase) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	return rawdb.ReadAccountTrieNode(db.db, path), nil
+}
+
+// Preimage implements database.PreimageStore, no preimage is tracked.
+func (db *nodeDatabase) Preimage(hash common.Hash) []byte { return nil }
+
+// InsertPreimage implements database.PreimageStore, no preimage is tracked.
+func (db *nodeDatabase) InsertPreimage(preimages map[common.Hash][]byte) {}
+
+// Converter walks the accounts and storage of a Merkle-Patricia state, from the
+// snapshot if it's available or from the tries otherwise, and inserts them into
+// a verkle tree. The address and slot keys are recovered from the preimages,
+// which must be all recorded.
+//
+// The verkle tree is written into a dedicated database, along with the progress
+// of the conversion at each checkpoint, allowing to resume it after a restart.
+type Converter struct {
+	chaindb  ethdb.Database
+	triedb   *triedb.Database
+	snaptree *snapshot.Tree // Source of the state, nil to walk the tries
+	dst      ethdb.KeyValueStore
+	config   Config
+
+	status *Status
+	trie   *trie.VerkleTrie
+	nodedb *nodeDatabase
+	cache  *utils.PointCache
+	leaves int // Number of leaves converted since the last checkpoint
+}
+
+// New creates a converter of the state with the given root. The snapshot tree
+// is optional, the tries are walked if it's nil or the snapshot is not fully
+// generated. The conversion is resumed if the destination database holds the
+// progress of the same state.
+func New(chaindb ethdb.Database, triedb *triedb.Database, snaptree *snapshot.Tree, root common.Hash, dst ethdb.KeyValueStore, config Config) (*Converter, error) {
+	if config.Checkpoint <= 0 {
+		config.Checkpoint = defaultCheckpoint
+	}
+	if snaptree != nil {
+		it, err := snaptree.AccountIterator(root, common.Hash{})
+		if err != nil {
+			log.Info("Snapshot unavailable, converting from tries", "root", root, "err", err)
+			snaptree = nil
+		} else {
+			it.Release()
+		}
+	}
+	status, err := ReadStatus(dst)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		status = &Status{Source: root, Root: types.EmptyRootHash}
+	} else if status.Source != root {
+		return nil, fmt.Errorf("database holds the conversion of another state %#x", status.Source)
+	}
+	c := &Converter{
+		chaindb:  chaindb,
+		triedb:   triedb,
+		snaptree: snaptree,
+		dst:      dst,
+		config:   config,
+		status:   status,
+		nodedb:   &nodeDatabase{db: dst},
+		cache:    utils.NewPointCache(pointCacheItems),
+	}
+	if c.trie, err = trie.NewVerkleTrie(status.Root, c.nodedb, c.cache); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Status returns the progress of the conversion.
+func (c *Converter) Status() *Status {
+	return c.status
+}
+
+// accountIterator opens an iterator over the accounts starting at the given
+// hash. The iterated values are either in the slim or the full format.
+func (c *Converter) accountIterator(start common.Hash) (snapshot.AccountIterator, error) {
+	if c.snaptree != nil {
+		return c.snaptree.AccountIterator(c.status.Source, start)
+	}
+	tr, err := trie.New(trie.StateTrieID(c.status.Source), c.triedb)
+	if err != nil {
+		return nil, err
+	}
+	it, err := tr.NodeIterator(start[:])
+	if err != nil {
+		return nil, err
+	}
+	return &trieIterator{it: trie.NewIterator(it)}, nil
+}
+
+// storageIterator opens an iterator over the storage slots of the account,
+// starting at the given hash.
+func (c *Converter) storageIterator(account common.Hash, root common.Hash, start common.Hash) (snapshot.StorageIterator, error) {
+	if c.snaptree != nil {
+		return c.snaptree.StorageIterator(c.status.Source, account, start)
+	}
+	tr, err := trie.New(trie.StorageTrieID(c.status.Source, account, root), c.triedb)
+	if err != nil {
+		return nil, err
+	}
+	it, err := tr.NodeIterator(start[:])
+	if err != nil {
+		return nil, err
+	}
+	return &trieIterator{it: trie.NewIterator(it)}, nil
+}
+
+// preimage retrieves the preimage of the hashed key.
+func (c *Converter) preimage(hash common.Hash, size int) ([]byte, error) {
+	preimage := rawdb.ReadPreimage(c.chaindb, hash)
+	if len(preimage) != size {
+		return nil, fmt.Errorf("missing preimage of %#x", hash)
+	}
+	return preimage, nil
+}
+
+// Run converts the state from the last checkpoint. If the interrupt channel is
+// closed, the conversion stops at the next checkpoint with ErrInterrupted.
+func (c *Converter) Run(interrupt <-chan struct{}) (*Status, error) {
+	if c.status.Done {
+		return c.status, nil
+	}
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		elapsed = c.status.Elapsed
+		marker  = c.status.Marker
+
+		accStart  common.Hash
+		slotStart []byte // Slot to resume the storage of the first account from
+	)
+	switch len(marker) {
+	case 0:
+	case common.HashLength:
+		next, ok := nextHash(common.BytesToHash(marker))
+		if !ok {
+			return c.finish(start, elapsed)
+		}
+		accStart = next
+	case 2 * common.HashLength:
+		accStart = common.BytesToHash(marker[:common.HashLength])
+		slotStart = marker[common.HashLength:]
+	default:
+		return nil, fmt.Errorf("invalid conversion marker %#x", marker)
+	}
+	log.Info("Converting state into verkle tree", "root", c.status.Source, "snapshot", c.snaptree != nil, "marker", marker)
+
+	// checkpoint flushes the tree if enough leaves are converted, reporting
+	// whether the conversion is interrupted.
+	checkpoint := func(marker []byte) (bool, error) {
+		c.leaves++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting state into verkle tree", "at", common.BytesToHash(marker[:common.HashLength]), "accounts", c.status.Accounts, "slots", c.status.Slots, "codes", c.status.Codes,
+				"elapsed", common.PrettyDuration(time.Duration(elapsed)+time.Since(start)))
+			logged = time.Now()
+		}
+		if c.leaves < c.config.Checkpoint {
+			return false, nil
+		}
+		c.status.Elapsed = elapsed + uint64(time.Since(start))
+		if err := c.commit(marker); err != nil {
+			return false, err
+		}
+		select {
+		case <-interrupt:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+	it, err := c.accountIterator(accStart)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		var (
+			hash     = it.Hash()
+			resuming = slotStart != nil && hash == accStart
+		)
+		account, err := types.FullAccount(it.Account())
+		if err != nil {
+			return nil, fmt.Errorf("invalid account %#x: %v", hash, err)
+		}
+		preimage, err := c.preimage(hash, common.AddressLength)
+		if err != nil {
+			return nil, err
+		}
+		addr := common.BytesToAddress(preimage)
+
+		// The account and code of the account to resume the storage of are
+		// already converted, reinserting them is harmless though.
+		if err := c.trie.UpdateAccount(addr, account); err != nil {
+			return nil, err
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+			code := rawdb.ReadCode(c.chaindb, codeHash)
+			if len(code) == 0 {
+				return nil, fmt.Errorf("missing contract code %#x", codeHash)
+			}
+			if err := c.trie.UpdateContractCode(addr, codeHash, code); err != nil {
+				return nil, err
+			}
+			if !resuming {
+				c.status.Codes++
+				c.status.CodeBytes += uint64(len(code))
+			}
+		}
+		if !resuming {
+			c.status.Accounts++
+		}
+		if account.Root != types.EmptyRootHash {
+			var (
+				from common.Hash
+				skip bool
+			)
+			if resuming {
+				from, skip = nextHash(common.BytesToHash(slotStart))
+				skip = !skip
+			}
+			if !skip {
+				interrupted, err := c.convertStorage(hash, addr, account.Root, from, checkpoint)
+				if err != nil || interrupted {
+					if err == nil {
+						err = ErrInterrupted
+					}
+					return nil, err
+				}
+			}
+		}
+		interrupted, err := checkpoint(hash[:])
+		if err != nil {
+			return nil, err
+		}
+		if interrupted {
+			return nil, ErrInterrupted
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return c.finish(start, elapsed)
+}
+
+// convertStorage inserts the storage slots of the account into the tree, from
+// the given slot on.
+func (c *Converter) convertStorage(account common.Hash, addr common.Address, root common.Hash, start common.Hash, checkpoint func([]byte) (bool, error)) (bool, error) {
+	it, err := c.storageIterator(account, root, start)
+	if err != nil {
+		return false, err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		hash := it.Hash()
+		key, err := c.preimage(hash, common.HashLength)
+		if err != nil {
+			return false, err
+		}
+		_, value, _, err := rlp.Split(it.Slot())
+		if err != nil {
+			return false, fmt.Errorf("invalid storage slot %#x of %#x: %v", hash, account, err)
+		}
+		if err := c.trie.UpdateStorage(addr, key, value); err != nil {
+			return false, err
+		}
+		c.status.Slots++
+
+		interrupted, err := checkpoint(append(account.Bytes(), hash[:]...))
+		if err != nil || interrupted {
+			return interrupted, err
+		}
+	}
+	return false, it.Error()
+}
+
+// commit flushes the converted tree into the database along with the progress,
+// reopening the tree to release the memory.
+func (c *Converter) commit(marker []byte) error {
+	root, nodes, err := c.trie.Commit(false)
+	if err != nil {
+		return err
+	}
+	batch := c.dst.NewBatch()
+	nodes.ForEachWithOrder(func(path string, n *trienode.Node) {
+		rawdb.WriteAccountTrieNode(batch, []byte(path), n.Blob)
+	})
+	c.status.Root, c.status.Marker = root, common.CopyBytes(marker)
+
+	blob, err := rlp.EncodeToBytes(c.status)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteVerkleConversionStatus(batch, blob)
+
+	// The nodes and the progress are written atomically, since the nodes of
+	// the last checkpoint are overwritten in place.
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	c.leaves = 0
+	c.trie, err = trie.NewVerkleTrie(root, c.nodedb, c.cache)
+	return err
+}
+
+// finish flushes the remaining tree and measures the size of the tree stored,
+// marking the conversion as done.
+func (c *Converter) finish(start time.Time, elapsed uint64) (*Status, error) {
+	c.status.Done = true
+	c.status.Elapsed = elapsed + uint64(time.Since(start))
+	if err := c.commit(nil); err != nil {
+		return nil, err
+	}
+	c.status.Nodes, c.status.NodeBytes = 0, 0
+
+	it := c.dst.NewIterator(rawdb.TrieNodeAccountPrefix, nil)
+	for it.Next() {
+		if !rawdb.IsAccountTrieNode(it.Key()) {
+			continue
+		}
+		c.status.Nodes++
+		c.status.NodeBytes += uint64(len(it.Key()) + len(it.Value()))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	blob, err := rlp.EncodeToBytes(c.status)
+	if err != nil {
+		return nil, err
+	}
+	rawdb.WriteVerkleConversionStatus(c.dst, blob)
+
+	log.Info("Converted state into verkle tree", "source", c.status.Source, "root", c.status.Root, "accounts", c.status.Accounts, "slots", c.status.Slots,
+		"codes", c.status.Codes, "nodes", c.status.Nodes, "size", common.StorageSize(c.status.NodeBytes), "elapsed", common.PrettyDuration(time.Duration(c.status.Elapsed)))
+	return c.status, nil
+}
+
+// trieIterator iterates the leaves of a Merkle-Patricia trie, as an account or
+// storage iterator.
+type trieIterator struct {
+	it *trie.Iterator
+}
+
+func (it *trieIterator) Next() bool        { return it.it.Next() }
+func (it *trieIterator) Error() error      { return it.it.Err }
+func (it *trieIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *trieIterator) Release()          {}
+func (it *trieIterator) Account() []byte   { return it.it.Value }
+func (it *trieIterator) Slot() []byte      { return it.it.Value }
+
+// nextHash returns the hash following the given one, reporting false if the
+// entire hash space is exhausted.
+func nextHash(hash common.Hash) (common.Hash, bool) {
+	next := new(big.Int).Add(hash.Big(), common.Big1)
+	if next.BitLen() > 8*common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BigToHash(next), true
+}
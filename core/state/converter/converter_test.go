@@ -0,0 +1,185 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+)
+
+func testAddress(i int) common.Address {
+	return common.BigToAddress(new(big.Int).Lsh(common.Big1, uint(i)))
+}
+
+func testKey(i int) common.Hash {
+	return common.BigToHash(new(big.Int).Lsh(common.Big1, uint(i)))
+}
+
+// newTestState creates a state with plain accounts and contracts, optionally
+// recording the preimages of all the keys.
+func newTestState(t *testing.T, preimages bool) (ethdb.Database, *triedb.Database, common.Hash) {
+	t.Helper()
+
+	db := rawdb.NewMemoryDatabase()
+	tdb := triedb.NewDatabase(db, &triedb.Config{Preimages: preimages})
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseWithNodeDB(db, tdb), nil)
+	for i := 0; i < 50; i++ {
+		addr := testAddress(i)
+		statedb.SetBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+		statedb.SetNonce(addr, uint64(i))
+		if i%5 == 0 {
+			statedb.SetCode(addr, bytes.Repeat([]byte{byte(i)}, 100*i+1))
+			for j := 0; j < i; j++ {
+				statedb.SetState(addr, testKey(j), common.BytesToHash([]byte(fmt.Sprintf("value-%d", j))))
+			}
+		}
+	}
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := tdb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return db, tdb, root
+}
+
+// convert runs the conversion into the database to the end, resuming it after
+// every checkpoint if interrupted.
+func convert(t *testing.T, db ethdb.Database, tdb *triedb.Database, snaptree *snapshot.Tree, root common.Hash, dst ethdb.KeyValueStore, checkpoint int, interrupt bool) *Status {
+	t.Helper()
+
+	ch := make(chan struct{})
+	if interrupt {
+		close(ch)
+	}
+	for i := 0; ; i++ {
+		c, err := New(db, tdb, snaptree, root, dst, Config{Checkpoint: checkpoint})
+		if err != nil {
+			t.Fatalf("failed to create converter: %v", err)
+		}
+		status, err := c.Run(ch)
+		if errors.Is(err, ErrInterrupted) {
+			if i > 1000 {
+				t.Fatal("conversion not progressing")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to convert: %v", err)
+		}
+		return status
+	}
+}
+
+func TestConvert(t *testing.T) {
+	db, tdb, root := newTestState(t, true)
+	snaptree, err := snapshot.New(snapshot.Config{CacheSize: 16}, db, tdb, root)
+	if err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	var want *Status
+	for _, tc := range []struct {
+		snaptree   *snapshot.Tree
+		checkpoint int
+		interrupt  bool
+	}{
+		{nil, 0, false},
+		{nil, 3, true},
+		{snaptree, 0, false},
+		{snaptree, 7, true},
+	} {
+		dst := rawdb.NewMemoryDatabase()
+		status := convert(t, db, tdb, tc.snaptree, root, dst, tc.checkpoint, tc.interrupt)
+		if !status.Done || status.Accounts != 50 || status.Slots != 225 || status.Codes != 10 || status.Nodes == 0 {
+			t.Fatalf("unexpected status: %+v", status)
+		}
+		if want == nil {
+			want = status
+		} else if status.Root != want.Root || status.Nodes != want.Nodes || status.NodeBytes != want.NodeBytes {
+			t.Fatalf("conversion mismatch: have %+v, want %+v", status, want)
+		}
+		// The stored status must match the returned one, and a finished
+		// conversion is not redone
+		if stored, _ := ReadStatus(dst); stored == nil || stored.Root != status.Root || !stored.Done {
+			t.Fatalf("unexpected stored status: %+v", stored)
+		}
+		if again := convert(t, db, tdb, tc.snaptree, root, dst, tc.checkpoint, false); again.Elapsed != status.Elapsed {
+			t.Fatal("finished conversion redone")
+		}
+		// Check the converted state against the source one
+		vt, err := trie.NewVerkleTrie(status.Root, &nodeDatabase{db: dst}, utils.NewPointCache(1024))
+		if err != nil {
+			t.Fatalf("failed to open verkle tree: %v", err)
+		}
+		statedb, _ := state.New(root, state.NewDatabaseWithNodeDB(db, tdb), nil)
+		for i := 0; i < 50; i++ {
+			addr := testAddress(i)
+			acc, err := vt.GetAccount(addr)
+			if err != nil || acc == nil {
+				t.Fatalf("missing account %x: %v", addr, err)
+			}
+			if acc.Nonce != statedb.GetNonce(addr) || acc.Balance.Cmp(statedb.GetBalance(addr)) != 0 || !bytes.Equal(acc.CodeHash, statedb.GetCodeHash(addr).Bytes()) {
+				t.Fatalf("account %x mismatch: %+v", addr, acc)
+			}
+			for j := 0; j < i && i%5 == 0; j++ {
+				key := testKey(j)
+				val, err := vt.GetStorage(addr, key[:])
+				if err != nil || common.BytesToHash(val) != statedb.GetState(addr, key) {
+					t.Fatalf("slot %x of %x mismatch: %x %v", key, addr, val, err)
+				}
+			}
+		}
+	}
+}
+
+func TestConvertOtherState(t *testing.T) {
+	db, tdb, root := newTestState(t, true)
+	dst := rawdb.NewMemoryDatabase()
+	convert(t, db, tdb, nil, root, dst, 0, false)
+
+	if _, err := New(db, tdb, nil, common.Hash{1}, dst, Config{}); err == nil {
+		t.Fatal("conversion of another state accepted")
+	}
+}
+
+func TestConvertMissingPreimage(t *testing.T) {
+	db, tdb, root := newTestState(t, false)
+
+	c, err := New(db, tdb, nil, root, rawdb.NewMemoryDatabase(), Config{})
+	if err != nil {
+		t.Fatalf("failed to create converter: %v", err)
+	}
+	if _, err := c.Run(nil); err == nil {
+		t.Fatal("conversion without preimage succeeded")
+	}
+}
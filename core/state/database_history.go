@@ -0,0 +1,135 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+// errHistoricStateReadOnly is returned when mutating or iterating a historic
+// state, which is reconstructed from the state histories without its tries.
+var errHistoricStateReadOnly = errors.New("historic state is read-only")
+
+// historicDB is a state database serving the historical states still covered
+// by the state histories of the path-based trie database.
+type historicDB struct {
+	*cachingDB
+}
+
+// NewHistoricDatabase creates a state database serving the historical states
+// reconstructed from the state histories. The states opened through it can be
+// read and modified in memory, e.g. to execute transactions on top, but their
+// root can't be computed once modified, failing with errHistoricStateReadOnly.
+// It's only supported by the path-based trie database.
+func NewHistoricDatabase(db ethdb.Database, triedb *triedb.Database) Database {
+	return &historicDB{cachingDB: NewDatabaseWithNodeDB(db, triedb).(*cachingDB)}
+}
+
+// OpenTrie opens the historical state with the given root.
+func (db *historicDB) OpenTrie(root common.Hash) (Trie, error) {
+	reader, err := db.triedb.HistoricReader(root)
+	if err != nil {
+		return nil, err
+	}
+	return &historicTrie{root: root, reader: reader}, nil
+}
+
+// OpenStorageTrie returns the trie of the historical state itself, which
+// serves the storage slots of all the accounts.
+func (db *historicDB) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self Trie) (Trie, error) {
+	return self, nil
+}
+
+// CopyTrie returns the given trie, as the historic tries are immutable.
+func (db *historicDB) CopyTrie(t Trie) Trie {
+	return t
+}
+
+// historicTrie implements the Trie interface on top of a historical state
+// reader. Only the reads are supported.
+type historicTrie struct {
+	root   common.Hash
+	reader database.StateReader
+}
+
+// GetKey implements Trie, preimages are not available.
+func (t *historicTrie) GetKey([]byte) []byte { return nil }
+
+// GetAccount implements Trie, retrieving the account from the historical state.
+func (t *historicTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	blob, err := t.reader.Account(address)
+	if err != nil || len(blob) == 0 {
+		return nil, err
+	}
+	return types.FullAccount(blob)
+}
+
+// GetStorage implements Trie, retrieving the slot from the historical state.
+func (t *historicTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	return t.reader.Storage(addr, common.BytesToHash(key))
+}
+
+// UpdateAccount implements Trie, it's not supported.
+func (t *historicTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	return errHistoricStateReadOnly
+}
+
+// UpdateStorage implements Trie, it's not supported.
+func (t *historicTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	return errHistoricStateReadOnly
+}
+
+// DeleteAccount implements Trie, it's not supported.
+func (t *historicTrie) DeleteAccount(address common.Address) error {
+	return errHistoricStateReadOnly
+}
+
+// DeleteStorage implements Trie, it's not supported.
+func (t *historicTrie) DeleteStorage(addr common.Address, key []byte) error {
+	return errHistoricStateReadOnly
+}
+
+// UpdateContractCode implements Trie, the code is not part of the trie.
+func (t *historicTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash implements Trie, returning the root of the historical state.
+func (t *historicTrie) Hash() common.Hash { return t.root }
+
+// Commit implements Trie, it's not supported.
+func (t *historicTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errHistoricStateReadOnly
+}
+
+// NodeIterator implements Trie, it's not supported.
+func (t *historicTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errHistoricStateReadOnly
+}
+
+// Prove implements Trie, it's not supported.
+func (t *historicTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errHistoricStateReadOnly
+}
@@ -0,0 +1,571 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// sweepBatchNodes is the maximum number of trie nodes deleted in a single
+	// database batch by the online pruner.
+	sweepBatchNodes = 4096
+
+	// sweepBatchKeys is the maximum number of database entries iterated before
+	// the online pruner writes the deletions and persists its progress.
+	sweepBatchKeys = 65536
+
+	// onlineRetryDelay is the time waited before an interrupted pruning cycle
+	// is resumed.
+	onlineRetryDelay = time.Minute
+)
+
+var (
+	onlinePhaseGauge    = metrics.NewRegisteredGauge("state/pruner/online/phase", nil)
+	onlineProgressGauge = metrics.NewRegisteredGaugeFloat64("state/pruner/online/progress", nil)
+	onlineMarkedMeter   = metrics.NewRegisteredMeter("state/pruner/online/marked", nil)
+	onlineSweptMeter    = metrics.NewRegisteredMeter("state/pruner/online/swept", nil)
+	onlineDeletedMeter  = metrics.NewRegisteredMeter("state/pruner/online/deleted", nil)
+	onlineBytesMeter    = metrics.NewRegisteredMeter("state/pruner/online/deleted/bytes", nil)
+)
+
+var (
+	// errPruningAborted is returned if the online pruner is stopped in the
+	// middle of a pruning cycle.
+	errPruningAborted = errors.New("pruning aborted")
+
+	// errStateSyncing is returned if the state is being synced, whose trie
+	// nodes are written without going through the trie database.
+	errStateSyncing = errors.New("state is being synced")
+)
+
+// OnlinePhase is the phase of a pruning cycle of the online pruner.
+type OnlinePhase uint8
+
+const (
+	OnlineIdle     OnlinePhase = iota // No pruning cycle is running
+	OnlineWaiting                     // Waiting for a state to be committed
+	OnlineMarking                     // Collecting the trie nodes of the committed state
+	OnlineSweeping                    // Deleting the trie nodes not collected
+)
+
+// String implements fmt.Stringer.
+func (p OnlinePhase) String() string {
+	switch p {
+	case OnlineIdle:
+		return "idle"
+	case OnlineWaiting:
+		return "waiting"
+	case OnlineMarking:
+		return "marking"
+	case OnlineSweeping:
+		return "sweeping"
+	default:
+		return "unknown"
+	}
+}
+
+// OnlineConfig includes all the configurations for online pruning.
+type OnlineConfig struct {
+	BloomSize uint64        // The Megabytes of memory allocated to bloom-filter
+	Rate      int           // Maximum number of trie nodes deleted per second, 0 means unlimited
+	Interval  time.Duration // Time between the end of a pruning cycle and the start of the next one
+}
+
+// OnlineStatus is the progress of the online pruning, which is persisted in
+// the database so that an interrupted cycle resumes after a restart.
+type OnlineStatus struct {
+	Phase    OnlinePhase // Phase of the running cycle, idle if there is none
+	Target   common.Hash // State the stale trie nodes are determined against, zero until committed
+	Marker   []byte      // Key the sweeping resumes from, nil if not started yet
+	Started  uint64      // Unix time the running cycle started, zero if there is none
+	Finished uint64      // Unix time the last cycle finished, zero if there is none
+	Swept    uint64      // Number of database entries iterated in the current or last cycle
+	Deleted  uint64      // Number of trie nodes deleted in the current or last cycle
+	Size     uint64      // Total size of the trie nodes deleted in the current or last cycle
+}
+
+// Progress returns the approximate fraction of the database already swept in
+// the running cycle. As the trie nodes are keyed by their hash, the position
+// of the marker in the key space is a good estimate.
+func (s *OnlineStatus) Progress() float64 {
+	if s.Started == 0 || len(s.Marker) == 0 {
+		return 0
+	}
+	var pos [8]byte
+	copy(pos[:], s.Marker)
+	return float64(binary.BigEndian.Uint64(pos[:])) / math.MaxUint64
+}
+
+// ReadOnlineStatus retrieves the progress of the online pruning, nil is returned
+// if the online pruner never ran on the database.
+func ReadOnlineStatus(db ethdb.KeyValueReader) (*OnlineStatus, error) {
+	blob := rawdb.ReadOnlinePruningStatus(db)
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	status := new(OnlineStatus)
+	if err := rlp.DecodeBytes(blob, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ParseOnlineStatus parses the serialized progress of the online pruning into
+// a human readable form.
+func ParseOnlineStatus(blob []byte) string {
+	if len(blob) == 0 {
+		return ""
+	}
+	var status OnlineStatus
+	if err := rlp.DecodeBytes(blob, &status); err != nil {
+		log.Warn("failed to decode online pruning status", "err", err)
+		return ""
+	}
+	formatTime := func(t uint64) string {
+		if t == 0 {
+			return "never"
+		}
+		return time.Unix(int64(t), 0).Format(time.RFC3339)
+	}
+	return fmt.Sprintf(`Phase: %v, Target: %#x, Progress: %.2f%%, Swept: %d, Deleted: %d, Size: %v, Started: %s, Finished: %s`,
+		status.Phase, status.Target, status.Progress()*100, status.Swept, status.Deleted, common.StorageSize(status.Size),
+		formatTime(status.Started), formatTime(status.Finished))
+}
+
+// writeOnlineStatus persists the progress of the online pruning.
+func writeOnlineStatus(db ethdb.KeyValueWriter, status *OnlineStatus) {
+	blob, err := rlp.EncodeToBytes(status)
+	if err != nil {
+		log.Crit("Failed to encode online pruning status", "err", err)
+	}
+	rawdb.WriteOnlinePruningStatus(db, blob)
+}
+
+// OnlinePruner deletes the stale trie nodes of a hash-based state database
+// while the node keeps running. A pruning cycle consists of:
+//
+//   - tracking all the trie nodes inserted into the trie database from the
+//     start of the cycle, which may be persisted at any later point
+//   - waiting for the first state updated after the start to be committed
+//   - iterating the committed state and the genesis, collecting the trie
+//     nodes in a bloom filter together with the tracked ones
+//   - iterating the database, deleting the trie nodes not collected
+//
+// All the states built on top of the committed one only consist of its trie
+// nodes and the tracked ones, so they are retained. The states of side chains
+// forking off below it might not be.
+//
+// The deletions are rate-limited and the progress is persisted after every
+// batch, a cycle interrupted by a restart collects the nodes of a new state
+// and resumes deleting where it stopped.
+type OnlinePruner struct {
+	config  OnlineConfig
+	db      ethdb.Database
+	triedb  *triedb.Database
+	syncing func() bool // Reports whether the state is being snap synced, nil if never
+	limiter *rate.Limiter
+
+	lock   sync.Mutex               // Lock protecting the tracking fields below
+	bloom  *stateBloom              // Trie nodes retained in the running cycle, nil if not tracking
+	roots  map[common.Hash]struct{} // State roots updated in the running cycle, nil once the target is known
+	target chan common.Hash         // Channel delivering the first tracked state committed
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewOnlinePruner creates the online pruner for the given database and starts
+// it, resuming the interrupted pruning cycle if there is one. The trie database
+// must be hash-based.
+func NewOnlinePruner(db ethdb.Database, triedb *triedb.Database, config OnlineConfig, syncing func() bool) (*OnlinePruner, error) {
+	if config.BloomSize == 0 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	p := &OnlinePruner{
+		config:  config,
+		db:      db,
+		triedb:  triedb,
+		syncing: syncing,
+		limiter: rate.NewLimiter(rate.Inf, sweepBatchNodes),
+		quit:    make(chan struct{}),
+	}
+	if config.Rate > 0 {
+		p.limiter.SetLimit(rate.Limit(config.Rate))
+	}
+	if err := triedb.SetTracker(p); err != nil {
+		return nil, err
+	}
+	p.wg.Add(1)
+	go p.loop()
+	return p, nil
+}
+
+// Stop terminates the online pruner, the running pruning cycle is resumed when
+// it's restarted.
+func (p *OnlinePruner) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+	p.triedb.SetTracker(nil)
+}
+
+// setPhase moves the pruning cycle into the given phase.
+func (p *OnlinePruner) setPhase(status *OnlineStatus, phase OnlinePhase) {
+	status.Phase = phase
+	writeOnlineStatus(p.db, status)
+	onlinePhaseGauge.Update(int64(phase))
+}
+
+// OnNode implements hashdb.Tracker, retaining the inserted trie node.
+func (p *OnlinePruner) OnNode(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		p.bloom.Put(hash.Bytes(), nil)
+	}
+}
+
+// OnUpdate implements hashdb.Tracker, recording the state updated while the
+// target of the running cycle is not known.
+func (p *OnlinePruner) OnUpdate(root common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.roots != nil {
+		p.roots[root] = struct{}{}
+	}
+}
+
+// OnCommit implements hashdb.Tracker, picking the committed state as the target
+// of the running cycle if its update was tracked.
+func (p *OnlinePruner) OnCommit(root common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.roots[root]; ok {
+		p.roots = nil
+		p.target <- root
+	}
+}
+
+// loop runs the pruning cycles, waiting the configured interval in between.
+func (p *OnlinePruner) loop() {
+	defer p.wg.Done()
+
+	for {
+		status, err := ReadOnlineStatus(p.db)
+		if err != nil {
+			log.Warn("Discarding corrupted online pruning status", "err", err)
+		}
+		if status == nil {
+			status = new(OnlineStatus)
+		}
+		// Start a new cycle if there is none running
+		if status.Started == 0 {
+			if status.Finished != 0 {
+				wait := time.Until(time.Unix(int64(status.Finished), 0).Add(p.config.Interval))
+				if wait > 0 {
+					log.Info("Scheduled online state pruning", "wait", common.PrettyDuration(wait))
+					select {
+					case <-time.After(wait):
+					case <-p.quit:
+						return
+					}
+				}
+			}
+			status = &OnlineStatus{Started: uint64(time.Now().Unix())}
+			writeOnlineStatus(p.db, status)
+		} else {
+			log.Info("Resuming online state pruning", "swept", status.Swept, "deleted", status.Deleted, "progress", status.Progress())
+		}
+		err = p.prune(status)
+		onlinePhaseGauge.Update(int64(OnlineIdle))
+
+		switch {
+		case errors.Is(err, errPruningAborted):
+			return
+		case err != nil:
+			log.Warn("Online state pruning interrupted", "err", err, "retry", onlineRetryDelay)
+			select {
+			case <-time.After(onlineRetryDelay):
+			case <-p.quit:
+				return
+			}
+		default:
+			log.Info("Online state pruning finished", "swept", status.Swept, "deleted", status.Deleted,
+				"size", common.StorageSize(status.Size), "elapsed", common.PrettyDuration(time.Since(time.Unix(int64(status.Started), 0))))
+
+			status.Target, status.Marker = common.Hash{}, nil
+			status.Started, status.Finished = 0, uint64(time.Now().Unix())
+			p.setPhase(status, OnlineIdle)
+		}
+	}
+}
+
+// prune runs the given pruning cycle, starting the tracking of the trie nodes
+// and sweeping the database from the persisted marker.
+func (p *OnlinePruner) prune(status *OnlineStatus) error {
+	bloom, err := newStateBloomWithSize(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.bloom, p.roots, p.target = bloom, make(map[common.Hash]struct{}), make(chan common.Hash, 1)
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		p.bloom, p.roots, p.target = nil, nil, nil
+		p.lock.Unlock()
+	}()
+	// Wait for a tracked state to be committed, a resumed cycle switches over
+	// to the new target.
+	p.setPhase(status, OnlineWaiting)
+
+	var target common.Hash
+	select {
+	case target = <-p.target:
+	case <-p.quit:
+		return errPruningAborted
+	}
+	// Collect all the trie nodes of the target and the genesis
+	status.Target = target
+	p.setPhase(status, OnlineMarking)
+	log.Info("Marking live state for online pruning", "root", target)
+
+	mstart := time.Now()
+	genesis := rawdb.ReadCanonicalHash(p.db, 0)
+	if genesis == (common.Hash{}) {
+		return errors.New("missing genesis hash")
+	}
+	header := rawdb.ReadHeader(p.db, genesis, 0)
+	if header == nil {
+		return errors.New("missing genesis header")
+	}
+	for _, root := range []common.Hash{header.Root, target} {
+		// The genesis state is missing if the node was synced from a
+		// checkpoint, there is nothing to retain then.
+		if root == header.Root && !rawdb.HasLegacyTrieNode(p.db, root) {
+			continue
+		}
+		if err := p.mark(root); err != nil {
+			return err
+		}
+	}
+	log.Info("Marked live state for online pruning", "root", target, "elapsed", common.PrettyDuration(time.Since(mstart)))
+
+	// Delete all the trie nodes not collected
+	p.setPhase(status, OnlineSweeping)
+	return p.sweep(status)
+}
+
+// mark iterates the state with the given root, retaining all its trie nodes
+// and the legacy contract codes keyed by their hash.
+func (p *OnlinePruner) mark(root common.Hash) error {
+	var (
+		// The target is entirely on disk, read it directly to keep the caches
+		// of the live trie database intact.
+		db     = triedb.NewDatabase(p.db, triedb.HashDefaults)
+		marked int
+		logged = time.Now()
+	)
+	retain := func(key []byte) error {
+		p.lock.Lock()
+		p.bloom.Put(key, nil)
+		p.lock.Unlock()
+
+		marked++
+		onlineMarkedMeter.Mark(1)
+
+		if marked%1024 == 0 {
+			select {
+			case <-p.quit:
+				return errPruningAborted
+			default:
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Marking live state", "root", root, "nodes", marked)
+				logged = time.Now()
+			}
+		}
+		return nil
+	}
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		return err
+	}
+	accIter, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	for accIter.Next(true) {
+		// Embedded nodes don't have hash.
+		if hash := accIter.Hash(); hash != (common.Hash{}) {
+			if err := retain(hash.Bytes()); err != nil {
+				return err
+			}
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root != types.EmptyRootHash {
+			id := trie.StorageTrieID(root, common.BytesToHash(accIter.LeafKey()), acc.Root)
+			storageTrie, err := trie.NewStateTrie(id, db)
+			if err != nil {
+				return err
+			}
+			storageIter, err := storageTrie.NodeIterator(nil)
+			if err != nil {
+				return err
+			}
+			for storageIter.Next(true) {
+				if hash := storageIter.Hash(); hash != (common.Hash{}) {
+					if err := retain(hash.Bytes()); err != nil {
+						return err
+					}
+				}
+			}
+			if storageIter.Error() != nil {
+				return storageIter.Error()
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash.Bytes()) {
+			if err := retain(acc.CodeHash); err != nil {
+				return err
+			}
+		}
+	}
+	return accIter.Error()
+}
+
+// sweep iterates the database from the persisted marker, deleting the trie
+// nodes and legacy contract codes not retained, at the configured rate.
+func (p *OnlinePruner) sweep(status *OnlineStatus) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		batch  = p.db.NewBatch()
+	)
+	for {
+		if p.syncing != nil && p.syncing() {
+			return errStateSyncing
+		}
+		// Collect the candidates of the next batch, the iterator is recreated
+		// for every batch in order to allow the compactor to drop the entries.
+		var (
+			iter  = p.db.NewIterator(nil, status.Marker)
+			keys  [][]byte
+			sizes []int
+			swept int
+			last  []byte
+		)
+		for len(keys) < sweepBatchNodes && swept < sweepBatchKeys && iter.Next() {
+			key := iter.Key()
+			swept++
+			last = key
+			if len(key) == common.HashLength {
+				keys = append(keys, common.CopyBytes(key))
+				sizes = append(sizes, len(key)+len(iter.Value()))
+			}
+		}
+		done := swept < sweepBatchKeys && len(keys) < sweepBatchNodes
+		if last != nil {
+			last = append(common.CopyBytes(last), 0)
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+		// Delete the candidates not retained. The check and the write must be
+		// atomic, so that a trie node inserted meanwhile is never deleted.
+		var deleted, size int
+		p.lock.Lock()
+		for i, key := range keys {
+			if p.bloom.Contain(key) {
+				continue
+			}
+			batch.Delete(key)
+			deleted++
+			size += sizes[i]
+		}
+		err := batch.Write()
+		p.lock.Unlock()
+		if err != nil {
+			return err
+		}
+		batch.Reset()
+
+		// Persist the progress and report it
+		status.Swept += uint64(swept)
+		status.Deleted += uint64(deleted)
+		status.Size += uint64(size)
+
+		onlineSweptMeter.Mark(int64(swept))
+		onlineDeletedMeter.Mark(int64(deleted))
+		onlineBytesMeter.Mark(int64(size))
+		if done {
+			onlineProgressGauge.Update(1)
+			return nil
+		}
+		status.Marker = last
+		writeOnlineStatus(p.db, status)
+		onlineProgressGauge.Update(status.Progress())
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning stale state", "swept", status.Swept, "deleted", status.Deleted, "size", common.StorageSize(status.Size),
+				"progress", status.Progress(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		// Throttle the deletions
+		if deleted > 0 {
+			select {
+			case <-time.After(p.limiter.ReserveN(time.Now(), deleted).Delay()):
+			case <-p.quit:
+				return errPruningAborted
+			}
+		} else {
+			select {
+			case <-p.quit:
+				return errPruningAborted
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+// newPruningTestChain creates a hash-based chain which flushes the state of
+// every block once it's old enough, along with the blocks to import into it.
+func newPruningTestChain(t *testing.T, n int) (ethdb.Database, *core.BlockChain, []*types.Block) {
+	t.Helper()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				address:           {Balance: big.NewInt(params.Ether)},
+				common.Address{1}: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{1}: {1}, {2}: {2}}},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, b *core.BlockGen) {
+		var to common.Address
+		binary.BigEndian.PutUint64(to[:], uint64(i+1))
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: b.TxNonce(address), To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: b.BaseFee()})
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	config := core.DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	config.SnapshotLimit = 0
+	chain, err := core.NewBlockChain(db, config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	chain.SetTrieFlushInterval(0)
+	return db, chain, blocks
+}
+
+// checkState iterates the entire state with the given root, failing if any
+// trie node is missing.
+func checkState(t *testing.T, db *triedb.Database, root common.Hash) {
+	t.Helper()
+
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		t.Fatalf("missing state %x: %v", root, err)
+	}
+	it, err := tr.NodeIterator(nil)
+	if err != nil {
+		t.Fatalf("failed to iterate state %x: %v", root, err)
+	}
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+			t.Fatalf("invalid account: %v", err)
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		st, err := trie.NewStateTrie(trie.StorageTrieID(root, common.BytesToHash(it.LeafKey()), acc.Root), db)
+		if err != nil {
+			t.Fatalf("missing storage %x: %v", acc.Root, err)
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("failed to iterate storage %x: %v", acc.Root, err)
+		}
+		for sit.Next(true) {
+		}
+		if sit.Error() != nil {
+			t.Fatalf("incomplete storage %x: %v", acc.Root, sit.Error())
+		}
+	}
+	if it.Error() != nil {
+		t.Fatalf("incomplete state %x: %v", root, it.Error())
+	}
+}
+
+// importUntilPruned imports the blocks one by one until the running pruning
+// cycle finishes, returning the number of blocks imported.
+func importUntilPruned(t *testing.T, db ethdb.Database, chain *core.BlockChain, blocks []*types.Block) int {
+	t.Helper()
+
+	for i, block := range blocks {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("failed to import block %d: %v", block.NumberU64(), err)
+		}
+		// Give the pruner a chance to progress between the imports
+		time.Sleep(time.Millisecond)
+
+		if status, _ := ReadOnlineStatus(db); status != nil && status.Finished != 0 {
+			return i + 1
+		}
+	}
+	t.Fatal("pruning cycle not finished")
+	return 0
+}
+
+func TestOnlinePruning(t *testing.T) {
+	db, chain, blocks := newPruningTestChain(t, 640)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:200]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	stale := blocks[10].Root()
+	if !rawdb.HasLegacyTrieNode(db, stale) {
+		t.Fatal("old state not flushed")
+	}
+	p, err := NewOnlinePruner(db, chain.TrieDB(), OnlineConfig{BloomSize: 1, Interval: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	defer p.Stop()
+
+	imported := 200 + importUntilPruned(t, db, chain, blocks[200:])
+	if _, err := chain.InsertChain(blocks[imported:]); err != nil {
+		t.Fatalf("failed to import blocks after pruning: %v", err)
+	}
+	status, err := ReadOnlineStatus(db)
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	if status.Phase != OnlineIdle || status.Started != 0 || len(status.Marker) != 0 {
+		t.Fatalf("unexpected status after pruning: %+v", status)
+	}
+	if status.Deleted == 0 || status.Swept < status.Deleted {
+		t.Fatalf("unexpected pruning counters: %+v", status)
+	}
+	if rawdb.HasLegacyTrieNode(db, stale) {
+		t.Fatal("stale state not pruned")
+	}
+	// The genesis and all the states still referenced must be retained
+	checkState(t, chain.TrieDB(), chain.Genesis().Root())
+	head := chain.CurrentBlock().Number.Uint64()
+	for number := head - 127; number <= head; number++ {
+		checkState(t, chain.TrieDB(), chain.GetHeaderByNumber(number).Root)
+	}
+}
+
+func TestOnlinePruningResume(t *testing.T) {
+	db, chain, blocks := newPruningTestChain(t, 640)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:200]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	// Pick a stale state root on each side of the marker, only the one above
+	// it is expected to be deleted.
+	var below, above common.Hash
+	for _, block := range blocks[:64] {
+		if block.Root()[0] < 0x80 {
+			below = block.Root()
+		} else {
+			above = block.Root()
+		}
+	}
+	if below == (common.Hash{}) || above == (common.Hash{}) {
+		t.Fatal("no stale state on both sides of the marker")
+	}
+	// Simulate a cycle interrupted in the middle of the sweeping
+	writeOnlineStatus(db, &OnlineStatus{
+		Phase:   OnlineSweeping,
+		Target:  blocks[50].Root(),
+		Marker:  []byte{0x80},
+		Started: uint64(time.Now().Unix()),
+		Swept:   100,
+	})
+	p, err := NewOnlinePruner(db, chain.TrieDB(), OnlineConfig{BloomSize: 1, Rate: 100000, Interval: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	defer p.Stop()
+
+	importUntilPruned(t, db, chain, blocks[200:])
+
+	status, err := ReadOnlineStatus(db)
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	if status.Swept <= 100 || status.Deleted == 0 {
+		t.Fatalf("unexpected pruning counters: %+v", status)
+	}
+	if !rawdb.HasLegacyTrieNode(db, below) {
+		t.Fatal("state below the marker pruned")
+	}
+	if rawdb.HasLegacyTrieNode(db, above) {
+		t.Fatal("stale state above the marker not pruned")
+	}
+	head := chain.CurrentBlock().Number.Uint64()
+	for number := head - 127; number <= head; number++ {
+		checkState(t, chain.TrieDB(), chain.GetHeaderByNumber(number).Root)
+	}
+}
@@ -0,0 +1,684 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/golang/snappy"
+)
+
+// exportMagic is the leading bytes of a state export, followed by the version
+// of the format.
+var exportMagic = []byte("gethsnap")
+
+// exportVersion is the current version of the state export format.
+const exportVersion = 0
+
+// exportChunkSize is the soft limit of the uncompressed size of a chunk, it's
+// a variable to allow testing with small chunks.
+var exportChunkSize = 1024 * 1024
+
+// Chunk kinds of the state export. The export starts with a header chunk,
+// followed by each range of accounts along with the storage of the accounts
+// in it, ending with the contract codes referenced by the accounts.
+const (
+	chunkHeader uint8 = iota
+	chunkAccounts
+	chunkStorage
+	chunkCodes
+)
+
+// exportChunk is the envelope of a chunk in the export, the data being the
+// snappy compressed RLP of the chunk content.
+type exportChunk struct {
+	Kind uint8
+	Data []byte
+}
+
+// exportAccount is an account in the slim snapshot format.
+type exportAccount struct {
+	Hash common.Hash
+	Body []byte
+}
+
+// exportAccounts is a consecutive range of accounts, along with the proof of
+// the range boundaries. The proof is omitted if the range covers the whole
+// account trie.
+type exportAccounts struct {
+	Accounts []exportAccount
+	Proof    [][]byte
+}
+
+// exportSlot is a storage slot in the snapshot format.
+type exportSlot struct {
+	Hash  common.Hash
+	Value []byte
+}
+
+// exportStorage is a consecutive range of storage slots of an account, along
+// with the proof of the range boundaries. The proof is omitted if the range
+// covers the whole storage trie.
+type exportStorage struct {
+	Account common.Hash
+	Slots   []exportSlot
+	Proof   [][]byte
+}
+
+// exportCodes is a batch of contract codes.
+type exportCodes struct {
+	Codes [][]byte
+}
+
+// exporter writes the chunks of a state export.
+type exporter struct {
+	w     io.Writer
+	start time.Time
+	log   time.Time
+
+	accounts uint64
+	slots    uint64
+	codes    uint64
+	size     common.StorageSize
+}
+
+// write encodes and compresses the chunk content, writing it out.
+func (e *exporter) write(kind uint8, content interface{}) error {
+	blob, err := rlp.EncodeToBytes(content)
+	if err != nil {
+		return err
+	}
+	chunk, err := rlp.EncodeToBytes(&exportChunk{Kind: kind, Data: snappy.Encode(nil, blob)})
+	if err != nil {
+		return err
+	}
+	if _, err := e.w.Write(chunk); err != nil {
+		return err
+	}
+	e.size += common.StorageSize(len(chunk))
+
+	if time.Since(e.log) > 8*time.Second {
+		log.Info("Exporting state snapshot", "accounts", e.accounts, "slots", e.slots, "codes", e.codes, "size", e.size, "elapsed", common.PrettyDuration(time.Since(e.start)))
+		e.log = time.Now()
+	}
+	return nil
+}
+
+// prove generates the proof of the range boundaries in the given trie.
+func prove(tr *trie.Trie, origin common.Hash, last common.Hash) ([][]byte, error) {
+	proof := trienode.NewProofSet()
+	if err := tr.Prove(origin[:], proof); err != nil {
+		return nil, err
+	}
+	if err := tr.Prove(last[:], proof); err != nil {
+		return nil, err
+	}
+	var nodes [][]byte
+	for _, node := range proof.List() {
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// nextHash returns the hash following the given one, reporting false if the
+// entire hash space is exhausted.
+func nextHash(hash common.Hash) (common.Hash, bool) {
+	next := increaseKey(common.CopyBytes(hash[:]))
+	if next == nil {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(next), true
+}
+
+// Export writes the state of the given block out in the binary export format,
+// retrieving the accounts and storage from the snapshot and proving each range
+// against the tries. The snapshot of the state must be fully generated.
+func Export(w io.Writer, snaptree *Tree, header *types.Header) error {
+	root := header.Root
+	if snaptree.Snapshot(root) == nil {
+		return fmt.Errorf("snapshot of state %#x is not available", root)
+	}
+	if generating, err := snaptree.generating(); err != nil {
+		return err
+	} else if generating {
+		return errors.New("snapshot is not fully generated")
+	}
+	accTrie, err := trie.New(trie.StateTrieID(root), snaptree.triedb)
+	if err != nil {
+		return err
+	}
+	it, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	if _, err := w.Write(append(common.CopyBytes(exportMagic), exportVersion)); err != nil {
+		return err
+	}
+	e := &exporter{w: w, start: time.Now(), log: time.Now()}
+	if err := e.write(chunkHeader, header); err != nil {
+		return err
+	}
+	var (
+		codes  = make(map[common.Hash]struct{})
+		origin common.Hash
+		first  = true
+		more   = it.Next()
+	)
+	for more {
+		var (
+			chunk    exportAccounts
+			size     int
+			accounts []*types.StateAccount
+		)
+		for more && size < exportChunkSize {
+			account, err := types.FullAccount(it.Account())
+			if err != nil {
+				return err
+			}
+			chunk.Accounts = append(chunk.Accounts, exportAccount{Hash: it.Hash(), Body: common.CopyBytes(it.Account())})
+			accounts = append(accounts, account)
+			size += common.HashLength + len(it.Account())
+			more = it.Next()
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+		last := chunk.Accounts[len(chunk.Accounts)-1].Hash
+		if !first || more {
+			if chunk.Proof, err = prove(accTrie, origin, last); err != nil {
+				return err
+			}
+		}
+		if err := e.write(chunkAccounts, &chunk); err != nil {
+			return err
+		}
+		e.accounts += uint64(len(chunk.Accounts))
+
+		// Export the storage of the accounts in the range, before moving on
+		// to the next range.
+		for i, account := range accounts {
+			if account.Root != types.EmptyRootHash {
+				if err := exportAccountStorage(e, snaptree, root, chunk.Accounts[i].Hash, account.Root); err != nil {
+					return err
+				}
+			}
+			if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+				codes[codeHash] = struct{}{}
+			}
+		}
+		first = false
+		origin, _ = nextHash(last)
+	}
+	// Export all the contract codes referenced
+	var (
+		chunk exportCodes
+		size  int
+	)
+	for hash := range codes {
+		code := rawdb.ReadCode(snaptree.diskdb, hash)
+		if len(code) == 0 {
+			return fmt.Errorf("missing contract code %#x", hash)
+		}
+		chunk.Codes = append(chunk.Codes, code)
+		size += len(code)
+
+		if size >= exportChunkSize {
+			if err := e.write(chunkCodes, &chunk); err != nil {
+				return err
+			}
+			e.codes += uint64(len(chunk.Codes))
+			chunk, size = exportCodes{}, 0
+		}
+	}
+	if len(chunk.Codes) > 0 {
+		if err := e.write(chunkCodes, &chunk); err != nil {
+			return err
+		}
+		e.codes += uint64(len(chunk.Codes))
+	}
+	log.Info("Exported state snapshot", "root", root, "accounts", e.accounts, "slots", e.slots, "codes", e.codes, "size", e.size, "elapsed", common.PrettyDuration(time.Since(e.start)))
+	return nil
+}
+
+// exportAccountStorage writes the storage of the account out in ranges.
+func exportAccountStorage(e *exporter, snaptree *Tree, root common.Hash, account common.Hash, storageRoot common.Hash) error {
+	it, err := snaptree.StorageIterator(root, account, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	var (
+		storageTrie *trie.Trie
+		origin      common.Hash
+		first       = true
+		more        = it.Next()
+	)
+	for more {
+		var (
+			chunk = exportStorage{Account: account}
+			size  int
+		)
+		for more && size < exportChunkSize {
+			chunk.Slots = append(chunk.Slots, exportSlot{Hash: it.Hash(), Value: common.CopyBytes(it.Slot())})
+			size += common.HashLength + len(it.Slot())
+			more = it.Next()
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+		last := chunk.Slots[len(chunk.Slots)-1].Hash
+		if !first || more {
+			if storageTrie == nil {
+				if storageTrie, err = trie.New(trie.StorageTrieID(root, account, storageRoot), snaptree.triedb); err != nil {
+					return err
+				}
+			}
+			if chunk.Proof, err = prove(storageTrie, origin, last); err != nil {
+				return err
+			}
+		}
+		if err := e.write(chunkStorage, &chunk); err != nil {
+			return err
+		}
+		e.slots += uint64(len(chunk.Slots))
+
+		first = false
+		origin, _ = nextHash(last)
+	}
+	return it.Error()
+}
+
+// importer verifies and writes the chunks of a state export.
+type importer struct {
+	batch  ethdb.Batch
+	scheme string
+	start  time.Time
+	log    time.Time
+
+	accTrie    *trie.StackTrie // Regenerated account trie
+	accOrigin  common.Hash     // Origin of the next account range
+	accDone    bool            // Whether all the accounts are imported
+	accounts   uint64
+	slots      uint64
+	codes      uint64
+	pendCodes  map[common.Hash]struct{} // Contract codes referenced but not imported yet
+	pendStores []pendingStorage         // Storage of the last account range not imported yet
+
+	storageTrie   *trie.StackTrie // Regenerated storage trie of the first pending account
+	storageOrigin common.Hash     // Origin of the next storage range of the first pending account
+}
+
+// pendingStorage is an account whose storage is not imported yet.
+type pendingStorage struct {
+	account common.Hash
+	root    common.Hash
+}
+
+// flush writes the batch out if it's large enough, or unconditionally if
+// forced.
+func (imp *importer) flush(force bool) error {
+	if !force && imp.batch.ValueSize() < ethdb.IdealBatchSize {
+		return nil
+	}
+	if err := imp.batch.Write(); err != nil {
+		return err
+	}
+	imp.batch.Reset()
+	return nil
+}
+
+// newStackTrie creates a stack trie which writes the nodes of the given trie
+// out into the batch.
+func (imp *importer) newStackTrie(owner common.Hash) *trie.StackTrie {
+	return trie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+		rawdb.WriteTrieNode(imp.batch, owner, path, hash, blob, imp.scheme)
+	})
+}
+
+// importAccounts verifies and imports a range of accounts.
+func (imp *importer) importAccounts(chunk *exportAccounts, root common.Hash) error {
+	if imp.accDone {
+		return errors.New("unexpected account range after the last one")
+	}
+	if len(imp.pendStores) > 0 {
+		return fmt.Errorf("missing storage of account %#x", imp.pendStores[0].account)
+	}
+	if len(chunk.Accounts) == 0 {
+		return errors.New("empty account range")
+	}
+	var (
+		keys   = make([][]byte, len(chunk.Accounts))
+		values = make([][]byte, len(chunk.Accounts))
+		slims  = make([][]byte, len(chunk.Accounts))
+	)
+	for i, entry := range chunk.Accounts {
+		account, err := types.FullAccount(entry.Body)
+		if err != nil {
+			return fmt.Errorf("invalid account %#x: %v", entry.Hash, err)
+		}
+		if values[i], err = rlp.EncodeToBytes(account); err != nil {
+			return err
+		}
+		keys[i] = common.CopyBytes(entry.Hash[:])
+		slims[i] = types.SlimAccountRLP(*account)
+
+		if account.Root != types.EmptyRootHash {
+			imp.pendStores = append(imp.pendStores, pendingStorage{account: entry.Hash, root: account.Root})
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+			imp.pendCodes[codeHash] = struct{}{}
+		}
+	}
+	var proof ethdb.KeyValueReader
+	if len(chunk.Proof) > 0 {
+		nodes := make(trienode.ProofList, 0, len(chunk.Proof))
+		for _, node := range chunk.Proof {
+			nodes = append(nodes, node)
+		}
+		proof = nodes.Set()
+	} else if imp.accOrigin != (common.Hash{}) {
+		return errors.New("missing proof of account range")
+	}
+	cont, err := trie.VerifyRangeProof(root, imp.accOrigin[:], keys, values, proof)
+	if err != nil {
+		return fmt.Errorf("invalid account range at %#x: %v", imp.accOrigin, err)
+	}
+	for i, entry := range chunk.Accounts {
+		rawdb.WriteAccountSnapshot(imp.batch, entry.Hash, slims[i])
+		imp.accTrie.Update(keys[i], values[i])
+	}
+	imp.accounts += uint64(len(chunk.Accounts))
+
+	last := chunk.Accounts[len(chunk.Accounts)-1].Hash
+	if next, ok := nextHash(last); ok && cont {
+		imp.accOrigin = next
+	} else {
+		imp.accDone = true
+	}
+	return imp.flush(false)
+}
+
+// importStorage verifies and imports a range of storage slots, which must
+// belong to the first account with pending storage.
+func (imp *importer) importStorage(chunk *exportStorage) error {
+	if len(imp.pendStores) == 0 || imp.pendStores[0].account != chunk.Account {
+		return fmt.Errorf("unexpected storage of account %#x", chunk.Account)
+	}
+	if len(chunk.Slots) == 0 {
+		return errors.New("empty storage range")
+	}
+	pending := imp.pendStores[0]
+	if imp.storageTrie == nil {
+		imp.storageTrie = imp.newStackTrie(chunk.Account)
+	}
+	var (
+		keys   = make([][]byte, len(chunk.Slots))
+		values = make([][]byte, len(chunk.Slots))
+	)
+	for i, slot := range chunk.Slots {
+		keys[i] = common.CopyBytes(slot.Hash[:])
+		values[i] = slot.Value
+	}
+	var proof ethdb.KeyValueReader
+	if len(chunk.Proof) > 0 {
+		nodes := make(trienode.ProofList, 0, len(chunk.Proof))
+		for _, node := range chunk.Proof {
+			nodes = append(nodes, node)
+		}
+		proof = nodes.Set()
+	} else if imp.storageOrigin != (common.Hash{}) {
+		return errors.New("missing proof of storage range")
+	}
+	cont, err := trie.VerifyRangeProof(pending.root, imp.storageOrigin[:], keys, values, proof)
+	if err != nil {
+		return fmt.Errorf("invalid storage range of %#x at %#x: %v", chunk.Account, imp.storageOrigin, err)
+	}
+	for i, slot := range chunk.Slots {
+		rawdb.WriteStorageSnapshot(imp.batch, chunk.Account, slot.Hash, slot.Value)
+		imp.storageTrie.Update(keys[i], values[i])
+	}
+	imp.slots += uint64(len(chunk.Slots))
+
+	last := chunk.Slots[len(chunk.Slots)-1].Hash
+	if next, ok := nextHash(last); ok && cont {
+		imp.storageOrigin = next
+		return imp.flush(false)
+	}
+	// The storage of the account is complete, verify the regenerated root
+	if hash := imp.storageTrie.Hash(); hash != pending.root {
+		return fmt.Errorf("storage root mismatch of account %#x: have %#x, want %#x", chunk.Account, hash, pending.root)
+	}
+	imp.pendStores = imp.pendStores[1:]
+	imp.storageTrie, imp.storageOrigin = nil, common.Hash{}
+	return imp.flush(false)
+}
+
+// importCodes verifies and imports a batch of contract codes.
+func (imp *importer) importCodes(chunk *exportCodes) error {
+	for _, code := range chunk.Codes {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := imp.pendCodes[hash]; !ok {
+			return fmt.Errorf("unexpected contract code %#x", hash)
+		}
+		delete(imp.pendCodes, hash)
+		rawdb.WriteCode(imp.batch, hash, code)
+	}
+	imp.codes += uint64(len(chunk.Codes))
+	return imp.flush(false)
+}
+
+// wipeEntries deletes all the entries with the given prefix which are accepted
+// by the filter.
+func wipeEntries(db ethdb.KeyValueStore, prefix []byte, filter func(key []byte) bool) error {
+	var (
+		it    = db.NewIterator(prefix, nil)
+		batch = db.NewBatch()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if !filter(it.Key()) {
+			continue
+		}
+		batch.Delete(it.Key())
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// wipeSnapshot deletes all the account and storage snapshot entries.
+func wipeSnapshot(db ethdb.KeyValueStore) error {
+	for _, prefix := range [][]byte{rawdb.SnapshotAccountPrefix, rawdb.SnapshotStoragePrefix} {
+		keyLen := len(prefix) + common.HashLength
+		if bytes.Equal(prefix, rawdb.SnapshotStoragePrefix) {
+			keyLen += common.HashLength
+		}
+		if err := wipeEntries(db, prefix, func(key []byte) bool { return len(key) == keyLen }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wipeTrieNodes deletes all the account and storage trie nodes of the path-based
+// scheme. The nodes are keyed by path, so the nodes of the old state which are
+// not overwritten by the imported one would be left dangling otherwise.
+func wipeTrieNodes(db ethdb.KeyValueStore) error {
+	if err := wipeEntries(db, rawdb.TrieNodeAccountPrefix, rawdb.IsAccountTrieNode); err != nil {
+		return err
+	}
+	return wipeEntries(db, rawdb.TrieNodeStoragePrefix, rawdb.IsStorageTrieNode)
+}
+
+// Import reads a state export, verifying each range against the state root of
+// the exported header, and writes the regenerated tries along with the snapshot
+// and contract codes into the database. The header of the block the state
+// belongs to is returned.
+//
+// The existing snapshot is replaced by the imported one. The path-based trie
+// database is reset to the imported state, the trie nodes of the old state and
+// any state history being dropped.
+func Import(r io.Reader, db ethdb.KeyValueStore, triedb *triedb.Database) (*types.Header, error) {
+	magic := make([]byte, len(exportMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("failed to read export magic: %v", err)
+	}
+	if !bytes.Equal(magic[:len(exportMagic)], exportMagic) {
+		return nil, errors.New("not a state export")
+	}
+	if version := magic[len(exportMagic)]; version != exportVersion {
+		return nil, fmt.Errorf("unsupported state export version %d", version)
+	}
+	var (
+		stream = rlp.NewStream(r, 0)
+		header *types.Header
+	)
+	next := func() (uint8, []byte, error) {
+		var chunk exportChunk
+		if err := stream.Decode(&chunk); err != nil {
+			return 0, nil, err
+		}
+		blob, err := snappy.Decode(nil, chunk.Data)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to decompress chunk: %v", err)
+		}
+		return chunk.Kind, blob, nil
+	}
+	kind, blob, err := next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	if kind != chunkHeader {
+		return nil, fmt.Errorf("unexpected chunk kind %d, want header", kind)
+	}
+	if err := rlp.DecodeBytes(blob, &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	log.Info("Importing state snapshot", "number", header.Number, "hash", header.Hash(), "root", header.Root)
+
+	// Drop the existing snapshot and deactivate the path-based trie database,
+	// the persistent state of which is replaced.
+	if triedb.Scheme() == rawdb.PathScheme {
+		if err := triedb.Disable(); err != nil {
+			return nil, err
+		}
+		if err := wipeTrieNodes(db); err != nil {
+			return nil, err
+		}
+	}
+	rawdb.DeleteSnapshotRoot(db)
+	rawdb.DeleteSnapshotJournal(db)
+	rawdb.DeleteSnapshotGenerator(db)
+	rawdb.DeleteSnapshotRecoveryNumber(db)
+	if err := wipeSnapshot(db); err != nil {
+		return nil, err
+	}
+	imp := &importer{
+		batch:     db.NewBatch(),
+		scheme:    triedb.Scheme(),
+		start:     time.Now(),
+		log:       time.Now(),
+		pendCodes: make(map[common.Hash]struct{}),
+	}
+	imp.accTrie = imp.newStackTrie(common.Hash{})
+
+	for {
+		kind, blob, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk: %v", err)
+		}
+		switch kind {
+		case chunkAccounts:
+			var chunk exportAccounts
+			if err := rlp.DecodeBytes(blob, &chunk); err != nil {
+				return nil, fmt.Errorf("invalid account range: %v", err)
+			}
+			err = imp.importAccounts(&chunk, header.Root)
+		case chunkStorage:
+			var chunk exportStorage
+			if err := rlp.DecodeBytes(blob, &chunk); err != nil {
+				return nil, fmt.Errorf("invalid storage range: %v", err)
+			}
+			err = imp.importStorage(&chunk)
+		case chunkCodes:
+			var chunk exportCodes
+			if err := rlp.DecodeBytes(blob, &chunk); err != nil {
+				return nil, fmt.Errorf("invalid contract codes: %v", err)
+			}
+			err = imp.importCodes(&chunk)
+		default:
+			err = fmt.Errorf("unexpected chunk kind %d", kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(imp.log) > 8*time.Second {
+			log.Info("Importing state snapshot", "accounts", imp.accounts, "slots", imp.slots, "codes", imp.codes, "elapsed", common.PrettyDuration(time.Since(imp.start)))
+			imp.log = time.Now()
+		}
+	}
+	// Ensure the state is complete and matches the header
+	if len(imp.pendStores) > 0 {
+		return nil, fmt.Errorf("missing storage of account %#x", imp.pendStores[0].account)
+	}
+	if len(imp.pendCodes) > 0 {
+		return nil, fmt.Errorf("missing %d contract codes", len(imp.pendCodes))
+	}
+	if hash := imp.accTrie.Hash(); hash != header.Root {
+		return nil, fmt.Errorf("state root mismatch: have %#x, want %#x", hash, header.Root)
+	}
+	rawdb.WriteSnapshotRoot(imp.batch, header.Root)
+	journalProgress(imp.batch, nil, nil)
+	if err := imp.flush(true); err != nil {
+		return nil, err
+	}
+	if triedb.Scheme() == rawdb.PathScheme {
+		if err := triedb.Enable(header.Root); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("Imported state snapshot", "root", header.Root, "accounts", imp.accounts, "slots", imp.slots, "codes", imp.codes, "elapsed", common.PrettyDuration(time.Since(imp.start)))
+	return header, nil
+}
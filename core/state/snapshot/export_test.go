@@ -0,0 +1,245 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// newExportTestState creates a state with plain accounts, contracts with
+// storage and code, and a contract with a large storage, returning the
+// snapshot tree of it.
+func newExportTestState(t *testing.T, scheme string) (*testHelper, *Tree, common.Hash) {
+	t.Helper()
+
+	helper := newHelper(scheme)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("acc-%d", i)
+		acc := &types.StateAccount{Balance: uint256.NewInt(uint64(i + 1)), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+		if i%10 == 0 {
+			var keys, vals []string
+			for j := 0; j < 1+i*2; j++ {
+				keys = append(keys, fmt.Sprintf("key-%d", j))
+				vals = append(vals, fmt.Sprintf("val-%d-%d", i, j))
+			}
+			acc.Root = helper.makeStorageTrie(hashData([]byte(key)), keys, vals, true)
+
+			code := []byte(fmt.Sprintf("code-%d", i%30))
+			rawdb.WriteCode(helper.diskdb, crypto.Keccak256Hash(code), code)
+			acc.CodeHash = crypto.Keccak256(code)
+		}
+		helper.addTrieAccount(key, acc)
+	}
+	root := helper.Commit()
+	snaptree, err := New(Config{CacheSize: 16}, helper.diskdb, helper.triedb, root)
+	if err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	return helper, snaptree, root
+}
+
+// newExportTestDatabase creates an empty database to import the state into.
+func newExportTestDatabase(scheme string) (ethdb.Database, *triedb.Database) {
+	db := rawdb.NewMemoryDatabase()
+	config := &triedb.Config{}
+	if scheme == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{}
+	} else {
+		config.HashDB = &hashdb.Config{}
+	}
+	return db, triedb.NewDatabase(db, config)
+}
+
+func TestExportImport(t *testing.T) {
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		for _, size := range []int{exportChunkSize, 256} {
+			t.Run(fmt.Sprintf("%s-%d", scheme, size), func(t *testing.T) {
+				testExportImport(t, scheme, size)
+			})
+		}
+	}
+}
+
+func testExportImport(t *testing.T, scheme string, chunkSize int) {
+	defer func(old int) { exportChunkSize = old }(exportChunkSize)
+	exportChunkSize = chunkSize
+
+	_, snaptree, root := newExportTestState(t, scheme)
+	header := &types.Header{Number: big.NewInt(10), Root: root, Difficulty: common.Big0}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, snaptree, header); err != nil {
+		t.Fatalf("failed to export state: %v", err)
+	}
+	db, tdb := newExportTestDatabase(scheme)
+	imported, err := Import(&buf, db, tdb)
+	if err != nil {
+		t.Fatalf("failed to import state: %v", err)
+	}
+	if imported.Hash() != header.Hash() {
+		t.Fatalf("header mismatch: have %x, want %x", imported.Hash(), header.Hash())
+	}
+	// The regenerated tries must be complete and equal to the exported ones
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), tdb)
+	if err != nil {
+		t.Fatalf("missing imported state: %v", err)
+	}
+	it, err := tr.NodeIterator(nil)
+	if err != nil {
+		t.Fatalf("failed to iterate state: %v", err)
+	}
+	var accounts, slots int
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		accounts++
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+			t.Fatalf("invalid account: %v", err)
+		}
+		if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash[:]) && !rawdb.HasCode(db, common.BytesToHash(acc.CodeHash)) {
+			t.Fatalf("missing code %x", acc.CodeHash)
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		st, err := trie.NewStateTrie(trie.StorageTrieID(root, common.BytesToHash(it.LeafKey()), acc.Root), tdb)
+		if err != nil {
+			t.Fatalf("missing imported storage: %v", err)
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("failed to iterate storage: %v", err)
+		}
+		for sit.Next(true) {
+			if sit.Leaf() {
+				slots++
+			}
+		}
+		if sit.Error() != nil {
+			t.Fatalf("incomplete storage: %v", sit.Error())
+		}
+	}
+	if it.Error() != nil {
+		t.Fatalf("incomplete state: %v", it.Error())
+	}
+	if accounts != 200 || slots != 3820 {
+		t.Fatalf("unexpected state size: have %d accounts %d slots", accounts, slots)
+	}
+	// The imported snapshot must be usable right away
+	imptree, err := New(Config{CacheSize: 16, NoBuild: true}, db, tdb, root)
+	if err != nil {
+		t.Fatalf("failed to load imported snapshot: %v", err)
+	}
+	if err := imptree.Verify(root); err != nil {
+		t.Fatalf("invalid imported snapshot: %v", err)
+	}
+}
+
+// Tests that importing a state with inconsistent flat data is rejected.
+func TestImportCorrupted(t *testing.T) {
+	defer func(old int) { exportChunkSize = old }(exportChunkSize)
+	exportChunkSize = 256
+
+	for i, corrupt := range []func(db ethdb.KeyValueWriter){
+		// Modified account in the middle of the state
+		func(db ethdb.KeyValueWriter) {
+			acc := types.StateAccount{Balance: uint256.NewInt(1000), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+			rawdb.WriteAccountSnapshot(db, hashData([]byte("acc-101")), types.SlimAccountRLP(acc))
+		},
+		// Extra account
+		func(db ethdb.KeyValueWriter) {
+			acc := types.StateAccount{Balance: uint256.NewInt(1), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+			rawdb.WriteAccountSnapshot(db, hashData([]byte("acc-extra")), types.SlimAccountRLP(acc))
+		},
+		// Modified storage slot
+		func(db ethdb.KeyValueWriter) {
+			rawdb.WriteStorageSnapshot(db, hashData([]byte("acc-190")), hashData([]byte("key-100")), []byte("val"))
+		},
+		// Missing storage slot
+		func(db ethdb.KeyValueWriter) {
+			rawdb.DeleteStorageSnapshot(db, hashData([]byte("acc-20")), hashData([]byte("key-0")))
+		},
+	} {
+		helper, snaptree, root := newExportTestState(t, rawdb.HashScheme)
+		snaptree.Release()
+		corrupt(helper.diskdb)
+
+		snaptree, err := New(Config{CacheSize: 16, NoBuild: true}, helper.diskdb, helper.triedb, root)
+		if err != nil {
+			t.Fatalf("case %d: failed to load snapshot: %v", i, err)
+		}
+		var buf bytes.Buffer
+		if err := Export(&buf, snaptree, &types.Header{Number: common.Big1, Root: root, Difficulty: common.Big0}); err != nil {
+			t.Fatalf("case %d: failed to export state: %v", i, err)
+		}
+		db, tdb := newExportTestDatabase(rawdb.HashScheme)
+		if _, err := Import(&buf, db, tdb); err == nil {
+			t.Fatalf("case %d: corrupted state imported", i)
+		}
+	}
+}
+
+// Tests that importing into a path-based database holding another state drops
+// the trie nodes of the old state.
+func TestImportStaleTrieNodes(t *testing.T) {
+	_, snaptree, root := newExportTestState(t, rawdb.PathScheme)
+	header := &types.Header{Number: common.Big1, Root: root, Difficulty: common.Big0}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, snaptree, header); err != nil {
+		t.Fatalf("failed to export state: %v", err)
+	}
+	// Leave trie nodes of an old state behind at paths which the imported state
+	// doesn't overwrite
+	db, tdb := newExportTestDatabase(rawdb.PathScheme)
+	var (
+		owner = common.HexToHash("0xdead")
+		path  = []byte{0xf, 0xf, 0xf, 0xf, 0xf, 0xf}
+	)
+	rawdb.WriteAccountTrieNode(db, path, []byte("stale node"))
+	rawdb.WriteStorageTrieNode(db, owner, nil, []byte("stale node"))
+
+	if _, err := Import(&buf, db, tdb); err != nil {
+		t.Fatalf("failed to import state: %v", err)
+	}
+	if rawdb.HasAccountTrieNode(db, path) {
+		t.Error("stale account trie node left behind")
+	}
+	if rawdb.HasStorageTrieNode(db, owner, nil) {
+		t.Error("stale storage trie node left behind")
+	}
+	if _, err := trie.NewStateTrie(trie.StateTrieID(root), tdb); err != nil {
+		t.Fatalf("missing imported state: %v", err)
+	}
+}
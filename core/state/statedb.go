@@ -208,6 +208,11 @@ func (s *StateDB) SetLogger(l *tracing.Hooks) {
 	s.logger = l
 }
 
+// Logger returns the logger for account update hooks.
+func (s *StateDB) Logger() *tracing.Hooks {
+	return s.logger
+}
+
 // StartPrefetcher initializes a new trie prefetcher to pull in nodes from the
 // state trie concurrently while the state is mutated so that when we reach the
 // commit phase, most of the needed data is already hot.
@@ -841,6 +846,18 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 	// Finalise all the dirty storage states and write them into the tries
 	s.Finalise(deleteEmptyObjects)
 
+	// The historical states are served without their tries, so a mutated one
+	// can't be hashed. Fail explicitly instead of returning the original root.
+	if _, ok := s.db.(*historicDB); ok {
+		for _, op := range s.mutations {
+			if !op.applied {
+				s.setError(errHistoricStateReadOnly)
+				return common.Hash{}
+			}
+		}
+		return s.trie.Hash()
+	}
+
 	// If there was a trie prefetcher operating, it gets aborted and irrevocably
 	// modified after we start retrieving tries. Remove it from the statedb after
 	// this round of use.
@@ -1143,6 +1160,9 @@ func (s *StateDB) Commit(block uint64, deleteEmptyObjects bool) (common.Hash, er
 	}
 	// Finalize any pending changes and merge everything into the tries
 	s.IntermediateRoot(deleteEmptyObjects)
+	if s.dbErr != nil {
+		return common.Hash{}, fmt.Errorf("commit aborted due to database error: %v", s.dbErr)
+	}
 
 	// Commit objects to the trie, measuring the elapsed time
 	var (
@@ -311,8 +311,12 @@ type BlobPool struct {
 	spent  map[common.Address]*uint256.Int  // Expenditure tracking for individual accounts
 	evict  *evictHeap                       // Heap of cheapest accounts for eviction when full
 
-	discoverFeed event.Feed // Event feed to send out new tx events on pool discovery (reorg excluded)
-	insertFeed   event.Feed // Event feed to send out new tx events on pool inclusion (reorg included)
+	senders     map[common.Hash]common.Address // Senders from the metadata snapshot, only set during init
+	snapshotted time.Time                      // Time of the last metadata snapshot regeneration
+
+	discoverFeed event.Feed         // Event feed to send out new tx events on pool discovery (reorg excluded)
+	insertFeed   event.Feed         // Event feed to send out new tx events on pool inclusion (reorg included)
+	txEvents     txpool.TxEventFeed // Event feed to send out tx lifecycle events
 
 	lock sync.RWMutex // Mutex protecting the pool during reorg handling
 }
@@ -371,6 +375,13 @@ func (p *BlobPool) Init(gasTip uint64, head *types.Header, reserve txpool.Addres
 	}
 	p.head, p.state = head, state
 
+	// Load the metadata snapshot, if enabled, to avoid recovering the senders of
+	// the stored transactions
+	if path := p.snapshotPath(); path != "" {
+		if p.senders, err = loadMetaSnapshot(path); err != nil {
+			log.Warn("Failed to load blob pool metadata snapshot", "err", err)
+		}
+	}
 	// Index all transactions on disk and delete anything unprocessable
 	var fails []uint64
 	index := func(id uint64, size uint32, blob []byte) {
@@ -383,6 +394,7 @@ func (p *BlobPool) Init(gasTip uint64, head *types.Header, reserve txpool.Addres
 		return err
 	}
 	p.store = store
+	p.senders = nil
 
 	if len(fails) > 0 {
 		log.Warn("Dropping invalidated blob transactions", "ids", fails)
@@ -430,12 +442,20 @@ func (p *BlobPool) Init(gasTip uint64, head *types.Header, reserve txpool.Addres
 	// Update the metrics and return the constructed pool
 	datacapGauge.Update(int64(p.config.Datacap))
 	p.updateStorageMetrics()
+	p.snapshotted = time.Now()
 	return nil
 }
 
 // Close closes down the underlying persistent store.
 func (p *BlobPool) Close() error {
 	var errs []error
+	if p.store != nil && p.snapshotPath() != "" { // Close might be invoked due to error in constructor, before the pool is indexed
+		p.lock.Lock()
+		if err := p.saveMetaSnapshot(); err != nil {
+			errs = append(errs, err)
+		}
+		p.lock.Unlock()
+	}
 	if p.limbo != nil { // Close might be invoked due to error in constructor, before p,limbo is set
 		if err := p.limbo.Close(); err != nil {
 			errs = append(errs, err)
@@ -478,13 +498,16 @@ func (p *BlobPool) parseTransaction(id uint64, size uint32, blob []byte) error {
 		log.Error("Rejecting duplicate blob pool entry", "id", id, "hash", tx.Hash())
 		return errors.New("duplicate blob entry")
 	}
-	sender, err := p.signer.Sender(tx)
-	if err != nil {
-		// This path is impossible unless the signature validity changes across
-		// restarts. For that ever improbable case, recover gracefully by ignoring
-		// this data entry.
-		log.Error("Failed to recover blob tx sender", "id", id, "hash", tx.Hash(), "err", err)
-		return err
+	sender, ok := p.senders[meta.hash]
+	if !ok {
+		var err error
+		if sender, err = p.signer.Sender(tx); err != nil {
+			// This path is impossible unless the signature validity changes across
+			// restarts. For that ever improbable case, recover gracefully by ignoring
+			// this data entry.
+			log.Error("Failed to recover blob tx sender", "id", id, "hash", tx.Hash(), "err", err)
+			return err
+		}
 	}
 	if _, ok := p.index[sender]; !ok {
 		if err := p.reserve(sender, true); err != nil {
@@ -526,12 +549,17 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids    []uint64
 			nonces []uint64
 		)
+		reason := txpool.TxEventNonceTooLow
+		if gapped {
+			reason = txpool.TxEventInvalidated
+		}
 		for i := 0; i < len(txs); i++ {
 			ids = append(ids, txs[i].id)
 			nonces = append(nonces, txs[i].nonce)
 
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
+			p.txEvent(reason, addr, txs[i].hash)
 
 			// Included transactions blobs need to be moved to the limbo
 			if filled && inclusions != nil {
@@ -573,6 +601,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[0].costCap)
 			p.stored -= uint64(txs[0].size)
 			delete(p.lookup, txs[0].hash)
+			p.txEvent(txpool.TxEventNonceTooLow, addr, txs[0].hash)
 
 			// Included transactions blobs need to be moved to the limbo
 			if inclusions != nil {
@@ -629,6 +658,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
+			p.txEvent(txpool.TxEventInvalidated, addr, txs[i].hash)
 
 			if err := p.store.Delete(id); err != nil {
 				log.Error("Failed to delete blob transaction", "from", addr, "id", id, "err", err)
@@ -651,6 +681,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[j].costCap)
 			p.stored -= uint64(txs[j].size)
 			delete(p.lookup, txs[j].hash)
+			p.txEvent(txpool.TxEventInvalidated, addr, txs[j].hash)
 		}
 		txs = txs[:i]
 
@@ -689,6 +720,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.txEvent(txpool.TxEventInvalidated, addr, last.hash)
 		}
 		if len(txs) == 0 {
 			delete(p.index, addr)
@@ -729,6 +761,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.txEvent(txpool.TxEventEvicted, addr, last.hash)
 		}
 		p.index[addr] = txs
 
@@ -780,6 +813,8 @@ func (p *BlobPool) offload(addr common.Address, nonce uint64, id uint64, inclusi
 // Reset implements txpool.SubPool, allowing the blob pool's internal state to be
 // kept in sync with the main transaction pool's internal state.
 func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	defer p.txEvents.Flush()
+
 	waitStart := time.Now()
 	p.lock.Lock()
 	resetwaitHist.Update(time.Since(waitStart).Nanoseconds())
@@ -806,6 +841,7 @@ func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
 			for _, tx := range txs {
 				if err := p.reinject(addr, tx.Hash()); err == nil {
 					adds = append(adds, tx.WithoutBlobTxSidecar())
+					p.txEvent(txpool.TxEventAdded, addr, tx.Hash())
 				}
 			}
 			// Recheck the account's pooled transactions to drop included and
@@ -833,6 +869,13 @@ func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
 	basefeeGauge.Update(int64(basefee.Uint64()))
 	blobfeeGauge.Update(int64(blobfee.Uint64()))
 	p.updateStorageMetrics()
+
+	// Periodically regenerate the metadata snapshot to survive crashes
+	if p.snapshotPath() != "" && time.Since(p.snapshotted) > snapshotInterval {
+		if err := p.saveMetaSnapshot(); err != nil {
+			log.Warn("Failed to write blob pool metadata snapshot", "err", err)
+		}
+	}
 }
 
 // reorg assembles all the transactors and missing transactions between an old
@@ -1014,6 +1057,8 @@ func (p *BlobPool) reinject(addr common.Address, txhash common.Hash) error {
 // SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
 // to be kept in sync with the main transaction pool's gas requirements.
 func (p *BlobPool) SetGasTip(tip *big.Int) {
+	defer p.txEvents.Flush()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -1034,6 +1079,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 					p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 					p.stored -= uint64(tx.size)
 					delete(p.lookup, tx.hash)
+					p.txEvent(txpool.TxEventUnderpriced, addr, tx.hash)
 					txs[i] = nil
 
 					// Drop everything afterwards, no gaps allowed
@@ -1044,6 +1090,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 						p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], tx.costCap)
 						p.stored -= uint64(tx.size)
 						delete(p.lookup, tx.hash)
+						p.txEvent(txpool.TxEventInvalidated, addr, tx.hash)
 						txs[i+1+j] = nil
 					}
 					// Clear out the dropped transactions from the index
@@ -1223,6 +1270,7 @@ func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error
 		p.discoverFeed.Send(core.NewTxsEvent{Txs: adds})
 		p.insertFeed.Send(core.NewTxsEvent{Txs: adds})
 	}
+	p.txEvents.Flush()
 	return errs
 }
 
@@ -1322,6 +1370,8 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 		delete(p.lookup, prev.hash)
 		p.lookup[meta.hash] = meta.id
 		p.stored += uint64(meta.size) - uint64(prev.size)
+
+		p.txEvents.Queue(txpool.TxEvent{Hash: prev.hash, From: from, Type: txpool.TxEventReplaced, Replacement: &meta.hash})
 	} else {
 		// Transaction extends previously scheduled ones
 		p.index[from] = append(p.index[from], meta)
@@ -1380,6 +1430,8 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 			heap.Fix(p.evict, p.evict.index[from])
 		}
 	}
+	p.txEvent(txpool.TxEventAdded, from, meta.hash)
+
 	// If the pool went over the allowed data limit, evict transactions until
 	// we're again below the threshold
 	for p.stored > p.config.Datacap {
@@ -1420,6 +1472,7 @@ func (p *BlobPool) drop() {
 	}
 	p.stored -= uint64(drop.size)
 	delete(p.lookup, drop.hash)
+	p.txEvent(txpool.TxEventEvicted, from, drop.hash)
 
 	// Remove the transaction from the pool's eviction heap:
 	//   - If the entire account was dropped, pop off the address
@@ -1595,6 +1648,17 @@ func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool
 	}
 }
 
+// SubscribeTxEvents registers a subscription for the lifecycle events of the
+// pooled transactions.
+func (p *BlobPool) SubscribeTxEvents(ch chan<- []txpool.TxEvent) event.Subscription {
+	return p.txEvents.Subscribe(ch)
+}
+
+// txEvent queues a lifecycle event of the given type for a transaction.
+func (p *BlobPool) txEvent(typ txpool.TxEventType, from common.Address, hash common.Hash) {
+	p.txEvents.Queue(txpool.TxEvent{Hash: hash, From: from, Type: typ})
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *BlobPool) Nonce(addr common.Address) uint64 {
@@ -1313,6 +1313,154 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+// Tests that the metadata snapshot is written on shutdown and used to index the
+// stored transactions on the next startup.
+func TestMetadataSnapshot(t *testing.T) {
+	storage, _ := os.MkdirTemp("", "blobpool-")
+	defer os.RemoveAll(storage)
+
+	os.MkdirAll(filepath.Join(storage, pendingTransactionStore), 0700)
+	store, _ := billy.Open(billy.Options{Path: filepath.Join(storage, pendingTransactionStore)}, newSlotter(), nil)
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	var hashes []common.Hash
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx := makeTx(nonce, 1, 1, 1, key)
+		blob, _ := rlp.EncodeToBytes(tx)
+		store.Put(blob)
+		hashes = append(hashes, tx.Hash())
+	}
+	store.Close()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewDatabase(memorydb.New())), nil)
+	statedb.AddBalance(addr, uint256.NewInt(1000000000), tracing.BalanceChangeUnspecified)
+	statedb.Commit(0, true)
+
+	chain := &testBlockChain{
+		config:  testChainConfig,
+		basefee: uint256.NewInt(params.InitialBaseFee),
+		blobfee: uint256.NewInt(params.BlobTxMinBlobGasprice),
+		statedb: statedb,
+	}
+	config := Config{Datadir: storage, Snapshot: true}
+
+	pool := New(config, chain)
+	if err := pool.Init(1, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("failed to close blob pool: %v", err)
+	}
+	senders, err := loadMetaSnapshot(filepath.Join(storage, metadataSnapshot))
+	if err != nil {
+		t.Fatalf("failed to load metadata snapshot: %v", err)
+	}
+	if len(senders) != len(hashes) {
+		t.Fatalf("snapshot size mismatch: have %d, want %d", len(senders), len(hashes))
+	}
+	for _, hash := range hashes {
+		if senders[hash] != addr {
+			t.Errorf("snapshot sender mismatch for %x: have %x, want %x", hash, senders[hash], addr)
+		}
+	}
+	// Reopen the pool from the snapshot and ensure it is indexed correctly
+	pool = New(config, chain)
+	if err := pool.Init(1, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to reopen blob pool: %v", err)
+	}
+	defer pool.Close()
+
+	if len(pool.index[addr]) != len(hashes) {
+		t.Fatalf("indexed transaction count mismatch: have %d, want %d", len(pool.index[addr]), len(hashes))
+	}
+	if pool.senders != nil {
+		t.Errorf("snapshotted senders retained after startup")
+	}
+	verifyPoolInternals(t, pool)
+}
+
+// Tests that the lifecycle events of the pooled transactions are emitted with
+// the reasons of them being dropped.
+func TestTxEvents(t *testing.T) {
+	storage, _ := os.MkdirTemp("", "blobpool-")
+	defer os.RemoveAll(storage)
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewDatabase(memorydb.New())), nil)
+	statedb.AddBalance(addr, uint256.NewInt(1000000000), tracing.BalanceChangeUnspecified)
+	statedb.Commit(0, true)
+
+	chain := &testBlockChain{
+		config:  testChainConfig,
+		basefee: uint256.NewInt(params.InitialBaseFee),
+		blobfee: uint256.NewInt(params.BlobTxMinBlobGasprice),
+		statedb: statedb,
+	}
+	pool := New(Config{Datadir: storage}, chain)
+	if err := pool.Init(1, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Close()
+
+	events := make(chan []txpool.TxEvent, 16)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	check := func(want ...txpool.TxEvent) {
+		t.Helper()
+
+		var have []txpool.TxEvent
+		select {
+		case have = <-events:
+		case <-time.After(time.Second):
+		}
+		if len(have) != len(want) {
+			t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash != want[i].Hash || have[i].From != want[i].From || have[i].Type != want[i].Type {
+				t.Errorf("event %d mismatch: have %+v, want %+v", i, have[i], want[i])
+			}
+			if (have[i].Replacement == nil) != (want[i].Replacement == nil) || (want[i].Replacement != nil && *have[i].Replacement != *want[i].Replacement) {
+				t.Errorf("event %d replacement mismatch: have %v, want %v", i, have[i].Replacement, want[i].Replacement)
+			}
+		}
+	}
+	// Add a transaction and replace it with a better paying one
+	tx := makeTx(0, 10, 10, 10, key)
+	if errs := pool.Add([]*types.Transaction{tx}, false, true); errs[0] != nil {
+		t.Fatalf("failed to add transaction: %v", errs[0])
+	}
+	check(txpool.TxEvent{Hash: tx.Hash(), From: addr, Type: txpool.TxEventAdded})
+
+	replacement := makeTx(0, 20, 20, 20, key)
+	if errs := pool.Add([]*types.Transaction{replacement}, false, true); errs[0] != nil {
+		t.Fatalf("failed to replace transaction: %v", errs[0])
+	}
+	hash := replacement.Hash()
+	check(
+		txpool.TxEvent{Hash: tx.Hash(), From: addr, Type: txpool.TxEventReplaced, Replacement: &hash},
+		txpool.TxEvent{Hash: hash, From: addr, Type: txpool.TxEventAdded},
+	)
+	// Append a transaction and raise the minimum tip above the first one
+	next := makeTx(1, 30, 30, 30, key)
+	if errs := pool.Add([]*types.Transaction{next}, false, true); errs[0] != nil {
+		t.Fatalf("failed to add transaction: %v", errs[0])
+	}
+	check(txpool.TxEvent{Hash: next.Hash(), From: addr, Type: txpool.TxEventAdded})
+
+	pool.SetGasTip(big.NewInt(25))
+	check(
+		txpool.TxEvent{Hash: hash, From: addr, Type: txpool.TxEventUnderpriced},
+		txpool.TxEvent{Hash: next.Hash(), From: addr, Type: txpool.TxEventInvalidated},
+	)
+	verifyPoolInternals(t, pool)
+}
+
 // Benchmarks the time it takes to assemble the lazy pending transaction list
 // from the pool contents.
 func BenchmarkPoolPending100Mb(b *testing.B) { benchmarkPoolPending(b, 100_000_000) }
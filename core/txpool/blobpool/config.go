@@ -25,6 +25,7 @@ type Config struct {
 	Datadir   string // Data directory containing the currently executable blobs
 	Datacap   uint64 // Soft-cap of database storage (hard cap is larger due to overhead)
 	PriceBump uint64 // Minimum price bump percentage to replace an already existing nonce
+	Snapshot  bool   // Whether to snapshot the pool metadata to speed up restarts
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
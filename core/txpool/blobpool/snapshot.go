@@ -0,0 +1,107 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// metadataSnapshot is the file within the data directory containing the
+	// snapshot of the pool metadata.
+	metadataSnapshot = "metadata.rlp"
+
+	// snapshotInterval is the time interval to regenerate the metadata snapshot.
+	snapshotInterval = 5 * time.Minute
+)
+
+// snapshotEntry is the metadata of a pooled transaction kept in the snapshot.
+//
+// The blob transactions themselves are persisted in the pool's store and survive
+// restarts anyway, but the metadata needs to be rebuilt from them on startup. The
+// costliest part is recovering the senders, so they are snapshotted. A sender is
+// fully determined by the transaction hash, so a stale snapshot is never wrong,
+// it just misses entries.
+type snapshotEntry struct {
+	Hash   common.Hash
+	Sender common.Address
+}
+
+// loadMetaSnapshot parses the metadata snapshot from disk, returning the known
+// transaction senders keyed by transaction hash.
+func loadMetaSnapshot(path string) (map[common.Hash]common.Address, error) {
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []snapshotEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		return nil, err
+	}
+	senders := make(map[common.Hash]common.Address, len(entries))
+	for _, entry := range entries {
+		senders[entry.Hash] = entry.Sender
+	}
+	log.Info("Loaded blob pool metadata snapshot", "transactions", len(entries))
+	return senders, nil
+}
+
+// saveMetaSnapshot dumps the metadata of the pooled transactions into the
+// snapshot on disk. The lock must be held by the caller.
+func (p *BlobPool) saveMetaSnapshot() error {
+	var entries []snapshotEntry
+	for addr, txs := range p.index {
+		for _, meta := range txs {
+			entries = append(entries, snapshotEntry{Hash: meta.hash, Sender: addr})
+		}
+	}
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return err
+	}
+	path := p.snapshotPath()
+	if err := os.WriteFile(path+".new", blob, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		return err
+	}
+	p.snapshotted = time.Now()
+
+	log.Debug("Regenerated blob pool metadata snapshot", "transactions", len(entries))
+	return nil
+}
+
+// snapshotPath returns the path of the metadata snapshot, or an empty string if
+// snapshotting is disabled or the pool is not persisted to disk.
+func (p *BlobPool) snapshotPath() string {
+	if !p.config.Snapshot || p.config.Datadir == "" {
+		return ""
+	}
+	return filepath.Join(p.config.Datadir, metadataSnapshot)
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bundlepool implements a pool of transaction bundles, ordered groups of
+// transactions which are included into a block atomically.
+package bundlepool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrEmptyBundle is returned if a bundle contains no transactions.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// allowed by the pool.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleBlobTx is returned if a bundle contains a blob transaction, which
+	// are not supported in bundles.
+	ErrBundleBlobTx = errors.New("blob transaction in bundle")
+
+	// ErrBundleExpired is returned if a bundle targets a block which is already
+	// in the chain.
+	ErrBundleExpired = errors.New("bundle target block already mined")
+
+	// ErrBundleTooFar is returned if a bundle targets a block too far ahead of
+	// the chain head.
+	ErrBundleTooFar = errors.New("bundle target block too far in the future")
+
+	// ErrBundleTimestamp is returned if the timestamp range of a bundle is empty.
+	ErrBundleTimestamp = errors.New("invalid bundle timestamp range")
+
+	// ErrBundleNonceGap is returned if the transactions of a sender in a bundle
+	// don't have consecutive nonces.
+	ErrBundleNonceGap = errors.New("non-consecutive nonces in bundle")
+
+	// ErrSenderLimit is returned if a sender of the bundle already has the
+	// maximum number of bundles allowed in the pool.
+	ErrSenderLimit = errors.New("too many bundles from sender")
+
+	// ErrPoolFull is returned if the pool reached its capacity.
+	ErrPoolFull = errors.New("bundle pool full")
+)
+
+// Config are the configuration parameters of the bundle pool.
+type Config struct {
+	MaxBundles       int    // Maximum number of bundles kept in the pool
+	MaxBundleTxs     int    // Maximum number of transactions in a bundle
+	MaxSenderBundles int    // Maximum number of bundles with transactions of a single sender
+	MaxBlocksAhead   uint64 // Maximum distance of the target block from the chain head
+}
+
+// DefaultConfig contains the default configurations for the bundle pool.
+var DefaultConfig = Config{
+	MaxBundles:       4096,
+	MaxBundleTxs:     64,
+	MaxSenderBundles: 16,
+	MaxBlocksAhead:   256,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.MaxBundles < 1 {
+		log.Warn("Sanitizing invalid bundle pool capacity", "provided", conf.MaxBundles, "updated", DefaultConfig.MaxBundles)
+		conf.MaxBundles = DefaultConfig.MaxBundles
+	}
+	if conf.MaxBundleTxs < 1 {
+		log.Warn("Sanitizing invalid bundle size limit", "provided", conf.MaxBundleTxs, "updated", DefaultConfig.MaxBundleTxs)
+		conf.MaxBundleTxs = DefaultConfig.MaxBundleTxs
+	}
+	if conf.MaxSenderBundles < 1 {
+		log.Warn("Sanitizing invalid bundle sender limit", "provided", conf.MaxSenderBundles, "updated", DefaultConfig.MaxSenderBundles)
+		conf.MaxSenderBundles = DefaultConfig.MaxSenderBundles
+	}
+	if conf.MaxBlocksAhead < 1 {
+		log.Warn("Sanitizing invalid bundle target distance", "provided", conf.MaxBlocksAhead, "updated", DefaultConfig.MaxBlocksAhead)
+		conf.MaxBlocksAhead = DefaultConfig.MaxBlocksAhead
+	}
+	return conf
+}
+
+// Bundle is an ordered group of transactions which must be included into the
+// target block together and in order, or not at all.
+type Bundle struct {
+	Txs               types.Transactions // Transactions of the bundle, in execution order
+	BlockNumber       uint64             // Number of the block the bundle targets
+	MinTimestamp      uint64             // Minimum timestamp of the block, zero if unbounded
+	MaxTimestamp      uint64             // Maximum timestamp of the block, zero if unbounded
+	RevertingTxHashes []common.Hash      // Transactions allowed to revert without invalidating the bundle
+
+	hash atomic.Pointer[common.Hash] // Cached bundle hash
+}
+
+// Hash returns the bundle hash, the keccak256 hash of the concatenated hashes
+// of the bundle transactions.
+func (b *Bundle) Hash() common.Hash {
+	if hash := b.hash.Load(); hash != nil {
+		return *hash
+	}
+	var (
+		hasher = crypto.NewKeccakState()
+		hash   common.Hash
+	)
+	for _, tx := range b.Txs {
+		txHash := tx.Hash()
+		hasher.Write(txHash[:])
+	}
+	hasher.Read(hash[:])
+	b.hash.Store(&hash)
+	return hash
+}
+
+// CanRevert reports whether the transaction with the hash is allowed to revert.
+func (b *Bundle) CanRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Includable reports whether the bundle can be included into the block with
+// the given number and timestamp.
+func (b *Bundle) Includable(number uint64, time uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && time < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && time > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// BlockChain defines the minimal set of methods needed to back a bundle pool
+// with a chain.
+type BlockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// BundlePool keeps the bundles submitted for inclusion until their target block
+// is built.
+type BundlePool struct {
+	config Config
+	chain  BlockChain
+
+	bundles map[common.Hash]*Bundle // Bundles keyed by their hash
+	order   []common.Hash           // Bundle hashes in arrival order
+	senders map[common.Address]int  // Number of bundles with transactions of each sender
+	lock    sync.RWMutex
+}
+
+// New creates a new bundle pool backed by the chain.
+func New(config Config, chain BlockChain) *BundlePool {
+	return &BundlePool{
+		config:  config.sanitize(),
+		chain:   chain,
+		bundles: make(map[common.Hash]*Bundle),
+		senders: make(map[common.Address]int),
+	}
+}
+
+// Config returns the sanitized configuration of the pool.
+func (p *BundlePool) Config() Config {
+	return p.config
+}
+
+// Add validates the bundle and inserts it into the pool.
+func (p *BundlePool) Add(bundle *Bundle) error {
+	senders, err := p.validate(bundle)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	hash := bundle.Hash()
+	if _, ok := p.bundles[hash]; ok {
+		return txpool.ErrAlreadyKnown
+	}
+	p.prune(p.chain.CurrentBlock().Number.Uint64() + 1)
+	if len(p.bundles) >= p.config.MaxBundles {
+		return ErrPoolFull
+	}
+	for _, sender := range senders {
+		if p.senders[sender] >= p.config.MaxSenderBundles {
+			return fmt.Errorf("%w: %v", ErrSenderLimit, sender)
+		}
+	}
+	p.bundles[hash] = bundle
+	p.order = append(p.order, hash)
+	p.track(bundle, 1)
+
+	log.Debug("Added bundle to pool", "hash", hash, "txs", len(bundle.Txs), "block", bundle.BlockNumber)
+	return nil
+}
+
+// validate checks the bundle against the pool limits and the chain head, and
+// returns the distinct senders of its transactions.
+//
+// The transactions of each sender must have consecutive nonces not below the
+// sender's nonce in the head state, and the sender must be able to pay for
+// their gas at the fee cap. Transactions funded by earlier ones of the bundle
+// are hence rejected, but throwaway accounts can't flood the pool for free.
+func (p *BundlePool) validate(bundle *Bundle) ([]common.Address, error) {
+	if len(bundle.Txs) == 0 {
+		return nil, ErrEmptyBundle
+	}
+	if len(bundle.Txs) > p.config.MaxBundleTxs {
+		return nil, fmt.Errorf("%w: %d transactions, limit %d", ErrBundleTooLarge, len(bundle.Txs), p.config.MaxBundleTxs)
+	}
+	if bundle.MaxTimestamp != 0 && bundle.MaxTimestamp < bundle.MinTimestamp {
+		return nil, ErrBundleTimestamp
+	}
+	head := p.chain.CurrentBlock()
+	if number := head.Number.Uint64(); bundle.BlockNumber <= number {
+		return nil, fmt.Errorf("%w: target %d, head %d", ErrBundleExpired, bundle.BlockNumber, number)
+	} else if bundle.BlockNumber > number+p.config.MaxBlocksAhead {
+		return nil, fmt.Errorf("%w: target %d, head %d", ErrBundleTooFar, bundle.BlockNumber, number)
+	}
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		return nil, fmt.Errorf("head state unavailable: %v", err)
+	}
+	var (
+		signer  = types.LatestSigner(p.chain.Config())
+		senders []common.Address
+		nonces  = make(map[common.Address]uint64)
+		costs   = make(map[common.Address]*big.Int)
+	)
+	for _, tx := range bundle.Txs {
+		if tx.Type() == types.BlobTxType {
+			return nil, ErrBundleBlobTx
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: transaction %x", txpool.ErrInvalidSender, tx.Hash())
+		}
+		if next, ok := nonces[from]; !ok {
+			if nonce := statedb.GetNonce(from); tx.Nonce() < nonce {
+				return nil, fmt.Errorf("%w: transaction %x, next nonce %v, tx nonce %v", core.ErrNonceTooLow, tx.Hash(), nonce, tx.Nonce())
+			}
+			senders = append(senders, from)
+			costs[from] = new(big.Int)
+		} else if tx.Nonce() != next {
+			return nil, fmt.Errorf("%w: transaction %x, expected nonce %v, tx nonce %v", ErrBundleNonceGap, tx.Hash(), next, tx.Nonce())
+		}
+		nonces[from] = tx.Nonce() + 1
+
+		cost := costs[from].Add(costs[from], new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas())))
+		if balance := statedb.GetBalance(from).ToBig(); balance.Cmp(cost) < 0 {
+			return nil, fmt.Errorf("%w: address %v, balance %v, gas cost %v", core.ErrInsufficientFunds, from, balance, cost)
+		}
+	}
+	return senders, nil
+}
+
+// Pending returns the bundles includable into the block with the given number
+// and timestamp, in arrival order. The bundles targeting earlier blocks are
+// dropped from the pool.
+func (p *BundlePool) Pending(number uint64, time uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, hash := range p.order {
+		if bundle := p.bundles[hash]; bundle.Includable(number, time) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// Get returns the bundle with the hash if it's in the pool.
+func (p *BundlePool) Get(hash common.Hash) *Bundle {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.bundles[hash]
+}
+
+// Drop removes the bundle with the hash from the pool.
+func (p *BundlePool) Drop(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	bundle, ok := p.bundles[hash]
+	if !ok {
+		return
+	}
+	p.track(bundle, -1)
+	delete(p.bundles, hash)
+	for i, h := range p.order {
+		if h == hash {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of bundles in the pool.
+func (p *BundlePool) Len() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.bundles)
+}
+
+// prune drops the bundles targeting blocks before the given number. The lock
+// must be held by the caller.
+func (p *BundlePool) prune(number uint64) {
+	order := p.order[:0]
+	for _, hash := range p.order {
+		if bundle := p.bundles[hash]; bundle.BlockNumber < number {
+			p.track(bundle, -1)
+			delete(p.bundles, hash)
+			continue
+		}
+		order = append(order, hash)
+	}
+	p.order = order
+}
+
+// track adjusts the bundle counters of the distinct senders of the bundle by
+// delta. The lock must be held by the caller.
+func (p *BundlePool) track(bundle *Bundle, delta int) {
+	signer := types.LatestSigner(p.chain.Config())
+
+	seen := make(map[common.Address]struct{})
+	for _, tx := range bundle.Txs {
+		from, _ := types.Sender(signer, tx) // already validated
+		if _, ok := seen[from]; ok {
+			continue
+		}
+		seen[from] = struct{}{}
+
+		if p.senders[from] += delta; p.senders[from] <= 0 {
+			delete(p.senders, from)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundlepool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var testKey, _ = crypto.GenerateKey()
+
+// testBlockChain is a mock of the chain the pool is tracking.
+type testBlockChain struct {
+	head    uint64
+	statedb *state.StateDB
+}
+
+// newTestBlockChain creates a mock chain with the test account funded.
+func newTestBlockChain(head uint64) *testBlockChain {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(crypto.PubkeyToAddress(testKey.PublicKey), uint256.NewInt(params.Ether), tracing.BalanceChangeUnspecified)
+	return &testBlockChain{head: head, statedb: statedb}
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (bc *testBlockChain) CurrentBlock() *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(bc.head)}
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb.Copy(), nil
+}
+
+func newTestTx(nonce uint64) *types.Transaction {
+	return newTestTxWithKey(testKey, nonce)
+}
+
+func newTestTxWithKey(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+	return types.MustSignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &common.Address{0xaa},
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(params.GWei),
+	})
+}
+
+func TestAddValidation(t *testing.T) {
+	chain := newTestBlockChain(10)
+	pool := New(Config{MaxBundles: 2, MaxBundleTxs: 2, MaxBlocksAhead: 5}, chain)
+
+	blobTx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(params.TestChainConfig.ChainID),
+		Gas:        params.TxGas,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(1),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: []common.Hash{{0x01}},
+	})
+	unsigned := types.NewTx(&types.LegacyTx{Gas: params.TxGas, GasPrice: big.NewInt(1)})
+
+	for i, tt := range []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{&Bundle{BlockNumber: 11}, ErrEmptyBundle},
+		{&Bundle{Txs: types.Transactions{newTestTx(0), newTestTx(1), newTestTx(2)}, BlockNumber: 11}, ErrBundleTooLarge},
+		{&Bundle{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 10}, ErrBundleExpired},
+		{&Bundle{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 16}, ErrBundleTooFar},
+		{&Bundle{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 11, MinTimestamp: 2, MaxTimestamp: 1}, ErrBundleTimestamp},
+		{&Bundle{Txs: types.Transactions{blobTx}, BlockNumber: 11}, ErrBundleBlobTx},
+		{&Bundle{Txs: types.Transactions{unsigned}, BlockNumber: 11}, txpool.ErrInvalidSender},
+		{&Bundle{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 11}, nil},
+		{&Bundle{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 11}, txpool.ErrAlreadyKnown},
+		{&Bundle{Txs: types.Transactions{newTestTx(1)}, BlockNumber: 15}, nil},
+		{&Bundle{Txs: types.Transactions{newTestTx(2)}, BlockNumber: 12}, ErrPoolFull},
+	} {
+		if err := pool.Add(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("case %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if pool.Len() != 2 {
+		t.Fatalf("pool size mismatch: have %d, want 2", pool.Len())
+	}
+	// Progressing the chain makes room for new bundles
+	chain.head = 11
+	if err := pool.Add(&Bundle{Txs: types.Transactions{newTestTx(2)}, BlockNumber: 12}); err != nil {
+		t.Fatalf("failed to add bundle after pruning: %v", err)
+	}
+}
+
+func TestPending(t *testing.T) {
+	pool := New(DefaultConfig, newTestBlockChain(10))
+
+	bundles := []*Bundle{
+		{Txs: types.Transactions{newTestTx(0)}, BlockNumber: 11},
+		{Txs: types.Transactions{newTestTx(1)}, BlockNumber: 12},
+		{Txs: types.Transactions{newTestTx(2)}, BlockNumber: 11, MinTimestamp: 100},
+		{Txs: types.Transactions{newTestTx(3)}, BlockNumber: 11, MaxTimestamp: 50},
+		{Txs: types.Transactions{newTestTx(4)}, BlockNumber: 11, MinTimestamp: 50, MaxTimestamp: 100},
+	}
+	for _, bundle := range bundles {
+		if err := pool.Add(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	check := func(number, time uint64, want ...int) {
+		t.Helper()
+		have := pool.Pending(number, time)
+		if len(have) != len(want) {
+			t.Fatalf("pending bundle count mismatch at %d/%d: have %d, want %d", number, time, len(have), len(want))
+		}
+		for i, bundle := range have {
+			if bundle != bundles[want[i]] {
+				t.Fatalf("pending bundle %d mismatch at %d/%d", i, number, time)
+			}
+		}
+	}
+	check(11, 10, 0, 3)
+	check(11, 75, 0, 4)
+	check(11, 150, 0, 2)
+
+	pool.Drop(bundles[0].Hash())
+	check(11, 10, 3)
+
+	// Bundles targeting past blocks are dropped
+	check(12, 0, 1)
+	if pool.Len() != 1 {
+		t.Fatalf("stale bundles not dropped: %d left", pool.Len())
+	}
+}
+
+func TestAddStateChecks(t *testing.T) {
+	chain := newTestBlockChain(10)
+	chain.statedb.SetNonce(crypto.PubkeyToAddress(testKey.PublicKey), 5)
+	pool := New(Config{MaxBundles: 16, MaxBundleTxs: 4, MaxSenderBundles: 2, MaxBlocksAhead: 5}, chain)
+
+	poorKey, _ := crypto.GenerateKey()
+	for i, tt := range []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{&Bundle{Txs: types.Transactions{newTestTx(4)}, BlockNumber: 11}, core.ErrNonceTooLow},
+		{&Bundle{Txs: types.Transactions{newTestTx(5), newTestTx(7)}, BlockNumber: 11}, ErrBundleNonceGap},
+		{&Bundle{Txs: types.Transactions{newTestTx(5), newTestTxWithKey(poorKey, 0)}, BlockNumber: 11}, core.ErrInsufficientFunds},
+		{&Bundle{Txs: types.Transactions{newTestTx(5), newTestTx(6)}, BlockNumber: 11}, nil},
+		{&Bundle{Txs: types.Transactions{newTestTx(6)}, BlockNumber: 12}, nil},
+		{&Bundle{Txs: types.Transactions{newTestTx(7)}, BlockNumber: 13}, ErrSenderLimit},
+	} {
+		if err := pool.Add(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("case %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Dropping a bundle frees up a slot of the sender
+	pool.Drop(crypto.Keccak256Hash(newTestTx(6).Hash().Bytes()))
+	if err := pool.Add(&Bundle{Txs: types.Transactions{newTestTx(7)}, BlockNumber: 13}); err != nil {
+		t.Fatalf("failed to add bundle after drop: %v", err)
+	}
+}
+
+func TestBundleHash(t *testing.T) {
+	txs := types.Transactions{newTestTx(0), newTestTx(1)}
+	a := &Bundle{Txs: txs, BlockNumber: 1}
+	b := &Bundle{Txs: txs, BlockNumber: 2}
+	c := &Bundle{Txs: types.Transactions{txs[1], txs[0]}, BlockNumber: 1}
+
+	if a.Hash() != b.Hash() {
+		t.Fatal("bundle hash depends on the target block")
+	}
+	if a.Hash() == c.Hash() {
+		t.Fatal("bundle hash independent of the transaction order")
+	}
+	want := crypto.Keccak256Hash(txs[0].Hash().Bytes(), txs[1].Hash().Bytes())
+	if a.Hash() != want {
+		t.Fatalf("bundle hash mismatch: have %x, want %x", a.Hash(), want)
+	}
+}
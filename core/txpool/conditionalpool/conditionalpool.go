@@ -0,0 +1,689 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package conditionalpool implements a subpool of conditional transactions, which
+// are only includable while the storage of some accounts holds the expected
+// values and within block number and timestamp bounds.
+package conditionalpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// txMaxSize is the maximum size a single transaction can have, matching the
+// limit of the legacy pool.
+const txMaxSize = 128 * 1024
+
+var (
+	// ErrInvalidConditions is returned if the conditions of a transaction are
+	// inconsistent.
+	ErrInvalidConditions = errors.New("invalid transaction conditions")
+
+	// ErrConditionsTooCostly is returned if checking the conditions of a
+	// transaction needs more storage lookups than allowed by the pool.
+	ErrConditionsTooCostly = errors.New("transaction conditions too costly")
+
+	// ErrConditionsExpired is returned if the conditions of a transaction can't
+	// be met anymore by any future block.
+	ErrConditionsExpired = errors.New("transaction conditions expired")
+
+	// ErrConditionsTooFar is returned if the conditions of a transaction only
+	// allow its inclusion too far ahead of the chain head.
+	ErrConditionsTooFar = errors.New("transaction conditions too far in the future")
+
+	// ErrConditionFailed is returned if the storage of an account doesn't hold
+	// the value expected by the conditions of a transaction.
+	ErrConditionFailed = errors.New("transaction condition not met")
+
+	// ErrConditionalOnly is returned if a transaction is added to the pool
+	// without conditions.
+	ErrConditionalOnly = errors.New("only conditional transactions accepted")
+
+	// ErrPoolFull is returned if the pool reached its capacity and the
+	// transaction doesn't pay more than the cheapest evictable one.
+	ErrPoolFull = errors.New("conditional pool full")
+
+	// ErrStateUnavailable is returned if the state of the chain head is not
+	// available to check the conditions against.
+	ErrStateUnavailable = errors.New("conditional pool state unavailable")
+)
+
+// Config are the configuration parameters of the conditional transaction pool.
+type Config struct {
+	MaxTxs           int           // Maximum number of transactions kept in the pool
+	MaxAccountTxs    int           // Maximum number of transactions kept for a single account
+	MaxConditionCost int           // Maximum number of storage lookups to check the conditions of a transaction
+	MaxBlocksAhead   uint64        // Maximum distance of the minimum block number from the chain head
+	MaxTimeAhead     time.Duration // Maximum distance of the minimum timestamp from the chain head
+	PriceBump        uint64        // Minimum price bump percentage to replace an already existing nonce
+	Lifetime         time.Duration // Maximum amount of time a transaction is kept in the pool
+}
+
+// DefaultConfig contains the default configurations for the conditional pool.
+var DefaultConfig = Config{
+	MaxTxs:           1024,
+	MaxAccountTxs:    16,
+	MaxConditionCost: 1000,
+	MaxBlocksAhead:   64,
+	MaxTimeAhead:     15 * time.Minute,
+	PriceBump:        10,
+	Lifetime:         time.Hour,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.MaxTxs < 1 {
+		log.Warn("Sanitizing invalid conditional pool capacity", "provided", conf.MaxTxs, "updated", DefaultConfig.MaxTxs)
+		conf.MaxTxs = DefaultConfig.MaxTxs
+	}
+	if conf.MaxAccountTxs < 1 {
+		log.Warn("Sanitizing invalid conditional pool account slots", "provided", conf.MaxAccountTxs, "updated", DefaultConfig.MaxAccountTxs)
+		conf.MaxAccountTxs = DefaultConfig.MaxAccountTxs
+	}
+	if conf.MaxConditionCost < 1 {
+		log.Warn("Sanitizing invalid conditional pool condition cost", "provided", conf.MaxConditionCost, "updated", DefaultConfig.MaxConditionCost)
+		conf.MaxConditionCost = DefaultConfig.MaxConditionCost
+	}
+	if conf.MaxBlocksAhead < 1 {
+		log.Warn("Sanitizing invalid conditional pool block distance", "provided", conf.MaxBlocksAhead, "updated", DefaultConfig.MaxBlocksAhead)
+		conf.MaxBlocksAhead = DefaultConfig.MaxBlocksAhead
+	}
+	if conf.MaxTimeAhead < time.Second {
+		log.Warn("Sanitizing invalid conditional pool time distance", "provided", conf.MaxTimeAhead, "updated", DefaultConfig.MaxTimeAhead)
+		conf.MaxTimeAhead = DefaultConfig.MaxTimeAhead
+	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid conditional pool price bump", "provided", conf.PriceBump, "updated", DefaultConfig.PriceBump)
+		conf.PriceBump = DefaultConfig.PriceBump
+	}
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid conditional pool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
+		conf.Lifetime = DefaultConfig.Lifetime
+	}
+	return conf
+}
+
+// BlockChain defines the minimal set of methods needed to back a conditional
+// pool with a chain.
+type BlockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// conditionalTx is a pooled transaction along with its conditions.
+type conditionalTx struct {
+	tx         *types.Transaction
+	conditions *Conditions
+	added      time.Time // Time the transaction was added to the pool
+}
+
+// ConditionalPool is a subpool of transactions only includable under certain
+// conditions. The transactions are submitted over RPC only, they are never
+// accepted from or announced to the network, as peers would not enforce the
+// conditions.
+//
+// The storage conditions are checked against the state of the chain head, which
+// is the state a block built on top of it starts from. They are re-checked on
+// every head change and transactions not meeting them anymore are dropped. The
+// pending transactions carry their conditions, so the miner can re-check them
+// against the in-progress block state right before inclusion.
+//
+// While the pool holds transactions of an account, the account is reserved and
+// its plain transactions are rejected by the other subpools. To not lock out
+// accounts for long, transactions are dropped after the configured lifetime and
+// their conditions may only start being includable close to the chain head.
+type ConditionalPool struct {
+	config  Config
+	chain   BlockChain
+	signer  types.Signer
+	reserve txpool.AddressReserver
+
+	head   *types.Header  // Current head of the chain
+	state  *state.StateDB // Current state at the head of the chain
+	gasTip *uint256.Int   // Currently accepted minimum gas tip
+
+	txs    map[common.Address][]*conditionalTx // Transactions grouped by account, sorted by nonce
+	lookup map[common.Hash]*conditionalTx      // Transactions keyed by their hash
+
+	txFeed   event.Feed         // Never fired, conditional transactions are not propagated
+	txEvents txpool.TxEventFeed // Event feed to send out tx lifecycle events
+
+	lock sync.RWMutex
+}
+
+// New creates a new conditional transaction pool backed by the chain.
+func New(config Config, chain BlockChain) *ConditionalPool {
+	return &ConditionalPool{
+		config: config.sanitize(),
+		chain:  chain,
+		signer: types.LatestSigner(chain.Config()),
+		txs:    make(map[common.Address][]*conditionalTx),
+		lookup: make(map[common.Hash]*conditionalTx),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the pool. The
+// conditional pool never consumes transactions routed by the main pool, they
+// can only be added along with their conditions via AddConditional.
+func (p *ConditionalPool) Filter(tx *types.Transaction) bool {
+	return false
+}
+
+// Init sets the gas price needed to keep a transaction in the pool and the chain
+// head to allow balance / nonce checks.
+func (p *ConditionalPool) Init(gasTip uint64, head *types.Header, reserve txpool.AddressReserver) error {
+	p.reserve = reserve
+
+	p.gasTip = uint256.NewInt(gasTip)
+
+	// Same as in Reset, reject transactions until a head with state arrives
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		log.Warn("Conditional pool head state unavailable", "number", head.Number, "root", head.Root, "err", err)
+		p.head, p.state = head, nil
+		return nil
+	}
+	p.head, p.state = head, statedb
+	return nil
+}
+
+// Close terminates the pool. The conditional transactions are not persisted.
+func (p *ConditionalPool) Close() error {
+	return nil
+}
+
+// Reset implements txpool.SubPool, re-checking the conditions of all pooled
+// transactions against the new head and dropping the ones not includable anymore.
+func (p *ConditionalPool) Reset(oldHead, newHead *types.Header) {
+	defer p.txEvents.Flush()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		// The conditions can't be checked against a stale state, so drop all the
+		// transactions and reject new ones until a head with state arrives
+		log.Error("Failed to reset conditional pool state", "err", err)
+		p.head, p.state = newHead, nil
+		p.dropAll()
+		return
+	}
+	p.head, p.state = newHead, statedb
+
+	for addr := range p.txs {
+		p.recheck(addr)
+	}
+}
+
+// recheck verifies the pooled transactions of an account against the current
+// head and drops anything that's not includable anymore, along with all the
+// transactions after it. The lock must be held by the caller.
+func (p *ConditionalPool) recheck(addr common.Address) {
+	var (
+		next    = p.state.GetNonce(addr)
+		balance = p.state.GetBalance(addr).ToBig()
+		spent   = new(big.Int)
+		kept    []*conditionalTx
+		failed  bool
+	)
+	for _, ctx := range p.txs[addr] {
+		var reason txpool.TxEventType
+		switch {
+		case ctx.tx.Nonce() < next:
+			reason = txpool.TxEventNonceTooLow
+		case failed || ctx.tx.Nonce() != next+uint64(len(kept)):
+			reason = txpool.TxEventInvalidated
+		case ctx.conditions.Expired(p.head) || time.Since(ctx.added) > p.config.Lifetime:
+			reason = txpool.TxEventExpired
+		case ctx.conditions.Check(p.state) != nil:
+			reason = txpool.TxEventInvalidated
+		case new(big.Int).Add(spent, ctx.tx.Cost()).Cmp(balance) > 0:
+			reason = txpool.TxEventInvalidated
+		}
+		if reason != "" {
+			log.Trace("Dropping conditional transaction", "hash", ctx.tx.Hash(), "from", addr, "reason", reason)
+			delete(p.lookup, ctx.tx.Hash())
+			p.txEvents.Queue(txpool.TxEvent{Hash: ctx.tx.Hash(), From: addr, Type: reason})
+
+			// Anything after a dropped transaction is gapped, drop it too
+			if reason != txpool.TxEventNonceTooLow {
+				failed = true
+			}
+			continue
+		}
+		spent.Add(spent, ctx.tx.Cost())
+		kept = append(kept, ctx)
+	}
+	if len(kept) == 0 {
+		delete(p.txs, addr)
+		p.reserve(addr, false)
+		return
+	}
+	p.txs[addr] = kept
+}
+
+// dropAll drops all the pooled transactions. The lock must be held by the caller.
+func (p *ConditionalPool) dropAll() {
+	for addr, txs := range p.txs {
+		for _, ctx := range txs {
+			delete(p.lookup, ctx.tx.Hash())
+			p.txEvents.Queue(txpool.TxEvent{Hash: ctx.tx.Hash(), From: addr, Type: txpool.TxEventInvalidated})
+		}
+		delete(p.txs, addr)
+		p.reserve(addr, false)
+	}
+}
+
+// SetGasTip updates the minimum price required by the pool for a new transaction,
+// and drops all transactions below this threshold.
+func (p *ConditionalPool) SetGasTip(tip *big.Int) {
+	defer p.txEvents.Flush()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	old := p.gasTip
+	p.gasTip = uint256.MustFromBig(tip)
+
+	if old != nil && p.gasTip.Cmp(old) <= 0 {
+		return
+	}
+	for addr, txs := range p.txs {
+		for i, ctx := range txs {
+			if ctx.tx.GasTipCapIntCmp(tip) >= 0 {
+				continue
+			}
+			// Drop the underpriced transaction and everything after it
+			for j, drop := range txs[i:] {
+				reason := txpool.TxEventUnderpriced
+				if j > 0 {
+					reason = txpool.TxEventInvalidated
+				}
+				delete(p.lookup, drop.tx.Hash())
+				p.txEvents.Queue(txpool.TxEvent{Hash: drop.tx.Hash(), From: addr, Type: reason})
+			}
+			if i == 0 {
+				delete(p.txs, addr)
+				p.reserve(addr, false)
+			} else {
+				p.txs[addr] = txs[:i]
+			}
+			break
+		}
+	}
+}
+
+// Has returns an indicator whether the pool has a transaction cached with the
+// given hash.
+func (p *ConditionalPool) Has(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.lookup[hash] != nil
+}
+
+// Get returns a transaction if it is contained in the pool, or nil otherwise.
+func (p *ConditionalPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if ctx := p.lookup[hash]; ctx != nil {
+		return ctx.tx
+	}
+	return nil
+}
+
+// Conditions returns the conditions of a pooled transaction, or nil if it's not
+// contained in the pool.
+func (p *ConditionalPool) Conditions(hash common.Hash) *Conditions {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if ctx := p.lookup[hash]; ctx != nil {
+		return ctx.conditions
+	}
+	return nil
+}
+
+// Add implements txpool.SubPool, rejecting all transactions since they come
+// without conditions. Use AddConditional instead.
+func (p *ConditionalPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i := range txs {
+		errs[i] = ErrConditionalOnly
+	}
+	return errs
+}
+
+// AddConditional validates the transaction along with its conditions and inserts
+// it into the pool. The storage conditions must hold at the current head.
+func (p *ConditionalPool) AddConditional(tx *types.Transaction, conditions *Conditions) (err error) {
+	defer p.txEvents.Flush()
+
+	if conditions == nil {
+		return ErrConditionalOnly
+	}
+	if err := conditions.validate(); err != nil {
+		return err
+	}
+	if cost := conditions.Cost(); cost > p.config.MaxConditionCost {
+		return fmt.Errorf("%w: cost %d, limit %d", ErrConditionsTooCostly, cost, p.config.MaxConditionCost)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.state == nil {
+		return ErrStateUnavailable
+	}
+	if p.lookup[tx.Hash()] != nil {
+		return txpool.ErrAlreadyKnown
+	}
+	if err := p.validateTx(tx, conditions); err != nil {
+		return err
+	}
+	from, _ := types.Sender(p.signer, tx) // already validated
+
+	// If the address is not yet known, request exclusivity to track the account
+	// only by this subpool until all transactions are evicted
+	txs, known := p.txs[from]
+	if !known {
+		if err := p.reserve(from, true); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				p.reserve(from, false)
+			}
+		}()
+	}
+	ctx := &conditionalTx{tx: tx, conditions: conditions, added: time.Now()}
+
+	offset := int(tx.Nonce() - p.state.GetNonce(from))
+	if offset < len(txs) {
+		// Transaction replaces a pooled one, ensure the required price bump is met
+		prev := txs[offset].tx
+		if !p.bumped(prev, tx) {
+			return txpool.ErrReplaceUnderpriced
+		}
+		delete(p.lookup, prev.Hash())
+		txs[offset] = ctx
+
+		replacement := tx.Hash()
+		p.txEvents.Queue(txpool.TxEvent{Hash: prev.Hash(), From: from, Type: txpool.TxEventReplaced, Replacement: &replacement})
+	} else {
+		if len(p.lookup) >= p.config.MaxTxs && !p.evict(from, tx) {
+			return ErrPoolFull
+		}
+		p.txs[from] = append(txs, ctx)
+	}
+	p.lookup[tx.Hash()] = ctx
+	p.txEvents.Queue(txpool.TxEvent{Hash: tx.Hash(), From: from, Type: txpool.TxEventAdded})
+
+	log.Debug("Pooled new conditional transaction", "hash", tx.Hash(), "from", from, "nonce", tx.Nonce())
+	return nil
+}
+
+// validateTx checks whether a transaction and its conditions are valid against
+// the current head. The lock must be held by the caller.
+func (p *ConditionalPool) validateTx(tx *types.Transaction, conditions *Conditions) error {
+	opts := &txpool.ValidationOptions{
+		Config: p.chain.Config(),
+		Accept: 0 |
+			1<<types.LegacyTxType |
+			1<<types.AccessListTxType |
+			1<<types.DynamicFeeTxType |
+			1<<types.SetCodeTxType,
+		MaxSize: txMaxSize,
+		MinTip:  p.gasTip.ToBig(),
+	}
+	if err := txpool.ValidateTransaction(tx, p.head, p.signer, opts); err != nil {
+		return err
+	}
+	if conditions.Expired(p.head) {
+		return ErrConditionsExpired
+	}
+	if conditions.BlockNumberMin > p.head.Number.Uint64()+p.config.MaxBlocksAhead {
+		return fmt.Errorf("%w: minimum block %d, head %d", ErrConditionsTooFar, conditions.BlockNumberMin, p.head.Number)
+	}
+	if conditions.TimestampMin > p.head.Time+uint64(p.config.MaxTimeAhead/time.Second) {
+		return fmt.Errorf("%w: minimum timestamp %d, head %d", ErrConditionsTooFar, conditions.TimestampMin, p.head.Time)
+	}
+	if err := conditions.Check(p.state); err != nil {
+		return err
+	}
+	stateOpts := &txpool.ValidationOptionsWithState{
+		State: p.state,
+
+		FirstNonceGap: func(addr common.Address) uint64 {
+			return p.state.GetNonce(addr) + uint64(len(p.txs[addr]))
+		},
+		UsedAndLeftSlots: func(addr common.Address) (int, int) {
+			have := len(p.txs[addr])
+			return have, p.config.MaxAccountTxs - have
+		},
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			spent := new(big.Int)
+			for _, ctx := range p.txs[addr] {
+				spent.Add(spent, ctx.tx.Cost())
+			}
+			return spent
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			next := p.state.GetNonce(addr)
+			if txs := p.txs[addr]; nonce >= next && nonce < next+uint64(len(txs)) {
+				return txs[nonce-next].tx.Cost()
+			}
+			return nil
+		},
+	}
+	return txpool.ValidateTransactionWithState(tx, p.signer, stateOpts)
+}
+
+// evict makes room for the transaction by dropping the cheapest transaction of
+// the pool, if it pays less than the new one. Only the last transactions of the
+// accounts other than the sender are considered, so no nonce gaps are created.
+// The lock must be held by the caller.
+func (p *ConditionalPool) evict(from common.Address, tx *types.Transaction) bool {
+	var (
+		cheapest *conditionalTx
+		owner    common.Address
+	)
+	for addr, txs := range p.txs {
+		if addr == from {
+			continue
+		}
+		last := txs[len(txs)-1]
+		if cheapest == nil || last.tx.EffectiveGasTipCmp(cheapest.tx, p.head.BaseFee) < 0 {
+			cheapest, owner = last, addr
+		}
+	}
+	if cheapest == nil || tx.EffectiveGasTipCmp(cheapest.tx, p.head.BaseFee) <= 0 {
+		return false
+	}
+	log.Trace("Evicting conditional transaction", "hash", cheapest.tx.Hash(), "from", owner)
+	delete(p.lookup, cheapest.tx.Hash())
+	p.txEvents.Queue(txpool.TxEvent{Hash: cheapest.tx.Hash(), From: owner, Type: txpool.TxEventEvicted})
+
+	if txs := p.txs[owner]; len(txs) > 1 {
+		p.txs[owner] = txs[:len(txs)-1]
+	} else {
+		delete(p.txs, owner)
+		p.reserve(owner, false)
+	}
+	return true
+}
+
+// bumped reports whether the replacement pays at least the configured price bump
+// more than the original transaction, both in fee cap and in tip.
+func (p *ConditionalPool) bumped(old, replacement *types.Transaction) bool {
+	var (
+		oldFeeCap = new(big.Int).Mul(old.GasFeeCap(), big.NewInt(int64(100+p.config.PriceBump)))
+		oldTip    = new(big.Int).Mul(old.GasTipCap(), big.NewInt(int64(100+p.config.PriceBump)))
+		newFeeCap = new(big.Int).Mul(replacement.GasFeeCap(), big.NewInt(100))
+		newTip    = new(big.Int).Mul(replacement.GasTipCap(), big.NewInt(100))
+	)
+	return newFeeCap.Cmp(oldFeeCap) >= 0 && newTip.Cmp(oldTip) >= 0
+}
+
+// Pending retrieves the transactions includable into the block being built,
+// grouped by origin account and sorted by nonce. Conditional transactions are
+// only released for block building, so nothing is returned if the filter does
+// not specify the block.
+func (p *ConditionalPool) Pending(filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	if filter.OnlyBlobTxs || filter.BlockNumber == 0 {
+		return nil
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var (
+		minTipBig  *big.Int
+		baseFeeBig *big.Int
+	)
+	if filter.MinTip != nil {
+		minTipBig = filter.MinTip.ToBig()
+	}
+	if filter.BaseFee != nil {
+		baseFeeBig = filter.BaseFee.ToBig()
+	}
+	pending := make(map[common.Address][]*txpool.LazyTransaction)
+	for addr, txs := range p.txs {
+		var lazies []*txpool.LazyTransaction
+		for _, ctx := range txs {
+			tx := ctx.tx
+			if !ctx.conditions.Includable(filter.BlockNumber, filter.BlockTime) {
+				break
+			}
+			if minTipBig != nil && tx.EffectiveGasTipIntCmp(minTipBig, baseFeeBig) < 0 {
+				break
+			}
+			lazies = append(lazies, &txpool.LazyTransaction{
+				Pool:       p,
+				Hash:       tx.Hash(),
+				Tx:         tx,
+				Time:       tx.Time(),
+				GasFeeCap:  uint256.MustFromBig(tx.GasFeeCap()),
+				GasTipCap:  uint256.MustFromBig(tx.GasTipCap()),
+				Gas:        tx.Gas(),
+				BlobGas:    tx.BlobGas(),
+				Conditions: ctx.conditions,
+			})
+		}
+		if len(lazies) > 0 {
+			pending[addr] = lazies
+		}
+	}
+	return pending
+}
+
+// SubscribeTransactions subscribes to new transaction events. Conditional
+// transactions are never propagated, so no events are ever sent.
+func (p *ConditionalPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	return p.txFeed.Subscribe(ch)
+}
+
+// SubscribeTxEvents registers a subscription for the lifecycle events of the
+// pooled transactions.
+func (p *ConditionalPool) SubscribeTxEvents(ch chan<- []txpool.TxEvent) event.Subscription {
+	return p.txEvents.Subscribe(ch)
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (p *ConditionalPool) Nonce(addr common.Address) uint64 {
+	// The state database is not safe for concurrent reads, so take the write lock
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.state == nil {
+		return 0
+	}
+	return p.state.GetNonce(addr) + uint64(len(p.txs[addr]))
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (p *ConditionalPool) Stats() (int, int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.lookup), 0
+}
+
+// Content retrieves the data content of the transaction pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+func (p *ConditionalPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	pending := make(map[common.Address][]*types.Transaction, len(p.txs))
+	for addr, txs := range p.txs {
+		for _, ctx := range txs {
+			pending[addr] = append(pending[addr], ctx.tx)
+		}
+	}
+	return pending, make(map[common.Address][]*types.Transaction)
+}
+
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+func (p *ConditionalPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var pending []*types.Transaction
+	for _, ctx := range p.txs[addr] {
+		pending = append(pending, ctx.tx)
+	}
+	return pending, nil
+}
+
+// Locals retrieves the accounts currently considered local by the pool.
+func (p *ConditionalPool) Locals() []common.Address {
+	return nil
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by their hashes.
+func (p *ConditionalPool) Status(hash common.Hash) txpool.TxStatus {
+	if p.Has(hash) {
+		return txpool.TxStatusPending
+	}
+	return txpool.TxStatusUnknown
+}
@@ -0,0 +1,393 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package conditionalpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testKey, _  = crypto.GenerateKey()
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testAccount = common.Address{0xaa}
+	testSlot    = common.Hash{0x01}
+)
+
+// testBlockChain is a mock of the chain the pool is tracking, with all states
+// kept in a shared in-memory database.
+type testBlockChain struct {
+	db   state.Database
+	head *types.Header
+}
+
+func newTestBlockChain(t *testing.T) *testBlockChain {
+	bc := &testBlockChain{db: state.NewDatabase(rawdb.NewMemoryDatabase())}
+	bc.commit(t, 1, func(statedb *state.StateDB) {
+		statedb.SetBalance(testAddr, uint256.NewInt(params.Ether), tracing.BalanceChangeUnspecified)
+		statedb.SetNonce(testAccount, 1) // Keep the account from being deleted as empty
+		statedb.SetState(testAccount, testSlot, common.Hash{0x01})
+	})
+	return bc
+}
+
+// commit applies the modifications on top of the current head state, and makes
+// the resulting state the new head.
+func (bc *testBlockChain) commit(t *testing.T, number uint64, modify func(*state.StateDB)) {
+	root := types.EmptyRootHash
+	if bc.head != nil {
+		root = bc.head.Root
+	}
+	statedb, err := state.New(root, bc.db, nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	modify(statedb)
+	if root, err = statedb.Commit(number, true); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	bc.head = &types.Header{
+		Number:   new(big.Int).SetUint64(number),
+		Time:     number * 10,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Root:     root,
+	}
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (bc *testBlockChain) CurrentBlock() *types.Header { return bc.head }
+
+func (bc *testBlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, bc.db, nil)
+}
+
+// newTestPool creates a conditional pool tracking the chain, with all accounts
+// reservable.
+func newTestPool(t *testing.T, chain *testBlockChain) *ConditionalPool {
+	pool := New(DefaultConfig, chain)
+	reserve := func(addr common.Address, reserve bool) error { return nil }
+	if err := pool.Init(1, chain.head, reserve); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	return pool
+}
+
+func newTestTx(nonce uint64, tip int64) *types.Transaction {
+	return newTestTxWithFeeCap(nonce, tip, params.InitialBaseFee+tip)
+}
+
+func newTestTxWithFeeCap(nonce uint64, tip int64, feeCap int64) *types.Transaction {
+	return newTestTxWithKey(testKey, nonce, tip, feeCap)
+}
+
+func newTestTxWithKey(key *ecdsa.PrivateKey, nonce uint64, tip int64, feeCap int64) *types.Transaction {
+	return types.MustSignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     nonce,
+		To:        &testAccount,
+		Gas:       params.TxGas,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(feeCap),
+	})
+}
+
+// slotCondition returns conditions expecting the test slot to hold the value.
+func slotCondition(value common.Hash) *Conditions {
+	return &Conditions{
+		KnownAccounts: map[common.Address]KnownAccount{
+			testAccount: {StorageSlots: map[common.Hash]common.Hash{testSlot: value}},
+		},
+	}
+}
+
+func TestAddValidation(t *testing.T) {
+	chain := newTestBlockChain(t)
+	pool := newTestPool(t, chain)
+
+	root := chain.head.Root // Not the storage root of any account
+	for i, tt := range []struct {
+		tx         *types.Transaction
+		conditions *Conditions
+		err        error
+	}{
+		{newTestTx(0, 1), nil, ErrConditionalOnly},
+		{newTestTx(0, 1), &Conditions{BlockNumberMin: 5, BlockNumberMax: 4}, ErrInvalidConditions},
+		{newTestTx(0, 1), &Conditions{TimestampMin: 5, TimestampMax: 4}, ErrInvalidConditions},
+		{newTestTx(0, 1), &Conditions{BlockNumberMax: 1}, ErrConditionsExpired},
+		{newTestTx(0, 1), &Conditions{TimestampMax: 10}, ErrConditionsExpired},
+		{newTestTx(0, 1), &Conditions{BlockNumberMin: 2 + DefaultConfig.MaxBlocksAhead}, ErrConditionsTooFar},
+		{newTestTx(0, 1), &Conditions{TimestampMin: 11 + uint64(DefaultConfig.MaxTimeAhead/time.Second)}, ErrConditionsTooFar},
+		{newTestTx(0, 1), slotCondition(common.Hash{0x02}), ErrConditionFailed},
+		{newTestTx(0, 1), &Conditions{KnownAccounts: map[common.Address]KnownAccount{testAccount: {StorageRoot: &root}}}, ErrConditionFailed},
+		{newTestTx(1, 1), slotCondition(common.Hash{0x01}), core.ErrNonceTooHigh},
+		{newTestTx(0, 0), slotCondition(common.Hash{0x01}), txpool.ErrUnderpriced},
+		{newTestTx(0, 1), slotCondition(common.Hash{0x01}), nil},
+		{newTestTx(0, 1), slotCondition(common.Hash{0x01}), txpool.ErrAlreadyKnown},
+		{newTestTx(1, 1), &Conditions{BlockNumberMax: 2}, nil},
+	} {
+		if err := pool.AddConditional(tt.tx, tt.conditions); !errors.Is(err, tt.err) {
+			t.Errorf("case %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Plain transactions are never accepted
+	if errs := pool.Add([]*types.Transaction{newTestTx(2, 1)}, false, false); !errors.Is(errs[0], ErrConditionalOnly) {
+		t.Errorf("plain transaction error mismatch: have %v, want %v", errs[0], ErrConditionalOnly)
+	}
+	// Replacements need to bump the price
+	if err := pool.AddConditional(newTestTx(1, 2), &Conditions{}); !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Errorf("replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	if err := pool.AddConditional(newTestTxWithFeeCap(1, 10, 2*params.InitialBaseFee), &Conditions{}); err != nil {
+		t.Errorf("failed to replace transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pool size mismatch: have %d, want 2", pending)
+	}
+	if nonce := pool.Nonce(testAddr); nonce != 2 {
+		t.Fatalf("pool nonce mismatch: have %d, want 2", nonce)
+	}
+}
+
+func TestReset(t *testing.T) {
+	chain := newTestBlockChain(t)
+	pool := newTestPool(t, chain)
+
+	events := make(chan []txpool.TxEvent, 16)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	txs := []*types.Transaction{newTestTx(0, 1), newTestTx(1, 1), newTestTx(2, 1)}
+	for i, conditions := range []*Conditions{{}, {BlockNumberMax: 3}, slotCondition(common.Hash{0x01})} {
+		if err := pool.AddConditional(txs[i], conditions); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	for i := 0; i < len(txs); i++ {
+		<-events
+	}
+	check := func(want map[common.Hash]txpool.TxEventType) {
+		t.Helper()
+
+		var have []txpool.TxEvent
+		if len(want) > 0 {
+			have = <-events
+		}
+		if len(have) != len(want) {
+			t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for _, ev := range have {
+			if want[ev.Hash] != ev.Type {
+				t.Errorf("event mismatch for %x: have %v, want %v", ev.Hash, ev.Type, want[ev.Hash])
+			}
+		}
+	}
+	// Including the first transaction drops it as stale, the rest remains
+	old := chain.head
+	chain.commit(t, 2, func(statedb *state.StateDB) { statedb.SetNonce(testAddr, 1) })
+	pool.Reset(old, chain.head)
+	check(map[common.Hash]txpool.TxEventType{txs[0].Hash(): txpool.TxEventNonceTooLow})
+
+	// Changing the expected storage slot drops the third transaction
+	old = chain.head
+	chain.commit(t, 3, func(statedb *state.StateDB) { statedb.SetState(testAccount, testSlot, common.Hash{0x02}) })
+	pool.Reset(old, chain.head)
+
+	// The second transaction expired at the same time, dropping everything
+	check(map[common.Hash]txpool.TxEventType{
+		txs[1].Hash(): txpool.TxEventExpired,
+		txs[2].Hash(): txpool.TxEventInvalidated,
+	})
+	if pending, _ := pool.Stats(); pending != 0 {
+		t.Fatalf("pool size mismatch: have %d, want 0", pending)
+	}
+}
+
+func TestPending(t *testing.T) {
+	chain := newTestBlockChain(t)
+	pool := newTestPool(t, chain)
+
+	txs := []*types.Transaction{newTestTx(0, 1), newTestTx(1, 1), newTestTx(2, 5)}
+	for i, conditions := range []*Conditions{{}, {BlockNumberMin: 3, TimestampMax: 100}, {}} {
+		if err := pool.AddConditional(txs[i], conditions); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	for i, tt := range []struct {
+		filter txpool.PendingFilter
+		want   int
+	}{
+		// Nothing is released if not building a block
+		{txpool.PendingFilter{}, 0},
+		{txpool.PendingFilter{OnlyPlainTxs: true}, 0},
+
+		// Block bounds of a transaction gate all the following ones too
+		{txpool.PendingFilter{BlockNumber: 2, BlockTime: 20}, 1},
+		{txpool.PendingFilter{BlockNumber: 3, BlockTime: 30}, 3},
+		{txpool.PendingFilter{BlockNumber: 3, BlockTime: 200}, 1},
+
+		// Blob and tip filters are honored too
+		{txpool.PendingFilter{BlockNumber: 3, BlockTime: 30, OnlyBlobTxs: true}, 0},
+		{txpool.PendingFilter{BlockNumber: 3, BlockTime: 30, MinTip: uint256.NewInt(2)}, 0},
+	} {
+		if have := len(pool.Pending(tt.filter)[testAddr]); have != tt.want {
+			t.Errorf("case %d: pending count mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+func TestResetMissingState(t *testing.T) {
+	chain := newTestBlockChain(t)
+	pool := newTestPool(t, chain)
+
+	if err := pool.AddConditional(newTestTx(0, 1), &Conditions{}); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Resetting to a head without state drops everything, as the conditions can't
+	// be checked anymore, and rejects new transactions
+	missing := &types.Header{Number: big.NewInt(2), Time: 20, Root: common.Hash{0xff}}
+	pool.Reset(chain.head, missing)
+
+	if pending, _ := pool.Stats(); pending != 0 {
+		t.Fatalf("pool size mismatch: have %d, want 0", pending)
+	}
+	if pending := pool.Pending(txpool.PendingFilter{BlockNumber: 3, BlockTime: 30}); len(pending) != 0 {
+		t.Fatalf("pending transactions served without state: %v", pending)
+	}
+	if err := pool.AddConditional(newTestTx(0, 1), &Conditions{}); !errors.Is(err, ErrStateUnavailable) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStateUnavailable)
+	}
+	// Once a head with state arrives, the pool accepts transactions again
+	chain.commit(t, 3, func(statedb *state.StateDB) {})
+	pool.Reset(missing, chain.head)
+
+	if err := pool.AddConditional(newTestTx(0, 1), &Conditions{}); err != nil {
+		t.Fatalf("failed to add transaction after recovery: %v", err)
+	}
+}
+
+func TestInitMissingState(t *testing.T) {
+	chain := newTestBlockChain(t)
+	chain.head = &types.Header{Number: big.NewInt(2), Time: 20, Root: common.Hash{0xff}}
+
+	// Initializing on a head without state must not check against any other state
+	pool := newTestPool(t, chain)
+	if err := pool.AddConditional(newTestTx(0, 1), &Conditions{}); !errors.Is(err, ErrStateUnavailable) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStateUnavailable)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	chain := newTestBlockChain(t)
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	old := chain.head
+	chain.commit(t, 2, func(statedb *state.StateDB) {
+		for _, key := range keys {
+			statedb.SetBalance(crypto.PubkeyToAddress(key.PublicKey), uint256.NewInt(params.Ether), tracing.BalanceChangeUnspecified)
+		}
+	})
+	config := DefaultConfig
+	config.MaxTxs = 3
+	pool := New(config, chain)
+
+	released := make(map[common.Address]bool)
+	reserve := func(addr common.Address, reserve bool) error {
+		if !reserve {
+			released[addr] = true
+		}
+		return nil
+	}
+	if err := pool.Init(1, old, reserve); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	pool.Reset(old, chain.head)
+
+	// Fill the pool, the first account paying the least
+	fill := []*types.Transaction{
+		newTestTxWithKey(keys[0], 0, 2, params.InitialBaseFee+2),
+		newTestTxWithKey(keys[1], 0, 5, params.InitialBaseFee+5),
+		newTestTxWithKey(keys[1], 1, 5, params.InitialBaseFee+5),
+	}
+	for i, tx := range fill {
+		if err := pool.AddConditional(tx, &Conditions{}); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	// A transaction not paying more than the cheapest one is rejected
+	if err := pool.AddConditional(newTestTxWithKey(keys[2], 0, 2, params.InitialBaseFee+2), &Conditions{}); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrPoolFull)
+	}
+	// A better paying one evicts the cheapest and releases its account
+	if err := pool.AddConditional(newTestTxWithKey(keys[2], 0, 3, params.InitialBaseFee+3), &Conditions{}); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pool.Has(fill[0].Hash()) {
+		t.Fatal("cheapest transaction not evicted")
+	}
+	if !released[crypto.PubkeyToAddress(keys[0].PublicKey)] {
+		t.Fatal("account of the evicted transaction not released")
+	}
+	// Only the last transactions of other accounts are evictable, so the tail of
+	// the second account is evicted now even though all its transactions pay more
+	if err := pool.AddConditional(newTestTxWithKey(keys[2], 1, 10, params.InitialBaseFee+10), &Conditions{}); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if !pool.Has(fill[1].Hash()) || pool.Has(fill[2].Hash()) {
+		t.Fatal("wrong transaction evicted")
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	chain := newTestBlockChain(t)
+	pool := newTestPool(t, chain)
+
+	txs := []*types.Transaction{newTestTx(0, 1), newTestTx(1, 1)}
+	for i, tx := range txs {
+		if err := pool.AddConditional(tx, &Conditions{}); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	// Age the first transaction beyond the lifetime, dropping everything
+	pool.lookup[txs[0].Hash()].added = time.Now().Add(-DefaultConfig.Lifetime - time.Second)
+
+	old := chain.head
+	chain.commit(t, 2, func(statedb *state.StateDB) {})
+	pool.Reset(old, chain.head)
+
+	if pending, _ := pool.Stats(); pending != 0 {
+		t.Fatalf("pool size mismatch: have %d, want 0", pending)
+	}
+}
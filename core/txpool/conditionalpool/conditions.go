@@ -0,0 +1,126 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package conditionalpool
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// KnownAccount is the expected storage of an account. Either the whole storage
+// root or a set of individual storage slots is checked.
+type KnownAccount struct {
+	StorageRoot  *common.Hash                // Expected storage root, if checking the whole storage
+	StorageSlots map[common.Hash]common.Hash // Expected values of individual storage slots
+}
+
+// Conditions are the requirements a conditional transaction can only be included
+// under. The block number and timestamp bounds are inclusive, zero values leave
+// them unbounded.
+type Conditions struct {
+	KnownAccounts  map[common.Address]KnownAccount // Expected storage of accounts before the including block
+	BlockNumberMin uint64                          // Minimum number of the including block
+	BlockNumberMax uint64                          // Maximum number of the including block
+	TimestampMin   uint64                          // Minimum timestamp of the including block
+	TimestampMax   uint64                          // Maximum timestamp of the including block
+}
+
+// Cost returns the number of storage lookups needed to check the conditions.
+func (c *Conditions) Cost() int {
+	var cost int
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		}
+		cost += len(account.StorageSlots)
+	}
+	return cost
+}
+
+// validate checks the conditions for internal consistency.
+func (c *Conditions) validate() error {
+	if c.BlockNumberMax != 0 && c.BlockNumberMax < c.BlockNumberMin {
+		return fmt.Errorf("%w: block number range [%d, %d]", ErrInvalidConditions, c.BlockNumberMin, c.BlockNumberMax)
+	}
+	if c.TimestampMax != 0 && c.TimestampMax < c.TimestampMin {
+		return fmt.Errorf("%w: timestamp range [%d, %d]", ErrInvalidConditions, c.TimestampMin, c.TimestampMax)
+	}
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil && len(account.StorageSlots) > 0 {
+			return fmt.Errorf("%w: both storage root and slots of %x", ErrInvalidConditions, addr)
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the conditions can't be met anymore by any block built
+// on top of the given head.
+func (c *Conditions) Expired(head *types.Header) bool {
+	if c.BlockNumberMax != 0 && c.BlockNumberMax <= head.Number.Uint64() {
+		return true
+	}
+	if c.TimestampMax != 0 && c.TimestampMax <= head.Time {
+		return true
+	}
+	return false
+}
+
+// Includable reports whether the block bounds of the conditions allow inclusion
+// into the block with the given number and timestamp.
+func (c *Conditions) Includable(number uint64, time uint64) bool {
+	if number < c.BlockNumberMin || (c.BlockNumberMax != 0 && number > c.BlockNumberMax) {
+		return false
+	}
+	if time < c.TimestampMin || (c.TimestampMax != 0 && time > c.TimestampMax) {
+		return false
+	}
+	return true
+}
+
+// Verify checks the conditions against the block being built and its state,
+// implementing txpool.TxConditions.
+func (c *Conditions) Verify(header *types.Header, statedb *state.StateDB) error {
+	if !c.Includable(header.Number.Uint64(), header.Time) {
+		return fmt.Errorf("%w: block %d at time %d out of bounds", ErrConditionFailed, header.Number, header.Time)
+	}
+	return c.Check(statedb)
+}
+
+// Check verifies the storage conditions against the given state.
+func (c *Conditions) Check(statedb *state.StateDB) error {
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			root := statedb.GetStorageRoot(addr)
+			if root == (common.Hash{}) {
+				root = types.EmptyRootHash
+			}
+			if root != *account.StorageRoot {
+				return fmt.Errorf("%w: storage root of %x is %x, want %x", ErrConditionFailed, addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for slot, want := range account.StorageSlots {
+			if have := statedb.GetState(addr, slot); have != want {
+				return fmt.Errorf("%w: storage slot %x of %x is %x, want %x", ErrConditionFailed, slot, addr, have, want)
+			}
+		}
+	}
+	return nil
+}
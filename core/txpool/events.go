@@ -0,0 +1,173 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// TxEventType is the kind of a transaction lifecycle event.
+type TxEventType string
+
+const (
+	// TxEventAdded is emitted when a transaction is accepted into the pool.
+	TxEventAdded TxEventType = "added"
+
+	// TxEventReplaced is emitted when a transaction is replaced by another one
+	// from the same sender with the same nonce, paying higher fees.
+	TxEventReplaced TxEventType = "replaced"
+
+	// TxEventUnderpriced is emitted when a transaction is dropped for paying
+	// less than the minimum tip of the pool, or less than the transactions it
+	// is evicted in favour of when the pool is full.
+	TxEventUnderpriced TxEventType = "underpriced"
+
+	// TxEventEvicted is emitted when a transaction is dropped to enforce the
+	// capacity limits of the pool or of the sender's account.
+	TxEventEvicted TxEventType = "evicted"
+
+	// TxEventNonceTooLow is emitted when a transaction is dropped because its
+	// nonce was used by a transaction included into the chain, either itself
+	// or a different one.
+	TxEventNonceTooLow TxEventType = "nonceTooLow"
+
+	// TxEventExpired is emitted when a transaction is dropped for being queued
+	// for longer than the lifetime allowed by the pool.
+	TxEventExpired TxEventType = "expired"
+
+	// TxEventInvalidated is emitted when a transaction is dropped for becoming
+	// invalid against the chain state, e.g. insufficient funds, exceeding the
+	// block gas limit or depending on a dropped transaction.
+	TxEventInvalidated TxEventType = "invalidated"
+)
+
+// txEventTypes are all the known lifecycle event kinds.
+var txEventTypes = []TxEventType{
+	TxEventAdded,
+	TxEventReplaced,
+	TxEventUnderpriced,
+	TxEventEvicted,
+	TxEventNonceTooLow,
+	TxEventExpired,
+	TxEventInvalidated,
+}
+
+// txEventMeters count the lifecycle events by kind.
+var txEventMeters = make(map[TxEventType]metrics.Meter)
+
+// txEventLaggingMeter counts the subscribers dropped for lagging behind.
+var txEventLaggingMeter = metrics.NewRegisteredMeter("txpool/events/lagging", nil)
+
+func init() {
+	for _, typ := range txEventTypes {
+		txEventMeters[typ] = metrics.NewRegisteredMeter("txpool/events/"+string(typ), nil)
+	}
+}
+
+// TxEvent is a lifecycle event of a pooled transaction.
+type TxEvent struct {
+	Hash        common.Hash    `json:"hash"`
+	From        common.Address `json:"from"`
+	Type        TxEventType    `json:"type"`
+	Replacement *common.Hash   `json:"replacement,omitempty"` // Hash of the replacing transaction, if replaced
+}
+
+// ErrTxEventsLagging is returned on the subscription of a lifecycle event
+// subscriber which was dropped for not keeping up with the events.
+var ErrTxEventsLagging = errors.New("transaction event subscriber lagging behind")
+
+// TxEventFeed collects the lifecycle events of a pool and delivers them to the
+// subscribers in batches. The events are queued while the pool holds its locks
+// and are only sent out on Flush, after the locks are released.
+//
+// Delivery never blocks: the batches are buffered in the subscribers' channels,
+// and subscribers whose channel is full are dropped, their subscription failing
+// with ErrTxEventsLagging. Slow subscribers thus can't stall pool maintenance.
+type TxEventFeed struct {
+	queue []TxEvent
+	subs  map[*txEventSub]struct{}
+	lock  sync.Mutex // Protects the event queue and the subscribers, serializes flushes
+}
+
+// txEventSub is a subscriber of a lifecycle event feed.
+type txEventSub struct {
+	ch      chan<- []TxEvent
+	lagging chan struct{} // Closed when the subscriber is dropped for lagging
+}
+
+// Queue records lifecycle events to be sent out on the next flush.
+func (f *TxEventFeed) Queue(events ...TxEvent) {
+	for _, ev := range events {
+		txEventMeters[ev.Type].Mark(1)
+	}
+	f.lock.Lock()
+	f.queue = append(f.queue, events...)
+	f.lock.Unlock()
+}
+
+// Flush sends out all the queued lifecycle events, dropping the subscribers
+// which have no room left for them.
+func (f *TxEventFeed) Flush() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	events := f.queue
+	f.queue = nil
+	if len(events) == 0 {
+		return
+	}
+	for sub := range f.subs {
+		select {
+		case sub.ch <- events:
+		default:
+			delete(f.subs, sub)
+			close(sub.lagging)
+			txEventLaggingMeter.Mark(1)
+		}
+	}
+}
+
+// Subscribe registers a subscription for batches of lifecycle events. The
+// channel should be buffered, the subscription is dropped as soon as an event
+// batch can't be delivered without blocking.
+func (f *TxEventFeed) Subscribe(ch chan<- []TxEvent) event.Subscription {
+	sub := &txEventSub{ch: ch, lagging: make(chan struct{})}
+
+	f.lock.Lock()
+	if f.subs == nil {
+		f.subs = make(map[*txEventSub]struct{})
+	}
+	f.subs[sub] = struct{}{}
+	f.lock.Unlock()
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		select {
+		case <-quit:
+			f.lock.Lock()
+			delete(f.subs, sub)
+			f.lock.Unlock()
+			return nil
+		case <-sub.lagging:
+			return ErrTxEventsLagging
+		}
+	})
+}
@@ -126,6 +126,9 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot   string        // Snapshot of all pooled transactions to survive node restarts (empty disables)
+	Resnapshot time.Duration // Time interval to regenerate the pool snapshot
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -142,6 +145,8 @@ var DefaultConfig = Config{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	Resnapshot: 5 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -161,6 +166,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Resnapshot < time.Second {
+		log.Warn("Sanitizing invalid txpool snapshot time", "provided", conf.Resnapshot, "updated", time.Second)
+		conf.Resnapshot = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
 		conf.PriceLimit = DefaultConfig.PriceLimit
@@ -205,6 +214,7 @@ type LegacyPool struct {
 	chain       BlockChain
 	gasTip      atomic.Pointer[uint256.Int]
 	txFeed      event.Feed
+	txEvents    txpool.TxEventFeed
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -323,6 +333,13 @@ func (pool *LegacyPool) Init(gasTip uint64, head *types.Header, reserve txpool.A
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the pool snapshot is enabled, reload all the transactions pooled before
+	// the last shutdown. Locals are loaded first, so they retain their status.
+	if pool.config.Snapshot != "" {
+		if err := loadSnapshot(pool.config.Snapshot, pool.Has, pool.addRemotesSync); err != nil {
+			log.Warn("Failed to load transaction pool snapshot", "err", err)
+		}
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -341,10 +358,12 @@ func (pool *LegacyPool) loop() {
 		report  = time.NewTicker(statsReportInterval)
 		evict   = time.NewTicker(evictionInterval)
 		journal = time.NewTicker(pool.config.Rejournal)
+		snap    = time.NewTicker(pool.config.Resnapshot)
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer snap.Stop()
 
 	// Notify tests that the init phase is done
 	close(pool.initDoneCh)
@@ -380,10 +399,12 @@ func (pool *LegacyPool) loop() {
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true, true)
 					}
+					pool.txEvent(txpool.TxEventExpired, addr, list...)
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.txEvents.Flush()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -394,6 +415,14 @@ func (pool *LegacyPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle full pool snapshot regeneration
+		case <-snap.C:
+			if pool.config.Snapshot != "" {
+				if err := pool.writeSnapshot(); err != nil {
+					log.Warn("Failed to write transaction pool snapshot", "err", err)
+				}
+			}
 		}
 	}
 }
@@ -407,6 +436,11 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.config.Snapshot != "" {
+		if err := pool.writeSnapshot(); err != nil {
+			log.Warn("Failed to write transaction pool snapshot", "err", err)
+		}
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -428,9 +462,17 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs
 	return pool.txFeed.Subscribe(ch)
 }
 
+// SubscribeTxEvents registers a subscription for the lifecycle events of the
+// pooled transactions.
+func (pool *LegacyPool) SubscribeTxEvents(ch chan<- []txpool.TxEvent) event.Subscription {
+	return pool.txEvents.Subscribe(ch)
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
+	defer pool.txEvents.Flush()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -445,6 +487,9 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 		drop := pool.all.RemotesBelowTip(tip)
 		for _, tx := range drop {
 			pool.removeTx(tx.Hash(), false, true)
+
+			from, _ := types.Sender(pool.signer, tx)
+			pool.txEvent(txpool.TxEventUnderpriced, from, tx)
 		}
 		pool.priced.Removed(len(drop))
 	}
@@ -599,6 +644,23 @@ func (pool *LegacyPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// writeSnapshot dumps all the currently pooled transactions, pending and queued,
+// into the pool snapshot on disk.
+func (pool *LegacyPool) writeSnapshot() error {
+	txs := make(map[common.Address]types.Transactions)
+
+	pool.mu.RLock()
+	for addr, list := range pool.pending {
+		txs[addr] = list.Flatten()
+	}
+	for addr, list := range pool.queue {
+		txs[addr] = append(txs[addr], list.Flatten()...)
+	}
+	pool.mu.RUnlock()
+
+	return saveSnapshot(pool.config.Snapshot, txs)
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -609,7 +671,8 @@ func (pool *LegacyPool) validateTxBasics(tx *types.Transaction, local bool) erro
 		Accept: 0 |
 			1<<types.LegacyTxType |
 			1<<types.AccessListTxType |
-			1<<types.DynamicFeeTxType,
+			1<<types.DynamicFeeTxType |
+			1<<types.SetCodeTxType,
 		MaxSize: txMaxSize,
 		MinTip:  pool.gasTip.Load().ToBig(),
 	}
@@ -767,6 +830,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 
 			sender, _ := types.Sender(pool.signer, tx)
 			dropped := pool.removeTx(tx.Hash(), false, sender != from) // Don't unreserve the sender of the tx being added if last from the acc
+			pool.txEvent(txpool.TxEventUnderpriced, sender, tx)
 
 			pool.changesSinceReorg += dropped
 		}
@@ -785,11 +849,13 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.replaceEvent(from, old.Hash(), hash)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
+		pool.txEvent(txpool.TxEventAdded, from, tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// Successful promotion, bump the heartbeat
@@ -811,6 +877,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		localGauge.Inc(1)
 	}
 	pool.journalTx(from, tx)
+	pool.txEvent(txpool.TxEventAdded, from, tx)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
@@ -860,6 +927,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.replaceEvent(from, old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -880,6 +948,20 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 	return old != nil, nil
 }
 
+// txEvent queues a lifecycle event of the given type for each of the transactions
+// of the sender.
+func (pool *LegacyPool) txEvent(typ txpool.TxEventType, from common.Address, txs ...*types.Transaction) {
+	for _, tx := range txs {
+		pool.txEvents.Queue(txpool.TxEvent{Hash: tx.Hash(), From: from, Type: typ})
+	}
+}
+
+// replaceEvent queues the lifecycle event of a transaction being replaced by
+// another one with the same nonce.
+func (pool *LegacyPool) replaceEvent(from common.Address, hash common.Hash, replacement common.Hash) {
+	pool.txEvents.Queue(txpool.TxEvent{Hash: hash, From: from, Type: txpool.TxEventReplaced, Replacement: &replacement})
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
@@ -909,6 +991,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.replaceEvent(addr, hash, list.txs.Get(tx.Nonce()).Hash())
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -916,6 +999,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.replaceEvent(addr, old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -958,7 +1042,8 @@ func (pool *LegacyPool) addRemote(tx *types.Transaction) error {
 	return pool.addRemotes([]*types.Transaction{tx})[0]
 }
 
-// addRemotesSync is like addRemotes, but waits for pool reorganization. Tests use this method.
+// addRemotesSync is like addRemotes, but waits for pool reorganization. Tests and
+// the snapshot loading use this method.
 func (pool *LegacyPool) addRemotesSync(txs []*types.Transaction) []error {
 	return pool.Add(txs, false, true)
 }
@@ -1009,6 +1094,7 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 	pool.mu.Lock()
 	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
 	pool.mu.Unlock()
+	pool.txEvents.Flush()
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -1319,6 +1405,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
 	pool.mu.Unlock()
+	pool.txEvents.Flush()
 
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
@@ -1456,6 +1543,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.txEvent(txpool.TxEventNonceTooLow, addr, forwards...)
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
@@ -1463,6 +1551,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.txEvent(txpool.TxEventInvalidated, addr, drops...)
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
@@ -1486,6 +1575,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.txEvent(txpool.TxEventEvicted, addr, caps...)
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
@@ -1554,6 +1644,7 @@ func (pool *LegacyPool) truncatePending() {
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
+					pool.txEvent(txpool.TxEventEvicted, offenders[i], caps...)
 					pool.priced.Removed(len(caps))
 					pendingGauge.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
@@ -1581,6 +1672,7 @@ func (pool *LegacyPool) truncatePending() {
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
+				pool.txEvent(txpool.TxEventEvicted, addr, caps...)
 				pool.priced.Removed(len(caps))
 				pendingGauge.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
@@ -1621,9 +1713,11 @@ func (pool *LegacyPool) truncateQueue() {
 
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
-			for _, tx := range list.Flatten() {
+			txs := list.Flatten()
+			for _, tx := range txs {
 				pool.removeTx(tx.Hash(), true, true)
 			}
+			pool.txEvent(txpool.TxEventEvicted, addr.address, txs...)
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
 			continue
@@ -1632,6 +1726,7 @@ func (pool *LegacyPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true, true)
+			pool.txEvent(txpool.TxEventEvicted, addr.address, txs[i])
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1658,6 +1753,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
+		pool.txEvent(txpool.TxEventNonceTooLow, addr, olds...)
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
 		for _, tx := range drops {
@@ -1665,6 +1761,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 		}
+		pool.txEvent(txpool.TxEventInvalidated, addr, drops...)
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
 		for _, tx := range invalids {
@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -2458,6 +2459,213 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Close()
 }
 
+// Tests that the pool snapshot retains remote pending and queued transactions
+// across restarts, revalidating them against the new head.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	snapshot := filepath.Join(t.TempDir(), "txpool.rlp")
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	// Add two pending and one queued remote transactions
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 2/1", pending, queued)
+	}
+	pool.Close()
+
+	// Include the first transaction while the node is down and ensure the rest
+	// of the transactions survive the restart
+	statedb.SetNonce(addr, 1)
+	blockchain = newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool stats mismatch after restart: have %d/%d, want 1/1", pending, queued)
+	}
+	if pool.Has(pricedTransaction(0, 100000, big.NewInt(1), key).Hash()) {
+		t.Fatalf("included transaction reloaded from snapshot")
+	}
+	if pool.locals.contains(addr) {
+		t.Fatalf("snapshotted sender marked as local")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the snapshotted transactions already known to the pool, such as the
+// locals loaded from the journal, are not re-added.
+func TestSnapshotSkipsKnown(t *testing.T) {
+	t.Parallel()
+
+	snapshot := filepath.Join(t.TempDir(), "txpool.rlp")
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+		pricedTransaction(2, 100000, big.NewInt(1), key),
+	}
+	if err := saveSnapshot(snapshot, map[common.Address]types.Transactions{addr: txs}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	known := func(hash common.Hash) bool { return hash == txs[1].Hash() }
+
+	var added []common.Hash
+	add := func(batch []*types.Transaction) []error {
+		for _, tx := range batch {
+			added = append(added, tx.Hash())
+		}
+		return make([]error, len(batch))
+	}
+	if err := loadSnapshot(snapshot, known, add); err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(added) != 2 || added[0] != txs[0].Hash() || added[1] != txs[2].Hash() {
+		t.Fatalf("added transactions mismatch: have %x", added)
+	}
+}
+
+// Tests that the lifecycle events of the pooled transactions are emitted with
+// the reasons of them being dropped.
+func TestTxEvents(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	pool.Init(testTxPoolConfig.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	events := make(chan []txpool.TxEvent, 16)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	check := func(want ...txpool.TxEvent) {
+		t.Helper()
+
+		var have []txpool.TxEvent
+		for len(have) < len(want) {
+			select {
+			case batch := <-events:
+				have = append(have, batch...)
+			case <-time.After(time.Second):
+				t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+			}
+		}
+		if len(have) != len(want) {
+			t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash != want[i].Hash || have[i].From != want[i].From || have[i].Type != want[i].Type {
+				t.Errorf("event %d mismatch: have %+v, want %+v", i, have[i], want[i])
+			}
+			if (have[i].Replacement == nil) != (want[i].Replacement == nil) || (want[i].Replacement != nil && *have[i].Replacement != *want[i].Replacement) {
+				t.Errorf("event %d replacement mismatch: have %v, want %v", i, have[i].Replacement, want[i].Replacement)
+			}
+		}
+	}
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+	)
+	testAddBalance(pool, addr1, big.NewInt(1000000000))
+	testAddBalance(pool, addr2, big.NewInt(1000000000))
+
+	// Add a transaction and replace it with a better paying one
+	tx := pricedTransaction(0, 100000, big.NewInt(1), key1)
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check(txpool.TxEvent{Hash: tx.Hash(), From: addr1, Type: txpool.TxEventAdded})
+
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), key1)
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	hash := replacement.Hash()
+	check(
+		txpool.TxEvent{Hash: tx.Hash(), From: addr1, Type: txpool.TxEventReplaced, Replacement: &hash},
+		txpool.TxEvent{Hash: hash, From: addr1, Type: txpool.TxEventAdded},
+	)
+	// Raise the minimum tip above a pooled transaction
+	cheap := pricedTransaction(0, 100000, big.NewInt(1), key2)
+	if err := pool.addRemoteSync(cheap); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check(txpool.TxEvent{Hash: cheap.Hash(), From: addr2, Type: txpool.TxEventAdded})
+
+	pool.SetGasTip(big.NewInt(2))
+	check(txpool.TxEvent{Hash: cheap.Hash(), From: addr2, Type: txpool.TxEventUnderpriced})
+
+	// Include a transaction with the same nonce into the chain
+	statedb.SetNonce(addr1, 1)
+	<-pool.requestReset(nil, nil)
+	check(txpool.TxEvent{Hash: hash, From: addr1, Type: txpool.TxEventNonceTooLow})
+}
+
+// Tests that subscribers not keeping up with the lifecycle events are dropped
+// instead of stalling the pool.
+func TestTxEventsLagging(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	pool.Init(testTxPoolConfig.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	events := make(chan []txpool.TxEvent, 1)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// Fill the buffer of the subscriber and overflow it, which must not block
+	for i := 0; i < 3; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(uint64(i), 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	select {
+	case err := <-sub.Err():
+		if !errors.Is(err, txpool.ErrTxEventsLagging) {
+			t.Fatalf("unexpected subscription error: have %v, want %v", err, txpool.ErrTxEventsLagging)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lagging subscriber not dropped")
+	}
+	if len(events) != 1 {
+		t.Fatalf("buffered event batch count mismatch: have %d, want 1", len(events))
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
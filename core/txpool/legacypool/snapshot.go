@@ -0,0 +1,126 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// snapshotBatch is the number of transactions injected into the pool at once
+// when loading a pool snapshot.
+const snapshotBatch = 1024
+
+// loadSnapshot parses a pool snapshot from disk, injecting its transactions
+// into the pool as remote ones. The transactions are validated against the
+// current head like any other inbound transaction, so anything included or
+// invalidated while the node was down is dropped. The transactions already
+// known, i.e. the locals loaded from the journal, are skipped.
+func loadSnapshot(path string, known func(common.Hash) bool, add func([]*types.Transaction) []error) error {
+	input, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var (
+		stream  = rlp.NewStream(bufio.NewReader(input), 0)
+		total   int
+		skipped int
+		dropped int
+		failure error
+		batch   types.Transactions
+	)
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Trace("Failed to add snapshotted transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		total++
+
+		if known(tx.Hash()) {
+			skipped++
+			continue
+		}
+		if batch = append(batch, tx); batch.Len() >= snapshotBatch {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	if batch.Len() > 0 {
+		loadBatch(batch)
+	}
+	log.Info("Loaded transaction pool snapshot", "transactions", total, "known", skipped, "dropped", dropped)
+	return failure
+}
+
+// saveSnapshot dumps the given transactions into the pool snapshot on disk.
+// The snapshot is generated next to the live one and moved in place after,
+// so a crash mid-write never corrupts the previous snapshot.
+func saveSnapshot(path string, all map[common.Address]types.Transactions) error {
+	output, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		writer = bufio.NewWriter(output)
+		count  int
+	)
+	for _, txs := range all {
+		for _, tx := range txs {
+			if err = rlp.Encode(writer, tx); err != nil {
+				output.Close()
+				return err
+			}
+		}
+		count += len(txs)
+	}
+	if err = writer.Flush(); err != nil {
+		output.Close()
+		return err
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(path+".new", path); err != nil {
+		return err
+	}
+	log.Debug("Regenerated transaction pool snapshot", "transactions", count, "accounts", len(all))
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/holiman/uint256"
@@ -41,6 +42,18 @@ type LazyTransaction struct {
 
 	Gas     uint64 // Amount of gas required by the transaction
 	BlobGas uint64 // Amount of blob gas required by the transaction
+
+	Conditions TxConditions // Requirements to re-check right before inclusion, nil if unconditional
+}
+
+// TxConditions are the inclusion requirements of a conditional transaction. The
+// transactions preceding it in a block may invalidate them, so they need to be
+// re-checked against the block state right before inclusion.
+type TxConditions interface {
+	// Verify checks the requirements against the header of the block being built
+	// and its state, including the changes of all the preceding transactions. The
+	// storage roots of the state must be up to date.
+	Verify(header *types.Header, statedb *state.StateDB) error
 }
 
 // Resolve retrieves the full transaction belonging to a lazy handle if it is still
@@ -83,6 +96,9 @@ type PendingFilter struct {
 
 	OnlyPlainTxs bool // Return only plain EVM transactions (peer-join announces, block space filling)
 	OnlyBlobTxs  bool // Return only blob transactions (block blob-space filling)
+
+	BlockNumber uint64 // Number of the block being built, zero if not building a block
+	BlockTime   uint64 // Timestamp of the block being built, zero if not building a block
 }
 
 // SubPool represents a specialized transaction pool that lives on its own (e.g.
@@ -140,6 +156,10 @@ type SubPool interface {
 	// or also for reorged out ones.
 	SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription
 
+	// SubscribeTxEvents subscribes to the lifecycle events of pooled transactions,
+	// including the reasons of them being dropped from the pool.
+	SubscribeTxEvents(ch chan<- []TxEvent) event.Subscription
+
 	// Nonce returns the next nonce of an account, with all transactions executable
 	// by the pool already applied on top.
 	Nonce(addr common.Address) uint64
@@ -0,0 +1,151 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// TestParseDelegation tests a few possible delegation designator values and
+// ensures they are parsed correctly.
+func TestParseDelegation(t *testing.T) {
+	addr := common.Address{0x42}
+	for _, tt := range []struct {
+		val  []byte
+		want *common.Address
+	}{
+		{ // simple correct delegation
+			val:  append(DelegationPrefix, addr.Bytes()...),
+			want: &addr,
+		},
+		{ // wrong address size
+			val: append(DelegationPrefix, addr.Bytes()[0:19]...),
+		},
+		{ // short address
+			val: append(DelegationPrefix, 0x42),
+		},
+		{ // long address
+			val: append(append(DelegationPrefix, addr.Bytes()...), 0x42),
+		},
+		{ // wrong prefix size
+			val: append(DelegationPrefix[:2], addr.Bytes()...),
+		},
+		{ // wrong prefix
+			val: append([]byte{0xef, 0x01, 0x01}, addr.Bytes()...),
+		},
+		{ // wrong prefix
+			val: append([]byte{0xef, 0x00, 0x00}, addr.Bytes()...),
+		},
+		{ // no prefix
+			val: addr.Bytes(),
+		},
+		{ // no address
+			val: DelegationPrefix,
+		},
+	} {
+		got, ok := ParseDelegation(tt.val)
+		if ok && tt.want == nil {
+			t.Fatalf("expected fail, got %s", got.Hex())
+		}
+		if !ok && tt.want != nil {
+			t.Fatalf("failed to parse, want %s", tt.want.Hex())
+		}
+		if ok && got != *tt.want {
+			t.Fatalf("parsed wrong address: have %s, want %s", got.Hex(), tt.want.Hex())
+		}
+	}
+}
+
+func TestSetCodeAuthority(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	auth, err := SignSetCode(key, SetCodeAuthorization{
+		ChainID: *uint256.NewInt(1),
+		Address: common.Address{0x42},
+		Nonce:   7,
+	})
+	if err != nil {
+		t.Fatalf("failed to sign authorization: %v", err)
+	}
+	authority, err := auth.Authority()
+	if err != nil {
+		t.Fatalf("failed to recover authority: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); authority != want {
+		t.Fatalf("wrong authority: have %s, want %s", authority.Hex(), want.Hex())
+	}
+	// Tampering with the authorization must change the recovered authority.
+	auth.Nonce++
+	if authority, err := auth.Authority(); err == nil && authority == crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("authority unchanged after modifying the authorization")
+	}
+}
+
+func TestSetCodeTxCoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	auth, _ := SignSetCode(key, SetCodeAuthorization{
+		ChainID: *uint256.NewInt(1),
+		Address: common.Address{0x42},
+		Nonce:   1,
+	})
+	var (
+		signer = NewPragueSigner(big.NewInt(1))
+		tx     = MustSignNewTx(key, signer, &SetCodeTx{
+			ChainID:    uint256.NewInt(1),
+			Nonce:      1,
+			GasTipCap:  uint256.NewInt(1),
+			GasFeeCap:  uint256.NewInt(10),
+			Gas:        100000,
+			To:         common.Address{0x01},
+			Value:      uint256.NewInt(0),
+			Data:       []byte("abcdef"),
+			AccessList: AccessList{{Address: common.Address{0x02}, StorageKeys: []common.Hash{{0}}}},
+			AuthList:   []SetCodeAuthorization{auth},
+		})
+	)
+	for name, codec := range map[string]func(*Transaction) (*Transaction, error){
+		"rlp":  encodeDecodeBinary,
+		"json": encodeDecodeJSON,
+	} {
+		parsed, err := codec(tx)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := assertEqual(parsed, tx); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(parsed.SetCodeAuthorizations(), tx.SetCodeAuthorizations()) {
+			t.Fatalf("%s: authorization list mismatch", name)
+		}
+		from, err := Sender(signer, parsed)
+		if err != nil {
+			t.Fatalf("%s: failed to recover sender: %v", name, err)
+		}
+		if want := crypto.PubkeyToAddress(key.PublicKey); from != want {
+			t.Fatalf("%s: wrong sender: have %s, want %s", name, from.Hex(), want.Hex())
+		}
+	}
+	// Set code transactions are not accepted by the Cancun signer.
+	if _, err := Sender(NewCancunSigner(big.NewInt(1)), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("wrong error from cancun signer: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
@@ -59,6 +59,12 @@ type Contract struct {
 
 	Gas   uint64
 	value *uint256.Int
+
+	// EOF execution context, only set for EOF contracts. Code always holds
+	// the code section being executed.
+	Container   *Container
+	CodeSection uint64
+	returnStack []returnContext
 }
 
 // NewContract returns a new contract environment for the execution of EVM.
@@ -204,4 +210,12 @@ func (c *Contract) SetCodeOptionalHash(addr *common.Address, codeAndHash *codeAn
 	c.Code = codeAndHash.code
 	c.CodeHash = codeAndHash.hash
 	c.CodeAddr = addr
+	c.Container = codeAndHash.container
+}
+
+// setCodeSection switches the execution of an EOF contract to the code
+// section with the given index.
+func (c *Contract) setCodeSection(section uint64) {
+	c.CodeSection = section
+	c.Code = c.Container.codeSections[section]
 }
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -171,22 +172,28 @@ func init() {
 	}
 }
 
-// ActivePrecompiles returns the precompiles enabled with the current configuration.
+// ActivePrecompiles returns the precompiles enabled with the current configuration,
+// including the registered stateful precompiles.
 func ActivePrecompiles(rules params.Rules) []common.Address {
+	var precompiles []common.Address
 	switch {
 	case rules.IsPrague:
-		return PrecompiledAddressesPrague
+		precompiles = PrecompiledAddressesPrague
 	case rules.IsCancun:
-		return PrecompiledAddressesCancun
+		precompiles = PrecompiledAddressesCancun
 	case rules.IsBerlin:
-		return PrecompiledAddressesBerlin
+		precompiles = PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
-		return PrecompiledAddressesIstanbul
+		precompiles = PrecompiledAddressesIstanbul
 	case rules.IsByzantium:
-		return PrecompiledAddressesByzantium
+		precompiles = PrecompiledAddressesByzantium
 	default:
-		return PrecompiledAddressesHomestead
+		precompiles = PrecompiledAddressesHomestead
 	}
+	if stateful := activeStatePrecompileAddresses(rules); len(stateful) > 0 {
+		return append(slices.Clone(precompiles), stateful...)
+	}
+	return precompiles
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
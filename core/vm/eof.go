@@ -0,0 +1,540 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	offsetVersion   = 2
+	offsetTypesKind = 3
+	offsetCodeKind  = 6
+
+	kindTypes     = 1
+	kindCode      = 2
+	kindContainer = 3
+	kindData      = 0xff
+
+	eof1Version = 1
+
+	maxInputItems        = 127
+	maxOutputItems       = 127
+	maxStackIncrease     = 1023
+	maxCodeSections      = 1024
+	maxContainerSections = 256
+	maxContainerDepth    = 256
+
+	nonReturningFunction = 0x80
+)
+
+var (
+	eofMagic     = []byte{0xef, 0x00}
+	eofMagicHash = crypto.Keccak256Hash(eofMagic) // code hash of EOF contracts seen by legacy code
+)
+
+var (
+	errInvalidMagic              = errors.New("invalid magic")
+	errUndefinedInstruction      = errors.New("undefined instruction")
+	errTruncatedImmediate        = errors.New("truncated immediate")
+	errInvalidSectionArgument    = errors.New("invalid section argument")
+	errInvalidCallArgument       = errors.New("callf into non-returning section")
+	errInvalidDataloadNArgument  = errors.New("invalid dataloadN argument")
+	errInvalidJumpDest           = errors.New("invalid jump destination")
+	errInvalidBackwardJump       = errors.New("invalid backward jump")
+	errInvalidOutputs            = errors.New("invalid number of outputs")
+	errInvalidMaxStackHeight     = errors.New("invalid max stack height")
+	errInvalidCodeTermination    = errors.New("invalid code termination")
+	errUnreachableCode           = errors.New("unreachable code")
+	errEOFStackUnderflow         = errors.New("stack underflow")
+	errEOFStackOverflow          = errors.New("stack overflow")
+	errInvalidVersion            = errors.New("invalid version")
+	errMissingTypeHeader         = errors.New("missing type header")
+	errInvalidTypeSize           = errors.New("invalid type section size")
+	errMissingCodeHeader         = errors.New("missing code header")
+	errInvalidCodeSize           = errors.New("invalid code size")
+	errInvalidContainerSize      = errors.New("invalid container size")
+	errMissingDataHeader         = errors.New("missing data header")
+	errMissingTerminator         = errors.New("missing header terminator")
+	errTooManyInputs             = errors.New("invalid type content, too many inputs")
+	errTooManyOutputs            = errors.New("invalid type content, too many outputs")
+	errInvalidSection0Type       = errors.New("invalid section 0 type, input and output should be zero and non-returning (0x80)")
+	errTooLargeMaxStackHeight    = errors.New("invalid type content, max stack height exceeds limit")
+	errInvalidContainerArgument  = errors.New("invalid container argument")
+	errInvalidContainerKind      = errors.New("invalid container kind")
+	errOrphanedSubcontainer      = errors.New("subcontainer not referenced at all")
+	errUnreachableCodeSections   = errors.New("unreachable code sections")
+	errInvalidNonReturning       = errors.New("section marked as returning, but has no return instruction")
+	errInvalidJumpFTarget        = errors.New("invalid jumpf target")
+	errTruncatedData             = errors.New("data section smaller than declared")
+	errTrailingBytes             = errors.New("trailing bytes after container")
+	errTooDeeplyNestedContainers = errors.New("too deeply nested containers")
+)
+
+// functionMetadata is an EOF function signature.
+type functionMetadata struct {
+	inputs           uint8
+	outputs          uint8
+	maxStackIncrease uint16
+}
+
+// stackDelta returns the net change of the stack height caused by calling the
+// function.
+func (meta *functionMetadata) stackDelta() int {
+	return int(meta.outputs) - int(meta.inputs)
+}
+
+// returning reports whether the function returns to its caller.
+func (meta *functionMetadata) returning() bool {
+	return meta.outputs != nonReturningFunction
+}
+
+// Container is an EOF container object.
+type Container struct {
+	types             []*functionMetadata
+	codeSections      [][]byte
+	subContainers     []*Container
+	subContainerCodes [][]byte
+	data              []byte
+	dataSize          int // might be more than len(data) for containers yet to be deployed
+}
+
+// hasEOFMagic returns whether the code starts with the EOF magic.
+func hasEOFMagic(code []byte) bool {
+	return bytes.HasPrefix(code, eofMagic)
+}
+
+// isEOFVersion1 returns whether the code is an EOF container of version 1.
+func isEOFVersion1(code []byte) bool {
+	return hasEOFMagic(code) && len(code) > offsetVersion && code[offsetVersion] == eof1Version
+}
+
+// CodeSectionCount returns the number of code sections in the container.
+func (c *Container) CodeSectionCount() int {
+	return len(c.codeSections)
+}
+
+// SubContainerCount returns the number of sub-containers in the container.
+func (c *Container) SubContainerCount() int {
+	return len(c.subContainers)
+}
+
+// Data returns the data section of the container.
+func (c *Container) Data() []byte {
+	return c.data
+}
+
+// MarshalBinary encodes an EOF container into binary format.
+func (c *Container) MarshalBinary() []byte {
+	// Build EOF prefix.
+	b := make([]byte, 2)
+	copy(b, eofMagic)
+	b = append(b, eof1Version)
+
+	// Write section headers.
+	b = append(b, kindTypes)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(c.types)*4))
+	b = append(b, kindCode)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(c.codeSections)))
+	for _, codeSection := range c.codeSections {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(codeSection)))
+	}
+	if len(c.subContainers) != 0 {
+		b = append(b, kindContainer)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(c.subContainers)))
+		for _, section := range c.subContainerCodes {
+			b = binary.BigEndian.AppendUint32(b, uint32(len(section)))
+		}
+	}
+	b = append(b, kindData)
+	b = binary.BigEndian.AppendUint16(b, uint16(c.dataSize))
+	b = append(b, 0) // terminator
+
+	// Write section contents.
+	for _, ty := range c.types {
+		b = append(b, []byte{ty.inputs, ty.outputs, byte(ty.maxStackIncrease >> 8), byte(ty.maxStackIncrease & 0x00ff)}...)
+	}
+	for _, code := range c.codeSections {
+		b = append(b, code...)
+	}
+	for _, section := range c.subContainerCodes {
+		b = append(b, section...)
+	}
+	b = append(b, c.data...)
+
+	return b
+}
+
+// UnmarshalBinary decodes an EOF container, which must span the entire input
+// and contain its complete data section.
+func (c *Container) UnmarshalBinary(b []byte) error {
+	size, err := c.unmarshal(b, true, 0)
+	if err != nil {
+		return err
+	}
+	if size != len(b) {
+		return errTrailingBytes
+	}
+	return nil
+}
+
+// unmarshalInitcode decodes the EOF initcode container at the start of a
+// creation transaction's data. The rest of the data is returned as calldata.
+func unmarshalInitcode(b []byte) (*Container, []byte, error) {
+	var c Container
+	size, err := c.unmarshal(b, true, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &c, b[size:], nil
+}
+
+// unmarshal decodes the container at the start of b and returns its encoded
+// size. Unless full is set, the data section may be truncated.
+func (c *Container) unmarshal(b []byte, full bool, depth int) (int, error) {
+	if depth > maxContainerDepth {
+		return 0, errTooDeeplyNestedContainers
+	}
+	if !hasEOFMagic(b) {
+		return 0, fmt.Errorf("%w: want %x", errInvalidMagic, eofMagic)
+	}
+	if len(b) < 14 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if !isEOFVersion1(b) {
+		return 0, fmt.Errorf("%w: have %d, want %d", errInvalidVersion, b[2], eof1Version)
+	}
+
+	var (
+		kind, typesSize, dataSize int
+		codeSizes                 []int
+		containerSizes            []int
+		err                       error
+	)
+
+	// Parse type section header.
+	kind, typesSize, err = parseSection(b, offsetTypesKind)
+	if err != nil {
+		return 0, err
+	}
+	if kind != kindTypes {
+		return 0, fmt.Errorf("%w: found section kind %x instead", errMissingTypeHeader, kind)
+	}
+	if typesSize < 4 || typesSize%4 != 0 {
+		return 0, fmt.Errorf("%w: type section size must be divisible by 4, have %d", errInvalidTypeSize, typesSize)
+	}
+	if typesSize/4 > maxCodeSections {
+		return 0, fmt.Errorf("%w: type section must not exceed 4*%d, have %d", errInvalidTypeSize, maxCodeSections, typesSize)
+	}
+
+	// Parse code section header.
+	kind, codeSizes, err = parseSectionList(b, offsetCodeKind, 2)
+	if err != nil {
+		return 0, err
+	}
+	if kind != kindCode {
+		return 0, fmt.Errorf("%w: found section kind %x instead", errMissingCodeHeader, kind)
+	}
+	if len(codeSizes) != typesSize/4 {
+		return 0, fmt.Errorf("%w: mismatch of code sections found and type signatures, types %d, code %d", errInvalidCodeSize, typesSize/4, len(codeSizes))
+	}
+
+	// Parse (optional) container section header.
+	offset := offsetCodeKind + 2 + 2*len(codeSizes) + 1
+	if offset < len(b) && b[offset] == kindContainer {
+		kind, containerSizes, err = parseSectionList(b, offset, 4)
+		if err != nil {
+			return 0, err
+		}
+		if len(containerSizes) > maxContainerSections {
+			return 0, fmt.Errorf("%w: number of container sections exceeds %d: have %d", errInvalidContainerSize, maxContainerSections, len(containerSizes))
+		}
+		offset = offset + 2 + 4*len(containerSizes) + 1
+	}
+
+	// Parse data section header.
+	kind, dataSize, err = parseSection(b, offset)
+	if err != nil {
+		return 0, err
+	}
+	if kind != kindData {
+		return 0, fmt.Errorf("%w: found section %x instead", errMissingDataHeader, kind)
+	}
+	c.dataSize = dataSize
+
+	// Check for terminator.
+	offsetTerminator := offset + 3
+	if len(b) <= offsetTerminator {
+		return 0, fmt.Errorf("%w: invalid offset terminator", io.ErrUnexpectedEOF)
+	}
+	if b[offsetTerminator] != 0 {
+		return 0, fmt.Errorf("%w: have %x", errMissingTerminator, b[offsetTerminator])
+	}
+
+	// Verify overall container size.
+	expectedSize := offsetTerminator + 1 + typesSize + sum(codeSizes) + sum(containerSizes) + dataSize
+	if full && len(b) < expectedSize {
+		return 0, fmt.Errorf("%w: have %d, want %d", errTruncatedData, len(b), expectedSize)
+	}
+	if !full && len(b) > expectedSize {
+		return 0, fmt.Errorf("%w: have %d, want %d", errTrailingBytes, len(b), expectedSize)
+	}
+
+	// Parse types section.
+	idx := offsetTerminator + 1
+	if len(b) < idx+typesSize {
+		return 0, fmt.Errorf("%w: types section truncated", io.ErrUnexpectedEOF)
+	}
+	var types = make([]*functionMetadata, 0, typesSize/4)
+	for i := 0; i < typesSize/4; i++ {
+		sig := &functionMetadata{
+			inputs:           b[idx+i*4],
+			outputs:          b[idx+i*4+1],
+			maxStackIncrease: binary.BigEndian.Uint16(b[idx+i*4+2:]),
+		}
+		if sig.inputs > maxInputItems {
+			return 0, fmt.Errorf("%w for section %d: have %d", errTooManyInputs, i, sig.inputs)
+		}
+		if sig.outputs > maxOutputItems && sig.outputs != nonReturningFunction {
+			return 0, fmt.Errorf("%w for section %d: have %d", errTooManyOutputs, i, sig.outputs)
+		}
+		if int(sig.inputs)+int(sig.maxStackIncrease) > maxStackIncrease {
+			return 0, fmt.Errorf("%w for section %d: have %d", errTooLargeMaxStackHeight, i, int(sig.inputs)+int(sig.maxStackIncrease))
+		}
+		types = append(types, sig)
+	}
+	if types[0].inputs != 0 || types[0].outputs != nonReturningFunction {
+		return 0, fmt.Errorf("%w: have %d, %d", errInvalidSection0Type, types[0].inputs, types[0].outputs)
+	}
+	c.types = types
+
+	// Parse code sections.
+	idx += typesSize
+	codeSections := make([][]byte, len(codeSizes))
+	for i, size := range codeSizes {
+		if len(b) < idx+size {
+			return 0, fmt.Errorf("%w: code section %d truncated", io.ErrUnexpectedEOF, i)
+		}
+		codeSections[i] = b[idx : idx+size]
+		idx += size
+	}
+	c.codeSections = codeSections
+
+	// Parse the optional container sections.
+	if len(containerSizes) != 0 {
+		subContainerCodes := make([][]byte, 0, len(containerSizes))
+		subContainers := make([]*Container, 0, len(containerSizes))
+		for i, size := range containerSizes {
+			if len(b) < idx+size {
+				return 0, fmt.Errorf("%w: container section %d truncated", io.ErrUnexpectedEOF, i)
+			}
+			subC := new(Container)
+			if _, err := subC.unmarshal(b[idx:idx+size], false, depth+1); err != nil {
+				return 0, err
+			}
+			subContainers = append(subContainers, subC)
+			subContainerCodes = append(subContainerCodes, b[idx:idx+size])
+			idx += size
+		}
+		c.subContainers = subContainers
+		c.subContainerCodes = subContainerCodes
+	}
+
+	// Parse data section, which might be truncated.
+	end := min(idx+dataSize, len(b))
+	c.data = b[idx:end]
+
+	return end, nil
+}
+
+// ValidateCode validates each code section of the container against the EOF
+// v1 rule set, as well as all sub-containers. Initcode containers are the
+// ones executed by EOFCREATE and creation transactions.
+func (c *Container) ValidateCode(jt *JumpTable, isInitCode bool) error {
+	refBy := notRefByEither
+	if isInitCode {
+		refBy = refByEOFCreate
+	}
+	return c.validateSubContainer(jt, refBy)
+}
+
+// ParseAndValidateEOF decodes the given EOF container and validates it with
+// the rules applied when EOF contracts are created.
+func ParseAndValidateEOF(code []byte, isInitCode bool) (*Container, error) {
+	var c Container
+	if err := c.UnmarshalBinary(code); err != nil {
+		return nil, err
+	}
+	if err := c.ValidateCode(&eofInstructionSet, isInitCode); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *Container) validateSubContainer(jt *JumpTable, refBy int) error {
+	visitor := &validationResult{}
+	toVisit := []int{0}
+	visited := make(map[int]bool)
+	for len(toVisit) > 0 {
+		// Visit the sections reachable from section 0
+		index := toVisit[0]
+		toVisit = toVisit[1:]
+		if visited[index] {
+			continue
+		}
+		visited[index] = true
+
+		res, err := validateCode(c.codeSections[index], index, c, jt, refBy == refByEOFCreate)
+		if err != nil {
+			return err
+		}
+		if err := visitor.merge(res); err != nil {
+			return err
+		}
+		for idx := range res.accessedCodeSections {
+			if !visited[idx] {
+				toVisit = append(toVisit, idx)
+			}
+		}
+	}
+	// Make sure every code section is visited at least once.
+	if len(visited) != len(c.codeSections) {
+		return errUnreachableCodeSections
+	}
+	for idx, container := range c.subContainers {
+		reference, ok := visitor.visitedSubContainers[idx]
+		// Make sure every sub-container is only ever referenced once.
+		if !ok {
+			return errOrphanedSubcontainer
+		}
+		if reference == refByEOFCreate && len(container.data) != container.dataSize {
+			return fmt.Errorf("%w: eofcreate target with truncated data", errTruncatedData)
+		}
+		if err := container.validateSubContainer(jt, reference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSection decodes a (kind, size) pair from an EOF header.
+func parseSection(b []byte, idx int) (kind, size int, err error) {
+	if idx+3 > len(b) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	kind = int(b[idx])
+	size = int(binary.BigEndian.Uint16(b[idx+1 : idx+3]))
+	return kind, size, nil
+}
+
+// parseSectionList decodes a (kind, len, []sizes) tuple from an EOF header,
+// where every size has the given width in bytes.
+func parseSectionList(b []byte, idx int, width int) (kind int, list []int, err error) {
+	if idx >= len(b) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	kind = int(b[idx])
+	list, err = parseList(b, idx+1, width)
+	if err != nil {
+		return 0, nil, err
+	}
+	return kind, list, nil
+}
+
+// parseList decodes a list of sizes prefixed by a uint16 count.
+func parseList(b []byte, idx int, width int) ([]int, error) {
+	if len(b) < idx+2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	count := binary.BigEndian.Uint16(b[idx:])
+	if count == 0 {
+		return nil, fmt.Errorf("%w: empty section list", errInvalidCodeSize)
+	}
+	if len(b) <= idx+2+int(count)*width {
+		return nil, io.ErrUnexpectedEOF
+	}
+	list := make([]int, count)
+	for i := 0; i < int(count); i++ {
+		var size int
+		if width == 2 {
+			size = int(binary.BigEndian.Uint16(b[idx+2+i*width:]))
+		} else {
+			size = int(binary.BigEndian.Uint32(b[idx+2+i*width:]))
+		}
+		if size == 0 {
+			if width == 2 {
+				return nil, fmt.Errorf("%w: section %d size must not be 0", errInvalidCodeSize, i)
+			}
+			return nil, fmt.Errorf("%w: section %d size must not be 0", errInvalidContainerSize, i)
+		}
+		list[i] = size
+	}
+	return list, nil
+}
+
+// sum computes the sum of a slice.
+func sum(list []int) (s int) {
+	for _, n := range list {
+		s += n
+	}
+	return
+}
+
+// String returns a human readable dump of the container.
+func (c *Container) String() string {
+	var output = []string{
+		"Header",
+		fmt.Sprintf("  - EOFMagic: %02x", eofMagic),
+		fmt.Sprintf("  - EOFVersion: %02x", eof1Version),
+		fmt.Sprintf("  - KindType: %02x", kindTypes),
+		fmt.Sprintf("  - TypesSize: %04x", len(c.types)*4),
+		fmt.Sprintf("  - KindCode: %02x", kindCode),
+		fmt.Sprintf("  - KindData: %02x", kindData),
+		fmt.Sprintf("  - DataSize: %04x", len(c.data)),
+		fmt.Sprintf("  - Number of code sections: %d", len(c.codeSections)),
+	}
+	for i, code := range c.codeSections {
+		output = append(output, fmt.Sprintf("    - Code section %d length: %04x", i, len(code)))
+	}
+
+	output = append(output, fmt.Sprintf("  - Number of subcontainers: %d", len(c.subContainers)))
+	if len(c.subContainers) > 0 {
+		for i, section := range c.subContainerCodes {
+			output = append(output, fmt.Sprintf("    - subcontainer %d length: %04x\n", i, len(section)))
+		}
+	}
+	output = append(output, "Body")
+	for i, typ := range c.types {
+		output = append(output, fmt.Sprintf("  - Type %v: %x", i,
+			[]byte{typ.inputs, typ.outputs, byte(typ.maxStackIncrease >> 8), byte(typ.maxStackIncrease & 0x00ff)}))
+	}
+	for i, code := range c.codeSections {
+		output = append(output, fmt.Sprintf("  - Code section %d: %#x", i, code))
+	}
+	for i, section := range c.subContainerCodes {
+		output = append(output, fmt.Sprintf("  - Subcontainer %d: %x", i, section))
+	}
+	output = append(output, fmt.Sprintf("  - Data: %#x", c.data))
+	return strings.Join(output, "\n")
+}
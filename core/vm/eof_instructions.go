@@ -0,0 +1,438 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// returnStackLimit is the maximum depth of nested CALLF invocations.
+const returnStackLimit = 1024
+
+// returnContext is an entry of the return stack of an EOF contract, pointing
+// to the instruction following a CALLF.
+type returnContext struct {
+	section uint64
+	pc      uint64
+}
+
+// parseInt16 returns the int16 located at b[0:2].
+func parseInt16(b []byte) int16 {
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+// relativeJump moves the program counter to the target of the relative jump
+// with the given offset, measured from the given end of its immediates. The
+// program counter is placed one byte before the target as the interpreter
+// loop increments it after every operation.
+func relativeJump(pc *uint64, end uint64, offset int16) {
+	*pc = uint64(int64(end)+int64(offset)) - 1
+}
+
+// opRjump implements the RJUMP opcode.
+func opRjump(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	offset := parseInt16(scope.Contract.Code[*pc+1:])
+	relativeJump(pc, *pc+3, offset)
+	return nil, nil
+}
+
+// opRjumpi implements the RJUMPI opcode.
+func opRjumpi(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	condition := scope.Stack.pop()
+	if condition.BitLen() == 0 {
+		// Not branching, just skip over immediate argument.
+		*pc += 2
+		return nil, nil
+	}
+	return opRjump(pc, interpreter, scope)
+}
+
+// opRjumpv implements the RJUMPV opcode.
+func opRjumpv(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		code  = scope.Contract.Code
+		count = uint64(code[*pc+1]) + 1
+		idx   = scope.Stack.pop()
+	)
+	if idx, overflow := idx.Uint64WithOverflow(); overflow || idx >= count {
+		// Index out-of-bounds, don't branch, just skip over immediate
+		// argument.
+		*pc += 1 + count*2
+		return nil, nil
+	}
+	offset := parseInt16(code[*pc+2+2*idx.Uint64():])
+	relativeJump(pc, *pc+2+count*2, offset)
+	return nil, nil
+}
+
+// opCallf implements the CALLF opcode.
+func opCallf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		contract = scope.Contract
+		idx      = binary.BigEndian.Uint16(contract.Code[*pc+1:])
+		typ      = contract.Container.types[idx]
+	)
+	if scope.Stack.len()+int(typ.maxStackIncrease) > int(params.StackLimit) {
+		return nil, &ErrStackOverflow{stackLen: scope.Stack.len(), limit: int(params.StackLimit) - int(typ.maxStackIncrease)}
+	}
+	if len(contract.returnStack) >= returnStackLimit {
+		return nil, ErrReturnStackExceeded
+	}
+	contract.returnStack = append(contract.returnStack, returnContext{
+		section: contract.CodeSection,
+		pc:      *pc + 3,
+	})
+	contract.setCodeSection(uint64(idx))
+	*pc = math.MaxUint64 // wraps to the section start
+	return nil, nil
+}
+
+// opRetf implements the RETF opcode.
+func opRetf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		contract = scope.Contract
+		last     = len(contract.returnStack) - 1
+		retCtx   = contract.returnStack[last]
+	)
+	contract.returnStack = contract.returnStack[:last]
+	contract.setCodeSection(retCtx.section)
+	*pc = retCtx.pc - 1
+	return nil, nil
+}
+
+// opJumpf implements the JUMPF opcode.
+func opJumpf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		contract = scope.Contract
+		idx      = binary.BigEndian.Uint16(contract.Code[*pc+1:])
+		typ      = contract.Container.types[idx]
+	)
+	if scope.Stack.len()+int(typ.maxStackIncrease) > int(params.StackLimit) {
+		return nil, &ErrStackOverflow{stackLen: scope.Stack.len(), limit: int(params.StackLimit) - int(typ.maxStackIncrease)}
+	}
+	contract.setCodeSection(uint64(idx))
+	*pc = math.MaxUint64 // wraps to the section start
+	return nil, nil
+}
+
+// opDupN implements the DUPN opcode.
+func opDupN(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	n := int(scope.Contract.Code[*pc+1]) + 1
+	if scope.Stack.len() < n {
+		return nil, &ErrStackUnderflow{stackLen: scope.Stack.len(), required: n}
+	}
+	scope.Stack.dup(n)
+	*pc += 1
+	return nil, nil
+}
+
+// opSwapN implements the SWAPN opcode.
+func opSwapN(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	n := int(scope.Contract.Code[*pc+1]) + 2
+	if scope.Stack.len() < n {
+		return nil, &ErrStackUnderflow{stackLen: scope.Stack.len(), required: n}
+	}
+	scope.Stack.swap(n)
+	*pc += 1
+	return nil, nil
+}
+
+// opExchange implements the EXCHANGE opcode.
+func opExchange(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		imm = scope.Contract.Code[*pc+1]
+		n   = int(imm>>4) + 1
+		m   = int(imm&0x0f) + 1
+	)
+	if scope.Stack.len() < n+m+1 {
+		return nil, &ErrStackUnderflow{stackLen: scope.Stack.len(), required: n + m + 1}
+	}
+	a, b := scope.Stack.Back(n), scope.Stack.Back(n+m)
+	*a, *b = *b, *a
+	*pc += 1
+	return nil, nil
+}
+
+// opDataLoad implements the DATALOAD opcode.
+func opDataLoad(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	offset := scope.Stack.peek()
+	offset64, overflow := offset.Uint64WithOverflow()
+	if overflow {
+		offset64 = math.MaxUint64
+	}
+	offset.SetBytes(getData(scope.Contract.Container.data, offset64, 32))
+	return nil, nil
+}
+
+// opDataLoadN implements the DATALOADN opcode.
+func opDataLoadN(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	offset := uint64(binary.BigEndian.Uint16(scope.Contract.Code[*pc+1:]))
+	scope.Stack.push(new(uint256.Int).SetBytes(getData(scope.Contract.Container.data, offset, 32)))
+	*pc += 2
+	return nil, nil
+}
+
+// opDataSize implements the DATASIZE opcode.
+func opDataSize(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(uint64(len(scope.Contract.Container.data))))
+	return nil, nil
+}
+
+// opDataCopy implements the DATACOPY opcode.
+func opDataCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		memOffset  = scope.Stack.pop()
+		dataOffset = scope.Stack.pop()
+		length     = scope.Stack.pop()
+	)
+	offset64, overflow := dataOffset.Uint64WithOverflow()
+	if overflow {
+		offset64 = math.MaxUint64
+	}
+	data := getData(scope.Contract.Container.data, offset64, length.Uint64())
+	scope.Memory.Set(memOffset.Uint64(), length.Uint64(), data)
+	return nil, nil
+}
+
+// opReturnDataLoad implements the RETURNDATALOAD opcode.
+func opReturnDataLoad(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	offset := scope.Stack.peek()
+	offset64, overflow := offset.Uint64WithOverflow()
+	if overflow {
+		offset64 = math.MaxUint64
+	}
+	offset.SetBytes(getData(interpreter.returnData, offset64, 32))
+	return nil, nil
+}
+
+// opEOFCreate implements the EOFCREATE opcode.
+func opEOFCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if interpreter.readOnly {
+		return nil, ErrWriteProtection
+	}
+	var (
+		idx          = scope.Contract.Code[*pc+1]
+		initcode     = scope.Contract.Container.subContainerCodes[idx]
+		container    = scope.Contract.Container.subContainers[idx]
+		value        = scope.Stack.pop()
+		salt         = scope.Stack.pop()
+		offset, size = scope.Stack.pop(), scope.Stack.pop()
+		input        = scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+	)
+	// The initcontainer is hashed to derive the address
+	hashingCost := toWordSize(uint64(len(initcode))) * params.Keccak256WordGas
+	if !scope.Contract.UseGas(hashingCost, interpreter.evm.Config.Tracer, tracing.GasChangeIgnored) {
+		return nil, ErrOutOfGas
+	}
+	gas := scope.Contract.Gas
+	gas -= gas / 64
+	scope.Contract.UseGas(gas, interpreter.evm.Config.Tracer, tracing.GasChangeCallContractCreation2)
+
+	// reuse size int for stackvalue
+	stackvalue := size
+	res, addr, returnGas, suberr := interpreter.evm.EOFCreate(scope.Contract, container, initcode, input, gas, &value, &salt)
+	if suberr != nil {
+		stackvalue.Clear()
+	} else {
+		stackvalue.SetBytes(addr.Bytes())
+	}
+	scope.Stack.push(&stackvalue)
+
+	scope.Contract.RefundGas(returnGas, interpreter.evm.Config.Tracer, tracing.GasChangeCallLeftOverRefunded)
+	*pc += 1
+
+	if suberr == ErrExecutionReverted {
+		interpreter.returnData = res // set REVERT data to return data buffer
+		return res, nil
+	}
+	interpreter.returnData = nil // clear dirty return data buffer
+	return nil, nil
+}
+
+// opReturnContract implements the RETURNCONTRACT opcode.
+func opReturnContract(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		idx          = scope.Contract.Code[*pc+1]
+		offset, size = scope.Stack.pop(), scope.Stack.pop()
+		aux          = scope.Memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
+		deploy       = *scope.Contract.Container.subContainers[idx]
+	)
+	dataSize := len(deploy.data) + len(aux)
+	if dataSize < deploy.dataSize || dataSize > math.MaxUint16 {
+		return nil, ErrInvalidAuxData
+	}
+	deploy.data = append(append(make([]byte, 0, dataSize), deploy.data...), aux...)
+	deploy.dataSize = dataSize
+
+	return deploy.MarshalBinary(), errStopToken
+}
+
+// isEOFCallTarget reports whether the address pushed to the stack for an
+// EXT*CALL is valid, i.e. doesn't have any of its upper 12 bytes set.
+func isEOFCallTarget(addr *uint256.Int) bool {
+	return addr.BitLen() <= 160
+}
+
+// opExtCall implements the EXTCALL opcode.
+func opExtCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		stack                  = scope.Stack
+		addr, inOffset, inSize = stack.pop(), stack.pop(), stack.pop()
+		value                  = stack.pop()
+		toAddr                 = common.Address(addr.Bytes20())
+		args                   = scope.Memory.GetPtr(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	)
+	if interpreter.readOnly && !value.IsZero() {
+		return nil, ErrWriteProtection
+	}
+	return extCall(interpreter, scope, EXTCALL, toAddr, args, &value)
+}
+
+// opExtDelegateCall implements the EXTDELEGATECALL opcode.
+func opExtDelegateCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		stack                  = scope.Stack
+		addr, inOffset, inSize = stack.pop(), stack.pop(), stack.pop()
+		toAddr                 = common.Address(addr.Bytes20())
+		args                   = scope.Memory.GetPtr(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	)
+	return extCall(interpreter, scope, EXTDELEGATECALL, toAddr, args, nil)
+}
+
+// opExtStaticCall implements the EXTSTATICCALL opcode.
+func opExtStaticCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		stack                  = scope.Stack
+		addr, inOffset, inSize = stack.pop(), stack.pop(), stack.pop()
+		toAddr                 = common.Address(addr.Bytes20())
+		args                   = scope.Memory.GetPtr(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	)
+	return extCall(interpreter, scope, EXTSTATICCALL, toAddr, args, new(uint256.Int))
+}
+
+// Status codes pushed by the EXT*CALL opcodes.
+const (
+	extCallSuccess = 0
+	extCallRevert  = 1
+	extCallFailure = 2
+)
+
+// extCall executes the message call of an EXT*CALL opcode and pushes its
+// status code. Calls which cannot be started, because too little gas is left,
+// the call depth limit is reached or the balance does not cover the value,
+// are reported as reverted without consuming the gas.
+func extCall(interpreter *EVMInterpreter, scope *ScopeContext, op OpCode, to common.Address, args []byte, value *uint256.Int) ([]byte, error) {
+	var (
+		evm     = interpreter.evm
+		gas     = scope.Contract.Gas
+		retain  = max(gas/64, params.ExtCallMinRetainedGas)
+		callGas uint64
+		status  = new(uint256.Int)
+	)
+	if gas > retain {
+		callGas = gas - retain
+	}
+	interpreter.returnData = nil
+
+	switch {
+	case callGas < params.ExtCallMinCalleeGas,
+		evm.depth > int(params.CallCreateDepth),
+		value != nil && !value.IsZero() && !evm.Context.CanTransfer(evm.StateDB, scope.Contract.Address(), value),
+		op == EXTDELEGATECALL && !isEOFVersion1(evm.StateDB.GetCode(to)):
+		scope.Stack.push(status.SetUint64(extCallRevert))
+		return nil, nil
+	}
+	scope.Contract.UseGas(callGas, evm.Config.Tracer, tracing.GasChangeCallOpCode)
+
+	var (
+		ret       []byte
+		returnGas uint64
+		err       error
+	)
+	switch op {
+	case EXTCALL:
+		ret, returnGas, err = evm.Call(scope.Contract, to, args, callGas, value)
+	case EXTDELEGATECALL:
+		ret, returnGas, err = evm.DelegateCall(scope.Contract, to, args, callGas)
+	case EXTSTATICCALL:
+		ret, returnGas, err = evm.StaticCall(scope.Contract, to, args, callGas)
+	}
+	switch {
+	case err == nil:
+		status.SetUint64(extCallSuccess)
+	case err == ErrExecutionReverted:
+		status.SetUint64(extCallRevert)
+	default:
+		status.SetUint64(extCallFailure)
+	}
+	scope.Stack.push(status)
+
+	scope.Contract.RefundGas(returnGas, evm.Config.Tracer, tracing.GasChangeCallLeftOverRefunded)
+
+	interpreter.returnData = ret
+	return ret, nil
+}
+
+// gasExtCall computes the dynamic gas of the EXT*CALL opcodes: memory
+// expansion, cold account access and, for EXTCALL, the value transfer.
+func gasExtCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	target := stack.Back(0)
+	if !isEOFCallTarget(target) {
+		return 0, ErrInvalidEOFCallTarget
+	}
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	addr := common.Address(target.Bytes20())
+	if !evm.StateDB.AddressInAccessList(addr) {
+		evm.StateDB.AddAddressToAccessList(addr)
+		// The warm storage read cost is already charged as constantGas
+		gas += params.ColdAccountAccessCostEIP2929 - params.WarmStorageReadCostEIP2929
+	}
+	return gas, nil
+}
+
+// gasExtCallValue is gasExtCall extended by the value transfer costs of
+// EXTCALL.
+func gasExtCallValue(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := gasExtCall(evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if value := stack.Back(3); !value.IsZero() {
+		gas += params.CallValueTransferGas
+		if evm.StateDB.Empty(common.Address(stack.Back(0).Bytes20())) {
+			gas += params.CallNewAccountGas
+		}
+	}
+	return gas, nil
+}
+
+func memoryExtCall(stack *Stack) (uint64, bool) {
+	return calcMemSize64(stack.Back(1), stack.Back(2))
+}
+
+func memoryEOFCreate(stack *Stack) (uint64, bool) {
+	return calcMemSize64(stack.Back(2), stack.Back(3))
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEOFMarshaling(t *testing.T) {
+	deploy := &Container{
+		types:        []*functionMetadata{{inputs: 0, outputs: 0x80, maxStackIncrease: 0}},
+		codeSections: [][]byte{{byte(STOP)}},
+		data:         []byte{0x01},
+		dataSize:     4,
+	}
+	for i, test := range []struct {
+		want Container
+	}{
+		{
+			want: Container{
+				types:        []*functionMetadata{{inputs: 0, outputs: 0x80, maxStackIncrease: 1}},
+				codeSections: [][]byte{{byte(PUSH1), 0x01, byte(STOP)}},
+				data:         []byte{},
+			},
+		},
+		{
+			want: Container{
+				types: []*functionMetadata{
+					{inputs: 0, outputs: 0x80, maxStackIncrease: 1},
+					{inputs: 2, outputs: 3, maxStackIncrease: 4},
+					{inputs: 1, outputs: 1, maxStackIncrease: 1},
+				},
+				codeSections: [][]byte{
+					{0x60, 0x01, byte(STOP)},
+					{0x01, 0x02, 0x03},
+					{0xaa},
+				},
+				data:     []byte{0x01, 0x02, 0x03},
+				dataSize: 3,
+			},
+		},
+		{
+			want: Container{
+				types:             []*functionMetadata{{inputs: 0, outputs: 0x80, maxStackIncrease: 2}},
+				codeSections:      [][]byte{{byte(PUSH0), byte(PUSH0), byte(RETURNCONTRACT), 0x00}},
+				subContainers:     []*Container{deploy},
+				subContainerCodes: [][]byte{deploy.MarshalBinary()},
+				data:              []byte{},
+			},
+		},
+	} {
+		var (
+			b   = test.want.MarshalBinary()
+			got Container
+		)
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("test %d: failed to decode container: %v", i, err)
+		}
+		if !bytes.Equal(got.MarshalBinary(), b) {
+			t.Fatalf("test %d: encoding mismatch after roundtrip", i)
+		}
+		if !reflect.DeepEqual(got.types, test.want.types) || !reflect.DeepEqual(got.codeSections, test.want.codeSections) {
+			t.Fatalf("test %d: got %v, want %v", i, got, test.want)
+		}
+		if len(test.want.subContainers) > 0 {
+			if sub := got.subContainers[0]; sub.dataSize != deploy.dataSize || !bytes.Equal(sub.data, deploy.data) {
+				t.Fatalf("test %d: truncated sub-container data mismatch: have %x (%d), want %x (%d)", i, sub.data, sub.dataSize, deploy.data, deploy.dataSize)
+			}
+		}
+	}
+}
+
+func TestEOFUnmarshalErrors(t *testing.T) {
+	valid := (&Container{
+		types:        []*functionMetadata{{inputs: 0, outputs: 0x80, maxStackIncrease: 0}},
+		codeSections: [][]byte{{byte(STOP)}},
+		data:         []byte{0x01, 0x02},
+		dataSize:     2,
+	}).MarshalBinary()
+
+	for i, test := range []struct {
+		code []byte
+		err  error
+	}{
+		{append([]byte{0xef, 0x01}, valid[2:]...), errInvalidMagic},
+		{append([]byte{0xef, 0x00, 0x02}, valid[3:]...), errInvalidVersion},
+		{valid[:len(valid)-1], errTruncatedData},
+		{append(bytes.Clone(valid), 0x00), errTrailingBytes},
+		{valid[:10], io.ErrUnexpectedEOF},
+	} {
+		var c Container
+		if err := c.UnmarshalBinary(test.code); !errors.Is(err, test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestEOFValidation(t *testing.T) {
+	nonReturning := func(maxStack uint16) *functionMetadata {
+		return &functionMetadata{inputs: 0, outputs: nonReturningFunction, maxStackIncrease: maxStack}
+	}
+	deploy := &Container{
+		types:        []*functionMetadata{nonReturning(0)},
+		codeSections: [][]byte{{byte(INVALID)}},
+	}
+	for i, test := range []struct {
+		types      []*functionMetadata
+		code       [][]byte
+		containers []*Container
+		dataSize   int
+		initcode   bool
+		err        error
+	}{
+		{
+			types: []*functionMetadata{nonReturning(1)},
+			code:  [][]byte{{byte(PUSH1), 0x01, byte(POP), byte(STOP)}},
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(GAS), byte(STOP)}},
+			err:   errUndefinedInstruction,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(PUSH2), 0x00}},
+			err:   errTruncatedImmediate,
+		},
+		{
+			types: []*functionMetadata{nonReturning(1)},
+			code:  [][]byte{{byte(RJUMP), 0x00, 0x01, byte(PUSH1), 0x00, byte(STOP)}},
+			err:   errInvalidJumpDest,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(RJUMP), 0xff, 0xfd}},
+		},
+		{
+			types: []*functionMetadata{nonReturning(1)},
+			code:  [][]byte{{byte(PUSH1), 0x00, byte(RJUMP), 0xff, 0xfb}},
+			err:   errInvalidBackwardJump,
+		},
+		{
+			types: []*functionMetadata{nonReturning(1)},
+			code:  [][]byte{{byte(PUSH1), 0x00, byte(RJUMPI), 0x00, 0x01, byte(STOP), byte(STOP)}},
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(STOP), byte(STOP)}},
+			err:   errUnreachableCode,
+		},
+		{
+			types: []*functionMetadata{nonReturning(1)},
+			code:  [][]byte{{byte(PUSH1), 0x00, byte(POP)}},
+			err:   errInvalidCodeTermination,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(POP), byte(STOP)}},
+			err:   errEOFStackUnderflow,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0)},
+			code:  [][]byte{{byte(PUSH1), 0x01, byte(POP), byte(STOP)}},
+			err:   errInvalidMaxStackHeight,
+		},
+		{
+			types: []*functionMetadata{nonReturning(1), {inputs: 0, outputs: 1, maxStackIncrease: 1}},
+			code:  [][]byte{{byte(CALLF), 0x00, 0x01, byte(STOP)}, {byte(PUSH1), 0x2a, byte(RETF)}},
+		},
+		{
+			types: []*functionMetadata{nonReturning(0), nonReturning(0)},
+			code:  [][]byte{{byte(CALLF), 0x00, 0x01, byte(STOP)}, {byte(STOP)}},
+			err:   errInvalidCallArgument,
+		},
+		{
+			types: []*functionMetadata{nonReturning(1), {inputs: 0, outputs: 1, maxStackIncrease: 0}},
+			code:  [][]byte{{byte(CALLF), 0x00, 0x01, byte(STOP)}, {byte(RETF)}},
+			err:   errInvalidOutputs,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0), {inputs: 0, outputs: 0, maxStackIncrease: 0}},
+			code:  [][]byte{{byte(CALLF), 0x00, 0x01, byte(STOP)}, {byte(STOP)}},
+			err:   errInvalidNonReturning,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0), nonReturning(0)},
+			code:  [][]byte{{byte(STOP)}, {byte(STOP)}},
+			err:   errUnreachableCodeSections,
+		},
+		{
+			types: []*functionMetadata{nonReturning(0), nonReturning(0)},
+			code:  [][]byte{{byte(JUMPF), 0x00, 0x01}, {byte(STOP)}},
+		},
+		{
+			types:    []*functionMetadata{nonReturning(1)},
+			code:     [][]byte{{byte(DATALOADN), 0x00, 0x00, byte(POP), byte(STOP)}},
+			dataSize: 32,
+		},
+		{
+			types:    []*functionMetadata{nonReturning(1)},
+			code:     [][]byte{{byte(DATALOADN), 0x00, 0x01, byte(POP), byte(STOP)}},
+			dataSize: 32,
+			err:      errInvalidDataloadNArgument,
+		},
+		{
+			types:      []*functionMetadata{nonReturning(2)},
+			code:       [][]byte{{byte(PUSH0), byte(PUSH0), byte(RETURNCONTRACT), 0x00}},
+			containers: []*Container{deploy},
+			initcode:   true,
+		},
+		{
+			types:      []*functionMetadata{nonReturning(2)},
+			code:       [][]byte{{byte(PUSH0), byte(PUSH0), byte(RETURNCONTRACT), 0x00}},
+			containers: []*Container{deploy},
+			err:        errInvalidContainerKind,
+		},
+		{
+			types:    []*functionMetadata{nonReturning(0)},
+			code:     [][]byte{{byte(STOP)}},
+			initcode: true,
+			err:      errInvalidContainerKind,
+		},
+		{
+			types:      []*functionMetadata{nonReturning(0)},
+			code:       [][]byte{{byte(INVALID)}},
+			containers: []*Container{deploy},
+			err:        errOrphanedSubcontainer,
+		},
+		{
+			types: []*functionMetadata{nonReturning(3)},
+			code:  [][]byte{{byte(PUSH0), byte(PUSH0), byte(DUPN), 0x01, byte(SWAPN), 0x00, byte(EXCHANGE), 0x00, byte(STOP)}},
+		},
+		{
+			types: []*functionMetadata{nonReturning(2)},
+			code:  [][]byte{{byte(PUSH0), byte(PUSH0), byte(EXCHANGE), 0x00, byte(STOP)}},
+			err:   errEOFStackUnderflow,
+		},
+	} {
+		container := &Container{
+			types:        test.types,
+			codeSections: test.code,
+			dataSize:     test.dataSize,
+		}
+		for _, sub := range test.containers {
+			container.subContainers = append(container.subContainers, sub)
+			container.subContainerCodes = append(container.subContainerCodes, sub.MarshalBinary())
+		}
+		err := container.ValidateCode(&eofInstructionSet, test.initcode)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+}
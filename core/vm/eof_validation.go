@@ -0,0 +1,314 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Below are the kinds a sub-container can be referenced by.
+const (
+	notRefByEither      = iota // top level container
+	refByReturnContract        // runtime container deployed by RETURNCONTRACT
+	refByEOFCreate             // initcode container executed by EOFCREATE
+)
+
+// validationResult collects the sections referenced by validated code.
+type validationResult struct {
+	accessedCodeSections map[int]struct{}
+	visitedSubContainers map[int]int
+}
+
+// merge adds the references of another code section to the result. Every
+// sub-container must be referenced in one way only.
+func (v *validationResult) merge(other *validationResult) error {
+	if v.visitedSubContainers == nil {
+		v.visitedSubContainers = make(map[int]int)
+	}
+	for idx, refBy := range other.visitedSubContainers {
+		if prev, ok := v.visitedSubContainers[idx]; ok && prev != refBy {
+			return fmt.Errorf("%w: section referenced by both EOFCREATE and RETURNCONTRACT", errInvalidContainerKind)
+		}
+		v.visitedSubContainers[idx] = refBy
+	}
+	return nil
+}
+
+// immediateSize returns the size of the immediate arguments of the op at the
+// given position, or -1 if the immediates of RJUMPV are truncated.
+func immediateSize(code []byte, pos int) int {
+	op := OpCode(code[pos])
+	switch {
+	case op >= PUSH1 && op <= PUSH32:
+		return int(op-PUSH1) + 1
+	case op == RJUMP, op == RJUMPI, op == CALLF, op == JUMPF, op == DATALOADN:
+		return 2
+	case op == DUPN, op == SWAPN, op == EXCHANGE, op == EOFCREATE, op == RETURNCONTRACT:
+		return 1
+	case op == RJUMPV:
+		if pos+1 >= len(code) {
+			return -1
+		}
+		return 1 + 2*(int(code[pos+1])+1)
+	}
+	return 0
+}
+
+// isTerminal reports whether the op ends the execution of a code section.
+func isTerminal(op OpCode) bool {
+	switch op {
+	case STOP, RETURN, REVERT, INVALID, RETF, JUMPF, RETURNCONTRACT:
+		return true
+	}
+	return false
+}
+
+// relativeJumpTargets returns the destinations of the relative jump at the
+// given position, which has to be validated to have complete immediates.
+func relativeJumpTargets(code []byte, pos int) []int {
+	switch OpCode(code[pos]) {
+	case RJUMP, RJUMPI:
+		offset := int(int16(binary.BigEndian.Uint16(code[pos+1:])))
+		return []int{pos + 3 + offset}
+	case RJUMPV:
+		count := int(code[pos+1]) + 1
+		end := pos + 2 + 2*count
+		targets := make([]int, count)
+		for i := range targets {
+			offset := int(int16(binary.BigEndian.Uint16(code[pos+2+2*i:])))
+			targets[i] = end + offset
+		}
+		return targets
+	}
+	return nil
+}
+
+// validateCode validates the code section with the given index of the
+// container and returns the code sections and sub-containers it references.
+func validateCode(code []byte, section int, container *Container, jt *JumpTable, isInitCode bool) (*validationResult, error) {
+	var (
+		meta      = container.types[section]
+		returning bool
+		result    = &validationResult{
+			accessedCodeSections: make(map[int]struct{}),
+			visitedSubContainers: make(map[int]int),
+		}
+		boundaries = make([]bool, len(code)) // instruction starts
+		jumps      []int
+	)
+	// Check the instructions and their immediate arguments.
+	for pos := 0; pos < len(code); {
+		op := OpCode(code[pos])
+		if jt[op].undefined && op != INVALID {
+			return nil, fmt.Errorf("%w: op %s, pos %d", errUndefinedInstruction, op, pos)
+		}
+		boundaries[pos] = true
+
+		size := immediateSize(code, pos)
+		if size < 0 || pos+size >= len(code) {
+			return nil, fmt.Errorf("%w: op %s, pos %d", errTruncatedImmediate, op, pos)
+		}
+		switch op {
+		case RJUMP, RJUMPI, RJUMPV:
+			jumps = append(jumps, pos)
+
+		case CALLF:
+			idx := int(binary.BigEndian.Uint16(code[pos+1:]))
+			if idx >= len(container.types) {
+				return nil, fmt.Errorf("%w: arg %d, last %d, pos %d", errInvalidSectionArgument, idx, len(container.types)-1, pos)
+			}
+			if !container.types[idx].returning() {
+				return nil, fmt.Errorf("%w: section %d, pos %d", errInvalidCallArgument, idx, pos)
+			}
+			if idx != section {
+				result.accessedCodeSections[idx] = struct{}{}
+			}
+
+		case RETF:
+			if !meta.returning() {
+				return nil, fmt.Errorf("%w: RETF in non-returning section %d, pos %d", errInvalidNonReturning, section, pos)
+			}
+			returning = true
+
+		case JUMPF:
+			idx := int(binary.BigEndian.Uint16(code[pos+1:]))
+			if idx >= len(container.types) {
+				return nil, fmt.Errorf("%w: arg %d, last %d, pos %d", errInvalidSectionArgument, idx, len(container.types)-1, pos)
+			}
+			if target := container.types[idx]; target.returning() {
+				if !meta.returning() || target.outputs > meta.outputs {
+					return nil, fmt.Errorf("%w: section %d, pos %d", errInvalidJumpFTarget, idx, pos)
+				}
+				returning = true
+			}
+			if idx != section {
+				result.accessedCodeSections[idx] = struct{}{}
+			}
+
+		case DATALOADN:
+			offset := int(binary.BigEndian.Uint16(code[pos+1:]))
+			if offset+32 > container.dataSize {
+				return nil, fmt.Errorf("%w: arg %d, data size %d, pos %d", errInvalidDataloadNArgument, offset, container.dataSize, pos)
+			}
+
+		case EOFCREATE, RETURNCONTRACT:
+			idx := int(code[pos+1])
+			if idx >= len(container.subContainers) {
+				return nil, fmt.Errorf("%w: arg %d, containers %d, pos %d", errInvalidContainerArgument, idx, len(container.subContainers), pos)
+			}
+			refBy := refByEOFCreate
+			if op == RETURNCONTRACT {
+				if !isInitCode {
+					return nil, fmt.Errorf("%w: RETURNCONTRACT in runtime code, pos %d", errInvalidContainerKind, pos)
+				}
+				refBy = refByReturnContract
+			}
+			if prev, ok := result.visitedSubContainers[idx]; ok && prev != refBy {
+				return nil, fmt.Errorf("%w: section referenced by both EOFCREATE and RETURNCONTRACT", errInvalidContainerKind)
+			}
+			result.visitedSubContainers[idx] = refBy
+
+		case STOP, RETURN:
+			if isInitCode {
+				return nil, fmt.Errorf("%w: %s in initcode, pos %d", errInvalidContainerKind, op, pos)
+			}
+		}
+		pos += 1 + size
+	}
+	// Returning sections must actually return.
+	if meta.returning() && !returning {
+		return nil, fmt.Errorf("%w: section %d", errInvalidNonReturning, section)
+	}
+	// Relative jumps must land on instructions inside the section.
+	for _, pos := range jumps {
+		for _, target := range relativeJumpTargets(code, pos) {
+			if target < 0 || target >= len(code) || !boundaries[target] {
+				return nil, fmt.Errorf("%w: pos %d, target %d", errInvalidJumpDest, pos, target)
+			}
+		}
+	}
+	if err := validateStack(code, section, container, jt); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateStack checks the stack heights of every instruction of the code
+// section as defined by EIP-5450. Each instruction is assigned a range of
+// possible heights, which forward jumps may widen. Backward jumps need to
+// match the height range of their destination exactly.
+func validateStack(code []byte, section int, container *Container, jt *JumpTable) error {
+	var (
+		meta      = container.types[section]
+		minHeight = make([]int, len(code))
+		maxHeight = make([]int, len(code))
+		maxSeen   = int(meta.inputs)
+		limit     = int(params.StackLimit)
+	)
+	for i := range minHeight {
+		minHeight[i] = -1
+	}
+	minHeight[0], maxHeight[0] = int(meta.inputs), int(meta.inputs)
+
+	for pos := 0; pos < len(code); {
+		op := OpCode(code[pos])
+		if minHeight[pos] < 0 {
+			return fmt.Errorf("%w: pos %d", errUnreachableCode, pos)
+		}
+		var (
+			lo, hi       = minHeight[pos], maxHeight[pos]
+			pops, pushes int
+		)
+		switch op {
+		case CALLF:
+			target := container.types[binary.BigEndian.Uint16(code[pos+1:])]
+			pops, pushes = int(target.inputs), int(target.outputs)
+			if hi+int(target.maxStackIncrease) > limit {
+				return fmt.Errorf("%w: pos %d", errEOFStackOverflow, pos)
+			}
+		case RETF:
+			if lo != hi || lo != int(meta.outputs) {
+				return fmt.Errorf("%w: have %d-%d, want %d, pos %d", errInvalidOutputs, lo, hi, meta.outputs, pos)
+			}
+		case JUMPF:
+			target := container.types[binary.BigEndian.Uint16(code[pos+1:])]
+			if hi+int(target.maxStackIncrease) > limit {
+				return fmt.Errorf("%w: pos %d", errEOFStackOverflow, pos)
+			}
+			if target.returning() {
+				want := int(meta.outputs) + int(target.inputs) - int(target.outputs)
+				if lo != hi || lo != want {
+					return fmt.Errorf("%w: have %d-%d, want %d, pos %d", errInvalidOutputs, lo, hi, want, pos)
+				}
+			} else {
+				pops = int(target.inputs)
+			}
+		case DUPN:
+			pops, pushes = int(code[pos+1])+1, int(code[pos+1])+2
+		case SWAPN:
+			pops, pushes = int(code[pos+1])+2, int(code[pos+1])+2
+		case EXCHANGE:
+			n := int(code[pos+1]>>4) + int(code[pos+1]&0x0f) + 3
+			pops, pushes = n, n
+		default:
+			pops = jt[op].minStack
+			pushes = limit + pops - jt[op].maxStack
+		}
+		if lo < pops {
+			return fmt.Errorf("%w: op %s, have %d, want %d, pos %d", errEOFStackUnderflow, op, lo, pops, pos)
+		}
+		lo, hi = lo-pops+pushes, hi-pops+pushes
+		maxSeen = max(maxSeen, hi)
+
+		// Propagate the heights to the successors of the instruction.
+		next := pos + 1 + immediateSize(code, pos)
+		var successors []int
+		switch {
+		case op == RJUMP:
+			successors = relativeJumpTargets(code, pos)
+		case op == RJUMPI || op == RJUMPV:
+			successors = append([]int{next}, relativeJumpTargets(code, pos)...)
+		case !isTerminal(op):
+			successors = []int{next}
+		}
+		for _, succ := range successors {
+			if succ >= len(code) {
+				return fmt.Errorf("%w: pos %d", errInvalidCodeTermination, pos)
+			}
+			if succ > pos {
+				if minHeight[succ] < 0 {
+					minHeight[succ], maxHeight[succ] = lo, hi
+				} else {
+					minHeight[succ], maxHeight[succ] = min(minHeight[succ], lo), max(maxHeight[succ], hi)
+				}
+			} else if minHeight[succ] != lo || maxHeight[succ] != hi {
+				return fmt.Errorf("%w: pos %d, target %d", errInvalidBackwardJump, pos, succ)
+			}
+		}
+		pos = next
+	}
+	if maxSeen >= limit {
+		return fmt.Errorf("%w: have %d, limit %d", errEOFStackOverflow, maxSeen, limit-1)
+	}
+	if computed := maxSeen - int(meta.inputs); computed != int(meta.maxStackIncrease) {
+		return fmt.Errorf("%w: section %d, declared %d, computed %d", errInvalidMaxStackHeight, section, meta.maxStackIncrease, computed)
+	}
+	return nil
+}
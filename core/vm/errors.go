@@ -38,6 +38,10 @@ var (
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
 	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
 	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+	ErrInvalidEOFInitcode       = errors.New("invalid eof initcode")
+	ErrReturnStackExceeded      = errors.New("return stack limit reached")
+	ErrInvalidAuxData           = errors.New("invalid eof aux data size")
+	ErrInvalidEOFCallTarget     = errors.New("invalid eof call target address")
 
 	// errStopToken is an internal token indicating interpreter loop termination,
 	// never returned to outside callers.
@@ -139,6 +143,10 @@ const (
 	VMErrorCodeStackUnderflow
 	VMErrorCodeStackOverflow
 	VMErrorCodeInvalidOpCode
+	VMErrorCodeInvalidEOFInitcode
+	VMErrorCodeReturnStackExceeded
+	VMErrorCodeInvalidAuxData
+	VMErrorCodeInvalidEOFCallTarget
 
 	// VMErrorCodeUnknown explicitly marks an error as unknown, this is useful when error is converted
 	// from an actual `error` in which case if the mapping is not known, we can use this value to indicate that.
@@ -173,6 +181,14 @@ func vmErrorCodeFromErr(err error) int {
 		return VMErrorCodeInvalidCode
 	case errors.Is(err, ErrNonceUintOverflow):
 		return VMErrorCodeNonceUintOverflow
+	case errors.Is(err, ErrInvalidEOFInitcode):
+		return VMErrorCodeInvalidEOFInitcode
+	case errors.Is(err, ErrReturnStackExceeded):
+		return VMErrorCodeReturnStackExceeded
+	case errors.Is(err, ErrInvalidAuxData):
+		return VMErrorCodeInvalidAuxData
+	case errors.Is(err, ErrInvalidEOFCallTarget):
+		return VMErrorCodeInvalidEOFCallTarget
 
 	default:
 		// Dynamic errors
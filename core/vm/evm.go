@@ -60,13 +60,33 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 }
 
 func (evm *EVM) statePrecompile(addr common.Address) (PrecompiledStateContract, bool) {
-	if evm.Config.StatePrecompiles == nil {
-		return nil, false
+	// Contracts configured on the EVM take precedence over the registry.
+	if p, ok := evm.Config.StatePrecompiles[addr]; ok {
+		return p, true
 	}
-	p, ok := evm.Config.StatePrecompiles[addr]
+	p, ok := evm.statePrecompiles[addr]
 	return p, ok
 }
 
+// isEOF returns whether the code of the given account is an EOF container. The
+// code is only loaded if it's long enough to hold the magic and the result for
+// its hash is not cached yet.
+func (evm *EVM) isEOF(addr common.Address) bool {
+	if evm.StateDB.GetCodeSize(addr) < len(eofMagic) {
+		return false
+	}
+	hash := evm.StateDB.GetCodeHash(addr)
+	if eof, ok := evm.eofCodes[hash]; ok {
+		return eof
+	}
+	eof := hasEOFMagic(evm.StateDB.GetCode(addr))
+	if evm.eofCodes == nil {
+		evm.eofCodes = make(map[common.Hash]bool)
+	}
+	evm.eofCodes[hash] = eof
+	return eof
+}
+
 // BlockContext provides the EVM with auxiliary information. Once provided
 // it shouldn't be modified.
 type BlockContext struct {
@@ -136,6 +156,14 @@ type EVM struct {
 
 	// An optional override to intercept EVM calls.
 	CallInterceptor CallContextInterceptor
+
+	// statePrecompiles contains the registered stateful precompiles enabled
+	// by the chain rules.
+	statePrecompiles map[common.Address]PrecompiledStateContract
+
+	// eofCodes caches whether the code with a given hash is an EOF container,
+	// sparing the code loads of repeated EXTCODESIZE and EXTCODEHASH queries.
+	eofCodes map[common.Hash]bool
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -159,8 +187,15 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		Config:      config,
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil, blockCtx.Time),
+
+		CallInterceptor: config.CallInterceptor,
+	}
+	evm.statePrecompiles = ActiveStatePrecompiles(evm.chainRules)
+	if config.ShadowInterpreterImpl != "" {
+		evm.interpreter = newShadowInterpreter(evm, config)
+	} else {
+		evm.interpreter = NewInterpreter(config.InterpreterImpl, evm, config)
 	}
-	evm.interpreter = NewInterpreter(config.InterpreterImpl, evm, config)
 	return evm
 }
 
@@ -195,6 +230,12 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.Call(evm, caller, addr, input, gas, value)
 	}
+	return evm.nativeCall(caller, addr, input, gas, value)
+}
+
+// nativeCall is the Call implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeCall(caller ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
 	// Capture the tracer start/end events in debug mode
 	if evm.Config.Tracer != nil {
 		evm.captureBegin(evm.depth, CALL, caller.Address(), addr, input, gas, value.ToBig())
@@ -226,11 +267,11 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if isPrecompile {
 		ret, gas, err = RunPrecompiledContract(p, input, gas, evm.Config.Tracer)
 	} else if isStatePrecompile {
-		ret, gas, err = sp.Run(evm.StateDB, evm.Context, evm.TxContext, caller.Address(), input, gas)
+		ret, gas, err = RunPrecompiledStateContract(sp, evm, caller.Address(), input, gas)
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
-		code := evm.StateDB.GetCode(addr)
+		code := evm.resolveCode(addr)
 		if len(code) == 0 {
 			ret, err = nil, nil // gas is unchanged
 		} else {
@@ -238,7 +279,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 			// If the account has no code, we can abort here
 			// The depth-check is already done, and precompiles handled above
 			contract := NewContract(caller, AccountRef(addrCopy), value, gas)
-			contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), code)
+			contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), code)
 			ret, err = evm.interpreter.Run(contract, input, false)
 			gas = contract.Gas
 		}
@@ -273,6 +314,12 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.CallCode(evm, caller, addr, input, gas, value)
 	}
+	return evm.nativeCallCode(caller, addr, input, gas, value)
+}
+
+// nativeCallCode is the CallCode implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeCallCode(caller ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
 	// Invoke tracer hooks that signal entering/exiting a call frame
 	if evm.Config.Tracer != nil {
 		evm.captureBegin(evm.depth, CALLCODE, caller.Address(), addr, input, gas, value.ToBig())
@@ -301,7 +348,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(caller.Address()), value, gas)
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -327,6 +374,12 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.DelegateCall(evm, caller, addr, input, gas)
 	}
+	return evm.nativeDelegateCall(caller, addr, input, gas)
+}
+
+// nativeDelegateCall is the DelegateCall implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeDelegateCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
 	// Invoke tracer hooks that signal entering/exiting a call frame
 	if evm.Config.Tracer != nil {
 		// NOTE: caller must, at all times be a contract. It should never happen
@@ -351,7 +404,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
 		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -375,6 +428,12 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.StaticCall(evm, caller, addr, input, gas)
 	}
+	return evm.nativeStaticCall(caller, addr, input, gas)
+}
+
+// nativeStaticCall is the StaticCall implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeStaticCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
 	// Invoke tracer hooks that signal entering/exiting a call frame
 	if evm.Config.Tracer != nil {
 		evm.captureBegin(evm.depth, STATICCALL, caller.Address(), addr, input, gas, nil)
@@ -409,7 +468,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(addrCopy), new(uint256.Int), gas)
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		// When an error was returned by the EVM or when setting the creation code
 		// above we revert to the snapshot and consume any gas remaining. Additionally
 		// when we're in Homestead this also counts for code storage gas errors.
@@ -432,6 +491,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 type codeAndHash struct {
 	code []byte
 	hash common.Hash
+
+	// EOF initcode is executed as a parsed container with calldata.
+	container *Container
+	input     []byte
 }
 
 func (c *codeAndHash) Hash() common.Hash {
@@ -506,15 +569,22 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	contract := NewContract(caller, AccountRef(address), value, gas)
 	contract.SetCodeOptionalHash(&address, codeAndHash)
 
-	ret, err = evm.interpreter.Run(contract, nil, false)
+	if codeAndHash.container == nil && evm.chainRules.IsOsaka && hasEOFMagic(codeAndHash.code) {
+		// EOF initcode can only be executed by EOFCREATE and by creation
+		// transactions carrying a valid initcontainer.
+		err = ErrInvalidEOFInitcode
+	} else {
+		ret, err = evm.interpreter.Run(contract, codeAndHash.input, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
-	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
+	// Reject code starting with 0xEF if EIP-3541 is enabled. EOF initcode
+	// deploys validated EOF containers, which are exempt.
+	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon && codeAndHash.container == nil {
 		err = ErrInvalidCode
 	}
 
@@ -549,8 +619,24 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *uint2
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.Create(evm, caller, code, gas, value)
 	}
+	return evm.nativeCreate(caller, code, gas, value)
+}
+
+// nativeCreate is the Create implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeCreate(caller ContractRef, code []byte, gas uint64, value *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
-	return evm.create(caller, &codeAndHash{code: code}, gas, value, contractAddr, CREATE)
+
+	codeAndHash := &codeAndHash{code: code}
+	if evm.chainRules.IsOsaka && evm.depth == 0 && hasEOFMagic(code) {
+		// Creation transactions may carry an EOF initcontainer followed by
+		// its calldata. Invalid ones are rejected on execution.
+		container, input, err := unmarshalInitcode(code)
+		if err == nil && container.ValidateCode(&eofInstructionSet, true) == nil {
+			codeAndHash.container, codeAndHash.input = container, input
+		}
+	}
+	return evm.create(caller, codeAndHash, gas, value, contractAddr, CREATE)
 }
 
 // Create2 creates a new contract using code as deployment code.
@@ -561,11 +647,55 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 	if evm.CallInterceptor != nil {
 		return evm.CallInterceptor.Create2(evm, caller, code, gas, endowment, salt)
 	}
+	return evm.nativeCreate2(caller, code, gas, endowment, salt)
+}
+
+// nativeCreate2 is the Create2 implementation of the EVM itself, bypassing
+// the call interceptor.
+func (evm *EVM) nativeCreate2(caller ContractRef, code []byte, gas uint64, endowment *uint256.Int, salt *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	codeAndHash := &codeAndHash{code: code}
 	contractAddr = crypto.CreateAddress2(caller.Address(), salt.Bytes32(), codeAndHash.Hash().Bytes())
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr, CREATE2)
 }
 
+// EOFCreate creates a new contract from an EOF initcontainer, executing it
+// with the given input as calldata. The address is derived like for Create2,
+// from the hash of the initcontainer.
+func (evm *EVM) EOFCreate(caller ContractRef, container *Container, initcode []byte, input []byte, gas uint64, endowment *uint256.Int, salt *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	codeAndHash := &codeAndHash{code: initcode, container: container, input: input}
+	contractAddr = crypto.CreateAddress2(caller.Address(), salt.Bytes32(), codeAndHash.Hash().Bytes())
+	return evm.create(caller, codeAndHash, gas, endowment, contractAddr, EOFCREATE)
+}
+
+// resolveCode returns the code associated with the provided account. After
+// Prague, it can also resolve code pointed to by a delegation designator.
+func (evm *EVM) resolveCode(addr common.Address) []byte {
+	code := evm.StateDB.GetCode(addr)
+	if !evm.chainRules.IsPrague {
+		return code
+	}
+	if target, ok := types.ParseDelegation(code); ok {
+		// Note we only follow one level of delegation.
+		return evm.StateDB.GetCode(target)
+	}
+	return code
+}
+
+// resolveCodeHash returns the code hash associated with the provided address.
+// After Prague, it can also resolve code hash of the account pointed to by a
+// delegation designator. Although this is not accessible in the EVM it is used
+// internally to associate jumpdest analysis to code.
+func (evm *EVM) resolveCodeHash(addr common.Address) common.Hash {
+	if evm.chainRules.IsPrague {
+		code := evm.StateDB.GetCode(addr)
+		if target, ok := types.ParseDelegation(code); ok {
+			// Note we only follow one level of delegation.
+			return evm.StateDB.GetCodeHash(target)
+		}
+	}
+	return evm.StateDB.GetCodeHash(addr)
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
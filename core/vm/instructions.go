@@ -340,7 +340,13 @@ func opReturnDataCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeConte
 
 func opExtCodeSize(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	slot := scope.Stack.peek()
-	slot.SetUint64(uint64(interpreter.evm.StateDB.GetCodeSize(slot.Bytes20())))
+	address := common.Address(slot.Bytes20())
+	if interpreter.evm.chainRules.IsOsaka && interpreter.evm.isEOF(address) {
+		// EOF contracts are opaque to legacy code, only the magic is visible
+		slot.SetUint64(uint64(len(eofMagic)))
+		return nil, nil
+	}
+	slot.SetUint64(uint64(interpreter.evm.StateDB.GetCodeSize(address)))
 	return nil, nil
 }
 
@@ -378,7 +384,11 @@ func opExtCodeCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 		uint64CodeOffset = math.MaxUint64
 	}
 	addr := common.Address(a.Bytes20())
-	codeCopy := getData(interpreter.evm.StateDB.GetCode(addr), uint64CodeOffset, length.Uint64())
+	code := interpreter.evm.StateDB.GetCode(addr)
+	if interpreter.evm.chainRules.IsOsaka && hasEOFMagic(code) {
+		code = eofMagic
+	}
+	codeCopy := getData(code, uint64CodeOffset, length.Uint64())
 	scope.Memory.Set(memOffset.Uint64(), length.Uint64(), codeCopy)
 
 	return nil, nil
@@ -415,6 +425,8 @@ func opExtCodeHash(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 	address := common.Address(slot.Bytes20())
 	if interpreter.evm.StateDB.Empty(address) {
 		slot.Clear()
+	} else if interpreter.evm.chainRules.IsOsaka && interpreter.evm.isEOF(address) {
+		slot.SetBytes(eofMagicHash.Bytes())
 	} else {
 		slot.SetBytes(interpreter.evm.StateDB.GetCodeHash(address).Bytes())
 	}
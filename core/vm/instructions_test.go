@@ -928,3 +928,58 @@ func TestOpMCopy(t *testing.T) {
 		}
 	}
 }
+
+// codeLoadCounter is a state database counting the full code loads.
+type codeLoadCounter struct {
+	*state.StateDB
+	loads int
+}
+
+func (db *codeLoadCounter) GetCode(addr common.Address) []byte {
+	db.loads++
+	return db.StateDB.GetCode(addr)
+}
+
+func TestOpExtCodeSizeEOF(t *testing.T) {
+	var (
+		config = *params.MergedTestChainConfig
+		eof    = append(bytes.Clone(eofMagic), 0x01, 0x01, 0x00, 0x04)
+		eoa    = common.Address{0x01}
+		legacy = common.Address{0x02}
+		eofA   = common.Address{0x03}
+		eofB   = common.Address{0x04}
+	)
+	config.OsakaTime = new(uint64)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(legacy, []byte{byte(PUSH0), byte(PUSH0), byte(RETURN)})
+	statedb.SetCode(eofA, eof)
+	statedb.SetCode(eofB, eof)
+
+	var (
+		db          = &codeLoadCounter{StateDB: statedb}
+		env         = NewEVM(BlockContext{BlockNumber: big.NewInt(0), Random: &common.Hash{}}, TxContext{}, db, &config, Config{})
+		stack       = newstack()
+		pc          = uint64(0)
+		interpreter = env.interpreter.(*EVMInterpreter)
+	)
+	for i, tt := range []struct {
+		addr  common.Address
+		size  uint64
+		loads int
+	}{
+		{eoa, 0, 0},                      // No code, nothing to load
+		{legacy, 3, 1},                   // Code needs to be checked for the magic
+		{eofA, uint64(len(eofMagic)), 2}, // EOF code is checked once per hash
+		{eofB, uint64(len(eofMagic)), 2},
+	} {
+		stack.push(new(uint256.Int).SetBytes(tt.addr.Bytes()))
+		opExtCodeSize(&pc, interpreter, &ScopeContext{nil, stack, nil})
+		if have := stack.pop(); have.Uint64() != tt.size {
+			t.Errorf("test %d: size mismatch: have %d, want %d", i, have.Uint64(), tt.size)
+		}
+		if db.loads != tt.loads {
+			t.Errorf("test %d: code loads mismatch: have %d, want %d", i, db.loads, tt.loads)
+		}
+	}
+}
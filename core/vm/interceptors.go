@@ -0,0 +1,407 @@
+package vm
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// NativeCallContext is the CallContextInterceptor executing calls with the
+// EVM's own implementation, bypassing EVM.CallInterceptor. It terminates every
+// chain of interceptors.
+var NativeCallContext CallContextInterceptor = nativeCallContext{}
+
+type nativeCallContext struct{}
+
+func (nativeCallContext) Call(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	return env.nativeCall(me, addr, data, gas, value)
+}
+
+func (nativeCallContext) CallCode(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	return env.nativeCallCode(me, addr, data, gas, value)
+}
+
+func (nativeCallContext) DelegateCall(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64) ([]byte, uint64, error) {
+	return env.nativeDelegateCall(me, addr, data, gas)
+}
+
+func (nativeCallContext) Create(env *EVM, me ContractRef, data []byte, gas uint64, value *uint256.Int) ([]byte, common.Address, uint64, error) {
+	return env.nativeCreate(me, data, gas, value)
+}
+
+func (nativeCallContext) Create2(env *EVM, me ContractRef, code []byte, gas uint64, value *uint256.Int, salt *uint256.Int) ([]byte, common.Address, uint64, error) {
+	return env.nativeCreate2(me, code, gas, value, salt)
+}
+
+func (nativeCallContext) StaticCall(env *EVM, me ContractRef, addr common.Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	return env.nativeStaticCall(me, addr, input, gas)
+}
+
+// CallInterceptorFunc wraps the next interceptor of a chain. The returned
+// interceptor handles the calls it is interested in and delegates all others
+// to next.
+type CallInterceptorFunc func(next CallContextInterceptor) CallContextInterceptor
+
+// ChainCallInterceptors composes the given interceptors into a single one. The
+// first interceptor sees every call first, the last one delegates to the native
+// EVM implementation.
+func ChainCallInterceptors(interceptors ...CallInterceptorFunc) CallContextInterceptor {
+	next := NativeCallContext
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next = interceptors[i](next)
+	}
+	return next
+}
+
+// -- Call mocking --
+
+// CallMock is the canned result of a mocked call.
+type CallMock struct {
+	Output []byte // Data returned to the caller
+	Gas    uint64 // Gas consumed by the call
+	Revert bool   // Whether the call reverts with Output as revert data
+}
+
+type callMockKey struct {
+	addr     common.Address
+	selector [4]byte
+	any      bool // Matches every input, selector is ignored
+}
+
+// CallMocker is an interceptor returning fixed results for calls into given
+// addresses, optionally restricted to a function selector. The mocked code is
+// never executed and no value is transferred.
+type CallMocker struct {
+	mocks map[callMockKey]CallMock
+	lock  sync.RWMutex
+}
+
+// NewCallMocker creates an interceptor without any mocked calls.
+func NewCallMocker() *CallMocker {
+	return &CallMocker{mocks: make(map[callMockKey]CallMock)}
+}
+
+// Mock makes calls into addr with the given 4 byte selector return output. A
+// nil selector matches all calls into addr which have no selector specific mock.
+func (m *CallMocker) Mock(addr common.Address, selector []byte, output []byte) {
+	m.MockCall(addr, selector, CallMock{Output: common.CopyBytes(output)})
+}
+
+// MockCall makes calls into addr with the given 4 byte selector produce the
+// given result. A nil selector matches all calls into addr which have no
+// selector specific mock.
+func (m *CallMocker) MockCall(addr common.Address, selector []byte, mock CallMock) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.mocks[newCallMockKey(addr, selector)] = mock
+}
+
+// Remove deletes the mock of the given address and selector.
+func (m *CallMocker) Remove(addr common.Address, selector []byte) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.mocks, newCallMockKey(addr, selector))
+}
+
+func newCallMockKey(addr common.Address, selector []byte) callMockKey {
+	key := callMockKey{addr: addr, any: selector == nil}
+	copy(key.selector[:], selector)
+	return key
+}
+
+// lookup returns the mock matching a call into addr with the given input.
+func (m *CallMocker) lookup(addr common.Address, input []byte) (CallMock, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if len(input) >= 4 {
+		if mock, ok := m.mocks[newCallMockKey(addr, input[:4])]; ok {
+			return mock, true
+		}
+	}
+	mock, ok := m.mocks[callMockKey{addr: addr, any: true}]
+	return mock, ok
+}
+
+// Intercept implements CallInterceptorFunc.
+func (m *CallMocker) Intercept(next CallContextInterceptor) CallContextInterceptor {
+	return &callMockInterceptor{CallContextInterceptor: next, mocker: m}
+}
+
+type callMockInterceptor struct {
+	CallContextInterceptor
+	mocker *CallMocker
+}
+
+// run produces the result of a mocked call, emitting the tracer events of a
+// regular call frame.
+func (i *callMockInterceptor) run(env *EVM, typ OpCode, me ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int, mock CallMock) (ret []byte, leftOverGas uint64, err error) {
+	if env.Config.Tracer != nil {
+		env.captureBegin(env.depth, typ, me.Address(), addr, input, gas, value.ToBig())
+		defer func(startGas uint64) {
+			env.captureEnd(env.depth, startGas, leftOverGas, ret, err)
+		}(gas)
+	}
+	if gas < mock.Gas {
+		return nil, 0, ErrOutOfGas
+	}
+	ret = common.CopyBytes(mock.Output)
+	if mock.Revert {
+		return ret, gas - mock.Gas, ErrExecutionReverted
+	}
+	return ret, gas - mock.Gas, nil
+}
+
+func (i *callMockInterceptor) Call(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	if mock, ok := i.mocker.lookup(addr, data); ok {
+		return i.run(env, CALL, me, addr, data, gas, value, mock)
+	}
+	return i.CallContextInterceptor.Call(env, me, addr, data, gas, value)
+}
+
+func (i *callMockInterceptor) CallCode(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	if mock, ok := i.mocker.lookup(addr, data); ok {
+		return i.run(env, CALLCODE, me, addr, data, gas, value, mock)
+	}
+	return i.CallContextInterceptor.CallCode(env, me, addr, data, gas, value)
+}
+
+func (i *callMockInterceptor) DelegateCall(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64) ([]byte, uint64, error) {
+	if mock, ok := i.mocker.lookup(addr, data); ok {
+		return i.run(env, DELEGATECALL, me, addr, data, gas, nil, mock)
+	}
+	return i.CallContextInterceptor.DelegateCall(env, me, addr, data, gas)
+}
+
+func (i *callMockInterceptor) StaticCall(env *EVM, me ContractRef, addr common.Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	if mock, ok := i.mocker.lookup(addr, input); ok {
+		return i.run(env, STATICCALL, me, addr, input, gas, new(uint256.Int), mock)
+	}
+	return i.CallContextInterceptor.StaticCall(env, me, addr, input, gas)
+}
+
+// -- Call recording --
+
+// CallRecord describes a call or contract creation seen by a CallRecorder.
+type CallRecord struct {
+	Type    OpCode         // CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE or CREATE2
+	Depth   int            // Call depth, 0 for the outermost call
+	From    common.Address // Caller
+	To      common.Address // Callee, or the created contract
+	Input   []byte         // Call data or init code
+	Gas     uint64         // Gas supplied to the call
+	Value   *uint256.Int   // Value transferred, nil for DELEGATECALL
+	Output  []byte         // Returned data
+	GasUsed uint64         // Gas consumed by the call
+	Err     error          // Error the call failed with
+}
+
+// CallRecorder is an interceptor recording all calls and contract creations
+// passing through it, in the order they are entered.
+type CallRecorder struct {
+	records []*CallRecord
+	lock    sync.Mutex
+}
+
+// NewCallRecorder creates an empty call recorder.
+func NewCallRecorder() *CallRecorder {
+	return new(CallRecorder)
+}
+
+// Calls returns the calls recorded so far.
+func (r *CallRecorder) Calls() []CallRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	calls := make([]CallRecord, len(r.records))
+	for i, record := range r.records {
+		calls[i] = *record
+	}
+	return calls
+}
+
+// Reset drops all recorded calls.
+func (r *CallRecorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.records = nil
+}
+
+// Intercept implements CallInterceptorFunc.
+func (r *CallRecorder) Intercept(next CallContextInterceptor) CallContextInterceptor {
+	return &callRecordInterceptor{next: next, recorder: r}
+}
+
+// begin adds a record for an entered call, to be completed with end.
+func (r *CallRecorder) begin(env *EVM, typ OpCode, from, to common.Address, input []byte, gas uint64, value *uint256.Int) *CallRecord {
+	record := &CallRecord{
+		Type:  typ,
+		Depth: env.depth,
+		From:  from,
+		To:    to,
+		Input: common.CopyBytes(input),
+		Gas:   gas,
+	}
+	if value != nil {
+		record.Value = new(uint256.Int).Set(value)
+	}
+	r.lock.Lock()
+	r.records = append(r.records, record)
+	r.lock.Unlock()
+	return record
+}
+
+func (r *CallRecorder) end(record *CallRecord, output []byte, leftOverGas uint64, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	record.Output = common.CopyBytes(output)
+	record.GasUsed = record.Gas - leftOverGas
+	record.Err = err
+}
+
+type callRecordInterceptor struct {
+	next     CallContextInterceptor
+	recorder *CallRecorder
+}
+
+func (i *callRecordInterceptor) Call(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	record := i.recorder.begin(env, CALL, me.Address(), addr, data, gas, value)
+	ret, leftOverGas, err := i.next.Call(env, me, addr, data, gas, value)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, leftOverGas, err
+}
+
+func (i *callRecordInterceptor) CallCode(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	record := i.recorder.begin(env, CALLCODE, me.Address(), addr, data, gas, value)
+	ret, leftOverGas, err := i.next.CallCode(env, me, addr, data, gas, value)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, leftOverGas, err
+}
+
+func (i *callRecordInterceptor) DelegateCall(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64) ([]byte, uint64, error) {
+	record := i.recorder.begin(env, DELEGATECALL, me.Address(), addr, data, gas, nil)
+	ret, leftOverGas, err := i.next.DelegateCall(env, me, addr, data, gas)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, leftOverGas, err
+}
+
+func (i *callRecordInterceptor) StaticCall(env *EVM, me ContractRef, addr common.Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	record := i.recorder.begin(env, STATICCALL, me.Address(), addr, input, gas, new(uint256.Int))
+	ret, leftOverGas, err := i.next.StaticCall(env, me, addr, input, gas)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, leftOverGas, err
+}
+
+func (i *callRecordInterceptor) Create(env *EVM, me ContractRef, data []byte, gas uint64, value *uint256.Int) ([]byte, common.Address, uint64, error) {
+	addr := crypto.CreateAddress(me.Address(), env.StateDB.GetNonce(me.Address()))
+	record := i.recorder.begin(env, CREATE, me.Address(), addr, data, gas, value)
+	ret, addr, leftOverGas, err := i.next.Create(env, me, data, gas, value)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, addr, leftOverGas, err
+}
+
+func (i *callRecordInterceptor) Create2(env *EVM, me ContractRef, code []byte, gas uint64, value *uint256.Int, salt *uint256.Int) ([]byte, common.Address, uint64, error) {
+	addr := crypto.CreateAddress2(me.Address(), salt.Bytes32(), crypto.Keccak256(code))
+	record := i.recorder.begin(env, CREATE2, me.Address(), addr, code, gas, value)
+	ret, addr, leftOverGas, err := i.next.Create2(env, me, code, gas, value, salt)
+	i.recorder.end(record, ret, leftOverGas, err)
+	return ret, addr, leftOverGas, err
+}
+
+// -- Gas overrides --
+
+// CallGasOverrides is an interceptor replacing the gas consumed by calls into
+// given addresses with a fixed amount, regardless of the gas used by their
+// execution. It allows to model the cost of contracts which are mocked or
+// whose metering differs in production.
+type CallGasOverrides struct {
+	gas  map[common.Address]uint64
+	lock sync.RWMutex
+}
+
+// NewCallGasOverrides creates an interceptor without any gas overrides.
+func NewCallGasOverrides() *CallGasOverrides {
+	return &CallGasOverrides{gas: make(map[common.Address]uint64)}
+}
+
+// Set makes calls into addr consume exactly the given amount of gas.
+func (o *CallGasOverrides) Set(addr common.Address, gas uint64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.gas[addr] = gas
+}
+
+// Remove restores the regular gas metering of calls into addr.
+func (o *CallGasOverrides) Remove(addr common.Address) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.gas, addr)
+}
+
+func (o *CallGasOverrides) lookup(addr common.Address) (uint64, bool) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	gas, ok := o.gas[addr]
+	return gas, ok
+}
+
+// Intercept implements CallInterceptorFunc.
+func (o *CallGasOverrides) Intercept(next CallContextInterceptor) CallContextInterceptor {
+	return &callGasInterceptor{CallContextInterceptor: next, overrides: o}
+}
+
+type callGasInterceptor struct {
+	CallContextInterceptor
+	overrides *CallGasOverrides
+}
+
+// meter runs the given call with all supplied gas and charges the overridden
+// amount instead of the consumed one. Calls failing with an error other than a
+// revert still consume all gas.
+func (i *callGasInterceptor) meter(addr common.Address, gas uint64, call func(gas uint64) ([]byte, uint64, error)) ([]byte, uint64, error) {
+	cost, ok := i.overrides.lookup(addr)
+	if !ok {
+		return call(gas)
+	}
+	if gas < cost {
+		return nil, 0, ErrOutOfGas
+	}
+	ret, leftOverGas, err := call(gas)
+	if err != nil && err != ErrExecutionReverted {
+		return ret, leftOverGas, err
+	}
+	return ret, gas - cost, err
+}
+
+func (i *callGasInterceptor) Call(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	return i.meter(addr, gas, func(gas uint64) ([]byte, uint64, error) {
+		return i.CallContextInterceptor.Call(env, me, addr, data, gas, value)
+	})
+}
+
+func (i *callGasInterceptor) CallCode(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	return i.meter(addr, gas, func(gas uint64) ([]byte, uint64, error) {
+		return i.CallContextInterceptor.CallCode(env, me, addr, data, gas, value)
+	})
+}
+
+func (i *callGasInterceptor) DelegateCall(env *EVM, me ContractRef, addr common.Address, data []byte, gas uint64) ([]byte, uint64, error) {
+	return i.meter(addr, gas, func(gas uint64) ([]byte, uint64, error) {
+		return i.CallContextInterceptor.DelegateCall(env, me, addr, data, gas)
+	})
+}
+
+func (i *callGasInterceptor) StaticCall(env *EVM, me ContractRef, addr common.Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	return i.meter(addr, gas, func(gas uint64) ([]byte, uint64, error) {
+		return i.CallContextInterceptor.StaticCall(env, me, addr, input, gas)
+	})
+}
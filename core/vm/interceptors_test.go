@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func newInterceptorTestEVM(interceptor CallContextInterceptor) *EVM {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	return NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{CallInterceptor: interceptor})
+}
+
+func TestCallInterceptorChain(t *testing.T) {
+	var (
+		oracle   = common.HexToAddress("0x0a")
+		mocker   = NewCallMocker()
+		recorder = NewCallRecorder()
+		gas      = NewCallGasOverrides()
+	)
+	mocker.Mock(oracle, []byte{1, 2, 3, 4}, []byte("selector"))
+	mocker.Mock(oracle, nil, []byte("fallback"))
+	mocker.MockCall(oracle, []byte{5, 6, 7, 8}, CallMock{Output: []byte("reverted"), Gas: 100, Revert: true})
+
+	evm := newInterceptorTestEVM(ChainCallInterceptors(recorder.Intercept, gas.Intercept, mocker.Intercept))
+
+	for i, tt := range []struct {
+		input   []byte
+		output  string
+		gasLeft uint64
+		err     error
+	}{
+		{[]byte{1, 2, 3, 4, 5}, "selector", 1000, nil},
+		{[]byte{1, 2}, "fallback", 1000, nil},
+		{[]byte{5, 6, 7, 8}, "reverted", 900, ErrExecutionReverted},
+	} {
+		ret, gasLeft, err := evm.Call(AccountRef(common.Address{}), oracle, tt.input, 1000, new(uint256.Int))
+		if string(ret) != tt.output || gasLeft != tt.gasLeft || !errors.Is(err, tt.err) {
+			t.Errorf("test %d: result mismatch: have %q/%d/%v, want %q/%d/%v", i, ret, gasLeft, err, tt.output, tt.gasLeft, tt.err)
+		}
+	}
+	// Overridden gas is charged regardless of the mocked amount
+	gas.Set(oracle, 300)
+	if _, gasLeft, _ := evm.StaticCall(AccountRef(common.Address{}), oracle, nil, 1000); gasLeft != 700 {
+		t.Errorf("overridden gas mismatch: have %d left, want 700", gasLeft)
+	}
+	if _, gasLeft, err := evm.StaticCall(AccountRef(common.Address{}), oracle, nil, 200); gasLeft != 0 || err != ErrOutOfGas {
+		t.Errorf("insufficient gas mismatch: have %d left, error %v", gasLeft, err)
+	}
+	// Unmocked calls end up in the native implementation
+	mocker.Remove(oracle, nil)
+	if ret, gasLeft, err := evm.Call(AccountRef(common.Address{}), oracle, nil, 1000, new(uint256.Int)); ret != nil || gasLeft != 700 || err != nil {
+		t.Errorf("native call mismatch: have %x/%d/%v", ret, gasLeft, err)
+	}
+	calls := recorder.Calls()
+	if len(calls) != 6 {
+		t.Fatalf("recorded call count mismatch: have %d, want 6", len(calls))
+	}
+	if calls[0].Type != CALL || calls[3].Type != STATICCALL || !bytes.Equal(calls[0].Output, []byte("selector")) {
+		t.Errorf("recorded calls mismatch: %v", calls)
+	}
+	if calls[2].GasUsed != 100 || calls[2].Err != ErrExecutionReverted {
+		t.Errorf("recorded revert mismatch: gas used %d, error %v", calls[2].GasUsed, calls[2].Err)
+	}
+	recorder.Reset()
+	if len(recorder.Calls()) != 0 {
+		t.Errorf("recorder not reset")
+	}
+}
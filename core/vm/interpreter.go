@@ -34,8 +34,14 @@ type Config struct {
 	EnablePreimageRecording bool  // Enables recording of SHA3/keccak preimages
 	ExtraEips               []int // Additional EIPS that are to be enabled
 
-	StatePrecompiles map[common.Address]PrecompiledStateContract // Added by Fantom for custom precompiled contract
+	StatePrecompiles map[common.Address]PrecompiledStateContract // Added by Fantom for custom precompiled contract, see RegisterStatePrecompile for chain-wide ones
 	InterpreterImpl  string                                      // The interpreter implementation to use
+	OnStep           StepHook                                    // Called before every operation by the "step" interpreter
+
+	ShadowInterpreterImpl string                  // Interpreter implementation to cross-check the primary one against, disabled if empty
+	OnShadowDivergence    func(*ShadowDivergence) // Called when the shadow interpreter diverges, logged as an error if nil
+
+	CallInterceptor CallContextInterceptor // Installed as EVM.CallInterceptor, see ChainCallInterceptors for stacking several
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -87,8 +93,9 @@ func (ctx *ScopeContext) CallInput() []byte {
 
 // EVMInterpreter represents an EVM interpreter
 type EVMInterpreter struct {
-	evm   *EVM
-	table *JumpTable
+	evm      *EVM
+	table    *JumpTable
+	eofTable *JumpTable // instructions of EOF contracts, set once EOF is enabled
 
 	hasher    crypto.KeccakState // Keccak256 hasher instance shared across opcodes
 	hasherBuf common.Hash        // Keccak256 hasher result array shared across opcodes
@@ -102,6 +109,8 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	var table *JumpTable
 	switch {
+	case evm.chainRules.IsPrague:
+		table = &pragueInstructionSet
 	case evm.chainRules.IsCancun:
 		table = &cancunInstructionSet
 	case evm.chainRules.IsShanghai:
@@ -141,7 +150,12 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 		}
 	}
 	evm.Config.ExtraEips = extraEips
-	return &EVMInterpreter{evm: evm, table: table}
+
+	interpreter := &EVMInterpreter{evm: evm, table: table}
+	if evm.chainRules.IsOsaka {
+		interpreter.eofTable = &eofInstructionSet
+	}
+	return interpreter
 }
 
 // Run loops and evaluates the contract's code with the given input data and returns
@@ -151,6 +165,9 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 // considered a revert-and-consume-all-gas operation except for
 // ErrExecutionReverted which means revert-and-keep-gas-left.
 func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
+	if err := in.initEOF(contract); err != nil {
+		return nil, err
+	}
 	state := InterpreterState{
 		Contract: contract,
 		Stack:    newstack(),
@@ -164,6 +181,29 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	return in.run(&state, math.MaxUint64)
 }
 
+// initEOF prepares the execution of EOF contracts, parsing the container of
+// deployed EOF code and starting execution at the first code section. It is a
+// noop for legacy contracts and before EOF is enabled.
+func (in *EVMInterpreter) initEOF(contract *Contract) error {
+	if in.eofTable == nil {
+		return nil
+	}
+	if contract.Container == nil {
+		if !hasEOFMagic(contract.Code) {
+			return nil
+		}
+		// Deployed EOF code is validated on creation, parse it only
+		var container Container
+		if err := container.UnmarshalBinary(contract.Code); err != nil {
+			return err
+		}
+		contract.Container = &container
+	}
+	contract.returnStack = contract.returnStack[:0]
+	contract.setCodeSection(0)
+	return nil
+}
+
 func (in *EVMInterpreter) run(state *InterpreterState, maxSteps uint64) (ret []byte, err error) {
 	contract := state.Contract
 	input := state.Input
@@ -198,6 +238,7 @@ func (in *EVMInterpreter) run(state *InterpreterState, maxSteps uint64) (ret []b
 			Stack:    stack,
 			Contract: contract,
 		}
+		table = in.table
 		// For optimisation reason we're using uint64 as the program counter.
 		// It's theoretically possible to go above 2^64. The YP defines the PC
 		// to be uint256. Practically much less so feasible.
@@ -212,6 +253,9 @@ func (in *EVMInterpreter) run(state *InterpreterState, maxSteps uint64) (ret []b
 	)
 
 	contract.Input = input
+	if contract.Container != nil {
+		table = in.eofTable
+	}
 
 	if debug {
 		defer func() { // this deferred method handles exit-with-error
@@ -238,7 +282,7 @@ func (in *EVMInterpreter) run(state *InterpreterState, maxSteps uint64) (ret []b
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
-		operation := in.table[op]
+		operation := table[op]
 		cost = operation.constantGas // For tracing
 		// Validate stack
 		if sLen := stack.len(); sLen < operation.minStack {
@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"os"
@@ -913,3 +914,177 @@ func BenchmarkTracerStepVsCallFrame(b *testing.B) {
 	benchmarkNonModifyingCode(10000000, code, "tracer-step-10M", stepTracer, b)
 	benchmarkNonModifyingCode(10000000, code, "tracer-call-frame-10M", callFrameTracer, b)
 }
+
+// TestCallInterceptors tests that calls made by the executed code pass through
+// the configured interceptors.
+func TestCallInterceptors(t *testing.T) {
+	var (
+		oracle   = common.HexToAddress("0x0a")
+		mocker   = vm.NewCallMocker()
+		recorder = vm.NewCallRecorder()
+	)
+	mocker.Mock(oracle, []byte{0x50, 0xd2, 0x5b, 0xcd}, common.LeftPadBytes([]byte{42}, 32))
+
+	// STATICCALL the oracle with the selector stored in memory and return
+	// the first word of its output.
+	code := []byte{
+		byte(vm.PUSH4), 0x50, 0xd2, 0x5b, 0xcd, byte(vm.PUSH1), 0xe0, byte(vm.SHL), byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0x0a, byte(vm.GAS), byte(vm.STATICCALL),
+		byte(vm.POP), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	ret, _, err := Execute(code, nil, &Config{
+		EVMConfig: vm.Config{
+			CallInterceptor: vm.ChainCallInterceptors(recorder.Intercept, mocker.Intercept),
+		},
+	})
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Uint64() != 42 {
+		t.Errorf("return value mismatch: have %d, want 42", have)
+	}
+	calls := recorder.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded call count mismatch: have %d, want 2", len(calls))
+	}
+	if calls[1].Type != vm.STATICCALL || calls[1].To != oracle || calls[1].Depth != 1 {
+		t.Errorf("oracle call mismatch: have %v to %x at depth %d", calls[1].Type, calls[1].To, calls[1].Depth)
+	}
+}
+
+// eofContainer encodes an EOF container from the type entries (inputs,
+// outputs, max stack increase) and the contents of its sections.
+func eofContainer(types [][3]int, code [][]byte, containers [][]byte, data []byte) []byte {
+	b := []byte{0xef, 0x00, 0x01, 0x01, 0x00, byte(len(types) * 4), 0x02, 0x00, byte(len(code))}
+	for _, c := range code {
+		b = append(b, byte(len(c)>>8), byte(len(c)))
+	}
+	if len(containers) > 0 {
+		b = append(b, 0x03, 0x00, byte(len(containers)))
+		for _, c := range containers {
+			b = append(b, 0x00, 0x00, byte(len(c)>>8), byte(len(c)))
+		}
+	}
+	b = append(b, 0xff, byte(len(data)>>8), byte(len(data)), 0x00)
+	for _, t := range types {
+		b = append(b, byte(t[0]), byte(t[1]), byte(t[2]>>8), byte(t[2]))
+	}
+	for _, c := range code {
+		b = append(b, c...)
+	}
+	for _, c := range containers {
+		b = append(b, c...)
+	}
+	return append(b, data...)
+}
+
+func TestEOFExecution(t *testing.T) {
+	chainConfig := *params.MergedTestChainConfig
+	chainConfig.OsakaTime = new(uint64)
+
+	newConfig := func() *Config {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		return &Config{ChainConfig: &chainConfig, Random: &common.Hash{}, State: statedb}
+	}
+	var (
+		legacy = common.HexToAddress("0xca11")
+		// Deployed by the initcode below, consisting of a single INVALID
+		deploy = eofContainer([][3]int{{0, 0x80, 0}}, [][]byte{{byte(vm.INVALID)}}, nil, nil)
+		// Initcode deploying the container above
+		initcode = eofContainer([][3]int{{0, 0x80, 2}},
+			[][]byte{{byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.RETURNCONTRACT), 0x00}},
+			[][]byte{deploy}, nil)
+	)
+	for _, tt := range []struct {
+		name string
+		code []byte
+		want uint64
+	}{
+		{
+			// CALLF a section loading the data, return its output
+			name: "callf",
+			code: eofContainer([][3]int{{0, 0x80, 2}, {0, 1, 1}}, [][]byte{
+				{byte(vm.CALLF), 0x00, 0x01, byte(vm.PUSH0), byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN)},
+				{byte(vm.DATALOADN), 0x00, 0x00, byte(vm.RETF)},
+			}, nil, common.LeftPadBytes([]byte{42}, 32)),
+			want: 42,
+		},
+		{
+			// Loop decrementing a counter with RJUMPI until zero, return the
+			// number of iterations
+			name: "rjump",
+			code: eofContainer([][3]int{{0, 0x80, 3}}, [][]byte{{
+				byte(vm.PUSH1), 5, byte(vm.PUSH0), // counter, iterations
+				byte(vm.PUSH1), 1, byte(vm.ADD), // iterations++
+				byte(vm.SWAP1), byte(vm.PUSH1), 1, byte(vm.SWAP1), byte(vm.SUB), byte(vm.SWAP1), // counter--
+				byte(vm.DUP2), byte(vm.RJUMPI), 0xff, 0xf3, // loop while counter != 0
+				byte(vm.PUSH0), byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN),
+			}}, nil, nil),
+			want: 5,
+		},
+		{
+			// EXTCALL a legacy contract returning 7, return the first word
+			// of its return data
+			name: "extcall",
+			code: eofContainer([][3]int{{0, 0x80, 4}}, [][]byte{{
+				byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH2), 0xca, 0x11, byte(vm.EXTCALL),
+				byte(vm.RJUMPI), 0x00, 0x08, // skip to invalid on failure
+				byte(vm.PUSH0), byte(vm.RETURNDATALOAD), byte(vm.PUSH0), byte(vm.MSTORE),
+				byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN),
+				byte(vm.INVALID),
+			}}, nil, nil),
+			want: 7,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig()
+			cfg.State.SetCode(legacy, []byte{
+				byte(vm.PUSH1), 7, byte(vm.PUSH0), byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN),
+			})
+			ret, _, err := Execute(tt.code, nil, cfg)
+			if err != nil {
+				t.Fatalf("execution failed: %v", err)
+			}
+			if have := new(big.Int).SetBytes(ret); !have.IsUint64() || have.Uint64() != tt.want {
+				t.Errorf("return value mismatch: have %d, want %d", have, tt.want)
+			}
+		})
+	}
+	// EOFCREATE a contract and return its address
+	cfg := newConfig()
+	ret, _, err := Execute(eofContainer([][3]int{{0, 0x80, 4}}, [][]byte{{
+		byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.EOFCREATE), 0x00,
+		byte(vm.PUSH0), byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN),
+	}}, [][]byte{initcode}, nil), nil, cfg)
+	if err != nil {
+		t.Fatalf("EOFCREATE failed: %v", err)
+	}
+	created := common.BytesToAddress(ret)
+	if code := cfg.State.GetCode(created); !bytes.Equal(code, deploy) {
+		t.Errorf("EOFCREATE deployed code mismatch: have %x, want %x", code, deploy)
+	}
+	// Legacy code only sees the magic of EOF contracts
+	ret, _, err = Execute(append(append([]byte{byte(vm.PUSH20)}, created.Bytes()...),
+		byte(vm.EXTCODESIZE), byte(vm.PUSH0), byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH0), byte(vm.RETURN),
+	), nil, cfg)
+	if err != nil {
+		t.Fatalf("EXTCODESIZE failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Uint64() != 2 {
+		t.Errorf("EXTCODESIZE mismatch: have %d, want 2", have)
+	}
+	// Creation transactions deploy valid initcontainers followed by calldata
+	cfg = newConfig()
+	_, addr, _, err := Create(append(bytes.Clone(initcode), 0x01, 0x02), cfg)
+	if err != nil {
+		t.Fatalf("EOF creation failed: %v", err)
+	}
+	if code := cfg.State.GetCode(addr); !bytes.Equal(code, deploy) {
+		t.Errorf("deployed code mismatch: have %x, want %x", code, deploy)
+	}
+	// Invalid initcontainers are rejected, STOP is not allowed in initcode
+	invalid := eofContainer([][3]int{{0, 0x80, 0}}, [][]byte{{byte(vm.STOP)}}, nil, nil)
+	if _, _, _, err := Create(invalid, newConfig()); err != vm.ErrInvalidEOFInitcode {
+		t.Errorf("invalid initcode error mismatch: have %v, want %v", err, vm.ErrInvalidEOFInitcode)
+	}
+}
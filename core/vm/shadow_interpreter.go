@@ -0,0 +1,554 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// -- Differential execution against a shadow interpreter --
+
+// ShadowStep is a single opcode execution observed while locating the point
+// where the primary and the shadow interpreter diverged.
+type ShadowStep struct {
+	Frame   int            // Index of the call frame, in the order frames were entered
+	Depth   int            // Call depth of the frame
+	Address common.Address // Address of the contract executing in the frame
+	Pc      uint64
+	Op      OpCode
+	Gas     uint64 // Gas available before executing the opcode
+	Cost    uint64 // Gas charged for the opcode, so divergent costs show on the opcode itself
+}
+
+func (s *ShadowStep) String() string {
+	if s == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("frame=%d depth=%d address=%x pc=%d op=%v gas=%d cost=%d", s.Frame, s.Depth, s.Address, s.Pc, s.Op, s.Gas, s.Cost)
+}
+
+// ShadowDivergence describes a mismatch between the outcome of the primary
+// and the shadow interpreter for the same call.
+type ShadowDivergence struct {
+	Primary string         // Name of the primary interpreter
+	Shadow  string         // Name of the shadow interpreter
+	Address common.Address // Address of the outermost call frame
+	Reason  string         // First mismatching part of the outcome
+
+	// The first steps which differ between the two executions and the last
+	// step both agreed on. Steps are nil if the interpreters don't report
+	// opcodes to the tracer, or if the step sequences are identical.
+	PrimaryStep *ShadowStep
+	ShadowStep  *ShadowStep
+	Previous    *ShadowStep
+}
+
+func (d *ShadowDivergence) String() string {
+	return fmt.Sprintf("shadow interpreter %q diverged from %q calling %x: %s (primary: %v, shadow: %v, previous: %v)",
+		d.Shadow, d.Primary, d.Address, d.Reason, d.PrimaryStep, d.ShadowStep, d.Previous)
+}
+
+// stateLogger is implemented by state databases which emit tracing hooks for
+// state changes. The logger is detached while executing on the shadow
+// interpreter, so tracers only ever observe the primary execution.
+type stateLogger interface {
+	Logger() *tracing.Hooks
+	SetLogger(*tracing.Hooks)
+}
+
+// shadowInterpreter executes every outermost call frame on both a primary and
+// a shadow interpreter, starting from the same state snapshot. The outcomes
+// (return data, gas left, refunds, logs and state writes) are compared and any
+// mismatch is reported, after which both executions are replayed with opcode
+// recording to locate the first diverging step. The primary execution is the
+// one which is kept.
+//
+// Nested call frames are executed by the interpreter running the outermost
+// frame, so each interpreter processes the complete call tree on its own.
+type shadowInterpreter struct {
+	evm *EVM
+
+	primary     Interpreter
+	shadow      Interpreter
+	primaryName string
+	shadowName  string
+
+	report  func(*ShadowDivergence)
+	current Interpreter // Interpreter running the outermost frame, nil if idle
+}
+
+func newShadowInterpreter(evm *EVM, cfg Config) *shadowInterpreter {
+	in := &shadowInterpreter{
+		evm:         evm,
+		primary:     NewInterpreter(cfg.InterpreterImpl, evm, cfg),
+		shadow:      NewInterpreter(cfg.ShadowInterpreterImpl, evm, cfg),
+		primaryName: cfg.InterpreterImpl,
+		shadowName:  cfg.ShadowInterpreterImpl,
+		report:      cfg.OnShadowDivergence,
+	}
+	if in.primaryName == "" {
+		in.primaryName = "geth"
+	}
+	if in.report == nil {
+		in.report = func(d *ShadowDivergence) {
+			log.Error("Shadow interpreter diverged", "primary", d.Primary, "shadow", d.Shadow,
+				"number", evm.Context.BlockNumber, "origin", evm.Origin, "address", d.Address, "reason", d.Reason,
+				"step", d.PrimaryStep, "shadowstep", d.ShadowStep, "previous", d.Previous)
+		}
+	}
+	return in
+}
+
+// shadowResult is the outcome of executing a call frame on one interpreter.
+type shadowResult struct {
+	ret    []byte
+	err    error
+	gas    uint64
+	refund uint64
+	logs   []*types.Log
+	writes *shadowWrites
+}
+
+func (in *shadowInterpreter) Run(contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	if in.current != nil {
+		return in.current.Run(contract, input, readOnly)
+	}
+	var (
+		gas      = contract.Gas
+		snapshot = in.evm.StateDB.Snapshot()
+	)
+	// reset restores the state and gas the frame started with. Reverting drops
+	// the snapshot, so a new one is taken for subsequent executions.
+	reset := func() {
+		in.evm.StateDB.RevertToSnapshot(snapshot)
+		snapshot = in.evm.StateDB.Snapshot()
+		contract.Gas = gas
+	}
+	shadow := in.execute(in.shadow, contract, input, readOnly, true, nil)
+	reset()
+
+	primary := in.execute(in.primary, contract, input, readOnly, false, nil)
+	reason := compareShadowResults(primary, shadow)
+	if reason == "" {
+		return primary.ret, primary.err
+	}
+	// The outcomes differ, replay both executions recording every step to find
+	// where they went apart. Tracers have already observed the primary run, so
+	// the replay is hidden from them, but it does leave the primary state behind.
+	var shadowSteps, primarySteps shadowStepRecorder
+
+	reset()
+	in.execute(in.shadow, contract, input, readOnly, true, &shadowSteps)
+	reset()
+	primary = in.execute(in.primary, contract, input, readOnly, true, &primarySteps)
+
+	divergence := &ShadowDivergence{
+		Primary: in.primaryName,
+		Shadow:  in.shadowName,
+		Address: contract.Address(),
+		Reason:  reason,
+	}
+	divergence.PrimaryStep, divergence.ShadowStep, divergence.Previous = firstDivergingStep(primarySteps.steps, shadowSteps.steps)
+	in.report(divergence)
+
+	return primary.ret, primary.err
+}
+
+// execute runs the call frame on the given interpreter, collecting its outcome.
+// If muted, neither the EVM tracer nor the state logger observe the execution;
+// the optional step recorder is then installed as the tracer instead.
+func (in *shadowInterpreter) execute(interpreter Interpreter, contract *Contract, input []byte, readOnly bool, muted bool, steps *shadowStepRecorder) (res *shadowResult) {
+	var (
+		state  = newShadowState(in.evm.StateDB)
+		tracer = in.evm.Config.Tracer
+		depth  = in.evm.depth
+	)
+	if muted {
+		in.evm.Config.Tracer = nil
+		if steps != nil {
+			in.evm.Config.Tracer = steps.hooks()
+		}
+		if l, ok := state.inner.(stateLogger); ok {
+			logger := l.Logger()
+			l.SetLogger(nil)
+			defer l.SetLogger(logger)
+		}
+	}
+	in.evm.StateDB = state
+	in.current = interpreter
+
+	defer func() {
+		in.current = nil
+		in.evm.StateDB = state.inner
+		in.evm.Config.Tracer = tracer
+
+		// A misbehaving shadow interpreter must not take the node down.
+		if interpreter == in.shadow && interpreter != in.primary {
+			if r := recover(); r != nil {
+				in.evm.depth = depth
+				res = &shadowResult{err: fmt.Errorf("interpreter panicked: %v", r), writes: state.writes}
+			}
+		}
+	}()
+	ret, err := interpreter.Run(contract, input, readOnly)
+
+	return &shadowResult{
+		ret:    common.CopyBytes(ret),
+		err:    err,
+		gas:    contract.Gas,
+		refund: state.GetRefund(),
+		logs:   state.logs,
+		writes: state.finalise(),
+	}
+}
+
+// compareShadowResults returns a description of the first mismatch between the
+// two outcomes, or an empty string if they are identical.
+func compareShadowResults(primary, shadow *shadowResult) string {
+	if have, want := shadowErrorClass(shadow.err), shadowErrorClass(primary.err); have != want {
+		return fmt.Sprintf("error mismatch: primary %v, shadow %v", primary.err, shadow.err)
+	}
+	if !bytes.Equal(primary.ret, shadow.ret) {
+		return fmt.Sprintf("return data mismatch: primary %x, shadow %x", primary.ret, shadow.ret)
+	}
+	if primary.gas != shadow.gas {
+		return fmt.Sprintf("gas left mismatch: primary %d, shadow %d", primary.gas, shadow.gas)
+	}
+	if primary.refund != shadow.refund {
+		return fmt.Sprintf("refund mismatch: primary %d, shadow %d", primary.refund, shadow.refund)
+	}
+	if len(primary.logs) != len(shadow.logs) {
+		return fmt.Sprintf("log count mismatch: primary %d, shadow %d", len(primary.logs), len(shadow.logs))
+	}
+	for i := range primary.logs {
+		if !equalShadowLogs(primary.logs[i], shadow.logs[i]) {
+			return fmt.Sprintf("log %d mismatch: primary %v, shadow %v", i, primary.logs[i], shadow.logs[i])
+		}
+	}
+	return primary.writes.compare(shadow.writes)
+}
+
+// shadowErrorClass reduces an execution error to what is consensus relevant:
+// whether the frame succeeded, reverted or failed.
+func shadowErrorClass(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrExecutionReverted):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func equalShadowLogs(a, b *types.Log) bool {
+	if a.Address != b.Address || !bytes.Equal(a.Data, b.Data) || len(a.Topics) != len(b.Topics) {
+		return false
+	}
+	for i := range a.Topics {
+		if a.Topics[i] != b.Topics[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// firstDivergingStep returns the first steps at which the two sequences differ,
+// together with the last step they have in common.
+func firstDivergingStep(primary, shadow []ShadowStep) (*ShadowStep, *ShadowStep, *ShadowStep) {
+	var (
+		i    int
+		prev *ShadowStep
+	)
+	for ; i < len(primary) && i < len(shadow); i++ {
+		if primary[i] != shadow[i] {
+			break
+		}
+		prev = &primary[i]
+	}
+	var primaryStep, shadowStep *ShadowStep
+	if i < len(primary) {
+		primaryStep = &primary[i]
+	}
+	if i < len(shadow) {
+		shadowStep = &shadow[i]
+	}
+	return primaryStep, shadowStep, prev
+}
+
+// shadowStepRecorder collects the opcodes executed by an interpreter, along
+// with the call frame they were executed in.
+type shadowStepRecorder struct {
+	steps  []ShadowStep
+	frames []int // Stack of active frame indices
+	count  int   // Number of frames entered so far
+}
+
+func (r *shadowStepRecorder) hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnEnter: func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+			r.count++
+			r.frames = append(r.frames, r.count)
+		},
+		OnExit: func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+			if len(r.frames) > 0 {
+				r.frames = r.frames[:len(r.frames)-1]
+			}
+		},
+		OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			var frame int
+			if len(r.frames) > 0 {
+				frame = r.frames[len(r.frames)-1]
+			}
+			r.steps = append(r.steps, ShadowStep{
+				Frame:   frame,
+				Depth:   depth,
+				Address: scope.Address(),
+				Pc:      pc,
+				Op:      OpCode(op),
+				Gas:     gas,
+				Cost:    cost,
+			})
+		},
+	}
+}
+
+// shadowAccount is the comparable subset of an account's state.
+type shadowAccount struct {
+	exist          bool
+	balance        uint256.Int
+	nonce          uint64
+	codeHash       common.Hash
+	selfDestructed bool
+}
+
+// shadowSlot identifies a persistent or transient storage slot.
+type shadowSlot struct {
+	addr      common.Address
+	key       common.Hash
+	transient bool
+}
+
+// shadowWrites tracks the accounts and slots written during an execution,
+// holding their values before the first write and after the execution.
+type shadowWrites struct {
+	accounts map[common.Address][2]shadowAccount
+	slots    map[shadowSlot][2]common.Hash
+}
+
+// compare returns a description of the first state difference between the
+// two executions. Entries written by only one of them are compared against
+// the pre-state, which is shared by both.
+func (w *shadowWrites) compare(shadow *shadowWrites) string {
+	addrs := make(map[common.Address]struct{})
+	for addr := range w.accounts {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range shadow.accounts {
+		addrs[addr] = struct{}{}
+	}
+	sorted := make([]common.Address, 0, len(addrs))
+	for addr := range addrs {
+		sorted = append(sorted, addr)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+
+	for _, addr := range sorted {
+		have, want := shadowValue(shadow.accounts, w.accounts, addr), shadowValue(w.accounts, shadow.accounts, addr)
+		if have != want {
+			return fmt.Sprintf("account %x mismatch: primary %s, shadow %s", addr, want, have)
+		}
+	}
+	slots := make(map[shadowSlot]struct{})
+	for slot := range w.slots {
+		slots[slot] = struct{}{}
+	}
+	for slot := range shadow.slots {
+		slots[slot] = struct{}{}
+	}
+	sortedSlots := make([]shadowSlot, 0, len(slots))
+	for slot := range slots {
+		sortedSlots = append(sortedSlots, slot)
+	}
+	sort.Slice(sortedSlots, func(i, j int) bool {
+		if c := bytes.Compare(sortedSlots[i].addr[:], sortedSlots[j].addr[:]); c != 0 {
+			return c < 0
+		}
+		if c := bytes.Compare(sortedSlots[i].key[:], sortedSlots[j].key[:]); c != 0 {
+			return c < 0
+		}
+		return !sortedSlots[i].transient && sortedSlots[j].transient
+	})
+	for _, slot := range sortedSlots {
+		have, want := shadowValue(shadow.slots, w.slots, slot), shadowValue(w.slots, shadow.slots, slot)
+		if have != want {
+			kind := "storage"
+			if slot.transient {
+				kind = "transient storage"
+			}
+			return fmt.Sprintf("%s %x/%x mismatch: primary %x, shadow %x", kind, slot.addr, slot.key, want, have)
+		}
+	}
+	return ""
+}
+
+// shadowValue returns the post-execution value of the entry, falling back to
+// the pre-state recorded by the other execution if it was not written.
+func shadowValue[K comparable, V any](writes, other map[K][2]V, key K) V {
+	if v, ok := writes[key]; ok {
+		return v[1]
+	}
+	return other[key][0]
+}
+
+func (a shadowAccount) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "{exist: %t, balance: %v, nonce: %d, codehash: %x", a.exist, &a.balance, a.nonce, a.codeHash)
+	if a.selfDestructed {
+		b.WriteString(", selfdestructed")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// shadowState wraps the state database during an execution, tracking every
+// account and slot written to as well as the emitted logs.
+type shadowState struct {
+	StateDB
+	inner StateDB
+
+	writes    *shadowWrites
+	logs      []*types.Log
+	snapshots map[int]int // Number of logs at each snapshot
+}
+
+func newShadowState(inner StateDB) *shadowState {
+	return &shadowState{
+		StateDB: inner,
+		inner:   inner,
+		writes: &shadowWrites{
+			accounts: make(map[common.Address][2]shadowAccount),
+			slots:    make(map[shadowSlot][2]common.Hash),
+		},
+		snapshots: make(map[int]int),
+	}
+}
+
+func (s *shadowState) account(addr common.Address) shadowAccount {
+	return shadowAccount{
+		exist:          s.inner.Exist(addr),
+		balance:        *s.inner.GetBalance(addr),
+		nonce:          s.inner.GetNonce(addr),
+		codeHash:       s.inner.GetCodeHash(addr),
+		selfDestructed: s.inner.HasSelfDestructed(addr),
+	}
+}
+
+func (s *shadowState) slot(slot shadowSlot) common.Hash {
+	if slot.transient {
+		return s.inner.GetTransientState(slot.addr, slot.key)
+	}
+	return s.inner.GetState(slot.addr, slot.key)
+}
+
+func (s *shadowState) touchAccount(addr common.Address) {
+	if _, ok := s.writes.accounts[addr]; !ok {
+		s.writes.accounts[addr] = [2]shadowAccount{s.account(addr)}
+	}
+}
+
+func (s *shadowState) touchSlot(slot shadowSlot) {
+	if _, ok := s.writes.slots[slot]; !ok {
+		s.writes.slots[slot] = [2]common.Hash{s.slot(slot)}
+	}
+}
+
+// finalise records the current values of all written entries.
+func (s *shadowState) finalise() *shadowWrites {
+	for addr, v := range s.writes.accounts {
+		v[1] = s.account(addr)
+		s.writes.accounts[addr] = v
+	}
+	for slot, v := range s.writes.slots {
+		v[1] = s.slot(slot)
+		s.writes.slots[slot] = v
+	}
+	return s.writes
+}
+
+func (s *shadowState) CreateAccount(addr common.Address) {
+	s.touchAccount(addr)
+	s.inner.CreateAccount(addr)
+}
+
+func (s *shadowState) CreateContract(addr common.Address) {
+	s.touchAccount(addr)
+	s.inner.CreateContract(addr)
+}
+
+func (s *shadowState) SubBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	s.touchAccount(addr)
+	s.inner.SubBalance(addr, amount, reason)
+}
+
+func (s *shadowState) AddBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	s.touchAccount(addr)
+	s.inner.AddBalance(addr, amount, reason)
+}
+
+func (s *shadowState) SetNonce(addr common.Address, nonce uint64) {
+	s.touchAccount(addr)
+	s.inner.SetNonce(addr, nonce)
+}
+
+func (s *shadowState) SetCode(addr common.Address, code []byte) {
+	s.touchAccount(addr)
+	s.inner.SetCode(addr, code)
+}
+
+func (s *shadowState) SetState(addr common.Address, key, value common.Hash) {
+	s.touchSlot(shadowSlot{addr: addr, key: key})
+	s.inner.SetState(addr, key, value)
+}
+
+func (s *shadowState) SetTransientState(addr common.Address, key, value common.Hash) {
+	s.touchSlot(shadowSlot{addr: addr, key: key, transient: true})
+	s.inner.SetTransientState(addr, key, value)
+}
+
+func (s *shadowState) SelfDestruct(addr common.Address) {
+	s.touchAccount(addr)
+	s.inner.SelfDestruct(addr)
+}
+
+func (s *shadowState) Selfdestruct6780(addr common.Address) {
+	s.touchAccount(addr)
+	s.inner.Selfdestruct6780(addr)
+}
+
+func (s *shadowState) AddLog(l *types.Log) {
+	s.logs = append(s.logs, l)
+	s.inner.AddLog(l)
+}
+
+func (s *shadowState) Snapshot() int {
+	id := s.inner.Snapshot()
+	s.snapshots[id] = len(s.logs)
+	return id
+}
+
+func (s *shadowState) RevertToSnapshot(id int) {
+	s.inner.RevertToSnapshot(id)
+	if n, ok := s.snapshots[id]; ok {
+		s.logs = s.logs[:n]
+	}
+}
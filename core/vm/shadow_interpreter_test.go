@@ -0,0 +1,125 @@
+package vm
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	// An interpreter charging one more gas for ADD than it should.
+	RegisterInterpreterFactory("shadow-test-add", func(evm *EVM, cfg Config) Interpreter {
+		in := NewEVMInterpreter(evm)
+		in.table = copyJumpTable(in.table)
+		in.table[ADD].constantGas++
+		return in
+	})
+}
+
+// shadowTestCode stores 1+2 into slot 0, emits a log and returns.
+var shadowTestCode = []byte{
+	byte(PUSH1), 1, byte(PUSH1), 2, byte(ADD),
+	byte(PUSH1), 0, byte(SSTORE),
+	byte(PUSH1), 0, byte(PUSH1), 0, byte(LOG0),
+	byte(STOP),
+}
+
+func runShadowTest(t *testing.T, shadow string) ([]*ShadowDivergence, uint64, int) {
+	t.Helper()
+
+	address := common.BytesToAddress([]byte("contract"))
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, shadowTestCode)
+	statedb.Finalise(true)
+	statedb.AddAddressToAccessList(address)
+
+	var (
+		divergences []*ShadowDivergence
+		logs        int
+	)
+	statedb.SetLogger(&tracing.Hooks{OnLog: func(*types.Log) { logs++ }})
+
+	var opcodes int
+	cfg := Config{
+		Tracer: &tracing.Hooks{
+			OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+				opcodes++
+			},
+		},
+		ShadowInterpreterImpl: shadow,
+		OnShadowDivergence: func(d *ShadowDivergence) {
+			divergences = append(divergences, d)
+		},
+	}
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, cfg)
+	_, gas, err := evm.Call(AccountRef(common.Address{}), address, nil, 100000, new(uint256.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if have := statedb.GetState(address, common.Hash{}); have != common.BigToHash(big.NewInt(3)) {
+		t.Errorf("storage mismatch: have %x, want 3", have)
+	}
+	if opcodes != 9 || logs != 1 {
+		t.Errorf("tracer observed %d opcodes and %d logs, want 9 and 1", opcodes, logs)
+	}
+	return divergences, gas, len(statedb.Logs())
+}
+
+func TestShadowInterpreterAgreement(t *testing.T) {
+	divergences, gas, logs := runShadowTest(t, "geth")
+	if len(divergences) != 0 {
+		t.Fatalf("unexpected divergence: %v", divergences[0])
+	}
+	_, want, _ := runShadowTest(t, "")
+	if gas != want {
+		t.Errorf("gas left mismatch: have %d, want %d", gas, want)
+	}
+	if logs != 1 {
+		t.Errorf("log count mismatch: have %d, want 1", logs)
+	}
+}
+
+func TestShadowInterpreterDivergence(t *testing.T) {
+	divergences, gas, logs := runShadowTest(t, "shadow-test-add")
+	if len(divergences) != 1 {
+		t.Fatalf("divergence count mismatch: have %d, want 1", len(divergences))
+	}
+	d := divergences[0]
+	if d.Primary != "geth" || d.Shadow != "shadow-test-add" {
+		t.Errorf("interpreter names mismatch: have %q/%q", d.Primary, d.Shadow)
+	}
+	if !strings.HasPrefix(d.Reason, "gas left mismatch") {
+		t.Errorf("unexpected reason: %s", d.Reason)
+	}
+	if d.PrimaryStep == nil || d.PrimaryStep.Op != ADD || d.PrimaryStep.Pc != 4 {
+		t.Errorf("diverging opcode mismatch: have %v, want ADD at pc 4", d.PrimaryStep)
+	}
+	if d.ShadowStep == nil || d.ShadowStep.Pc != 4 || d.ShadowStep.Gas != d.PrimaryStep.Gas || d.ShadowStep.Cost != d.PrimaryStep.Cost+1 {
+		t.Errorf("diverging steps mismatch: primary %v, shadow %v", d.PrimaryStep, d.ShadowStep)
+	}
+	if d.Previous == nil || d.Previous.Op != PUSH1 || d.Previous.Pc != 2 {
+		t.Errorf("previous step mismatch: have %v, want PUSH1 at pc 2", d.Previous)
+	}
+	// The primary outcome must be kept
+	_, want, _ := runShadowTest(t, "")
+	if gas != want {
+		t.Errorf("gas left mismatch: have %d, want %d", gas, want)
+	}
+	if logs != 1 {
+		t.Errorf("log count mismatch: have %d, want 1", logs)
+	}
+}
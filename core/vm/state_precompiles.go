@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StatePrecompile describes a stateful precompiled contract installed at a
+// fixed address once activated.
+type StatePrecompile struct {
+	Name     string                   // Unique name, used to schedule the activation in the chain config
+	Address  common.Address           // Address the contract is installed at
+	Contract PrecompiledStateContract // Implementation of the contract
+
+	// Active optionally reports whether the contract is enabled by the given
+	// fork rules. Regardless of it, the contract is enabled once its name is
+	// scheduled and passed in the chain config's StatePrecompiles.
+	Active func(rules params.Rules) bool
+}
+
+// isActive returns whether the precompile is enabled with the given rules.
+func (p *StatePrecompile) isActive(rules params.Rules) bool {
+	if rules.IsStatePrecompile(p.Name) {
+		return true
+	}
+	return p.Active != nil && p.Active(rules)
+}
+
+// state_precompile_registry contains the registered stateful precompiles,
+// ordered by address. Registration is expected to happen during package
+// initialisation, the registry is not safe for concurrent modification.
+var state_precompile_registry []*StatePrecompile
+
+// RegisterStatePrecompile adds a stateful precompile to the registry. It panics
+// if the name or the address is already taken, or if the address belongs to a
+// standard precompile.
+func RegisterStatePrecompile(p StatePrecompile) {
+	if p.Name == "" || p.Contract == nil {
+		panic("state precompile requires a name and a contract")
+	}
+	if _, ok := PrecompiledContractsPrague[p.Address]; ok {
+		panic(fmt.Sprintf("state precompile %s conflicts with standard precompile %x", p.Name, p.Address))
+	}
+	for _, other := range state_precompile_registry {
+		if other.Name == p.Name {
+			panic(fmt.Sprintf("state precompile %s registered twice", p.Name))
+		}
+		if other.Address == p.Address {
+			panic(fmt.Sprintf("state precompiles %s and %s share address %x", other.Name, p.Name, p.Address))
+		}
+	}
+	state_precompile_registry = append(state_precompile_registry, &p)
+	slices.SortFunc(state_precompile_registry, func(a, b *StatePrecompile) int {
+		return bytes.Compare(a.Address[:], b.Address[:])
+	})
+}
+
+// LookupStatePrecompile returns the registered stateful precompile with the
+// given name.
+func LookupStatePrecompile(name string) (StatePrecompile, bool) {
+	for _, p := range state_precompile_registry {
+		if p.Name == name {
+			return *p, true
+		}
+	}
+	return StatePrecompile{}, false
+}
+
+// ActiveStatePrecompiles returns the registered stateful precompiles enabled
+// with the given rules, keyed by address.
+func ActiveStatePrecompiles(rules params.Rules) map[common.Address]PrecompiledStateContract {
+	var active map[common.Address]PrecompiledStateContract
+	for _, p := range state_precompile_registry {
+		if p.isActive(rules) {
+			if active == nil {
+				active = make(map[common.Address]PrecompiledStateContract)
+			}
+			active[p.Address] = p.Contract
+		}
+	}
+	return active
+}
+
+// activeStatePrecompileAddresses returns the addresses of the registered
+// stateful precompiles enabled with the given rules, in ascending order.
+func activeStatePrecompileAddresses(rules params.Rules) []common.Address {
+	var addrs []common.Address
+	for _, p := range state_precompile_registry {
+		if p.isActive(rules) {
+			addrs = append(addrs, p.Address)
+		}
+	}
+	return addrs
+}
+
+// RunPrecompiledStateContract runs a stateful precompiled contract in the
+// context of the given EVM and reports the consumed gas to the tracer.
+// It returns
+// - the returned bytes,
+// - the _remaining_ gas,
+// - any error that occurred
+func RunPrecompiledStateContract(p PrecompiledStateContract, evm *EVM, caller common.Address, input []byte, suppliedGas uint64) (ret []byte, remainingGas uint64, err error) {
+	ret, remainingGas, err = p.Run(evm.StateDB, evm.Context, evm.TxContext, caller, input, suppliedGas)
+	if logger := evm.Config.Tracer; logger != nil && logger.OnGasChange != nil && remainingGas < suppliedGas {
+		logger.OnGasChange(suppliedGas, remainingGas, tracing.GasChangeCallPrecompiledContract)
+	}
+	return ret, remainingGas, err
+}
@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var testCounterAddress = common.HexToAddress("0xfe00000000000000000000000000000000000001")
+
+// testCounter is a stateful precompile incrementing the counter in its first
+// storage slot and returning the new value.
+type testCounter struct{}
+
+func (testCounter) Run(stateDB StateDB, blockCtx BlockContext, txCtx TxContext, caller common.Address, input []byte, suppliedGas uint64) ([]byte, uint64, error) {
+	if suppliedGas < 100 {
+		return nil, 0, ErrOutOfGas
+	}
+	value := stateDB.GetState(testCounterAddress, common.Hash{}).Big()
+	value.Add(value, big.NewInt(1))
+	stateDB.SetState(testCounterAddress, common.Hash{}, common.BigToHash(value))
+	return common.BigToHash(value).Bytes(), suppliedGas - 100, nil
+}
+
+func init() {
+	RegisterStatePrecompile(StatePrecompile{
+		Name:     "test-counter",
+		Address:  testCounterAddress,
+		Contract: testCounter{},
+	})
+}
+
+func TestStatePrecompileActivation(t *testing.T) {
+	var (
+		time   = uint64(100)
+		config = *params.AllEthashProtocolChanges
+	)
+	config.StatePrecompiles = map[string]*uint64{"test-counter": &time}
+
+	for _, tt := range []struct {
+		time   uint64
+		active bool
+	}{
+		{99, false},
+		{100, true},
+		{200, true},
+	} {
+		rules := config.Rules(big.NewInt(0), false, tt.time)
+		if have := slices.Contains(ActivePrecompiles(rules), testCounterAddress); have != tt.active {
+			t.Errorf("time %d: active precompiles contain counter: have %v, want %v", tt.time, have, tt.active)
+		}
+		if _, have := ActiveStatePrecompiles(rules)[testCounterAddress]; have != tt.active {
+			t.Errorf("time %d: active state precompiles contain counter: have %v, want %v", tt.time, have, tt.active)
+		}
+	}
+	// The standard precompile lists must not be modified
+	if slices.Contains(PrecompiledAddressesCancun, testCounterAddress) {
+		t.Errorf("standard precompile list modified")
+	}
+	if _, ok := LookupStatePrecompile("test-counter"); !ok {
+		t.Errorf("registered precompile not found")
+	}
+}
+
+func TestStatePrecompileCall(t *testing.T) {
+	var (
+		time   = uint64(0)
+		config = *params.AllEthashProtocolChanges
+	)
+	config.StatePrecompiles = map[string]*uint64{"test-counter": &time}
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		entered, exited int
+		gasChanges      []tracing.GasChangeReason
+	)
+	cfg := Config{
+		Tracer: &tracing.Hooks{
+			OnEnter: func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+				if to == testCounterAddress {
+					entered++
+				}
+			},
+			OnExit: func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+				if gasUsed != 100 || reverted {
+					t.Errorf("exit mismatch: gas used %d, reverted %v", gasUsed, reverted)
+				}
+				exited++
+			},
+			OnGasChange: func(old, new uint64, reason tracing.GasChangeReason) {
+				gasChanges = append(gasChanges, reason)
+			},
+		},
+	}
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	evm := NewEVM(vmctx, TxContext{}, statedb, &config, cfg)
+	ret, gas, err := evm.Call(AccountRef(common.Address{}), testCounterAddress, nil, 1000, new(uint256.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if gas != 900 {
+		t.Errorf("gas left mismatch: have %d, want 900", gas)
+	}
+	if want := common.BigToHash(big.NewInt(1)); common.BytesToHash(ret) != want {
+		t.Errorf("return value mismatch: have %x, want %x", ret, want)
+	}
+	if entered != 1 || exited != 1 {
+		t.Errorf("call frame mismatch: entered %d, exited %d", entered, exited)
+	}
+	if !slices.Contains(gasChanges, tracing.GasChangeCallPrecompiledContract) {
+		t.Errorf("precompile gas change not reported: %v", gasChanges)
+	}
+	// The precompile is not available before activation
+	config.StatePrecompiles = nil
+	evm = NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+	if ret, _, _ := evm.Call(AccountRef(common.Address{}), testCounterAddress, nil, 1000, new(uint256.Int)); len(ret) != 0 {
+		t.Errorf("inactive precompile executed")
+	}
+}
@@ -80,6 +80,13 @@ func NewInterpreter(name string, evm *EVM, cfg Config) Interpreter {
 	return factory(evm, cfg)
 }
 
+// IsInterpreterRegistered reports whether a factory for the interpreter with
+// the given name has been registered.
+func IsInterpreterRegistered(name string) bool {
+	_, found := interpreter_registry[strings.ToLower(name)]
+	return found
+}
+
 func init() {
 	factory := func(evm *EVM, cfg Config) Interpreter {
 		return NewEVMInterpreter(evm)
@@ -132,3 +139,59 @@ func (in *EVMInterpreter) Step(state *InterpreterState) {
 	// extract internal interpreter state
 	state.LastCallReturnData = in.returnData
 }
+
+// StepHook is called by the stepping interpreter before every operation, with
+// the call depth and the state of the executing frame. The state must not be
+// modified, but it is safe to block in the hook to pause the execution.
+type StepHook func(depth int, state *InterpreterState)
+
+// stepInterpreter executes contracts one operation at a time using Step,
+// invoking the configured hook in between. It is registered as "step" and
+// produces the same results as the regular interpreter.
+type stepInterpreter struct {
+	*EVMInterpreter
+	hook StepHook
+}
+
+func init() {
+	RegisterInterpreterFactory("step", func(evm *EVM, cfg Config) Interpreter {
+		return &stepInterpreter{EVMInterpreter: NewEVMInterpreter(evm), hook: cfg.OnStep}
+	})
+}
+
+func (in *stepInterpreter) Run(contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	// Don't bother with the execution if there's no code.
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
+	if err := in.initEOF(contract); err != nil {
+		return nil, err
+	}
+	state := &InterpreterState{
+		Contract: contract,
+		Stack:    newstack(),
+		Memory:   NewMemory(),
+		Input:    input,
+		ReadOnly: readOnly,
+	}
+	defer returnStack(state.Stack)
+
+	for {
+		if in.evm.Cancelled() {
+			return nil, nil
+		}
+		if in.hook != nil {
+			in.hook(in.evm.depth+1, state)
+		}
+		in.Step(state)
+
+		switch state.Status {
+		case Stopped:
+			return state.ReturnData, nil
+		case Reverted:
+			return state.ReturnData, ErrExecutionReverted
+		case Failed:
+			return nil, state.Error
+		}
+	}
+}
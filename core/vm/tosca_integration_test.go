@@ -1,8 +1,16 @@
 package vm
 
 import (
+	"bytes"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +50,67 @@ func TestInterpreterFactory_RegisterInterpreter(t *testing.T) {
 		t.Error("Wrong interpreter factory has been called")
 	}
 }
+
+func TestStepInterpreter(t *testing.T) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		callee  = common.BytesToAddress([]byte("callee"))
+	)
+	// The contract loops three times, storing the counter and calling the
+	// callee which reverts with the data it was called with.
+	code := []byte{
+		byte(PUSH1), 3, // counter
+		byte(JUMPDEST), // pc 2
+		byte(DUP1), byte(PUSH1), 0, byte(SSTORE),
+		byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0,
+		byte(PUSH20)}
+	code = append(code, callee.Bytes()...)
+	code = append(code, byte(GAS), byte(CALL), byte(POP),
+		byte(PUSH1), 1, byte(SWAP1), byte(SUB),
+		byte(DUP1), byte(PUSH1), 2, byte(JUMPI),
+		byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN),
+	)
+	calleeCode := []byte{byte(PUSH1), 0, byte(PUSH1), 0, byte(REVERT)}
+
+	run := func(impl string, hook StepHook) ([]byte, uint64, common.Hash) {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(address, code)
+		statedb.SetCode(callee, calleeCode)
+		statedb.Finalise(true)
+		statedb.AddAddressToAccessList(address)
+
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+			BlockNumber: big.NewInt(0),
+		}
+		evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{InterpreterImpl: impl, OnStep: hook})
+		ret, gas, err := evm.Call(AccountRef(common.Address{}), address, nil, 1000000, new(uint256.Int))
+		if err != nil {
+			t.Fatalf("%q: call failed: %v", impl, err)
+		}
+		return ret, gas, statedb.GetState(address, common.Hash{})
+	}
+	wantRet, wantGas, wantSlot := run("geth", nil)
+
+	var (
+		steps    int
+		maxDepth int
+	)
+	haveRet, haveGas, haveSlot := run("step", func(depth int, state *InterpreterState) {
+		steps++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	})
+	if !bytes.Equal(haveRet, wantRet) || haveGas != wantGas || haveSlot != wantSlot {
+		t.Errorf("outcome mismatch: have %x/%d/%x, want %x/%d/%x", haveRet, haveGas, haveSlot, wantRet, wantGas, wantSlot)
+	}
+	// 1 + 3 loops of 19 + 3 (return) in the caller, 3 calls of 3 in the callee
+	if want := 1 + 3*19 + 3 + 3*3; steps != want {
+		t.Errorf("step count mismatch: have %d, want %d", steps, want)
+	}
+	if maxDepth != 2 {
+		t.Errorf("max depth mismatch: have %d, want 2", maxDepth)
+	}
+}
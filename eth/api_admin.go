@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -141,3 +142,19 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Checkpoint creates a consistent copy of the chain database in the given
+// directory while the node is running. The directory can be used as the datadir
+// of a new node to restore the copy, the head block of which is recorded in the
+// returned metadata.
+func (api *AdminAPI) Checkpoint(dir string) (*rawdb.CheckpointInfo, error) {
+	if api.eth.chainDbPath == "" {
+		return nil, errors.New("chain database is not persistent")
+	}
+	// The state history freezer is held open by the trie database in path scheme
+	var checkpointHistory func(string) error
+	if tdb := api.eth.BlockChain().TrieDB(); tdb.Scheme() == rawdb.PathScheme {
+		checkpointHistory = tdb.CheckpointHistory
+	}
+	return rawdb.CheckpointDatabase(api.eth.ChainDb(), dir, api.eth.chainDbPath, checkpointHistory)
+}
@@ -252,6 +252,11 @@ func (b *EthAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
 func (b *EthAPIBackend) GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext) *vm.EVM {
 	if vmConfig == nil {
 		vmConfig = b.eth.blockchain.GetVMConfig()
+	} else if vmConfig.CallInterceptor == nil {
+		// Calls are intercepted the same way as during block processing
+		config := *vmConfig
+		config.CallInterceptor = b.eth.blockchain.GetVMConfig().CallInterceptor
+		vmConfig = &config
 	}
 	txContext := core.NewEVMTxContext(msg)
 	var context vm.BlockContext
@@ -349,6 +354,10 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.txPool.SubscribeTransactions(ch, true)
 }
 
+func (b *EthAPIBackend) SubscribeTxPoolEvents(ch chan<- []txpool.TxEvent) event.Subscription {
+	return b.eth.txPool.SubscribeTxEvents(ch)
+}
+
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
 	prog := b.eth.Downloader().Progress()
 	if txProg, err := b.eth.blockchain.TxIndexProgress(); err == nil {
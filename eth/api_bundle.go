@@ -0,0 +1,201 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BundleAPI provides an API to submit and simulate transaction bundles.
+type BundleAPI struct {
+	e *Ethereum
+}
+
+// NewBundleAPI creates a new BundleAPI instance.
+func NewBundleAPI(e *Ethereum) *BundleAPI {
+	return &BundleAPI{e}
+}
+
+// SendBundleArgs are the arguments of eth_sendBundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundleResult is the result of eth_sendBundle.
+type SendBundleResult struct {
+	BundleHash common.Hash `json:"bundleHash"`
+}
+
+// CallBundleArgs are the arguments of eth_callBundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes       `json:"txs"`
+	BlockNumber      *hexutil.Uint64       `json:"blockNumber"`
+	StateBlockNumber rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	Timestamp        *hexutil.Uint64       `json:"timestamp"`
+	Coinbase         *common.Address       `json:"coinbase"`
+}
+
+// CallBundleTxResult is the outcome of a transaction in the result of
+// eth_callBundle.
+type CallBundleTxResult struct {
+	TxHash       common.Hash    `json:"txHash"`
+	FromAddress  common.Address `json:"fromAddress"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	CoinbaseDiff *hexutil.Big   `json:"coinbaseDiff"`
+	Value        hexutil.Bytes  `json:"value,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	Revert       hexutil.Bytes  `json:"revert,omitempty"`
+}
+
+// CallBundleResult is the result of eth_callBundle.
+type CallBundleResult struct {
+	BundleHash       common.Hash           `json:"bundleHash"`
+	BundleGasPrice   *hexutil.Big          `json:"bundleGasPrice"`
+	CoinbaseDiff     *hexutil.Big          `json:"coinbaseDiff"`
+	TotalGasUsed     hexutil.Uint64        `json:"totalGasUsed"`
+	StateBlockNumber hexutil.Uint64        `json:"stateBlockNumber"`
+	Results          []*CallBundleTxResult `json:"results"`
+}
+
+// decodeBundleTxs decodes the raw transactions of a bundle.
+func decodeBundleTxs(raw []hexutil.Bytes) (types.Transactions, error) {
+	if len(raw) == 0 {
+		return nil, bundlepool.ErrEmptyBundle
+	}
+	txs := make(types.Transactions, len(raw))
+	for i, input := range raw {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// SendBundle submits a bundle of transactions to be included atomically, in the
+// given order, into the target block.
+func (api *BundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (*SendBundleResult, error) {
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &bundlepool.Bundle{
+		Txs:               txs,
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := api.e.BundlePool().Add(bundle); err != nil {
+		return nil, err
+	}
+	return &SendBundleResult{BundleHash: bundle.Hash()}, nil
+}
+
+// CallBundle simulates a bundle of transactions on top of the state block, in
+// the block following it. The state block defaults to the latest block, the
+// timestamp and fee recipient of the simulated block default to the current time
+// and the fee recipient of the state block.
+func (api *BundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	if limit := api.e.BundlePool().Config().MaxBundleTxs; len(txs) > limit {
+		return nil, fmt.Errorf("%w: %d transactions, limit %d", bundlepool.ErrBundleTooLarge, len(txs), limit)
+	}
+	if gasCap := api.e.APIBackend.RPCGasCap(); gasCap != 0 {
+		var gas uint64
+		for _, tx := range txs {
+			if tx.Gas() > gasCap-gas {
+				return nil, fmt.Errorf("bundle gas exceeds the RPC gas cap %d", gasCap)
+			}
+			gas += tx.Gas()
+		}
+	}
+	stateBlock := args.StateBlockNumber
+	if _, ok := stateBlock.Number(); !ok {
+		if _, ok := stateBlock.Hash(); !ok {
+			stateBlock = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		}
+	}
+	parent, err := api.e.APIBackend.HeaderByNumberOrHash(ctx, stateBlock)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, errors.New("state block not found")
+	}
+	if args.BlockNumber != nil && uint64(*args.BlockNumber) != parent.Number.Uint64()+1 {
+		return nil, fmt.Errorf("block number %d does not follow state block %d", uint64(*args.BlockNumber), parent.Number.Uint64())
+	}
+	var (
+		timestamp = uint64(time.Now().Unix())
+		coinbase  = parent.Coinbase
+	)
+	if args.Timestamp != nil {
+		timestamp = uint64(*args.Timestamp)
+	}
+	if args.Coinbase != nil {
+		coinbase = *args.Coinbase
+	}
+	result, err := api.e.Miner().SimulateBundle(&bundlepool.Bundle{Txs: txs}, parent.Hash(), timestamp, coinbase)
+	if err != nil {
+		return nil, err
+	}
+	res := &CallBundleResult{
+		BundleHash:       result.Hash,
+		BundleGasPrice:   (*hexutil.Big)(result.GasPrice),
+		CoinbaseDiff:     (*hexutil.Big)(result.CoinbaseDiff),
+		TotalGasUsed:     hexutil.Uint64(result.GasUsed),
+		StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+	}
+	for _, tx := range result.Txs {
+		txres := &CallBundleTxResult{
+			TxHash:       tx.Hash,
+			FromAddress:  tx.From,
+			GasUsed:      hexutil.Uint64(tx.GasUsed),
+			CoinbaseDiff: (*hexutil.Big)(tx.CoinbaseDiff),
+		}
+		if tx.Err != nil {
+			txres.Error = tx.Err.Error()
+			txres.Revert = tx.ReturnData
+		} else {
+			txres.Value = tx.ReturnData
+		}
+		res.Results = append(res.Results, txres)
+	}
+	return res, nil
+}
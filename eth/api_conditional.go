@@ -0,0 +1,114 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool/conditionalpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ConditionalAPI provides an API to submit conditional transactions.
+type ConditionalAPI struct {
+	e *Ethereum
+}
+
+// NewConditionalAPI creates a new ConditionalAPI instance.
+func NewConditionalAPI(e *Ethereum) *ConditionalAPI {
+	return &ConditionalAPI{e}
+}
+
+// KnownAccountArgs is the expected storage of an account in the conditions of
+// eth_sendRawTransactionConditional. It is either a storage root hash, or an
+// object of expected storage slot values.
+type KnownAccountArgs struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// UnmarshalJSON parses either a storage root hash or a storage slot object.
+func (args *KnownAccountArgs) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		args.StorageRoot = new(common.Hash)
+		return json.Unmarshal(input, args.StorageRoot)
+	}
+	return json.Unmarshal(input, &args.StorageSlots)
+}
+
+// MarshalJSON encodes either the storage root hash or the storage slot object.
+func (args KnownAccountArgs) MarshalJSON() ([]byte, error) {
+	if args.StorageRoot != nil {
+		return json.Marshal(args.StorageRoot)
+	}
+	return json.Marshal(args.StorageSlots)
+}
+
+// TransactionConditionalArgs are the conditions of eth_sendRawTransactionConditional.
+type TransactionConditionalArgs struct {
+	KnownAccounts  map[common.Address]KnownAccountArgs `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Uint64                     `json:"blockNumberMin"`
+	BlockNumberMax *hexutil.Uint64                     `json:"blockNumberMax"`
+	TimestampMin   *hexutil.Uint64                     `json:"timestampMin"`
+	TimestampMax   *hexutil.Uint64                     `json:"timestampMax"`
+}
+
+// conditions converts the arguments into the conditions of the pool.
+func (args *TransactionConditionalArgs) conditions() *conditionalpool.Conditions {
+	conditions := &conditionalpool.Conditions{
+		KnownAccounts: make(map[common.Address]conditionalpool.KnownAccount, len(args.KnownAccounts)),
+	}
+	for addr, account := range args.KnownAccounts {
+		conditions.KnownAccounts[addr] = conditionalpool.KnownAccount{
+			StorageRoot:  account.StorageRoot,
+			StorageSlots: account.StorageSlots,
+		}
+	}
+	if args.BlockNumberMin != nil {
+		conditions.BlockNumberMin = uint64(*args.BlockNumberMin)
+	}
+	if args.BlockNumberMax != nil {
+		conditions.BlockNumberMax = uint64(*args.BlockNumberMax)
+	}
+	if args.TimestampMin != nil {
+		conditions.TimestampMin = uint64(*args.TimestampMin)
+	}
+	if args.TimestampMax != nil {
+		conditions.TimestampMax = uint64(*args.TimestampMax)
+	}
+	return conditions
+}
+
+// SendRawTransactionConditional submits a signed transaction which may only be
+// included into a block while the given conditions hold. The storage conditions
+// must hold at the current head, they are re-checked on every new head and right
+// before inclusion into a block. The transaction is not propagated to the network.
+func (api *ConditionalAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, options TransactionConditionalArgs) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.e.ConditionalPool().AddConditional(tx, options.conditions()); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted conditional transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce())
+	return tx.Hash(), nil
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"sync"
 
@@ -35,6 +36,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/txpool/conditionalpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -46,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
 	"github.com/ethereum/go-ethereum/log"
@@ -68,15 +72,19 @@ type Ethereum struct {
 	config *ethconfig.Config
 
 	// Handlers
-	txPool *txpool.TxPool
+	txPool          *txpool.TxPool
+	bundlePool      *bundlepool.BundlePool
+	conditionalPool *conditionalpool.ConditionalPool
 
 	blockchain         *core.BlockChain
+	pruner             *pruner.OnlinePruner // Online state pruner, nil if disabled
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb     ethdb.Database // Block chain database
+	chainDbPath string         // Path of the chain database relative to the datadir, empty if ephemeral
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -146,6 +154,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Expire the chain history below the cutoff if requested, serving it
+	// from the era1 archive afterwards.
+	if config.HistoryExpiry != 0 {
+		if config.HistoryEraDir == "" {
+			return nil, errors.New("history expiry requires an era1 archive directory")
+		}
+		network, ok := params.NetworkNames[chainConfig.ChainID.String()]
+		if !ok {
+			network = chainConfig.ChainID.String()
+		}
+		archive, err := era.OpenStore(stack.ResolvePath(config.HistoryEraDir), network, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open era1 archive: %w", err)
+		}
+		chainDb = rawdb.NewHistoryExpiryDatabase(chainDb, config.HistoryExpiry, archive)
+		log.Info("Enabled chain history expiry", "cutoff", config.HistoryExpiry, "archive", config.HistoryEraDir)
+	}
 	engine, err := ethconfig.CreateConsensusEngine(chainConfig, chainDb)
 	if err != nil {
 		return nil, err
@@ -157,6 +182,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth := &Ethereum{
 		config:            config,
 		chainDb:           chainDb,
+		chainDbPath:       chainDbPath(stack),
 		eventMux:          stack.EventMux(),
 		accountManager:    stack.AccountManager(),
 		engine:            engine,
@@ -188,6 +214,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
+			InterpreterImpl:         config.VMInterpreter,
+			ShadowInterpreterImpl:   config.VMShadowInterpreter,
+			CallInterceptor:         config.VMCallInterceptor,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:      config.TrieCleanCache,
@@ -201,12 +230,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			StateScheme:         scheme,
 		}
 	)
+	for _, name := range []string{config.VMInterpreter, config.VMShadowInterpreter} {
+		if !vm.IsInterpreterRegistered(name) {
+			return nil, fmt.Errorf("unknown interpreter implementation %q", name)
+		}
+	}
 	if config.VMTrace != "" {
 		var traceConfig json.RawMessage
 		if config.VMTraceJsonConfig != "" {
 			traceConfig = json.RawMessage(config.VMTraceJsonConfig)
 		}
-		t, err := tracers.LiveDirectory.New(config.VMTrace, traceConfig)
+		t, err := tracers.LiveDirectory.NewWithDB(config.VMTrace, traceConfig, chainDb)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to create tracer %s: %v", config.VMTrace, err)
 		}
@@ -238,12 +272,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
 	legacyPool := legacypool.New(config.TxPool, eth.blockchain)
-
-	eth.txPool, err = txpool.New(config.TxPool.PriceLimit, eth.blockchain, []txpool.SubPool{legacyPool, blobPool})
+	subpools := []txpool.SubPool{legacyPool, blobPool}
+	if config.ConditionalTxs {
+		eth.conditionalPool = conditionalpool.New(config.ConditionalPool, eth.blockchain)
+		subpools = append(subpools, eth.conditionalPool)
+	}
+	eth.txPool, err = txpool.New(config.TxPool.PriceLimit, eth.blockchain, subpools)
 	if err != nil {
 		return nil, err
 	}
+	if config.Bundles {
+		eth.bundlePool = bundlepool.New(config.BundlePool, eth.blockchain)
+	}
+
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
 	if eth.handler, err = newHandler(&handlerConfig{
@@ -315,6 +360,19 @@ func makeExtraData(extra []byte) []byte {
 	return extra
 }
 
+// chainDbPath returns the path of the chain database relative to the datadir
+// of the node, or an empty string if the node is ephemeral.
+func chainDbPath(stack *node.Node) string {
+	if stack.DataDir() == "" {
+		return ""
+	}
+	path, err := filepath.Rel(stack.DataDir(), stack.ResolvePath("chaindata"))
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
 // APIs return the collection of RPC services the ethereum package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Ethereum) APIs() []rpc.API {
@@ -323,6 +381,20 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the bundle and conditional transaction APIs only if explicitly
+	// enabled, they are served unauthenticated to anyone reaching the eth namespace
+	if s.bundlePool != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Service:   NewBundleAPI(s),
+		})
+	}
+	if s.conditionalPool != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Service:   NewConditionalAPI(s),
+		})
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -350,18 +422,20 @@ func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
 
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
-func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
-func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
-func (s *Ethereum) TxPool() *txpool.TxPool             { return s.txPool }
-func (s *Ethereum) EventMux() *event.TypeMux           { return s.eventMux }
-func (s *Ethereum) Engine() consensus.Engine           { return s.engine }
-func (s *Ethereum) ChainDb() ethdb.Database            { return s.chainDb }
-func (s *Ethereum) IsListening() bool                  { return true } // Always listening
-func (s *Ethereum) Downloader() *downloader.Downloader { return s.handler.downloader }
-func (s *Ethereum) Synced() bool                       { return s.handler.synced.Load() }
-func (s *Ethereum) SetSynced()                         { s.handler.enableSyncedFeatures() }
-func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
-func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) AccountManager() *accounts.Manager                 { return s.accountManager }
+func (s *Ethereum) BlockChain() *core.BlockChain                      { return s.blockchain }
+func (s *Ethereum) TxPool() *txpool.TxPool                            { return s.txPool }
+func (s *Ethereum) BundlePool() *bundlepool.BundlePool                { return s.bundlePool }
+func (s *Ethereum) ConditionalPool() *conditionalpool.ConditionalPool { return s.conditionalPool }
+func (s *Ethereum) EventMux() *event.TypeMux                          { return s.eventMux }
+func (s *Ethereum) Engine() consensus.Engine                          { return s.engine }
+func (s *Ethereum) ChainDb() ethdb.Database                           { return s.chainDb }
+func (s *Ethereum) IsListening() bool                                 { return true } // Always listening
+func (s *Ethereum) Downloader() *downloader.Downloader                { return s.handler.downloader }
+func (s *Ethereum) Synced() bool                                      { return s.handler.synced.Load() }
+func (s *Ethereum) SetSynced()                                        { s.handler.enableSyncedFeatures() }
+func (s *Ethereum) ArchiveMode() bool                                 { return s.config.NoPruning }
+func (s *Ethereum) BloomIndexer() *core.ChainIndexer                  { return s.bloomIndexer }
 
 // Protocols returns all the currently configured
 // network protocols to start.
@@ -394,6 +468,39 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Start pruning the stale state in the background if requested
+	if s.config.StatePruneOnline {
+		if err := s.startPruner(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startPruner starts the online state pruner, which is only supported for the
+// hash-based state of a non-archive node.
+func (s *Ethereum) startPruner() error {
+	if s.config.NoPruning {
+		return errors.New("online state pruning is not supported in archive mode")
+	}
+	if scheme := s.blockchain.TrieDB().Scheme(); scheme != rawdb.HashScheme {
+		return fmt.Errorf("online state pruning is not supported in %s scheme", scheme)
+	}
+	config := pruner.OnlineConfig{
+		BloomSize: s.config.StatePruneBloomSize,
+		Rate:      s.config.StatePruneRate,
+		Interval:  s.config.StatePruneInterval,
+	}
+	syncing := func() bool {
+		return s.SyncMode() == downloader.SnapSync
+	}
+	p, err := pruner.NewOnlinePruner(s.chainDb, s.blockchain.TrieDB(), config, syncing)
+	if err != nil {
+		return err
+	}
+	s.pruner = p
+	log.Info("Enabled online state pruning", "rate", config.Rate, "interval", config.Interval)
 	return nil
 }
 
@@ -409,6 +516,9 @@ func (s *Ethereum) Stop() error {
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
 	s.txPool.Close()
+	if s.pruner != nil {
+		s.pruner.Stop()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 
@@ -28,7 +28,10 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/txpool/conditionalpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -48,25 +51,30 @@ var FullNodeGPO = gasprice.Config{
 
 // Defaults contains default settings for use on the Ethereum main net.
 var Defaults = Config{
-	SyncMode:           downloader.SnapSync,
-	NetworkId:          0, // enable auto configuration of networkID == chainID
-	TxLookupLimit:      2350000,
-	TransactionHistory: 2350000,
-	StateHistory:       params.FullImmutabilityThreshold,
-	LightPeers:         100,
-	DatabaseCache:      512,
-	TrieCleanCache:     154,
-	TrieDirtyCache:     256,
-	TrieTimeout:        60 * time.Minute,
-	SnapshotCache:      102,
-	FilterLogCacheSize: 32,
-	Miner:              miner.DefaultConfig,
-	TxPool:             legacypool.DefaultConfig,
-	BlobPool:           blobpool.DefaultConfig,
-	RPCGasCap:          50000000,
-	RPCEVMTimeout:      5 * time.Second,
-	GPO:                FullNodeGPO,
-	RPCTxFeeCap:        1, // 1 ether
+	SyncMode:            downloader.SnapSync,
+	NetworkId:           0, // enable auto configuration of networkID == chainID
+	TxLookupLimit:       2350000,
+	TransactionHistory:  2350000,
+	StateHistory:        params.FullImmutabilityThreshold,
+	StatePruneBloomSize: 2048,
+	StatePruneRate:      20000,
+	StatePruneInterval:  24 * time.Hour,
+	LightPeers:          100,
+	DatabaseCache:       512,
+	TrieCleanCache:      154,
+	TrieDirtyCache:      256,
+	TrieTimeout:         60 * time.Minute,
+	SnapshotCache:       102,
+	FilterLogCacheSize:  32,
+	Miner:               miner.DefaultConfig,
+	TxPool:              legacypool.DefaultConfig,
+	BlobPool:            blobpool.DefaultConfig,
+	ConditionalPool:     conditionalpool.DefaultConfig,
+	BundlePool:          bundlepool.DefaultConfig,
+	RPCGasCap:           50000000,
+	RPCEVMTimeout:       5 * time.Second,
+	GPO:                 FullNodeGPO,
+	RPCTxFeeCap:         1, // 1 ether
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -95,11 +103,25 @@ type Config struct {
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
 
+	// History expiry options. The block bodies and receipts below the cutoff
+	// block are pruned from the ancient store, and served from the directory
+	// of era1 files instead. Zero cutoff retains the entire chain history.
+	HistoryExpiry uint64 `toml:",omitempty"`
+	HistoryEraDir string `toml:",omitempty"`
+
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
 	// consistent with persistent state.
 	StateScheme string `toml:",omitempty"`
 
+	// Online state pruning options, only relevant in hash scheme. The stale
+	// trie nodes are deleted while the node runs, in cycles separated by the
+	// given interval, at most the given number of nodes per second.
+	StatePruneOnline    bool          `toml:",omitempty"`
+	StatePruneBloomSize uint64        `toml:",omitempty"`
+	StatePruneRate      int           `toml:",omitempty"`
+	StatePruneInterval  time.Duration `toml:",omitempty"`
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
@@ -135,6 +157,15 @@ type Config struct {
 	TxPool   legacypool.Config
 	BlobPool blobpool.Config
 
+	// Conditional transaction pool options, the conditional transaction API is
+	// only served if enabled
+	ConditionalTxs  bool
+	ConditionalPool conditionalpool.Config
+
+	// Bundle pool options, the bundle APIs are only served if enabled
+	Bundles    bool
+	BundlePool bundlepool.Config
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
@@ -145,6 +176,14 @@ type Config struct {
 	VMTrace           string
 	VMTraceJsonConfig string
 
+	// Interpreter implementation to execute with and the one to cross-check
+	// every call against, if any
+	VMInterpreter       string
+	VMShadowInterpreter string
+
+	// Interceptor of all calls executed by the VM, meant for testing
+	VMCallInterceptor vm.CallContextInterceptor `toml:"-"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
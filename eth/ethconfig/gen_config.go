@@ -8,7 +8,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/txpool/conditionalpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -27,7 +30,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		HistoryExpiry           uint64                 `toml:",omitempty"`
+		HistoryEraDir           string                 `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
+		StatePruneOnline        bool                   `toml:",omitempty"`
+		StatePruneBloomSize     uint64                 `toml:",omitempty"`
+		StatePruneRate          int                    `toml:",omitempty"`
+		StatePruneInterval      time.Duration          `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -48,11 +57,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
 		BlobPool                blobpool.Config
+		ConditionalTxs          bool
+		ConditionalPool         conditionalpool.Config
+		Bundles                 bool
+		BundlePool              bundlepool.Config
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		VMTrace                 string
 		VMTraceJsonConfig       string
-		DocRoot                 string `toml:"-"`
+		VMInterpreter           string
+		VMShadowInterpreter     string
+		VMCallInterceptor       vm.CallContextInterceptor `toml:"-"`
+		DocRoot                 string                    `toml:"-"`
 		RPCGasCap               uint64
 		RPCEVMTimeout           time.Duration
 		RPCTxFeeCap             float64
@@ -70,7 +86,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.HistoryExpiry = c.HistoryExpiry
+	enc.HistoryEraDir = c.HistoryEraDir
 	enc.StateScheme = c.StateScheme
+	enc.StatePruneOnline = c.StatePruneOnline
+	enc.StatePruneBloomSize = c.StatePruneBloomSize
+	enc.StatePruneRate = c.StatePruneRate
+	enc.StatePruneInterval = c.StatePruneInterval
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -91,10 +113,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.BlobPool = c.BlobPool
+	enc.ConditionalTxs = c.ConditionalTxs
+	enc.ConditionalPool = c.ConditionalPool
+	enc.Bundles = c.Bundles
+	enc.BundlePool = c.BundlePool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.VMTrace = c.VMTrace
 	enc.VMTraceJsonConfig = c.VMTraceJsonConfig
+	enc.VMInterpreter = c.VMInterpreter
+	enc.VMShadowInterpreter = c.VMShadowInterpreter
+	enc.VMCallInterceptor = c.VMCallInterceptor
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
@@ -117,7 +146,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		HistoryExpiry           *uint64                `toml:",omitempty"`
+		HistoryEraDir           *string                `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
+		StatePruneOnline        *bool                  `toml:",omitempty"`
+		StatePruneBloomSize     *uint64                `toml:",omitempty"`
+		StatePruneRate          *int                   `toml:",omitempty"`
+		StatePruneInterval      *time.Duration         `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -138,11 +173,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
 		BlobPool                *blobpool.Config
+		ConditionalTxs          *bool
+		ConditionalPool         *conditionalpool.Config
+		Bundles                 *bool
+		BundlePool              *bundlepool.Config
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		VMTrace                 *string
 		VMTraceJsonConfig       *string
-		DocRoot                 *string `toml:"-"`
+		VMInterpreter           *string
+		VMShadowInterpreter     *string
+		VMCallInterceptor       vm.CallContextInterceptor `toml:"-"`
+		DocRoot                 *string                   `toml:"-"`
 		RPCGasCap               *uint64
 		RPCEVMTimeout           *time.Duration
 		RPCTxFeeCap             *float64
@@ -183,9 +225,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.HistoryExpiry != nil {
+		c.HistoryExpiry = *dec.HistoryExpiry
+	}
+	if dec.HistoryEraDir != nil {
+		c.HistoryEraDir = *dec.HistoryEraDir
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
+	if dec.StatePruneOnline != nil {
+		c.StatePruneOnline = *dec.StatePruneOnline
+	}
+	if dec.StatePruneBloomSize != nil {
+		c.StatePruneBloomSize = *dec.StatePruneBloomSize
+	}
+	if dec.StatePruneRate != nil {
+		c.StatePruneRate = *dec.StatePruneRate
+	}
+	if dec.StatePruneInterval != nil {
+		c.StatePruneInterval = *dec.StatePruneInterval
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -246,6 +306,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.BlobPool != nil {
 		c.BlobPool = *dec.BlobPool
 	}
+	if dec.ConditionalTxs != nil {
+		c.ConditionalTxs = *dec.ConditionalTxs
+	}
+	if dec.ConditionalPool != nil {
+		c.ConditionalPool = *dec.ConditionalPool
+	}
+	if dec.Bundles != nil {
+		c.Bundles = *dec.Bundles
+	}
+	if dec.BundlePool != nil {
+		c.BundlePool = *dec.BundlePool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -258,6 +330,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.VMTraceJsonConfig != nil {
 		c.VMTraceJsonConfig = *dec.VMTraceJsonConfig
 	}
+	if dec.VMInterpreter != nil {
+		c.VMInterpreter = *dec.VMInterpreter
+	}
+	if dec.VMShadowInterpreter != nil {
+		c.VMShadowInterpreter = *dec.VMShadowInterpreter
+	}
+	if dec.VMCallInterceptor != nil {
+		c.VMCallInterceptor = dec.VMCallInterceptor
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
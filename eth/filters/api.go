@@ -28,8 +28,10 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -190,6 +192,43 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 	return rpcSub, nil
 }
 
+// TxpoolEvents creates a subscription that is triggered on each lifecycle event
+// of a pooled transaction, e.g. it being added to the pool, replaced by another
+// one or dropped from the pool along with the reason. Subscribers which don't
+// keep up with the events stop receiving them.
+func (api *FilterAPI) TxpoolEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan []txpool.TxEvent, 128)
+		eventsSub := api.sys.backend.SubscribeTxPoolEvents(events)
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case events := <-events:
+				for _, ev := range events {
+					notifier.Notify(rpcSub.ID, ev)
+				}
+			case <-rpcSub.Err():
+				return
+			case err := <-eventsSub.Err():
+				if err != nil {
+					log.Debug("Dropped lagging transaction pool event subscription", "id", rpcSub.ID, "err", err)
+				}
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 func (api *FilterAPI) NewBlockFilter() rpc.ID {
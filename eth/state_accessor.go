@@ -181,10 +181,9 @@ func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, func(), erro
 	if err == nil {
 		return statedb, noopReleaser, nil
 	}
-	// TODO historic state is not supported in path-based scheme.
-	// Fully archive node in pbss will be implemented by relying
-	// on state history, but needs more work on top.
-	return nil, nil, errors.New("historical state not available in path scheme yet")
+	// The states covered by the retained state histories are served by the
+	// live chain as well, the requested one is beyond them.
+	return nil, nil, fmt.Errorf("historical state not available in path scheme: %w", err)
 }
 
 // stateAtBlock retrieves the state database associated with a certain block.
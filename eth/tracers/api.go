@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"reflect"
 	"runtime"
 	"sync"
 	"time"
@@ -92,12 +93,13 @@ type Backend interface {
 
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
-	backend Backend
+	backend       Backend
+	debugSessions *debugSessions
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
 func NewAPI(backend Backend) *API {
-	return &API{backend: backend}
+	return &API{backend: backend, debugSessions: newDebugSessions()}
 }
 
 // chainContext constructs the context reader which is used by the evm for reading
@@ -558,6 +560,9 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 		// calling IntermediateRoot will internally call Finalize on the state
 		// so any modifications are written to the trie
 		roots = append(roots, statedb.IntermediateRoot(deleteEmptyObjects))
+		if err := statedb.Error(); err != nil {
+			return nil, err
+		}
 	}
 	return roots, nil
 }
@@ -856,6 +861,11 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if blockNumber == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the trace from the call trace index if it was recorded during
+	// block import, only fall back to re-executing the block otherwise.
+	if trace := api.indexedCallTrace(hash, blockHash, config); trace != nil {
+		return trace, nil
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -883,6 +893,55 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	return api.traceTx(ctx, tx, msg, txctx, vmctx, statedb, config)
 }
 
+// indexedCallTrace returns the call trace of the given transaction persisted
+// by the call trace index live tracer. Traces are only served if they were
+// recorded in the canonical block containing the transaction and with the
+// same call tracer configuration as requested.
+func (api *API) indexedCallTrace(hash common.Hash, blockHash common.Hash, config *TraceConfig) json.RawMessage {
+	if config == nil || config.Tracer == nil || *config.Tracer != "callTracer" {
+		return nil
+	}
+	trace := rawdb.ReadCallTrace(api.backend.ChainDb(), blockHash, hash)
+	if trace == nil {
+		return nil
+	}
+	if !equalTracerConfig(trace.Config, config.TracerConfig) {
+		return nil
+	}
+	return trace.Result
+}
+
+// equalTracerConfig reports whether two JSON encoded tracer configs are
+// semantically identical. A missing config is treated as an empty one, and
+// fields set to their zero values as missing, since that's what the tracers
+// default them to.
+func equalTracerConfig(a, b json.RawMessage) bool {
+	decode := func(raw json.RawMessage) (map[string]interface{}, bool) {
+		config := make(map[string]interface{})
+		if len(raw) == 0 || string(raw) == "null" {
+			return config, true
+		}
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, false
+		}
+		for field, value := range config {
+			if value == nil || value == false || value == float64(0) || value == "" {
+				delete(config, field)
+			}
+		}
+		return config, true
+	}
+	configA, ok := decode(a)
+	if !ok {
+		return false
+	}
+	configB, ok := decode(b)
+	if !ok {
+		return false
+	}
+	return reflect.DeepEqual(configA, configB)
+}
+
 // TraceCall lets you trace a given eth_call. It collects the structured logs
 // created during the execution of EVM if the given transaction was added on
 // top of the provided block and returns them as a JSON object.
@@ -891,6 +950,37 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // the trace will be conducted on the state after executing the specified transaction
 // within the specified block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	msg, tx, vmctx, statedb, release, err := api.callEnv(ctx, args, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, tx, msg, new(Context), vmctx, statedb, traceConfig)
+}
+
+// callEnv assembles the message and the execution environment for running a
+// call on top of the given block, with the customizations of the config applied.
+func (api *API) callEnv(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*core.Message, *types.Transaction, vm.BlockContext, *state.StateDB, StateReleaseFunc, error) {
+	vmctx, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, nil, vm.BlockContext{}, nil, nil, err
+	}
+	// Execute the trace
+	if err := args.CallDefaults(api.backend.RPCGasCap(), vmctx.BaseFee, api.backend.ChainConfig().ChainID); err != nil {
+		release()
+		return nil, nil, vm.BlockContext{}, nil, nil, err
+	}
+	return args.ToMessage(vmctx.BaseFee), args.ToTransaction(), vmctx, statedb, release, nil
+}
+
+// callState retrieves the state and the block context for running calls on top
+// of the given block, with the customizations of the config applied.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (vm.BlockContext, *state.StateDB, StateReleaseFunc, error) {
 	// Try to retrieve the specified block
 	var (
 		err     error
@@ -907,14 +997,14 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			// more flexibility and stability than trying to trace on 'pending', since
 			// the contents of 'pending' is unstable and probably not a true representation
 			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
+			return vm.BlockContext{}, nil, nil, errors.New("tracing on top of pending is not supported")
 		}
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return vm.BlockContext{}, nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return vm.BlockContext{}, nil, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
@@ -928,31 +1018,18 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 		statedb, release, err = api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	}
 	if err != nil {
-		return nil, err
+		return vm.BlockContext{}, nil, nil, err
 	}
-	defer release()
-
 	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 	// Apply the customization rules if required.
 	if config != nil {
 		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
+			release()
+			return vm.BlockContext{}, nil, nil, err
 		}
 		config.BlockOverrides.Apply(&vmctx)
 	}
-	// Execute the trace
-	if err := args.CallDefaults(api.backend.RPCGasCap(), vmctx.BaseFee, api.backend.ChainConfig().ChainID); err != nil {
-		return nil, err
-	}
-	var (
-		msg         = args.ToMessage(vmctx.BaseFee)
-		tx          = args.ToTransaction()
-		traceConfig *TraceConfig
-	)
-	if config != nil {
-		traceConfig = &config.TraceConfig
-	}
-	return api.traceTx(ctx, tx, msg, new(Context), vmctx, statedb, traceConfig)
+	return vmctx, statedb, release, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
@@ -1019,6 +1096,10 @@ func APIs(backend Backend) []rpc.API {
 			Namespace: "debug",
 			Service:   NewAPI(backend),
 		},
+		{
+			Namespace: "trace",
+			Service:   NewTraceAPI(backend),
+		},
 	}
 }
 
@@ -17,6 +17,7 @@
 package tracers
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
@@ -458,6 +459,83 @@ func TestTraceTransaction(t *testing.T) {
 	}
 }
 
+func TestTraceTransactionIndexed(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	target := common.Hash{}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(i),
+			To:       &accounts[1].addr,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: b.BaseFee(),
+			Data:     nil}),
+			signer, accounts[0].key)
+		b.AddTx(tx)
+		target = tx.Hash()
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	// Store a fake trace of the default call tracer, so it can be told apart
+	// from a re-executed one
+	var (
+		block   = backend.chain.GetBlockByNumber(1)
+		tracer  = "callTracer"
+		indexed = json.RawMessage(`{"indexed":true}`)
+	)
+	rawdb.WriteCallTrace(backend.chaindb, block.Hash(), target, &rawdb.CallTrace{
+		Result: indexed,
+	})
+	result, err := api.TraceTransaction(context.Background(), target, &TraceConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if !bytes.Equal(result.(json.RawMessage), indexed) {
+		t.Errorf("indexed trace not served: have %s", result)
+	}
+	// Configs equal to the default must be served from the index, while other
+	// tracers and differing configs must not
+	var testSuite = []struct {
+		config  *TraceConfig
+		indexed bool
+	}{
+		{config: &TraceConfig{Tracer: &tracer}, indexed: true},
+		{config: &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{}`)}, indexed: true},
+		{config: &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"withLog":false}`)}, indexed: true},
+		{config: &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{ "withLog": true }`)}, indexed: false},
+		{config: &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)}, indexed: false},
+		{config: nil, indexed: false},
+	}
+	for i, tc := range testSuite {
+		if have := api.indexedCallTrace(target, block.Hash(), tc.config) != nil; have != tc.indexed {
+			t.Errorf("test %d: indexed trace mismatch: have %v, want %v", i, have, tc.indexed)
+		}
+	}
+	// Traces recorded in a different block must not be served
+	if api.indexedCallTrace(target, common.Hash{0x01}, testSuite[0].config) != nil {
+		t.Error("trace of foreign block served")
+	}
+	// Without an indexed trace, the transaction is re-executed
+	result, err = api.TraceTransaction(context.Background(), target, nil)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if bytes.Equal(result.(json.RawMessage), indexed) {
+		t.Error("indexed trace served to the struct logger")
+	}
+}
+
 func TestTraceBlock(t *testing.T) {
 	t.Parallel()
 
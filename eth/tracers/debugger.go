@@ -0,0 +1,649 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultDebugIdleTimeout is the amount of time a debug session is kept
+	// alive without any interaction before being terminated.
+	defaultDebugIdleTimeout = 5 * time.Minute
+
+	// maxDebugSessions is the maximum number of debug sessions which can be
+	// open at the same time. Each of them holds on to a historical state.
+	maxDebugSessions = 16
+)
+
+var (
+	errDebugSessionNotFound = errors.New("debug session not found")
+	errDebugSessionDone     = errors.New("debug session execution finished")
+	errDebugSessionLimit    = errors.New("too many debug sessions")
+)
+
+// DebugConfig holds the parameters of a debug session.
+type DebugConfig struct {
+	Reexec      *uint64
+	IdleTimeout *string      // Time after which an idle session is terminated
+	Breakpoints []Breakpoint // Breakpoints set when the session starts
+}
+
+// DebugCallConfig is the config of debug sessions of calls. It holds the same
+// customizations as the traceCall API.
+type DebugCallConfig struct {
+	DebugConfig
+	StateOverrides *ethapi.StateOverride
+	BlockOverrides *ethapi.BlockOverrides
+	TxIndex        *hexutil.Uint
+}
+
+// Breakpoint pauses the execution when reaching a program counter, executing
+// an opcode or accessing a storage slot, whichever of them is set. Program
+// counters are matched against the address of the executing code, storage
+// slots against the address of the accessed storage.
+type Breakpoint struct {
+	Address *common.Address `json:"address,omitempty"` // Restricts the breakpoint to a single contract
+	Pc      *hexutil.Uint64 `json:"pc,omitempty"`
+	Op      *string         `json:"op,omitempty"`
+	Slot    *common.Hash    `json:"slot,omitempty"` // Matches SLOAD and SSTORE of the slot
+}
+
+// validate checks that exactly one condition is set and that it's meaningful.
+func (bp *Breakpoint) validate() error {
+	var set int
+	for _, cond := range []bool{bp.Pc != nil, bp.Op != nil, bp.Slot != nil} {
+		if cond {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("breakpoint must set exactly one of pc, op and slot")
+	}
+	if bp.Op != nil && vm.StringToOp(*bp.Op).String() != *bp.Op {
+		return fmt.Errorf("unknown opcode %q", *bp.Op)
+	}
+	return nil
+}
+
+// matches reports whether the breakpoint is hit by the operation about to be
+// executed in the given frame.
+func (bp *Breakpoint) matches(state *vm.InterpreterState) bool {
+	var (
+		contract = state.Contract
+		op       = contract.GetOp(state.Pc)
+	)
+	switch {
+	case bp.Pc != nil:
+		if bp.Address != nil && (contract.CodeAddr == nil || *contract.CodeAddr != *bp.Address) {
+			return false
+		}
+		return uint64(*bp.Pc) == state.Pc
+	case bp.Op != nil:
+		if bp.Address != nil && contract.Address() != *bp.Address {
+			return false
+		}
+		return op.String() == *bp.Op
+	case bp.Slot != nil:
+		if bp.Address != nil && contract.Address() != *bp.Address {
+			return false
+		}
+		if (op != vm.SLOAD && op != vm.SSTORE) || state.Stack.Len() == 0 {
+			return false
+		}
+		return common.Hash(state.Stack.Back(0).Bytes32()) == *bp.Slot
+	}
+	return false
+}
+
+// DebugResult is the outcome of the debugged execution.
+type DebugResult struct {
+	Gas         uint64        `json:"gas"`
+	Failed      bool          `json:"failed"`
+	ReturnValue hexutil.Bytes `json:"returnValue"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// DebugState is the state of a debug session, either paused before executing
+// an operation or done.
+type DebugState struct {
+	Session     string          `json:"session"`
+	Done        bool            `json:"done"`
+	Depth       int             `json:"depth,omitempty"`
+	Address     *common.Address `json:"address,omitempty"`     // Address of the storage being accessed
+	CodeAddress *common.Address `json:"codeAddress,omitempty"` // Address of the code being executed
+	Pc          uint64          `json:"pc"`
+	Op          string          `json:"op,omitempty"`
+	Gas         uint64          `json:"gas"`
+	Stack       []string        `json:"stack,omitempty"` // Bottom first
+	MemorySize  int             `json:"memorySize"`
+	Breakpoint  *int            `json:"breakpoint,omitempty"` // Breakpoint which paused the execution
+	Result      *DebugResult    `json:"result,omitempty"`     // Set once the execution is done
+}
+
+// debugMode determines where a resumed execution pauses next.
+type debugMode int
+
+const (
+	debugStepInto debugMode = iota // Pause before the next operation
+	debugStepOver                  // Pause before the next operation of the current or a parent frame
+	debugStepOut                   // Pause before the next operation of a parent frame
+	debugContinue                  // Only pause on breakpoints
+)
+
+// debugRequest is sent to a paused execution, either resuming it or running
+// a function on it with access to the state of the paused frame.
+type debugRequest struct {
+	mode  debugMode
+	fn    func(*vm.InterpreterState) (interface{}, error)
+	reply chan debugReply
+}
+
+type debugReply struct {
+	result interface{}
+	err    error
+}
+
+// debugSession is an execution of a transaction or a call on the stepping
+// interpreter, which pauses before operations and waits to be resumed.
+//
+// The execution runs in its own goroutine and is only ever accessed from it,
+// API requests are forwarded to it while it's paused.
+type debugSession struct {
+	id      string
+	evm     *vm.EVM
+	statedb *state.StateDB
+	timeout time.Duration
+	timer   *time.Timer
+
+	// Fields owned by the execution goroutine
+	mode        debugMode
+	depth       int // Depth of the frame the execution was resumed in
+	breakpoints map[int]*Breakpoint
+	nextID      int
+
+	requests chan *debugRequest
+	events   chan *DebugState
+	closed   chan struct{}
+
+	lock      sync.Mutex // Serializes API requests
+	done      bool       // Whether the execution finished, guarded by lock
+	closeOnce sync.Once
+	onClose   func()
+}
+
+// step is the hook of the stepping interpreter, pausing the execution if it
+// reached its next stop.
+func (s *debugSession) step(depth int, state *vm.InterpreterState) {
+	hit := -1
+	for id, bp := range s.breakpoints {
+		if bp.matches(state) && (hit == -1 || id < hit) {
+			hit = id
+		}
+	}
+	if hit == -1 {
+		switch s.mode {
+		case debugStepOver:
+			if depth > s.depth {
+				return
+			}
+		case debugStepOut:
+			if depth >= s.depth {
+				return
+			}
+		case debugContinue:
+			return
+		}
+	}
+	ev := s.capture(depth, state)
+	if hit != -1 {
+		ev.Breakpoint = &hit
+	}
+	select {
+	case s.events <- ev:
+	case <-s.closed:
+		return
+	}
+	// Serve requests until the execution is resumed
+	for {
+		select {
+		case req := <-s.requests:
+			if req.fn != nil {
+				res, err := req.fn(state)
+				req.reply <- debugReply{res, err}
+				continue
+			}
+			s.mode, s.depth = req.mode, depth
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// capture assembles the state of the paused frame.
+func (s *debugSession) capture(depth int, state *vm.InterpreterState) *DebugState {
+	var (
+		contract = state.Contract
+		address  = contract.Address()
+		stack    = state.Stack.Data()
+	)
+	ev := &DebugState{
+		Session:     s.id,
+		Depth:       depth,
+		Address:     &address,
+		CodeAddress: contract.CodeAddr,
+		Pc:          state.Pc,
+		Op:          contract.GetOp(state.Pc).String(),
+		Gas:         contract.Gas,
+		Stack:       make([]string, len(stack)),
+		MemorySize:  state.Memory.Len(),
+	}
+	for i, v := range stack {
+		ev.Stack[i] = v.Hex()
+	}
+	return ev
+}
+
+// run executes the message, reporting the outcome once done. The state is
+// held on to until the session is closed, serving the reads of it meanwhile.
+func (s *debugSession) run(msg *core.Message, release StateReleaseFunc) {
+	defer release()
+
+	ev := &DebugState{Session: s.id, Done: true}
+	result, err := core.ApplyMessage(s.evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if err != nil {
+		ev.Result = &DebugResult{Failed: true, Error: err.Error()}
+	} else {
+		ev.Result = &DebugResult{
+			Gas:         result.UsedGas,
+			Failed:      result.Failed(),
+			ReturnValue: result.Return(),
+		}
+		if result.Err != nil {
+			ev.Result.Error = result.Err.Error()
+			if len(result.Revert()) > 0 {
+				ev.Result.ReturnValue = result.Revert()
+			}
+		}
+	}
+	select {
+	case s.events <- ev:
+	case <-s.closed:
+		return
+	}
+	// Serve requests inspecting the final state, it's only released once the
+	// session is closed and no request can be in flight anymore
+	for {
+		select {
+		case req := <-s.requests:
+			res, err := req.fn(nil)
+			req.reply <- debugReply{res, err}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// wait blocks until the execution pauses or finishes. If the request is
+// cancelled meanwhile, the session is terminated as its state is lost.
+func (s *debugSession) wait(ctx context.Context) (*DebugState, error) {
+	select {
+	case ev := <-s.events:
+		if ev.Done {
+			s.done = true
+		}
+		return ev, nil
+	case <-s.closed:
+		return nil, errDebugSessionNotFound
+	case <-ctx.Done():
+		s.close()
+		return nil, ctx.Err()
+	}
+}
+
+// resume continues the paused execution in the given mode.
+func (s *debugSession) resume(ctx context.Context, mode debugMode) (*DebugState, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.done {
+		return nil, errDebugSessionDone
+	}
+	select {
+	case s.requests <- &debugRequest{mode: mode}:
+	case <-s.closed:
+		return nil, errDebugSessionNotFound
+	}
+	return s.wait(ctx)
+}
+
+// call runs the function on the paused execution.
+func (s *debugSession) call(fn func(*vm.InterpreterState) (interface{}, error)) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.done {
+		return nil, errDebugSessionDone
+	}
+	return s.request(fn)
+}
+
+// inspect runs the function on the execution goroutine whether it's paused or
+// done, passing no interpreter state in the latter case.
+func (s *debugSession) inspect(fn func(*vm.InterpreterState) (interface{}, error)) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.request(fn)
+}
+
+// request forwards the function to the execution goroutine and waits for its
+// result. The caller must hold the lock.
+func (s *debugSession) request(fn func(*vm.InterpreterState) (interface{}, error)) (interface{}, error) {
+	req := &debugRequest{fn: fn, reply: make(chan debugReply, 1)}
+	select {
+	case s.requests <- req:
+	case <-s.closed:
+		return nil, errDebugSessionNotFound
+	}
+	reply := <-req.reply
+	return reply.result, reply.err
+}
+
+// touch postpones the idle timeout of the session.
+func (s *debugSession) touch() {
+	s.timer.Reset(s.timeout)
+}
+
+// close terminates the session, aborting the execution if still running.
+func (s *debugSession) close() {
+	s.closeOnce.Do(func() {
+		s.evm.Cancel()
+		close(s.closed)
+		s.onClose()
+	})
+}
+
+// debugSessions tracks the open debug sessions.
+type debugSessions struct {
+	lock     sync.Mutex
+	sessions map[string]*debugSession
+}
+
+func newDebugSessions() *debugSessions {
+	return &debugSessions{sessions: make(map[string]*debugSession)}
+}
+
+func (d *debugSessions) get(id string) (*debugSession, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	s, ok := d.sessions[id]
+	if !ok {
+		return nil, errDebugSessionNotFound
+	}
+	s.touch()
+	return s, nil
+}
+
+// start opens a debug session executing the message, and waits until the
+// execution pauses before its first operation.
+func (api *API) startDebugSession(ctx context.Context, msg *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, release StateReleaseFunc, config *DebugConfig) (*DebugState, error) {
+	timeout := defaultDebugIdleTimeout
+	if config.IdleTimeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.IdleTimeout); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	s := &debugSession{
+		id:          string(rpc.NewID()),
+		statedb:     statedb,
+		timeout:     timeout,
+		breakpoints: make(map[int]*Breakpoint),
+		requests:    make(chan *debugRequest),
+		events:      make(chan *DebugState),
+		closed:      make(chan struct{}),
+	}
+	for i := range config.Breakpoints {
+		if err := config.Breakpoints[i].validate(); err != nil {
+			release()
+			return nil, err
+		}
+		s.breakpoints[s.nextID] = &config.Breakpoints[i]
+		s.nextID++
+	}
+	s.evm = vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{
+		NoBaseFee:       true,
+		InterpreterImpl: "step",
+		OnStep:          s.step,
+	})
+	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
+
+	sessions := api.debugSessions
+	s.onClose = func() {
+		sessions.lock.Lock()
+		delete(sessions.sessions, s.id)
+		sessions.lock.Unlock()
+		log.Debug("Closed debug session", "id", s.id)
+	}
+	sessions.lock.Lock()
+	if len(sessions.sessions) >= maxDebugSessions {
+		sessions.lock.Unlock()
+		release()
+		return nil, errDebugSessionLimit
+	}
+	sessions.sessions[s.id] = s
+	s.timer = time.AfterFunc(timeout, s.close)
+	sessions.lock.Unlock()
+
+	go s.run(msg, release)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.wait(ctx)
+}
+
+// DebugTransaction opens a debug session replaying the given transaction,
+// paused before its first operation.
+func (api *API) DebugTransaction(ctx context.Context, hash common.Hash, config *DebugConfig) (*DebugState, error) {
+	if config == nil {
+		config = new(DebugConfig)
+	}
+	found, _, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, ethapi.NewTxIndexingError()
+	}
+	if !found {
+		return nil, errTxNotFound
+	}
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	reexec := defaultTraceReexec
+	if config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := core.TransactionToMessage(tx, types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time()), block.BaseFee())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	txctx := &Context{
+		BlockHash:   blockHash,
+		BlockNumber: block.Number(),
+		TxIndex:     int(index),
+		TxHash:      hash,
+	}
+	return api.startDebugSession(ctx, msg, txctx, vmctx, statedb, release, config)
+}
+
+// DebugCall opens a debug session executing the given call on top of a block,
+// paused before its first operation.
+func (api *API) DebugCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *DebugCallConfig) (*DebugState, error) {
+	if config == nil {
+		config = new(DebugCallConfig)
+	}
+	msg, _, vmctx, statedb, release, err := api.callEnv(ctx, args, blockNrOrHash, &TraceCallConfig{
+		TraceConfig:    TraceConfig{Reexec: config.Reexec},
+		StateOverrides: config.StateOverrides,
+		BlockOverrides: config.BlockOverrides,
+		TxIndex:        config.TxIndex,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return api.startDebugSession(ctx, msg, new(Context), vmctx, statedb, release, &config.DebugConfig)
+}
+
+// DebugStepInto resumes the session until the next operation.
+func (api *API) DebugStepInto(ctx context.Context, id string) (*DebugState, error) {
+	return api.resumeDebugSession(ctx, id, debugStepInto)
+}
+
+// DebugStepOver resumes the session until the next operation of the current
+// call frame, stepping over any calls made by it.
+func (api *API) DebugStepOver(ctx context.Context, id string) (*DebugState, error) {
+	return api.resumeDebugSession(ctx, id, debugStepOver)
+}
+
+// DebugStepOut resumes the session until the current call frame returns to
+// its caller.
+func (api *API) DebugStepOut(ctx context.Context, id string) (*DebugState, error) {
+	return api.resumeDebugSession(ctx, id, debugStepOut)
+}
+
+// DebugContinue resumes the session until a breakpoint is hit or the
+// execution finishes.
+func (api *API) DebugContinue(ctx context.Context, id string) (*DebugState, error) {
+	return api.resumeDebugSession(ctx, id, debugContinue)
+}
+
+func (api *API) resumeDebugSession(ctx context.Context, id string, mode debugMode) (*DebugState, error) {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.resume(ctx, mode)
+}
+
+// DebugSetBreakpoint adds a breakpoint to the session, returning its id.
+func (api *API) DebugSetBreakpoint(id string, bp Breakpoint) (int, error) {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := bp.validate(); err != nil {
+		return 0, err
+	}
+	res, err := s.call(func(*vm.InterpreterState) (interface{}, error) {
+		id := s.nextID
+		s.breakpoints[id] = &bp
+		s.nextID++
+		return id, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.(int), nil
+}
+
+// DebugRemoveBreakpoint removes a breakpoint from the session, returning
+// whether it existed.
+func (api *API) DebugRemoveBreakpoint(id string, breakpoint int) (bool, error) {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.call(func(*vm.InterpreterState) (interface{}, error) {
+		_, ok := s.breakpoints[breakpoint]
+		delete(s.breakpoints, breakpoint)
+		return ok, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.(bool), nil
+}
+
+// DebugMemory returns a slice of the memory of the paused call frame.
+func (api *API) DebugMemory(id string, offset hexutil.Uint64, size hexutil.Uint64) (hexutil.Bytes, error) {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.call(func(state *vm.InterpreterState) (interface{}, error) {
+		if end := uint64(offset) + uint64(size); end < uint64(offset) || end > uint64(state.Memory.Len()) {
+			return nil, fmt.Errorf("memory range [%d, %d) out of bounds, size %d", offset, end, state.Memory.Len())
+		}
+		return hexutil.Bytes(state.Memory.GetCopy(int64(offset), int64(size))), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(hexutil.Bytes), nil
+}
+
+// DebugStorage returns the value of a storage slot at the point where the
+// session is paused, or after the execution if it's done.
+func (api *API) DebugStorage(id string, address common.Address, slot common.Hash) (common.Hash, error) {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	res, err := s.inspect(func(*vm.InterpreterState) (interface{}, error) {
+		return s.statedb.GetState(address, slot), nil
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return res.(common.Hash), nil
+}
+
+// DebugEndSession terminates the session, returning whether it existed.
+func (api *API) DebugEndSession(id string) bool {
+	s, err := api.debugSessions.get(id)
+	if err != nil {
+		return false
+	}
+	s.close()
+	return true
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestDebugSession(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(1)
+		caller   = common.HexToAddress("0xca11e7")
+		callee   = common.HexToAddress("0xca11ee")
+	)
+	// The callee stores 0x2a into slot 1, the caller calls it and then stores
+	// 1 into its own slot 0.
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	code = append(code, callee.Bytes()...)
+	code = append(code,
+		byte(vm.GAS), byte(vm.CALL), byte(vm.POP), // pc 31, 32, 33
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP),
+	)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			caller:           {Code: code},
+			callee:           {Code: []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 1, byte(vm.SSTORE), byte(vm.STOP)}},
+		},
+	}
+	var tx *types.Transaction
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ = types.SignTx(types.NewTx(&types.LegacyTx{
+			To:       &caller,
+			Gas:      100000,
+			GasPrice: b.BaseFee(),
+		}), types.HomesteadSigner{}, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.teardown()
+
+	var (
+		api = NewAPI(backend)
+		ctx = context.Background()
+	)
+	check := func(state *DebugState, err error, depth int, pc uint64, op string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("debug request failed: %v", err)
+		}
+		if state.Done || state.Depth != depth || state.Pc != pc || state.Op != op {
+			t.Fatalf("paused state mismatch: have depth %d pc %d op %s (done: %t), want depth %d pc %d op %s",
+				state.Depth, state.Pc, state.Op, state.Done, depth, pc, op)
+		}
+	}
+	// Step over the call to the callee
+	state, err := api.DebugTransaction(ctx, tx.Hash(), nil)
+	check(state, err, 1, 0, "PUSH1")
+	id := state.Session
+
+	pc := hexutil.Uint64(32)
+	if _, err := api.DebugSetBreakpoint(id, Breakpoint{Pc: &pc}); err != nil {
+		t.Fatalf("failed to set breakpoint: %v", err)
+	}
+	state, err = api.DebugContinue(ctx, id)
+	check(state, err, 1, 32, "CALL")
+	if state.Breakpoint == nil || *state.Breakpoint != 0 || len(state.Stack) != 7 {
+		t.Fatalf("breakpoint state mismatch: breakpoint %v, stack %v", state.Breakpoint, state.Stack)
+	}
+	state, err = api.DebugStepOver(ctx, id)
+	check(state, err, 1, 33, "POP")
+	if slot, err := api.DebugStorage(id, callee, common.BigToHash(big.NewInt(1))); err != nil || slot != common.BigToHash(big.NewInt(0x2a)) {
+		t.Fatalf("callee storage mismatch: have %x (%v), want 0x2a", slot, err)
+	}
+	if !api.DebugEndSession(id) {
+		t.Fatal("failed to end session")
+	}
+	if _, err := api.DebugStepInto(ctx, id); err != errDebugSessionNotFound {
+		t.Fatalf("ended session error mismatch: have %v, want %v", err, errDebugSessionNotFound)
+	}
+
+	// Break on the storage write of the callee, then step out of it
+	slot := common.BigToHash(big.NewInt(1))
+	state, err = api.DebugTransaction(ctx, tx.Hash(), &DebugConfig{Breakpoints: []Breakpoint{{Slot: &slot}}})
+	check(state, err, 1, 0, "PUSH1")
+	id = state.Session
+
+	state, err = api.DebugContinue(ctx, id)
+	check(state, err, 2, 4, "SSTORE")
+	if state.Address == nil || *state.Address != callee {
+		t.Fatalf("paused address mismatch: have %v, want %x", state.Address, callee)
+	}
+	if value, _ := api.DebugStorage(id, callee, slot); value != (common.Hash{}) {
+		t.Fatalf("storage written before SSTORE: %x", value)
+	}
+	if _, err := api.DebugMemory(id, 0, 1); err == nil {
+		t.Fatal("out of bounds memory read succeeded")
+	}
+	state, err = api.DebugStepOut(ctx, id)
+	check(state, err, 1, 33, "POP")
+	state, err = api.DebugStepInto(ctx, id)
+	check(state, err, 1, 34, "PUSH1")
+
+	state, err = api.DebugContinue(ctx, id)
+	if err != nil || !state.Done || state.Result == nil || state.Result.Failed {
+		t.Fatalf("finished state mismatch: %+v (%v)", state, err)
+	}
+	if _, err := api.DebugStepInto(ctx, id); err != errDebugSessionDone {
+		t.Fatalf("finished session error mismatch: have %v, want %v", err, errDebugSessionDone)
+	}
+	if value, err := api.DebugStorage(id, caller, common.Hash{}); err != nil || value != common.BigToHash(big.NewInt(1)) {
+		t.Fatalf("caller storage mismatch: have %x (%v), want 1", value, err)
+	}
+	api.DebugEndSession(id)
+	if _, err := api.DebugStorage(id, caller, common.Hash{}); err != errDebugSessionNotFound {
+		t.Fatalf("ended session error mismatch: have %v, want %v", err, errDebugSessionNotFound)
+	}
+
+	// Debug a call, letting the session time out
+	timeout := "50ms"
+	state, err = api.DebugCall(ctx, ethapi.TransactionArgs{From: &accounts[0].addr, To: &caller}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &DebugCallConfig{
+		DebugConfig: DebugConfig{IdleTimeout: &timeout},
+	})
+	check(state, err, 1, 0, "PUSH1")
+
+	time.Sleep(200 * time.Millisecond)
+	if _, err := api.DebugStepInto(ctx, state.Session); err != errDebugSessionNotFound {
+		t.Fatalf("timed out session error mismatch: have %v, want %v", err, errDebugSessionNotFound)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCallTraceIndex(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		// Contract emitting a single log: LOG0(0, 0)
+		emitter = common.HexToAddress("0xe1")
+		genesis = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc: types.GenesisAlloc{
+				addr:    {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG0)}},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	makeTx := func(b *core.BlockGen, to common.Address) *types.Transaction {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(addr),
+			To:       &to,
+			Gas:      100000,
+			GasPrice: b.BaseFee(),
+		})
+		b.AddTx(tx)
+		return tx
+	}
+	// Create the original chain calling the emitter, and a longer fork sending
+	// a transaction somewhere else instead.
+	var (
+		original *types.Transaction
+		forked   *types.Transaction
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 1, func(i int, b *core.BlockGen) {
+		original = makeTx(b, emitter)
+	})
+	_, fork, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 3, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			forked = makeTx(b, common.HexToAddress("0xdead"))
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	tracer, err := tracers.LiveDirectory.NewWithDB("callTraceIndex", json.RawMessage(`{"tracerConfig":{"withLog":true}}`), db)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), genesis, nil, ethash.NewFaker(), vm.Config{Tracer: tracer}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	trace := rawdb.ReadCallTrace(db, blocks[0].Hash(), original.Hash())
+	if trace == nil {
+		t.Fatal("call trace not indexed")
+	}
+	var frame struct {
+		From common.Address    `json:"from"`
+		To   common.Address    `json:"to"`
+		Logs []json.RawMessage `json:"logs"`
+	}
+	if err := json.Unmarshal(trace.Result, &frame); err != nil {
+		t.Fatalf("failed to decode call trace: %v", err)
+	}
+	if frame.From != addr || frame.To != emitter || len(frame.Logs) != 1 {
+		t.Errorf("unexpected call frame: %+v", frame)
+	}
+	// Process a side chain block at the same height, the canonical trace must
+	// be left alone
+	if err := chain.InsertBlockWithoutSetHead(fork[0]); err != nil {
+		t.Fatalf("failed to insert side chain block: %v", err)
+	}
+	if chain.CurrentBlock().Hash() != blocks[0].Hash() {
+		t.Fatal("side chain block became canonical")
+	}
+	if rawdb.ReadCallTrace(db, blocks[0].Hash(), original.Hash()) == nil {
+		t.Error("call trace of canonical block dropped by side chain block")
+	}
+	if rawdb.ReadCallTrace(db, fork[0].Hash(), forked.Hash()) == nil {
+		t.Error("call trace of side chain block missing")
+	}
+	// Reorg to the fork and finalize it, the traces of the original chain must
+	// be pruned on the next block
+	if _, err := chain.InsertChain(fork[:2]); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if chain.CurrentBlock().Hash() != fork[1].Hash() {
+		t.Fatal("chain did not reorg")
+	}
+	chain.SetFinalized(fork[1].Header())
+	if _, err := chain.InsertChain(fork[2:]); err != nil {
+		t.Fatalf("failed to extend fork: %v", err)
+	}
+	if rawdb.ReadCallTrace(db, blocks[0].Hash(), original.Hash()) != nil {
+		t.Error("call trace of reorged block not pruned")
+	}
+	if rawdb.ReadCallTrace(db, fork[0].Hash(), forked.Hash()) == nil {
+		t.Error("call trace of finalized block pruned")
+	}
+	if numbers, _ := rawdb.ReadCallTraceBlockHashes(db, fork[2].NumberU64()); len(numbers) != 1 || numbers[0] != fork[2].NumberU64() {
+		t.Errorf("traced block index not pruned: have %v", numbers)
+	}
+}
+
+func TestCallTraceIndexPruneDepth(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	makeTx := func(b *core.BlockGen, to common.Address) *types.Transaction {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(addr),
+			To:       &to,
+			Gas:      21000,
+			GasPrice: b.BaseFee(),
+		})
+		b.AddTx(tx)
+		return tx
+	}
+	// Create a canonical chain and a side block at its first height, without
+	// ever finalizing anything as on clique or ethash chains
+	var (
+		canonical *types.Transaction
+		side      *types.Transaction
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 4, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			canonical = makeTx(b, common.HexToAddress("0xbeef"))
+		}
+	})
+	_, fork, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 1, func(i int, b *core.BlockGen) {
+		side = makeTx(b, common.HexToAddress("0xdead"))
+	})
+	db := rawdb.NewMemoryDatabase()
+	tracer, err := tracers.LiveDirectory.NewWithDB("callTraceIndex", json.RawMessage(`{"pruneDepth":2}`), db)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), genesis, nil, ethash.NewFaker(), vm.Config{Tracer: tracer}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:1]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if err := chain.InsertBlockWithoutSetHead(fork[0]); err != nil {
+		t.Fatalf("failed to insert side chain block: %v", err)
+	}
+	// Extend the canonical chain up to the prune depth, the side block must
+	// still be retained
+	if _, err := chain.InsertChain(blocks[1:2]); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if rawdb.ReadCallTrace(db, fork[0].Hash(), side.Hash()) == nil {
+		t.Error("call trace of side chain block pruned above the prune depth")
+	}
+	// Bury the side block under the prune depth, its trace must be pruned
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if rawdb.ReadCallTrace(db, fork[0].Hash(), side.Hash()) != nil {
+		t.Error("call trace of side chain block not pruned below the prune depth")
+	}
+	if rawdb.ReadCallTrace(db, blocks[0].Hash(), canonical.Hash()) == nil {
+		t.Error("call trace of canonical block pruned")
+	}
+}
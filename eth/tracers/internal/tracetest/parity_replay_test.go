@@ -0,0 +1,183 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// parityReplay is the result of the parity replay tracer.
+type parityReplay struct {
+	Output    hexutil.Bytes `json:"output"`
+	StateDiff map[common.Address]struct {
+		Balance json.RawMessage                 `json:"balance"`
+		Nonce   json.RawMessage                 `json:"nonce"`
+		Code    json.RawMessage                 `json:"code"`
+		Storage map[common.Hash]json.RawMessage `json:"storage"`
+	} `json:"stateDiff"`
+	Trace []struct {
+		Type         string `json:"type"`
+		TraceAddress []int  `json:"traceAddress"`
+		Subtraces    int    `json:"subtraces"`
+	} `json:"trace"`
+	VMTrace *parityVMTrace `json:"vmTrace"`
+}
+
+type parityVMTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []struct {
+		Pc   uint64 `json:"pc"`
+		Cost uint64 `json:"cost"`
+		Ex   struct {
+			Push  []string                      `json:"push"`
+			Mem   *struct{ Data hexutil.Bytes } `json:"mem"`
+			Store *struct{ Key, Val string }    `json:"store"`
+		} `json:"ex"`
+		Sub *parityVMTrace `json:"sub"`
+	} `json:"ops"`
+}
+
+// Tests that the parity replay tracer reports the call trace, the state diff
+// and the vm trace of a transaction calling into another contract.
+func TestParityReplayTracer(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		callee = common.HexToAddress("0xca11ee")
+		caller = common.HexToAddress("0xca11e7")
+		// Callee storing 0x2a into slot 1, caller calling the callee and
+		// returning the word 0x07 stored in memory.
+		calleeCode = []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 1, byte(vm.SSTORE), byte(vm.STOP)}
+		callerCode = append(append([]byte{
+			byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+			byte(vm.PUSH20)}, callee.Bytes()...),
+			byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+			byte(vm.PUSH1), 7, byte(vm.PUSH1), 0, byte(vm.MSTORE),
+			byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+		)
+		config = params.AllEthashProtocolChanges
+		alloc  = types.GenesisAlloc{
+			from:   {Balance: big.NewInt(params.Ether)},
+			caller: {Code: callerCode},
+			callee: {Code: calleeCode},
+		}
+	)
+	tx, _ := types.SignNewTx(key, types.LatestSigner(config), &types.LegacyTx{
+		To:       &caller,
+		Gas:      100000,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	state := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false, rawdb.HashScheme)
+	defer state.Close()
+
+	tracer, err := tracers.DefaultDirectory.New("parityReplayTracer", &tracers.Context{TxHash: tx.Hash()}, json.RawMessage(`{"trace":true,"stateDiff":true,"vmTrace":true}`))
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	state.StateDB.SetLogger(tracer.Hooks)
+
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		BaseFee:     big.NewInt(params.InitialBaseFee),
+		GasLimit:    params.GenesisGasLimit,
+	}
+	msg, err := core.TransactionToMessage(tx, types.LatestSigner(config), context.BaseFee)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction: %v", err)
+	}
+	evm := vm.NewEVM(context, core.NewEVMTxContext(msg), state.StateDB, config, vm.Config{Tracer: tracer.Hooks})
+	tracer.OnTxStart(evm.GetVMContext(), tx, msg.From)
+	res, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	state.StateDB.Finalise(true)
+	tracer.OnTxEnd(&types.Receipt{GasUsed: res.UsedGas}, nil)
+
+	blob, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve result: %v", err)
+	}
+	var result parityReplay
+	if err := json.Unmarshal(blob, &result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if want := common.LeftPadBytes([]byte{7}, 32); string(result.Output) != string(want) {
+		t.Errorf("output mismatch: have %x, want %x", result.Output, want)
+	}
+	// The flat trace must contain the outer call and the nested one
+	if len(result.Trace) != 2 {
+		t.Fatalf("trace count mismatch: have %d, want 2", len(result.Trace))
+	}
+	if result.Trace[0].Subtraces != 1 || len(result.Trace[1].TraceAddress) != 1 {
+		t.Errorf("unexpected trace structure: %+v", result.Trace)
+	}
+	// The state diff must report the storage write and the sender's nonce bump,
+	// leaving the unchanged fields of the callee out.
+	diff, ok := result.StateDiff[callee]
+	if !ok {
+		t.Fatal("callee missing from state diff")
+	}
+	if have, want := string(diff.Storage[common.BigToHash(big.NewInt(1))]), `{"*":{"from":"0x0000000000000000000000000000000000000000000000000000000000000000","to":"0x000000000000000000000000000000000000000000000000000000000000002a"}}`; have != want {
+		t.Errorf("storage diff mismatch: have %s, want %s", have, want)
+	}
+	if have := string(diff.Balance); have != `"="` {
+		t.Errorf("unchanged balance reported: %s", have)
+	}
+	if have := string(result.StateDiff[from].Nonce); have != `{"*":{"from":"0x0","to":"0x1"}}` {
+		t.Errorf("sender nonce diff mismatch: %s", have)
+	}
+	// The vm trace must nest the callee's execution into the CALL instruction
+	vmTrace := result.VMTrace
+	if vmTrace == nil || string(vmTrace.Code) != string(callerCode) {
+		t.Fatal("vm trace missing or with wrong code")
+	}
+	if len(vmTrace.Ops) != 15 {
+		t.Fatalf("op count mismatch: have %d, want 15", len(vmTrace.Ops))
+	}
+	call := vmTrace.Ops[7]
+	if call.Pc != 32 || call.Sub == nil || string(call.Sub.Code) != string(calleeCode) {
+		t.Fatalf("call op mismatch: pc %d, sub %v", call.Pc, call.Sub)
+	}
+	if have := call.Ex.Push; len(have) != 1 || have[0] != "0x1" {
+		t.Errorf("call result mismatch: have %v", have)
+	}
+	if len(call.Sub.Ops) != 4 {
+		t.Fatalf("sub op count mismatch: have %d, want 4", len(call.Sub.Ops))
+	}
+	if store := call.Sub.Ops[2].Ex.Store; store == nil || store.Key != "0x1" || store.Val != "0x2a" {
+		t.Errorf("store mismatch: have %v", store)
+	}
+	if mem := vmTrace.Ops[11].Ex.Mem; mem == nil || string(mem.Data) != string(common.LeftPadBytes([]byte{7}, 32)) {
+		t.Errorf("memory write mismatch: have %v", mem)
+	}
+}
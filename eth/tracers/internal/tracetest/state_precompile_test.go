@@ -0,0 +1,149 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+var counterAddress = common.HexToAddress("0xfe000000000000000000000000000000000000c0")
+
+// counterPrecompile increments the counter in its first storage slot.
+type counterPrecompile struct{}
+
+func (counterPrecompile) Run(stateDB vm.StateDB, blockCtx vm.BlockContext, txCtx vm.TxContext, caller common.Address, input []byte, suppliedGas uint64) ([]byte, uint64, error) {
+	if suppliedGas < 5000 {
+		return nil, 0, vm.ErrOutOfGas
+	}
+	value := stateDB.GetState(counterAddress, common.Hash{}).Big()
+	stateDB.SetState(counterAddress, common.Hash{}, common.BigToHash(value.Add(value, big.NewInt(1))))
+	return nil, suppliedGas - 5000, nil
+}
+
+func init() {
+	vm.RegisterStatePrecompile(vm.StatePrecompile{
+		Name:     "tracetest-counter",
+		Address:  counterAddress,
+		Contract: counterPrecompile{},
+	})
+}
+
+// Tests that calls into stateful precompiles show up in the call tracer and
+// that the state they modify is captured by the prestate tracer.
+func TestStatePrecompileTracing(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		// Contract forwarding the call to the counter:
+		// CALL(gas, counter, 0, 0, 0, 0, 0)
+		caller = common.HexToAddress("0xca11")
+		code   = append(append([]byte{
+			byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+			byte(vm.PUSH20)}, counterAddress.Bytes()...),
+			byte(vm.GAS), byte(vm.CALL), byte(vm.STOP),
+		)
+		activation = uint64(0)
+		config     = *params.AllEthashProtocolChanges
+	)
+	config.StatePrecompiles = map[string]*uint64{"tracetest-counter": &activation}
+
+	alloc := types.GenesisAlloc{
+		from:           {Balance: big.NewInt(params.Ether)},
+		caller:         {Code: code},
+		counterAddress: {Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(41))}},
+	}
+	tx, _ := types.SignNewTx(key, types.LatestSigner(&config), &types.LegacyTx{
+		To:       &caller,
+		Gas:      100000,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	run := func(name string, cfg string) json.RawMessage {
+		state := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false, rawdb.HashScheme)
+		defer state.Close()
+
+		tracer, err := tracers.DefaultDirectory.New(name, new(tracers.Context), json.RawMessage(cfg))
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		state.StateDB.SetLogger(tracer.Hooks)
+
+		context := vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			BaseFee:     big.NewInt(params.InitialBaseFee),
+			GasLimit:    params.GenesisGasLimit,
+		}
+		msg, err := core.TransactionToMessage(tx, types.LatestSigner(&config), context.BaseFee)
+		if err != nil {
+			t.Fatalf("failed to prepare transaction: %v", err)
+		}
+		evm := vm.NewEVM(context, core.NewEVMTxContext(msg), state.StateDB, &config, vm.Config{Tracer: tracer.Hooks})
+		tracer.OnTxStart(evm.GetVMContext(), tx, msg.From)
+		res, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+		if err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		if res.Failed() {
+			t.Fatalf("transaction failed: %v", res.Err)
+		}
+		tracer.OnTxEnd(&types.Receipt{GasUsed: res.UsedGas}, nil)
+
+		result, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve %s result: %v", name, err)
+		}
+		return result
+	}
+	// The call into the precompile must be a proper frame
+	var call callTrace
+	if err := json.Unmarshal(run("callTracer", "{}"), &call); err != nil {
+		t.Fatalf("failed to parse call trace: %v", err)
+	}
+	if len(call.Calls) != 1 {
+		t.Fatalf("call frame count mismatch: have %d, want 1", len(call.Calls))
+	}
+	if frame := call.Calls[0]; *frame.To != counterAddress || frame.Type != "CALL" || frame.GasUsed == nil || *frame.GasUsed != 5000 {
+		t.Errorf("precompile frame mismatch: to %x, type %s, gas used %v", *frame.To, frame.Type, frame.GasUsed)
+	}
+	// The storage written by the precompile must be part of the state diff
+	var diff struct {
+		Pre  map[common.Address]struct{ Storage map[common.Hash]common.Hash } `json:"pre"`
+		Post map[common.Address]struct{ Storage map[common.Hash]common.Hash } `json:"post"`
+	}
+	if err := json.Unmarshal(run("prestateTracer", `{"diffMode":true}`), &diff); err != nil {
+		t.Fatalf("failed to parse prestate trace: %v", err)
+	}
+	if have, want := diff.Pre[counterAddress].Storage[common.Hash{}], common.BigToHash(big.NewInt(41)); have != want {
+		t.Errorf("pre storage mismatch: have %x, want %x", have, want)
+	}
+	if have, want := diff.Post[counterAddress].Storage[common.Hash{}], common.BigToHash(big.NewInt(42)); have != want {
+		t.Errorf("post storage mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// supplyInfo mirrors the output of the supply live tracer.
+type supplyInfo struct {
+	Issuance *struct {
+		GenesisAlloc *hexBig `json:"genesisAlloc"`
+		Reward       *hexBig `json:"reward"`
+		Withdrawals  *hexBig `json:"withdrawals"`
+	} `json:"issuance"`
+	Burn *struct {
+		EIP1559 *hexBig `json:"1559"`
+		Blob    *hexBig `json:"blob"`
+		Misc    *hexBig `json:"misc"`
+	} `json:"burn"`
+	Number     uint64      `json:"blockNumber"`
+	Hash       common.Hash `json:"hash"`
+	ParentHash common.Hash `json:"parentHash"`
+}
+
+type hexBig = math.HexOrDecimal256
+
+func TestSupplyGenesisAndRewards(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.HexToAddress("0x1111")
+		genesis = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 1, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(addr),
+			To:       &to,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: b.BaseFee(),
+		})
+		b.AddTx(tx)
+	})
+	out := testSupplyTracer(t, genesis, ethash.NewFaker(), blocks)
+	if len(out) != 2 {
+		t.Fatalf("unexpected number of entries: have %d, want 2", len(out))
+	}
+	if have, want := (*big.Int)(out[0].Issuance.GenesisAlloc), big.NewInt(params.Ether); have.Cmp(want) != 0 {
+		t.Errorf("genesis alloc mismatch: have %v, want %v", have, want)
+	}
+	if out[1].Number != 1 || out[1].Hash != blocks[0].Hash() {
+		t.Errorf("block info mismatch: have %d/%x, want 1/%x", out[1].Number, out[1].Hash, blocks[0].Hash())
+	}
+	if have, want := (*big.Int)(out[1].Issuance.Reward), ethash.ConstantinopleBlockReward.ToBig(); have.Cmp(want) != 0 {
+		t.Errorf("block reward mismatch: have %v, want %v", have, want)
+	}
+	burn := new(big.Int).Mul(new(big.Int).SetUint64(blocks[0].GasUsed()), blocks[0].BaseFee())
+	if have := (*big.Int)(out[1].Burn.EIP1559); have.Cmp(burn) != 0 {
+		t.Errorf("eip1559 burn mismatch: have %v, want %v", have, burn)
+	}
+}
+
+func TestSupplyWithdrawals(t *testing.T) {
+	var (
+		genesis = &core.Genesis{
+			Config:     params.MergedTestChainConfig,
+			Difficulty: common.Big0,
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+		}
+		recipient = common.HexToAddress("0x2222")
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, beacon.New(ethash.NewFaker()), 1, func(i int, b *core.BlockGen) {
+		b.SetPoS()
+		b.AddWithdrawal(&types.Withdrawal{Validator: 1, Address: recipient, Amount: 1337})
+	})
+	out := testSupplyTracer(t, genesis, beacon.New(ethash.NewFaker()), blocks)
+	if len(out) != 2 {
+		t.Fatalf("unexpected number of entries: have %d, want 2", len(out))
+	}
+	if out[0].Issuance != nil {
+		t.Errorf("unexpected genesis issuance")
+	}
+	want := new(big.Int).Mul(big.NewInt(1337), big.NewInt(params.GWei))
+	if have := (*big.Int)(out[1].Issuance.Withdrawals); have.Cmp(want) != 0 {
+		t.Errorf("withdrawals mismatch: have %v, want %v", have, want)
+	}
+	if out[1].Issuance.Reward != nil {
+		t.Errorf("unexpected block reward after the merge: %v", out[1].Issuance.Reward)
+	}
+}
+
+func TestSupplySelfdestructBurn(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		// Init code which immediately self-destructs to its own address:
+		// ADDRESS SELFDESTRUCT
+		initCode = []byte{0x30, 0xff}
+		genesis  = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		value  = big.NewInt(12345)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 1, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(addr),
+			Value:    value,
+			Gas:      100000,
+			GasPrice: b.BaseFee(),
+			Data:     initCode,
+		})
+		b.AddTx(tx)
+	})
+	out := testSupplyTracer(t, genesis, ethash.NewFaker(), blocks)
+	if len(out) != 2 {
+		t.Fatalf("unexpected number of entries: have %d, want 2", len(out))
+	}
+	if out[1].Burn == nil || out[1].Burn.Misc == nil {
+		t.Fatalf("missing selfdestruct burn")
+	}
+	if have := (*big.Int)(out[1].Burn.Misc); have.Cmp(value) != 0 {
+		t.Errorf("selfdestruct burn mismatch: have %v, want %v", have, value)
+	}
+}
+
+// testSupplyTracer imports the given blocks into a fresh chain with the supply
+// tracer attached and returns the decoded entries of its output file.
+func testSupplyTracer(t *testing.T, genesis *core.Genesis, engine consensus.Engine, blocks []*types.Block) []supplyInfo {
+	t.Helper()
+
+	dir := t.TempDir()
+	tracer, err := tracers.LiveDirectory.New("supply", json.RawMessage(fmt.Sprintf(`{"path":%q}`, dir)))
+	if err != nil {
+		t.Fatalf("failed to create supply tracer: %v", err)
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), core.DefaultCacheConfigWithScheme(rawdb.HashScheme), genesis, nil, engine, vm.Config{Tracer: tracer}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	file, err := os.Open(filepath.Join(dir, "supply.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to open output file: %v", err)
+	}
+	defer file.Close()
+
+	var (
+		entries []supplyInfo
+		scanner = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		var entry supplyInfo
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to decode entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	return entries
+}
@@ -5,16 +5,22 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/ethdb"
 )
 
 type ctorFunc func(config json.RawMessage) (*tracing.Hooks, error)
 
+// dbCtorFunc is the constructor of a live tracer which persists its results
+// into the chain database.
+type dbCtorFunc func(config json.RawMessage, db ethdb.Database) (*tracing.Hooks, error)
+
 // LiveDirectory is the collection of tracers which can be used
 // during normal block import operations.
-var LiveDirectory = liveDirectory{elems: make(map[string]ctorFunc)}
+var LiveDirectory = liveDirectory{elems: make(map[string]ctorFunc), dbElems: make(map[string]dbCtorFunc)}
 
 type liveDirectory struct {
-	elems map[string]ctorFunc
+	elems   map[string]ctorFunc
+	dbElems map[string]dbCtorFunc
 }
 
 // Register registers a tracer constructor by name.
@@ -22,10 +28,28 @@ func (d *liveDirectory) Register(name string, f ctorFunc) {
 	d.elems[name] = f
 }
 
+// RegisterWithDB registers by name the constructor of a tracer which needs
+// access to the chain database.
+func (d *liveDirectory) RegisterWithDB(name string, f dbCtorFunc) {
+	d.dbElems[name] = f
+}
+
 // New instantiates a tracer by name.
 func (d *liveDirectory) New(name string, config json.RawMessage) (*tracing.Hooks, error) {
+	return d.NewWithDB(name, config, nil)
+}
+
+// NewWithDB instantiates a tracer by name, handing the chain database to it if
+// it was registered through RegisterWithDB.
+func (d *liveDirectory) NewWithDB(name string, config json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
 	if f, ok := d.elems[name]; ok {
 		return f(config)
 	}
+	if f, ok := d.dbElems[name]; ok {
+		if db == nil {
+			return nil, errors.New("tracer requires a database")
+		}
+		return f(config, db)
+	}
 	return nil, errors.New("not found")
 }
@@ -0,0 +1,221 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+
+	// Force-load the native tracers, the call tracer is instantiated by name.
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+func init() {
+	tracers.LiveDirectory.RegisterWithDB("callTraceIndex", newCallTraceIndex)
+}
+
+// defaultCallTracePruneDepth is the default depth below the processed block at
+// which the traces of non-canonical blocks are pruned if the chain doesn't
+// finalize them earlier.
+const defaultCallTracePruneDepth = 128
+
+type callTraceIndexConfig struct {
+	TracerConfig json.RawMessage `json:"tracerConfig"` // Config of the call tracer producing the stored frames
+	PruneDepth   uint64          `json:"pruneDepth"`   // Depth below the processed block at which reorged traces are pruned
+}
+
+// callTraceIndex is a live tracer which runs the call tracer for every
+// transaction imported into the chain and persists the resulting frames into
+// the database, keyed by block and transaction hash. Traces of blocks which did
+// not make it into the canonical chain are pruned once their height finalizes,
+// or once buried under the prune depth on chains without finality, e.g. clique
+// and ethash. The pruned traces are served by re-execution if a reorg deeper
+// than that brings their blocks back. The call tracer runs with its default
+// config unless configured otherwise.
+type callTraceIndex struct {
+	db     ethdb.Database
+	config json.RawMessage
+	depth  uint64 // Depth below the processed block at which reorged traces are pruned
+	pruned uint64 // Number of the block the index was last pruned up to
+
+	batch    ethdb.Batch     // Traces of the block being processed, nil outside of blocks
+	block    *types.Block    // Block being processed
+	txHashes []common.Hash   // Transactions traced in the current block
+	tracer   *tracers.Tracer // Call tracer of the transaction being processed
+	txHash   common.Hash     // Hash of the transaction being processed
+	txIndex  int             // Index of the next transaction within the block
+}
+
+func newCallTraceIndex(cfg json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
+	if db == nil {
+		return nil, errors.New("call trace index requires a database")
+	}
+	var config callTraceIndexConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %v", err)
+		}
+	}
+	// Ensure the tracer config is valid upfront rather than failing on
+	// every transaction.
+	if _, err := tracers.DefaultDirectory.New("callTracer", new(tracers.Context), config.TracerConfig); err != nil {
+		return nil, fmt.Errorf("invalid call tracer config: %v", err)
+	}
+	if config.PruneDepth == 0 {
+		config.PruneDepth = defaultCallTracePruneDepth
+	}
+	t := &callTraceIndex{
+		db:     db,
+		config: config.TracerConfig,
+		depth:  config.PruneDepth,
+	}
+	return &tracing.Hooks{
+		OnBlockStart: t.OnBlockStart,
+		OnBlockEnd:   t.OnBlockEnd,
+		OnTxStart:    t.OnTxStart,
+		OnTxEnd:      t.OnTxEnd,
+		OnEnter:      t.OnEnter,
+		OnExit:       t.OnExit,
+		OnLog:        t.OnLog,
+	}, nil
+}
+
+func (t *callTraceIndex) OnBlockStart(ev tracing.BlockEvent) {
+	// Prune the traces of the reorged blocks up to the finalized block, or the
+	// block buried under the prune depth if that's higher
+	var limit uint64
+	if ev.Finalized != nil {
+		limit = ev.Finalized.Number.Uint64()
+	}
+	if number := ev.Block.NumberU64(); number > t.depth && number-t.depth > limit {
+		limit = number - t.depth
+	}
+	if limit > t.pruned {
+		t.prune(limit)
+	}
+
+	t.batch = t.db.NewBatch()
+	t.block = ev.Block
+	t.txHashes = t.txHashes[:0]
+	t.txIndex = 0
+}
+
+func (t *callTraceIndex) OnBlockEnd(err error) {
+	defer func() {
+		t.batch, t.block, t.tracer = nil, nil, nil
+	}()
+	// Traces of blocks which failed to be processed are discarded.
+	if err != nil || t.batch == nil {
+		return
+	}
+	rawdb.WriteCallTraceBlock(t.batch, t.block.NumberU64(), t.block.Hash(), &rawdb.CallTraceBlock{
+		TxHashes: t.txHashes,
+	})
+	if err := t.batch.Write(); err != nil {
+		log.Error("Failed to write call traces", "number", t.block.NumberU64(), "hash", t.block.Hash(), "err", err)
+	}
+}
+
+func (t *callTraceIndex) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	// Transactions executed outside of block processing are not indexed.
+	if t.batch == nil {
+		return
+	}
+	tracer, err := tracers.DefaultDirectory.New("callTracer", &tracers.Context{
+		BlockHash:   t.block.Hash(),
+		BlockNumber: t.block.Number(),
+		TxIndex:     t.txIndex,
+		TxHash:      tx.Hash(),
+	}, t.config)
+	if err != nil {
+		log.Error("Failed to create call tracer", "tx", tx.Hash(), "err", err)
+		return
+	}
+	t.tracer, t.txHash = tracer, tx.Hash()
+	t.txIndex++
+	if tracer.OnTxStart != nil {
+		tracer.OnTxStart(env, tx, from)
+	}
+}
+
+func (t *callTraceIndex) OnTxEnd(receipt *types.Receipt, err error) {
+	if t.tracer == nil {
+		return
+	}
+	tracer := t.tracer
+	t.tracer = nil
+
+	if tracer.OnTxEnd != nil {
+		tracer.OnTxEnd(receipt, err)
+	}
+	// Failed transactions invalidate the whole block, don't bother storing them.
+	if err != nil {
+		return
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		log.Error("Failed to retrieve call trace", "tx", t.txHash, "err", err)
+		return
+	}
+	rawdb.WriteCallTrace(t.batch, t.block.Hash(), t.txHash, &rawdb.CallTrace{
+		Config: t.config,
+		Result: res,
+	})
+	t.txHashes = append(t.txHashes, t.txHash)
+}
+
+func (t *callTraceIndex) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.tracer != nil && t.tracer.OnEnter != nil {
+		t.tracer.OnEnter(depth, typ, from, to, input, gas, value)
+	}
+}
+
+func (t *callTraceIndex) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.tracer != nil && t.tracer.OnExit != nil {
+		t.tracer.OnExit(depth, output, gasUsed, err, reverted)
+	}
+}
+
+func (t *callTraceIndex) OnLog(l *types.Log) {
+	if t.tracer != nil && t.tracer.OnLog != nil {
+		t.tracer.OnLog(l)
+	}
+}
+
+// prune deletes the traces of all blocks at or below the given height which are
+// not part of the canonical chain, and drops the index entries of the canonical
+// ones as they are not expected to be reorged away anymore.
+func (t *callTraceIndex) prune(limit uint64) {
+	var (
+		numbers, hashes = rawdb.ReadCallTraceBlockHashes(t.db, limit)
+		batch           = t.db.NewBatch()
+		pruned          int
+	)
+	for i, number := range numbers {
+		if hashes[i] != rawdb.ReadCanonicalHash(t.db, number) {
+			if block := rawdb.ReadCallTraceBlock(t.db, number, hashes[i]); block != nil {
+				for _, hash := range block.TxHashes {
+					rawdb.DeleteCallTrace(batch, hashes[i], hash)
+				}
+			}
+			pruned++
+		}
+		rawdb.DeleteCallTraceBlock(batch, number, hashes[i])
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to prune call traces", "limit", limit, "err", err)
+		return
+	}
+	t.pruned = limit
+	if pruned > 0 {
+		log.Debug("Pruned call traces", "limit", limit, "blocks", pruned)
+	}
+}
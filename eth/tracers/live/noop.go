@@ -17,8 +17,7 @@ func init() {
 
 // noop is a no-op live tracer. It's there to
 // catch changes in the tracing interface, as well as
-// for testing live tracing performance. Can be removed
-// as soon as we have a real live tracer.
+// for testing live tracing performance.
 type noop struct{}
 
 func newNoopTracer(_ json.RawMessage) (*tracing.Hooks, error) {
@@ -56,7 +56,11 @@ func newMuxTracer(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, e
 		names = append(names, k)
 	}
 
-	t := &muxTracer{names: names, tracers: objects}
+	return newMuxTracerFrom(&muxTracer{names: names, tracers: objects})
+}
+
+// newMuxTracerFrom wraps an assembled mux tracer into a tracer.
+func newMuxTracerFrom(t *muxTracer) (*tracers.Tracer, error) {
 	return &tracers.Tracer{
 		Hooks: &tracing.Hooks{
 			OnTxStart:       t.OnTxStart,
@@ -90,9 +90,11 @@ func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Trac
 	}
 	return &tracers.Tracer{
 		Hooks: &tracing.Hooks{
-			OnTxStart: t.OnTxStart,
-			OnTxEnd:   t.OnTxEnd,
-			OnOpcode:  t.OnOpcode,
+			OnTxStart:       t.OnTxStart,
+			OnTxEnd:         t.OnTxEnd,
+			OnOpcode:        t.OnOpcode,
+			OnBalanceChange: t.OnBalanceChange,
+			OnStorageChange: t.OnStorageChange,
 		},
 		GetResult: t.GetResult,
 		Stop:      t.Stop,
@@ -146,6 +148,26 @@ func (t *prestateTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scop
 	}
 }
 
+// OnBalanceChange records the account whose balance is about to change. Most
+// accounts are already captured via the executed opcodes, this covers the
+// ones modified by stateful precompiles.
+func (t *prestateTracer) OnBalanceChange(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+	if t.env == nil || t.interrupt.Load() {
+		return
+	}
+	t.lookupAccount(addr)
+}
+
+// OnStorageChange records the storage slot which is about to change. Like
+// OnBalanceChange, it captures the slots written by stateful precompiles.
+func (t *prestateTracer) OnStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	if t.env == nil || t.interrupt.Load() {
+		return
+	}
+	t.lookupAccount(addr)
+	t.lookupStorage(addr, slot)
+}
+
 func (t *prestateTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
 	t.env = env
 	if tx.To() == nil {
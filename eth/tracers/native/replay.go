@@ -0,0 +1,124 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("parityReplayTracer", newParityReplayTracer, false)
+}
+
+// parityReplayResult is the result of a parity trace_replay* or trace_call*
+// request. The trace types which weren't requested are reported as null.
+type parityReplayResult struct {
+	Output          hexutil.Bytes   `json:"output"`
+	StateDiff       json.RawMessage `json:"stateDiff"`
+	Trace           json.RawMessage `json:"trace"`
+	VMTrace         json.RawMessage `json:"vmTrace"`
+	TransactionHash *common.Hash    `json:"transactionHash,omitempty"`
+}
+
+type parityReplayTracerConfig struct {
+	Trace     bool `json:"trace"`     // If true, the flat call trace is reported
+	StateDiff bool `json:"stateDiff"` // If true, the state diff is reported
+	VMTrace   bool `json:"vmTrace"`   // If true, the vm trace is reported
+}
+
+// parityReplayTracer runs the tracers behind the requested parity trace types
+// in one go and reports their results together with the call output.
+type parityReplayTracer struct {
+	*muxTracer
+	ctx    *tracers.Context
+	output []byte
+}
+
+// newParityReplayTracer returns a new parityReplayTracer.
+func newParityReplayTracer(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	var config parityReplayTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	mux := new(muxTracer)
+	for _, sub := range []struct {
+		enabled bool
+		name    string
+		tracer  string
+		config  json.RawMessage
+	}{
+		{config.Trace, "trace", "flatCallTracer", json.RawMessage(`{"convertParityErrors":true}`)},
+		{config.StateDiff, "stateDiff", "stateDiffTracer", nil},
+		{config.VMTrace, "vmTrace", "vmTracer", nil},
+	} {
+		if !sub.enabled {
+			continue
+		}
+		t, err := tracers.DefaultDirectory.New(sub.tracer, ctx, sub.config)
+		if err != nil {
+			return nil, err
+		}
+		mux.names = append(mux.names, sub.name)
+		mux.tracers = append(mux.tracers, t)
+	}
+	t := &parityReplayTracer{muxTracer: mux, ctx: ctx}
+
+	tracer, err := newMuxTracerFrom(mux)
+	if err != nil {
+		return nil, err
+	}
+	tracer.OnExit = t.OnExit
+	tracer.GetResult = t.GetResult
+	return tracer, nil
+}
+
+// OnExit records the output of the top-level call.
+func (t *parityReplayTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if depth == 0 {
+		t.output = common.CopyBytes(output)
+	}
+	t.muxTracer.OnExit(depth, output, gasUsed, err, reverted)
+}
+
+// GetResult returns the json-encoded replay result.
+func (t *parityReplayTracer) GetResult() (json.RawMessage, error) {
+	result := parityReplayResult{Output: t.output}
+	if t.ctx != nil && t.ctx.TxHash != (common.Hash{}) {
+		result.TransactionHash = &t.ctx.TxHash
+	}
+	for i, tt := range t.tracers {
+		r, err := tt.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		switch t.names[i] {
+		case "trace":
+			result.Trace = r
+		case "stateDiff":
+			result.StateDiff = r
+		case "vmTrace":
+			result.VMTrace = r
+		}
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("stateDiffTracer", newStateDiffTracer, false)
+}
+
+// diff is a single value change in the parity state diff format. It encodes
+// as "=" if the value is unchanged, {"+": new} if it was born, {"-": old} if it
+// died and {"*": {"from": old, "to": new}} if it was modified.
+type diff struct {
+	from, to interface{}
+	born     bool
+	died     bool
+}
+
+func (d *diff) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.born:
+		return json.Marshal(map[string]interface{}{"+": d.to})
+	case d.died:
+		return json.Marshal(map[string]interface{}{"-": d.from})
+	case d.from == nil && d.to == nil:
+		return json.Marshal("=")
+	default:
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.from, "to": d.to}})
+	}
+}
+
+// accountDiff is the parity state diff of a single account.
+type accountDiff struct {
+	Balance *diff                 `json:"balance"`
+	Code    *diff                 `json:"code"`
+	Nonce   *diff                 `json:"nonce"`
+	Storage map[common.Hash]*diff `json:"storage"`
+}
+
+// stateDiffTracer reports the state changes of a transaction in the format of
+// the parity stateDiff trace. It relies on the prestate tracer to collect the
+// accounts and slots touched by the transaction.
+type stateDiffTracer struct {
+	*prestateTracer
+	diff map[common.Address]*accountDiff
+}
+
+func newStateDiffTracer(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	t := newStateDiffTracerObject()
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart:       t.OnTxStart,
+			OnTxEnd:         t.OnTxEnd,
+			OnOpcode:        t.OnOpcode,
+			OnBalanceChange: t.OnBalanceChange,
+			OnStorageChange: t.OnStorageChange,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+func newStateDiffTracerObject() *stateDiffTracer {
+	return &stateDiffTracer{
+		prestateTracer: &prestateTracer{
+			pre:     stateMap{},
+			post:    stateMap{},
+			created: make(map[common.Address]bool),
+			deleted: make(map[common.Address]bool),
+		},
+		diff: make(map[common.Address]*accountDiff),
+	}
+}
+
+// OnTxEnd compares the collected prestate with the state after the execution
+// of the transaction. The state is finalised at this point, so destructed
+// accounts don't exist anymore.
+func (t *stateDiffTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if err != nil {
+		return
+	}
+	for addr, pre := range t.pre {
+		var (
+			state   = t.env.StateDB
+			existed = pre.exists()
+			exists  = state.Exist(addr)
+		)
+		switch {
+		case !existed && !exists:
+			continue
+
+		case !existed:
+			acc := &accountDiff{
+				Balance: &diff{to: (*hexutil.Big)(state.GetBalance(addr).ToBig()), born: true},
+				Code:    &diff{to: hexutil.Bytes(state.GetCode(addr)), born: true},
+				Nonce:   &diff{to: hexutil.Uint64(state.GetNonce(addr)), born: true},
+				Storage: make(map[common.Hash]*diff),
+			}
+			for key := range pre.Storage {
+				if val := state.GetState(addr, key); val != (common.Hash{}) {
+					acc.Storage[key] = &diff{to: val, born: true}
+				}
+			}
+			t.diff[addr] = acc
+
+		case !exists:
+			acc := &accountDiff{
+				Balance: &diff{from: (*hexutil.Big)(pre.Balance), died: true},
+				Code:    &diff{from: hexutil.Bytes(pre.Code), died: true},
+				Nonce:   &diff{from: hexutil.Uint64(pre.Nonce), died: true},
+				Storage: make(map[common.Hash]*diff),
+			}
+			for key, val := range pre.Storage {
+				if val != (common.Hash{}) {
+					acc.Storage[key] = &diff{from: val, died: true}
+				}
+			}
+			t.diff[addr] = acc
+
+		default:
+			var (
+				modified bool
+				acc      = &accountDiff{
+					Balance: new(diff),
+					Code:    new(diff),
+					Nonce:   new(diff),
+					Storage: make(map[common.Hash]*diff),
+				}
+			)
+			if balance := state.GetBalance(addr).ToBig(); balance.Cmp(pre.Balance) != 0 {
+				acc.Balance.from, acc.Balance.to = (*hexutil.Big)(pre.Balance), (*hexutil.Big)(balance)
+				modified = true
+			}
+			if code := state.GetCode(addr); !bytes.Equal(code, pre.Code) {
+				acc.Code.from, acc.Code.to = hexutil.Bytes(pre.Code), hexutil.Bytes(code)
+				modified = true
+			}
+			if nonce := state.GetNonce(addr); nonce != pre.Nonce {
+				acc.Nonce.from, acc.Nonce.to = hexutil.Uint64(pre.Nonce), hexutil.Uint64(nonce)
+				modified = true
+			}
+			for key, val := range pre.Storage {
+				if newVal := state.GetState(addr, key); newVal != val {
+					acc.Storage[key] = &diff{from: val, to: newVal}
+					modified = true
+				}
+			}
+			if modified {
+				t.diff[addr] = acc
+			}
+		}
+	}
+}
+
+// GetResult returns the json-encoded state diff, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *stateDiffTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.diff)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
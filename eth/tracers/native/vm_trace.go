@@ -0,0 +1,270 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/internal"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTrace is the parity vmTrace of a single call frame.
+type vmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is a single executed instruction. Sub holds the trace of the call
+// frame the instruction entered, if any.
+type vmTraceOp struct {
+	Cost uint64     `json:"cost"`
+	Ex   *vmTraceEx `json:"ex"`
+	Pc   uint64     `json:"pc"`
+	Sub  *vmTrace   `json:"sub"`
+}
+
+// vmTraceEx holds the effects of an executed instruction.
+type vmTraceEx struct {
+	Mem   *vmTraceMem    `json:"mem"`
+	Push  []hexutil.U256 `json:"push"`
+	Store *vmTraceStore  `json:"store"`
+	Used  uint64         `json:"used"`
+}
+
+type vmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+type vmTraceStore struct {
+	Key hexutil.U256 `json:"key"`
+	Val hexutil.U256 `json:"val"`
+}
+
+// vmTraceFrame tracks the call frame being traced and its last instruction,
+// whose effects are only known once the next instruction is executed.
+type vmTraceFrame struct {
+	trace *vmTrace
+	last  *vmTraceOp
+
+	pushes          int    // Number of stack items pushed by the last instruction
+	memOff, memSize uint64 // Memory region written by the last instruction
+	memWritten      bool
+}
+
+// vmTracer reports every executed instruction in the format of the parity
+// vmTrace.
+type vmTracer struct {
+	env       *tracing.VMContext
+	frames    []*vmTraceFrame
+	root      *vmTrace
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+func newVMTracer(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	t := new(vmTracer)
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnEnter:   t.OnEnter,
+			OnExit:    t.OnExit,
+			OnOpcode:  t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+func (t *vmTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.env = env
+}
+
+// OnEnter opens a new frame, nested into the instruction which entered it.
+func (t *vmTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	trace := &vmTrace{Ops: []*vmTraceOp{}}
+	switch vm.OpCode(typ) {
+	case vm.CREATE, vm.CREATE2:
+		trace.Code = common.CopyBytes(input)
+	case vm.SELFDESTRUCT:
+		// Selfdestructs are reported as a frame by the EVM, but don't execute code.
+		return
+	default:
+		code := t.env.StateDB.GetCode(to)
+		if target, ok := types.ParseDelegation(code); ok {
+			code = t.env.StateDB.GetCode(target)
+		}
+		trace.Code = code
+	}
+	if depth == 0 {
+		t.root = trace
+	} else if len(t.frames) > 0 {
+		if parent := t.frames[len(t.frames)-1]; parent.last != nil {
+			parent.last.Sub = trace
+		}
+	}
+	t.frames = append(t.frames, &vmTraceFrame{trace: trace})
+}
+
+// OnExit closes the current frame.
+func (t *vmTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	// Frames are only opened for the scopes executing code, skip the exit of
+	// the selfdestruct pseudo-frame.
+	if depth != len(t.frames)-1 {
+		return
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+// OnOpcode records an instruction, finalizing the effects of the previous one
+// in the same frame.
+func (t *vmTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.finalize(frame, gas, scope)
+
+	var (
+		op    = vm.OpCode(opcode)
+		stack = scope.StackData()
+		entry = &vmTraceOp{
+			Cost: cost,
+			Pc:   pc,
+			Ex:   &vmTraceEx{Push: []hexutil.U256{}},
+		}
+	)
+	if gas >= cost {
+		entry.Ex.Used = gas - cost
+	}
+	if op == vm.SSTORE && len(stack) >= 2 {
+		entry.Ex.Store = &vmTraceStore{
+			Key: hexutil.U256(stack[len(stack)-1]),
+			Val: hexutil.U256(stack[len(stack)-2]),
+		}
+	}
+	frame.trace.Ops = append(frame.trace.Ops, entry)
+	frame.last = entry
+	frame.pushes = stackPushes(op)
+	frame.memOff, frame.memSize, frame.memWritten = memoryWrite(op, stack)
+}
+
+// finalize fills in the effects of the last instruction of the frame using
+// the state of the frame before the next instruction.
+func (t *vmTracer) finalize(frame *vmTraceFrame, gas uint64, scope tracing.OpContext) {
+	if frame.last == nil {
+		return
+	}
+	ex := frame.last.Ex
+	ex.Used = gas
+
+	stack := scope.StackData()
+	if n := min(frame.pushes, len(stack)); n > 0 {
+		for _, item := range stack[len(stack)-n:] {
+			ex.Push = append(ex.Push, hexutil.U256(item))
+		}
+	}
+	if frame.memWritten && frame.memSize > 0 {
+		data, err := internal.GetMemoryCopyPadded(scope.MemoryData(), int64(frame.memOff), int64(frame.memSize))
+		if err == nil {
+			ex.Mem = &vmTraceMem{Data: data, Off: frame.memOff}
+		}
+	}
+	frame.last = nil
+}
+
+// GetResult returns the json-encoded vm trace, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	if t.root == nil {
+		return nil, errors.New("no call frame traced")
+	}
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// stackPushes returns the number of stack items reported as pushed by the
+// instruction. Like parity, DUP and SWAP report all the items they touch.
+func stackPushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.TSTORE, vm.JUMP, vm.JUMPI,
+		vm.JUMPDEST, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT, vm.CALLDATACOPY,
+		vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		return 0
+	}
+	return 1
+}
+
+// memoryWrite returns the memory region written by the instruction, given the
+// stack before its execution.
+func memoryWrite(op vm.OpCode, stack []uint256.Int) (offset, size uint64, ok bool) {
+	back := func(n int) uint64 {
+		return stack[len(stack)-1-n].Uint64()
+	}
+	switch {
+	case op == vm.MSTORE && len(stack) >= 1:
+		return back(0), 32, true
+	case op == vm.MSTORE8 && len(stack) >= 1:
+		return back(0), 1, true
+	case (op == vm.CALLDATACOPY || op == vm.CODECOPY || op == vm.RETURNDATACOPY || op == vm.MCOPY) && len(stack) >= 3:
+		return back(0), back(2), true
+	case op == vm.EXTCODECOPY && len(stack) >= 4:
+		return back(1), back(3), true
+	case (op == vm.CALL || op == vm.CALLCODE) && len(stack) >= 7:
+		return back(5), back(6), true
+	case (op == vm.DELEGATECALL || op == vm.STATICCALL) && len(stack) >= 6:
+		return back(4), back(5), true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,436 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// flatCallTracer is the native tracer producing parity style call traces.
+	flatCallTracer = "flatCallTracer"
+
+	// parityReplayTracer is the native tracer producing the results of the
+	// parity replay and call methods.
+	parityReplayTracer = "parityReplayTracer"
+
+	// maxTraceFilterBlocks is the maximum number of blocks a single trace_filter
+	// request is allowed to re-execute.
+	maxTraceFilterBlocks = 1000
+)
+
+var (
+	errUnknownTraceType = errors.New("unknown trace type")
+	errNoTraceType      = errors.New("no trace type requested")
+)
+
+// TraceAPI provides the parity (OpenEthereum) compatible tracing methods.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the parity compatible tracing
+// methods of the Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// flatTraceConfig returns the trace config running the flat call tracer with
+// the errors converted to their parity representation.
+func flatTraceConfig() *TraceConfig {
+	tracer := flatCallTracer
+	return &TraceConfig{
+		Tracer:       &tracer,
+		TracerConfig: json.RawMessage(`{"convertParityErrors":true}`),
+	}
+}
+
+// replayTraceConfig returns the trace config running the replay tracer with
+// the requested parity trace types.
+func replayTraceConfig(traceTypes []string) (*TraceConfig, error) {
+	if len(traceTypes) == 0 {
+		return nil, errNoTraceType
+	}
+	config := make(map[string]bool)
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace", "stateDiff", "vmTrace":
+			config[typ] = true
+		default:
+			return nil, fmt.Errorf("%w: %s", errUnknownTraceType, typ)
+		}
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tracer := parityReplayTracer
+	return &TraceConfig{Tracer: &tracer, TracerConfig: blob}, nil
+}
+
+// flattenTraces concatenates the flat call traces of the transactions of a
+// block into a single list.
+func flattenTraces(results []*txTraceResult) ([]json.RawMessage, error) {
+	traces := make([]json.RawMessage, 0, len(results))
+	for _, result := range results {
+		raw, ok := result.Result.(json.RawMessage)
+		if !ok {
+			return nil, fmt.Errorf("unexpected trace result for transaction %s", result.TxHash.Hex())
+		}
+		var frames []json.RawMessage
+		if err := json.Unmarshal(raw, &frames); err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// rewardAction is the action of a parity reward trace.
+type rewardAction struct {
+	Author     common.Address `json:"author"`
+	RewardType string         `json:"rewardType"`
+	Value      *hexutil.Big   `json:"value"`
+}
+
+// rewardTrace is a parity trace crediting a mining reward.
+type rewardTrace struct {
+	Action       rewardAction `json:"action"`
+	BlockHash    common.Hash  `json:"blockHash"`
+	BlockNumber  uint64       `json:"blockNumber"`
+	Result       *struct{}    `json:"result"`
+	Subtraces    int          `json:"subtraces"`
+	TraceAddress []int        `json:"traceAddress"`
+	Type         string       `json:"type"`
+}
+
+// rewardTraces returns the parity reward traces of the block, crediting its
+// miner and the miners of its uncles. Only proof-of-work blocks are rewarded.
+func (api *TraceAPI) rewardTraces(block *types.Block) ([]json.RawMessage, error) {
+	engine := api.api.backend.Engine()
+	if b, ok := engine.(*beacon.Beacon); ok {
+		if b.IsPoSHeader(block.Header()) {
+			return nil, nil
+		}
+		engine = b.InnerEngine()
+	}
+	if _, ok := engine.(*ethash.Ethash); !ok {
+		return nil, nil
+	}
+	reward, uncleRewards := ethash.BlockRewards(api.api.backend.ChainConfig(), block.Header(), block.Uncles())
+
+	traces := make([]json.RawMessage, 0, len(uncleRewards)+1)
+	add := func(author common.Address, typ string, value *big.Int) error {
+		blob, err := json.Marshal(&rewardTrace{
+			Action:       rewardAction{Author: author, RewardType: typ, Value: (*hexutil.Big)(value)},
+			BlockHash:    block.Hash(),
+			BlockNumber:  block.NumberU64(),
+			TraceAddress: []int{},
+			Type:         "reward",
+		})
+		if err != nil {
+			return err
+		}
+		traces = append(traces, blob)
+		return nil
+	}
+	if err := add(block.Coinbase(), "block", reward.ToBig()); err != nil {
+		return nil, err
+	}
+	for i, uncle := range block.Uncles() {
+		if err := add(uncle.Coinbase, "uncle", uncleRewards[i].ToBig()); err != nil {
+			return nil, err
+		}
+	}
+	return traces, nil
+}
+
+// blockTraces returns the flat call traces of all the transactions in the
+// block, followed by its reward traces.
+func (api *TraceAPI) blockTraces(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	results, err := api.api.traceBlock(ctx, block, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces, err := flattenTraces(results)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := api.rewardTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	return append(traces, rewards...), nil
+}
+
+// Block returns the flat call traces of all the transactions in the block and
+// the traces of the mining rewards.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.blockTraces(ctx, block)
+}
+
+// Transaction returns the flat call traces of the transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) (interface{}, error) {
+	return api.api.TraceTransaction(ctx, hash, flatTraceConfig())
+}
+
+// ReplayBlockTransactions replays all the transactions in the block and returns
+// the requested trace types for each of them.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]interface{}, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	replays := make([]interface{}, len(results))
+	for i, result := range results {
+		replays[i] = result.Result
+	}
+	return replays, nil
+}
+
+// Call executes the given call on top of the given block and returns the
+// requested trace types. The latest block is used if none is specified.
+func (api *TraceAPI) Call(ctx context.Context, args ethapi.TransactionArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (interface{}, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	return api.api.TraceCall(ctx, args, *blockNrOrHash, &TraceCallConfig{TraceConfig: *config})
+}
+
+// TraceCallRequest is a single call of a trace_callMany request, encoded as a
+// [call, traceTypes] tuple.
+type TraceCallRequest struct {
+	Args       ethapi.TransactionArgs
+	TraceTypes []string
+}
+
+// UnmarshalJSON decodes the [call, traceTypes] tuple.
+func (r *TraceCallRequest) UnmarshalJSON(input []byte) error {
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(input, &tuple); err != nil {
+		return err
+	}
+	if len(tuple) != 2 {
+		return fmt.Errorf("invalid call request: expected [call, traceTypes], got %d items", len(tuple))
+	}
+	if err := json.Unmarshal(tuple[0], &r.Args); err != nil {
+		return err
+	}
+	return json.Unmarshal(tuple[1], &r.TraceTypes)
+}
+
+// CallMany executes the given calls one after the other on top of the given
+// block, each call seeing the state changes of the previous ones, and returns
+// the requested trace types of every call. The latest block is used if none is
+// specified.
+func (api *TraceAPI) CallMany(ctx context.Context, calls []TraceCallRequest, blockNrOrHash *rpc.BlockNumberOrHash) ([]interface{}, error) {
+	configs := make([]*TraceConfig, len(calls))
+	for i, call := range calls {
+		config, err := replayTraceConfig(call.TraceTypes)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		configs[i] = config
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	vmctx, statedb, release, err := api.api.callState(ctx, *blockNrOrHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		backend = api.api.backend
+		results = make([]interface{}, len(calls))
+	)
+	for i, call := range calls {
+		args := call.Args
+		if err := args.CallDefaults(backend.RPCGasCap(), vmctx.BaseFee, backend.ChainConfig().ChainID); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		txctx := &Context{TxIndex: i}
+		res, err := api.api.traceTx(ctx, args.ToTransaction(), args.ToMessage(vmctx.BaseFee), txctx, vmctx, statedb, configs[i])
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		results[i] = res
+	}
+	return results, nil
+}
+
+// TraceFilterArgs are the criteria of a trace_filter request. A trace matches
+// if its sender is in FromAddress and its receiver is in ToAddress, an empty
+// list matching any address.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *hexutil.Uint64  `json:"after"`
+	Count       *hexutil.Uint64  `json:"count"`
+}
+
+// filterTrace holds the fields of a flat call trace needed for filtering.
+type filterTrace struct {
+	Action struct {
+		From           *common.Address `json:"from"`
+		To             *common.Address `json:"to"`
+		SelfDestructed *common.Address `json:"address"`
+		RefundAddress  *common.Address `json:"refundAddress"`
+		Author         *common.Address `json:"author"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// matches returns whether the trace satisfies the address criteria.
+func (f *TraceFilterArgs) matches(trace *filterTrace) bool {
+	match := func(addrs []common.Address, candidates ...*common.Address) bool {
+		if len(addrs) == 0 {
+			return true
+		}
+		for _, candidate := range candidates {
+			if candidate != nil && slices.Contains(addrs, *candidate) {
+				return true
+			}
+		}
+		return false
+	}
+	// Created contracts are the receivers of creations, refund addresses the
+	// receivers of selfdestructs and authors the receivers of rewards.
+	var created *common.Address
+	if trace.Result != nil {
+		created = trace.Result.Address
+	}
+	return match(f.FromAddress, trace.Action.From, trace.Action.SelfDestructed) &&
+		match(f.ToAddress, trace.Action.To, created, trace.Action.RefundAddress, trace.Action.Author)
+}
+
+// resolveBlockNumber returns the number of the given block, defaulting to the
+// latest block.
+func (api *TraceAPI) resolveBlockNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number != nil && *number >= 0 {
+		return uint64(*number), nil
+	}
+	target := rpc.LatestBlockNumber
+	if number != nil {
+		target = *number
+	}
+	if target == rpc.EarliestBlockNumber {
+		return 0, nil
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, target)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %s not found", target)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// Filter returns the flat call and reward traces in the given block range
+// matching the filter criteria.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.resolveBlockNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveBlockNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: from %d > to %d", from, to)
+	}
+	if to-from >= maxTraceFilterBlocks {
+		return nil, fmt.Errorf("block range too large: %d blocks, maximum is %d", to-from+1, maxTraceFilterBlocks)
+	}
+	// The genesis block has no transactions to trace
+	if from == 0 {
+		from = 1
+	}
+	var (
+		skipped uint64
+		traces  = []json.RawMessage{}
+	)
+	if args.Count != nil && *args.Count == 0 {
+		return traces, nil
+	}
+	for number := from; number <= to; number++ {
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		frames, err := api.blockTraces(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, frame := range frames {
+			var trace filterTrace
+			if err := json.Unmarshal(frame, &trace); err != nil {
+				return nil, err
+			}
+			if !args.matches(&trace) {
+				continue
+			}
+			if args.After != nil && skipped < uint64(*args.After) {
+				skipped++
+				continue
+			}
+			traces = append(traces, frame)
+			if args.Count != nil && uint64(len(traces)) >= uint64(*args.Count) {
+				return traces, nil
+			}
+		}
+	}
+	return traces, nil
+}
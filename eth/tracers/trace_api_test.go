@@ -0,0 +1,168 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestReplayTraceConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := replayTraceConfig([]string{"vmTrace", "trace"})
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	if *config.Tracer != parityReplayTracer {
+		t.Errorf("wrong tracer: have %s", *config.Tracer)
+	}
+	if !equalTracerConfig(config.TracerConfig, json.RawMessage(`{"trace":true,"vmTrace":true}`)) {
+		t.Errorf("wrong tracer config: have %s", config.TracerConfig)
+	}
+	if _, err := replayTraceConfig(nil); !errors.Is(err, errNoTraceType) {
+		t.Errorf("wrong error for missing trace types: %v", err)
+	}
+	if _, err := replayTraceConfig([]string{"trace", "foo"}); !errors.Is(err, errUnknownTraceType) {
+		t.Errorf("wrong error for unknown trace type: %v", err)
+	}
+}
+
+func TestTraceCallRequestDecoding(t *testing.T) {
+	t.Parallel()
+
+	var calls []TraceCallRequest
+	input := `[[{"from":"0x0000000000000000000000000000000000000001","to":"0x0000000000000000000000000000000000000002"},["trace","stateDiff"]]]`
+	if err := json.Unmarshal([]byte(input), &calls); err != nil {
+		t.Fatalf("failed to decode calls: %v", err)
+	}
+	if len(calls) != 1 || len(calls[0].TraceTypes) != 2 || *calls[0].Args.To != common.HexToAddress("0x02") {
+		t.Errorf("unexpected decoded calls: %+v", calls)
+	}
+	if err := json.Unmarshal([]byte(`[[{}]]`), &calls); err == nil {
+		t.Error("call without trace types accepted")
+	}
+}
+
+func TestTraceFilterMatching(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a = common.HexToAddress("0xa")
+		b = common.HexToAddress("0xb")
+		c = common.HexToAddress("0xc")
+	)
+	decode := func(blob string) *filterTrace {
+		var trace filterTrace
+		if err := json.Unmarshal([]byte(blob), &trace); err != nil {
+			t.Fatalf("failed to decode trace: %v", err)
+		}
+		return &trace
+	}
+	var (
+		call    = decode(`{"action":{"from":"0x000000000000000000000000000000000000000a","to":"0x000000000000000000000000000000000000000b"}}`)
+		create  = decode(`{"action":{"from":"0x000000000000000000000000000000000000000a"},"result":{"address":"0x000000000000000000000000000000000000000c"}}`)
+		suicide = decode(`{"action":{"address":"0x000000000000000000000000000000000000000b","refundAddress":"0x000000000000000000000000000000000000000c"}}`)
+	)
+	for i, tt := range []struct {
+		args  TraceFilterArgs
+		trace *filterTrace
+		want  bool
+	}{
+		{TraceFilterArgs{}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{a}}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{b}}, call, false},
+		{TraceFilterArgs{FromAddress: []common.Address{a}, ToAddress: []common.Address{b}}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{a}, ToAddress: []common.Address{c}}, call, false},
+		{TraceFilterArgs{ToAddress: []common.Address{c}}, create, true},
+		{TraceFilterArgs{FromAddress: []common.Address{b}, ToAddress: []common.Address{c}}, suicide, true},
+		{TraceFilterArgs{ToAddress: []common.Address{b}}, suicide, false},
+	} {
+		if have := tt.args.matches(tt.trace); have != tt.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestRewardTraces(t *testing.T) {
+	t.Parallel()
+
+	var (
+		miner   = common.HexToAddress("0xc0ffee")
+		uncler  = common.HexToAddress("0xdecaf")
+		genesis = &core.Genesis{Config: params.TestChainConfig}
+	)
+	backend := newTestBackend(t, 3, genesis, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(miner)
+		if i == 2 {
+			b.AddUncle(&types.Header{
+				ParentHash: b.PrevBlock(0).Hash(),
+				Number:     big.NewInt(2),
+				Coinbase:   uncler,
+			})
+		}
+	})
+	defer backend.chain.Stop()
+	api := NewTraceAPI(backend)
+
+	block, _ := backend.BlockByNumber(context.Background(), 3)
+	traces, err := api.rewardTraces(block)
+	if err != nil {
+		t.Fatalf("failed to create reward traces: %v", err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("trace count mismatch: have %d, want 2", len(traces))
+	}
+	// Constantinople rewards 2 ether per block, plus 1/32 of it per uncle to
+	// the miner and 7/8 of it to the miner of an uncle one block behind
+	want := []struct {
+		author common.Address
+		typ    string
+		value  *big.Int
+	}{
+		{miner, "block", new(big.Int).Mul(big.NewInt(20625), big.NewInt(1e14))},
+		{uncler, "uncle", new(big.Int).Mul(big.NewInt(17500), big.NewInt(1e14))},
+	}
+	for i, w := range want {
+		var trace rewardTrace
+		if err := json.Unmarshal(traces[i], &trace); err != nil {
+			t.Fatalf("reward %d: failed to decode trace: %v", i, err)
+		}
+		if trace.Type != "reward" || trace.Action.Author != w.author || trace.Action.RewardType != w.typ || trace.Action.Value.ToInt().Cmp(w.value) != 0 || trace.BlockNumber != 3 {
+			t.Errorf("reward %d: trace mismatch: %s", i, traces[i])
+		}
+	}
+	// Rewards are matched by their author as receiver
+	var trace filterTrace
+	if err := json.Unmarshal(traces[1], &trace); err != nil {
+		t.Fatalf("failed to decode filter trace: %v", err)
+	}
+	if !(&TraceFilterArgs{ToAddress: []common.Address{uncler}}).matches(&trace) {
+		t.Error("uncle reward not matched by its author")
+	}
+	if (&TraceFilterArgs{ToAddress: []common.Address{miner}}).matches(&trace) {
+		t.Error("uncle reward matched by the block miner")
+	}
+}
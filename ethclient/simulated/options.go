@@ -19,6 +19,7 @@ package simulated
 import (
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/node"
 )
@@ -53,3 +54,12 @@ func WithMinerMinTip(tip *big.Int) func(nodeConf *node.Config, ethConf *ethconfi
 		ethConf.Miner.GasPrice = tip
 	}
 }
+
+// WithCallInterceptors configures the simulated backend to pass all calls of
+// both transactions and client operations through the given interceptors, in
+// order, e.g. to mock contracts via vm.CallMocker without deploying them.
+func WithCallInterceptors(interceptors ...vm.CallInterceptorFunc) func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+	return func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+		ethConf.VMCallInterceptor = vm.ChainCallInterceptors(interceptors...)
+	}
+}
@@ -17,14 +17,17 @@
 package simulated
 
 import (
+	"bytes"
 	"context"
 	"math/big"
 	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -72,3 +75,36 @@ func TestWithCallGasLimitOption(t *testing.T) {
 		t.Fatalf("error mismatch: have %v, want %v", err, core.ErrIntrinsicGas)
 	}
 }
+
+// Tests that the simulator passes calls through the configured interceptors.
+func TestWithCallInterceptorsOption(t *testing.T) {
+	var (
+		oracle   = common.HexToAddress("0x0a")
+		selector = []byte{0x50, 0xd2, 0x5b, 0xcd} // latestRound()
+		answer   = common.LeftPadBytes([]byte{42}, 32)
+		mocker   = vm.NewCallMocker()
+		recorder = vm.NewCallRecorder()
+	)
+	mocker.Mock(oracle, selector, answer)
+
+	sim := NewBackend(types.GenesisAlloc{
+		testAddr: {Balance: big.NewInt(10000000000000000)},
+	}, WithCallInterceptors(recorder.Intercept, mocker.Intercept))
+	defer sim.Close()
+
+	client := sim.Client()
+	ret, err := client.CallContract(context.Background(), ethereum.CallMsg{
+		From: testAddr,
+		To:   &oracle,
+		Data: selector,
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to call oracle: %v", err)
+	}
+	if !bytes.Equal(ret, answer) {
+		t.Errorf("return value mismatch: have %x, want %x", ret, answer)
+	}
+	if calls := recorder.Calls(); len(calls) != 1 || calls[0].To != oracle {
+		t.Errorf("recorded calls mismatch: %v", calls)
+	}
+}
@@ -55,6 +55,16 @@ type Compacter interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// Checkpointer wraps the Checkpoint method of a backing data store. It's an
+// optional interface, implemented by the data stores able to take a copy of
+// themselves while in use.
+type Checkpointer interface {
+	// Checkpoint creates a consistent, openable copy of the data store in the
+	// given directory, which must not exist yet. The writes happening during
+	// the checkpoint are either fully included or not at all.
+	Checkpoint(dir string) error
+}
+
 // KeyValueStore contains all the methods required to allow handling different
 // key-value data stores backing the high level database.
 type KeyValueStore interface {
@@ -19,6 +19,8 @@ package dbtest
 import (
 	"bytes"
 	"crypto/rand"
+	"fmt"
+	"path/filepath"
 	"slices"
 	"sort"
 	"testing"
@@ -408,6 +410,52 @@ func TestDatabaseSuite(t *testing.T, New func() ethdb.KeyValueStore) {
 	})
 }
 
+// TestCheckpointSuite runs a suite of tests against the checkpoints of a
+// disk-based KeyValueStore database implementation.
+func TestCheckpointSuite(t *testing.T, Open func(dir string) ethdb.KeyValueStore) {
+	var (
+		dir = t.TempDir()
+		db  = Open(filepath.Join(dir, "db"))
+	)
+	defer db.Close()
+
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		t.Fatal("database doesn't support checkpoints")
+	}
+	for i := 0; i < 100; i++ {
+		db.Put([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("val-%03d", i)))
+	}
+	if err := cp.Checkpoint(filepath.Join(dir, "checkpoint")); err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	if err := cp.Checkpoint(filepath.Join(dir, "checkpoint")); err == nil {
+		t.Fatal("overwrote existing checkpoint")
+	}
+	// Writes after the checkpoint must not be included in it
+	db.Put([]byte("key-100"), []byte("val-100"))
+	db.Delete([]byte("key-000"))
+
+	checkpoint := Open(filepath.Join(dir, "checkpoint"))
+	defer checkpoint.Close()
+
+	it := checkpoint.NewIterator(nil, nil)
+	defer it.Release()
+
+	var n int
+	for ; it.Next(); n++ {
+		if want := fmt.Sprintf("key-%03d", n); string(it.Key()) != want {
+			t.Fatalf("unexpected key %d: have %q, want %q", n, it.Key(), want)
+		}
+		if want := fmt.Sprintf("val-%03d", n); string(it.Value()) != want {
+			t.Fatalf("unexpected value %d: have %q, want %q", n, it.Value(), want)
+		}
+	}
+	if n != 100 {
+		t.Fatalf("unexpected number of entries in checkpoint: %d", n)
+	}
+}
+
 // BenchDatabaseSuite runs a suite of benchmarks against a KeyValueStore database
 // implementation.
 func BenchDatabaseSuite(b *testing.B, New func() ethdb.KeyValueStore) {
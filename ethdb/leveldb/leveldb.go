@@ -22,6 +22,7 @@ package leveldb
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -265,6 +266,53 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory,
+// which must not exist yet. As leveldb has no native checkpoints, the content
+// of a snapshot is written into a fresh database.
+func (db *Database) Checkpoint(dir string) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	cdb, err := leveldb.OpenFile(dir, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = new(leveldb.Batch)
+		size  int
+	)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		size += len(it.Key()) + len(it.Value())
+		if size >= ethdb.IdealBatchSize {
+			if err := cdb.Write(batch, nil); err != nil {
+				cdb.Close()
+				return err
+			}
+			batch.Reset()
+			size = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		cdb.Close()
+		return err
+	}
+	if err := cdb.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		cdb.Close()
+		return err
+	}
+	return cdb.Close()
+}
+
 // Path returns the path to the database directory.
 func (db *Database) Path() string {
 	return db.fn
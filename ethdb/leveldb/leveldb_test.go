@@ -39,6 +39,16 @@ func TestLevelDB(t *testing.T) {
 	})
 }
 
+func TestLevelDBCheckpoint(t *testing.T) {
+	dbtest.TestCheckpointSuite(t, func(dir string) ethdb.KeyValueStore {
+		db, err := New(dir, 16, 16, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
 func BenchmarkLevelDB(b *testing.B) {
 	dbtest.BenchDatabaseSuite(b, func() ethdb.KeyValueStore {
 		db, err := leveldb.Open(storage.NewMemStorage(), nil)
@@ -41,6 +41,16 @@ func TestPebbleDB(t *testing.T) {
 	})
 }
 
+func TestPebbleDBCheckpoint(t *testing.T) {
+	dbtest.TestCheckpointSuite(t, func(dir string) ethdb.KeyValueStore {
+		db, err := New(dir, 16, 16, "", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
 func BenchmarkPebbleDB(b *testing.B) {
 	dbtest.BenchDatabaseSuite(b, func() ethdb.KeyValueStore {
 		db, err := pebble.Open("", &pebble.Options{
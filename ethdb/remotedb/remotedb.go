@@ -15,28 +15,40 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 // Package remotedb implements the key-value database layer based on a remote geth
-// node. Under the hood, it utilises the `debug_dbGet` method to implement a
-// read-only database.
+// node. Under the hood, it utilises the `debug_db*` methods to implement the
+// database: point reads, paged iterators, statistics and ancient reads are served
+// by the public debug namespace, whereas write batches are only served on the
+// authenticated endpoints of the node (e.g. IPC).
 // There really are no guarantees in this database, since the local geth does not
 // exclusive access, but it can be used for basic diagnostics of a remote node.
 package remotedb
 
 import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// iteratorPageSize is the number of entries requested per page by the iterators.
+const iteratorPageSize = 1024
+
+var errNotSupported = errors.New("this operation is not supported")
+
 // Database is a key-value lookup for a remote database via debug_dbGet.
 type Database struct {
 	remote *rpc.Client
 }
 
 func (db *Database) Has(key []byte) (bool, error) {
-	if _, err := db.Get(key); err != nil {
-		return false, nil
+	var resp bool
+	err := db.remote.Call(&resp, "debug_dbHas", hexutil.Bytes(key))
+	if err != nil {
+		return false, err
 	}
-	return true, nil
+	return resp, nil
 }
 
 func (db *Database) Get(key []byte) ([]byte, error) {
@@ -65,7 +77,16 @@ func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
 }
 
 func (db *Database) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	panic("not supported")
+	var resp []hexutil.Bytes
+	err := db.remote.Call(&resp, "debug_dbAncientRange", kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	items := make([][]byte, len(resp))
+	for i, item := range resp {
+		items[i] = item
+	}
+	return items, nil
 }
 
 func (db *Database) Ancients() (uint64, error) {
@@ -75,11 +96,15 @@ func (db *Database) Ancients() (uint64, error) {
 }
 
 func (db *Database) Tail() (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbAncientTail")
+	return resp, err
 }
 
 func (db *Database) AncientSize(kind string) (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbAncientSize", kind)
+	return resp, err
 }
 
 func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err error) {
@@ -87,23 +112,31 @@ func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err e
 }
 
 func (db *Database) Put(key []byte, value []byte) error {
-	panic("not supported")
+	b := db.NewBatch()
+	if err := b.Put(key, value); err != nil {
+		return err
+	}
+	return b.Write()
 }
 
 func (db *Database) Delete(key []byte) error {
-	panic("not supported")
+	b := db.NewBatch()
+	if err := b.Delete(key); err != nil {
+		return err
+	}
+	return b.Write()
 }
 
 func (db *Database) ModifyAncients(f func(ethdb.AncientWriteOp) error) (int64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
 func (db *Database) TruncateHead(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
 func (db *Database) TruncateTail(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
 func (db *Database) Sync() error {
@@ -111,27 +144,33 @@ func (db *Database) Sync() error {
 }
 
 func (db *Database) MigrateTable(s string, f func([]byte) ([]byte, error)) error {
-	panic("not supported")
+	return errNotSupported
 }
 
 func (db *Database) NewBatch() ethdb.Batch {
-	panic("not supported")
+	return &batch{db: db}
 }
 
 func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
-	panic("not supported")
+	return &batch{db: db}
 }
 
 func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("not supported")
+	return &iterator{
+		db:     db,
+		prefix: common.CopyBytes(prefix),
+		next:   common.CopyBytes(start),
+	}
 }
 
 func (db *Database) Stat(property string) (string, error) {
-	panic("not supported")
+	var resp string
+	err := db.remote.Call(&resp, "debug_dbStat", property)
+	return resp, err
 }
 
 func (db *Database) AncientDatadir() (string, error) {
-	panic("not supported")
+	return "", errNotSupported
 }
 
 func (db *Database) Compact(start []byte, limit []byte) error {
@@ -139,7 +178,7 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 }
 
 func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
-	panic("not supported")
+	return nil, errNotSupported
 }
 
 func (db *Database) Close() error {
@@ -152,3 +191,145 @@ func New(client *rpc.Client) ethdb.Database {
 		remote: client,
 	}
 }
+
+// batchOp is a single operation of a write batch, in the format expected by
+// debug_dbWrite.
+type batchOp struct {
+	Key    hexutil.Bytes `json:"key"`
+	Value  hexutil.Bytes `json:"value,omitempty"`
+	Delete bool          `json:"delete,omitempty"`
+}
+
+// batch is a write-only batch that commits changes to the remote database
+// atomically via debug_dbWrite when Write is called.
+type batch struct {
+	db   *Database
+	ops  []batchOp
+	size int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.ops = append(b.ops, batchOp{Key: common.CopyBytes(key), Value: common.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts a key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, batchOp{Key: common.CopyBytes(key), Delete: true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to the remote database.
+func (b *batch) Write() error {
+	return b.db.remote.Call(nil, "debug_dbWrite", b.ops)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, op := range b.ops {
+		if op.Delete {
+			if err := w.Delete(op.Key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(op.Key, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iteratorPage is a page of consecutive entries, in the format returned by
+// debug_dbIterate.
+type iteratorPage struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"`
+}
+
+// iterator iterates over the entries of the remote database, fetching them
+// page by page via debug_dbIterate.
+type iterator struct {
+	db     *Database
+	prefix []byte
+	next   []byte // Start key of the next page without the prefix
+	done   bool   // Whether the last page has been fetched
+
+	page *iteratorPage
+	pos  int
+	err  error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.page != nil {
+		it.pos++
+	}
+	for it.page == nil || it.pos >= len(it.page.Keys) {
+		if it.done {
+			return false
+		}
+		var page iteratorPage
+		if err := it.db.remote.Call(&page, "debug_dbIterate", hexutil.Bytes(it.prefix), hexutil.Bytes(it.next), iteratorPageSize); err != nil {
+			it.err = err
+			return false
+		}
+		if len(page.Keys) != len(page.Values) {
+			it.err = errors.New("mismatching keys and values in iterator page")
+			return false
+		}
+		it.page, it.pos = &page, 0
+		if page.Next == nil {
+			it.done = true
+		} else {
+			it.next = *page.Next
+		}
+	}
+	return true
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	if it.page == nil || it.pos >= len(it.page.Keys) {
+		return nil
+	}
+	return it.page.Keys[it.pos]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	if it.page == nil || it.pos >= len(it.page.Values) {
+		return nil
+	}
+	return it.page.Values[it.pos]
+}
+
+// Release releases associated resources.
+func (it *iterator) Release() {
+	it.page, it.done = nil, true
+}
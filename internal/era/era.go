@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/era/e2store"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/golang/snappy"
 )
 
@@ -154,6 +155,75 @@ func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
 	return types.NewBlockWithHeader(&header).WithBody(body), nil
 }
 
+// GetRawHeaderByNumber returns the RLP-encoded header of the given block number.
+func (e *Era) GetRawHeaderByNumber(num uint64) ([]byte, error) {
+	return e.readRawEntry(num, TypeCompressedHeader, 0)
+}
+
+// GetRawBodyByNumber returns the RLP-encoded body of the given block number.
+func (e *Era) GetRawBodyByNumber(num uint64) ([]byte, error) {
+	return e.readRawEntry(num, TypeCompressedBody, 1)
+}
+
+// GetRawReceiptsByNumber returns the RLP-encoded receipts of the given block
+// number, in their consensus encoding.
+func (e *Era) GetRawReceiptsByNumber(num uint64) ([]byte, error) {
+	return e.readRawEntry(num, TypeCompressedReceipts, 2)
+}
+
+// GetTotalDifficultyByNumber returns the total difficulty of the chain after
+// the given block number.
+func (e *Era) GetTotalDifficultyByNumber(num uint64) (*big.Int, error) {
+	off, err := e.skipEntries(num, 3)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := e.s.ReaderAt(TypeTotalDifficulty, off)
+	if err != nil {
+		return nil, err
+	}
+	rawTd, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(reverseOrder(rawTd)), nil
+}
+
+// readRawEntry reads and decompresses the entry of the given type belonging to
+// the specified block. The skip value is the number of entries preceding the
+// desired one in the block tuple.
+func (e *Era) readRawEntry(num uint64, typ uint16, skip int) ([]byte, error) {
+	off, err := e.skipEntries(num, skip)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := newSnappyReader(e.s, typ, off)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// skipEntries returns the offset of the entry following the given number of
+// entries in the block tuple of the specified block.
+func (e *Era) skipEntries(num uint64, skip int) (int64, error) {
+	if e.m.start > num || e.m.start+e.m.count <= num {
+		return 0, errors.New("out-of-bounds")
+	}
+	off, err := e.readOffset(num)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < skip; i++ {
+		length, err := e.s.LengthAt(off)
+		if err != nil {
+			return 0, err
+		}
+		off += length
+	}
+	return off, nil
+}
+
 // Accumulator reads the accumulator entry in the Era1 file.
 func (e *Era) Accumulator() (common.Hash, error) {
 	entry, err := e.s.Find(TypeAccumulator)
@@ -208,6 +278,74 @@ func (e *Era) InitialTD() (*big.Int, error) {
 	return td.Sub(td, header.Difficulty), nil
 }
 
+// Verify checks the content of the Era1 against its accumulator root, by
+// recomputing the accumulator, and the transaction and receipt roots of all
+// the blocks.
+func (e *Era) Verify() error {
+	var (
+		err    error
+		want   common.Hash
+		td     *big.Int
+		tds    = make([]*big.Int, 0)
+		hashes = make([]common.Hash, 0)
+	)
+	if want, err = e.Accumulator(); err != nil {
+		return fmt.Errorf("error reading accumulator: %w", err)
+	}
+	if td, err = e.InitialTD(); err != nil {
+		return fmt.Errorf("error reading total difficulty: %w", err)
+	}
+	it, err := NewIterator(e)
+	if err != nil {
+		return fmt.Errorf("error making era iterator: %w", err)
+	}
+	// To fully verify an era the following attributes must be checked:
+	//   1) the block index is constructed correctly
+	//   2) the tx root matches the value in the block
+	//   3) the receipts root matches the value in the block
+	//   4) the starting total difficulty value is correct
+	//   5) the accumulator is correct by recomputing it locally, which verifies
+	//      the blocks are all correct (via hash)
+	//
+	// The attributes 1), 2), and 3) are checked for each block. 4) and 5) require
+	// accumulation across the entire set and are verified at the end.
+	for it.Next() {
+		// 1) next() walks the block index, so we're able to implicitly verify it.
+		if it.Error() != nil {
+			return fmt.Errorf("error reading block %d: %w", it.Number(), it.Error())
+		}
+		block, receipts, err := it.BlockAndReceipts()
+		if err != nil {
+			return fmt.Errorf("error reading block %d: %w", it.Number(), err)
+		}
+		// 2) recompute tx root and verify against header.
+		tr := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil))
+		if tr != block.TxHash() {
+			return fmt.Errorf("tx root in block %d mismatch: want %s, got %s", block.NumberU64(), block.TxHash(), tr)
+		}
+		// 3) recompute receipt root and check value against block.
+		rr := types.DeriveSha(receipts, trie.NewStackTrie(nil))
+		if rr != block.ReceiptHash() {
+			return fmt.Errorf("receipt root in block %d mismatch: want %s, got %s", block.NumberU64(), block.ReceiptHash(), rr)
+		}
+		hashes = append(hashes, block.Hash())
+		td.Add(td, block.Difficulty())
+		tds = append(tds, new(big.Int).Set(td))
+	}
+	if it.Error() != nil {
+		return fmt.Errorf("error reading era: %w", it.Error())
+	}
+	// 4+5) Verify accumulator and total difficulty.
+	got, err := ComputeAccumulator(hashes, tds)
+	if err != nil {
+		return fmt.Errorf("error computing accumulator: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("expected accumulator root does not match calculated: got %s, want %s", got, want)
+	}
+	return nil
+}
+
 // Start returns the listed start block.
 func (e *Era) Start() uint64 {
 	return e.m.start
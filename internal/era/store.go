@@ -0,0 +1,303 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errOutOfBounds is returned if the requested block is not covered by the
+	// era1 files of the store.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errUnsupportedKind is returned if the requested ancient kind can't be
+	// served from the era1 files.
+	errUnsupportedKind = errors.New("unsupported ancient kind")
+)
+
+// StoreConfig contains the settings of an era1 store.
+type StoreConfig struct {
+	CacheSize uint64        // Maximum size of the recently read items cached, 0 disables caching
+	Verify    bool          // Whether to fully verify each era1 file against its accumulator when opened
+	Roots     []common.Hash // Optional expected accumulator roots of the era1 files, by epoch
+}
+
+// DefaultStoreConfig contains the default settings of an era1 store.
+var DefaultStoreConfig = &StoreConfig{
+	CacheSize: 16 * 1024 * 1024,
+}
+
+// storeCacheKey identifies an item in the cache of the store.
+type storeCacheKey struct {
+	kind   string
+	number uint64
+}
+
+// Store serves the chain history archived in a directory of era1 files in the
+// format of the chain freezer, mapping the headers, hashes, bodies, receipts
+// and total difficulties to the corresponding ancient kinds. It implements the
+// ethdb.AncientReader interface.
+type Store struct {
+	eras  []*Era // Opened era1 files, sorted by their start block
+	start uint64 // Number of the first block in the archive
+	end   uint64 // Number of the block after the last one in the archive
+
+	cache *lru.SizeConstrainedCache[storeCacheKey, []byte] // Recently read items, nil if disabled
+}
+
+// OpenStore opens all the era1 files of the given network in the directory.
+// The files must cover a contiguous range of blocks. If the configuration
+// requests so, each file is checked against its expected accumulator root and
+// fully verified before being served.
+func OpenStore(dir, network string, config *StoreConfig) (*Store, error) {
+	if config == nil {
+		config = DefaultStoreConfig
+	}
+	entries, err := ReadDir(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	if config.Roots != nil && len(config.Roots) != len(entries) {
+		return nil, fmt.Errorf("number of era1 files should match the number of accumulator roots, have: %d files, %d roots", len(entries), len(config.Roots))
+	}
+	s := new(Store)
+	if config.CacheSize > 0 {
+		s.cache = lru.NewSizeConstrainedCache[storeCacheKey, []byte](config.CacheSize)
+	}
+	for i, name := range entries {
+		e, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to open era1 file %s: %w", name, err)
+		}
+		s.eras = append(s.eras, e)
+
+		if len(s.eras) == 1 {
+			s.start = e.Start()
+		} else if e.Start() != s.end {
+			s.Close()
+			return nil, fmt.Errorf("non-contiguous era1 file %s, start: %d, want: %d", name, e.Start(), s.end)
+		}
+		s.end = e.Start() + e.Count()
+
+		if err := verifyEra(e, config, i); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("invalid era1 file %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+// verifyEra checks the era1 file with the given epoch as requested by the
+// store configuration.
+func verifyEra(e *Era, config *StoreConfig, epoch int) error {
+	if config.Roots != nil {
+		root, err := e.Accumulator()
+		if err != nil {
+			return err
+		}
+		if root != config.Roots[epoch] {
+			return fmt.Errorf("accumulator root mismatch, have: %s, want: %s", root, config.Roots[epoch])
+		}
+	}
+	if config.Verify {
+		return e.Verify()
+	}
+	return nil
+}
+
+// Close closes all the era1 files of the store.
+func (s *Store) Close() error {
+	var errs []error
+	for _, e := range s.eras {
+		if err := e.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.eras = nil
+	return errors.Join(errs...)
+}
+
+// era returns the era1 file containing the given block.
+func (s *Store) era(number uint64) (*Era, error) {
+	if number < s.start || number >= s.end {
+		return nil, errOutOfBounds
+	}
+	i := sort.Search(len(s.eras), func(i int) bool {
+		return s.eras[i].Start()+s.eras[i].Count() > number
+	})
+	return s.eras[i], nil
+}
+
+// supported reports whether the given ancient kind is served by the store.
+func supported(kind string) bool {
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable, rawdb.ChainFreezerHashTable, rawdb.ChainFreezerBodiesTable,
+		rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (s *Store) HasAncient(kind string, number uint64) (bool, error) {
+	if !supported(kind) {
+		return false, errUnsupportedKind
+	}
+	return number >= s.start && number < s.end, nil
+}
+
+// Ancient retrieves an ancient binary blob from the era1 files.
+func (s *Store) Ancient(kind string, number uint64) ([]byte, error) {
+	if !supported(kind) {
+		return nil, errUnsupportedKind
+	}
+	key := storeCacheKey{kind: kind, number: number}
+	if s.cache != nil {
+		if blob, ok := s.cache.Get(key); ok {
+			return blob, nil
+		}
+	}
+	e, err := s.era(number)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := readAncient(e, kind, number)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		s.cache.Add(key, blob)
+	}
+	return blob, nil
+}
+
+// readAncient reads the item of the given ancient kind from the era1 file,
+// converted to the encoding of the chain freezer.
+func readAncient(e *Era, kind string, number uint64) ([]byte, error) {
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable:
+		// The headers and bodies are archived in the same encoding as in
+		// the freezer.
+		return e.GetRawHeaderByNumber(number)
+	case rawdb.ChainFreezerHashTable:
+		header, err := e.GetRawHeaderByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(header), nil
+	case rawdb.ChainFreezerBodiesTable:
+		return e.GetRawBodyByNumber(number)
+	case rawdb.ChainFreezerReceiptTable:
+		// The receipts are archived in their consensus encoding, which needs
+		// to be converted to the storage encoding of the freezer.
+		blob, err := e.GetRawReceiptsByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		return convertReceipts(blob)
+	case rawdb.ChainFreezerDifficultyTable:
+		td, err := e.GetTotalDifficultyByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		return rlp.EncodeToBytes(td)
+	default:
+		return nil, errUnsupportedKind
+	}
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'. It will return at most 'count' items, but will abort earlier to
+// respect the 'maxBytes' limit if specified. At least one item is returned.
+func (s *Store) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for number := start; number < start+count; number++ {
+		blob, err := s.Ancient(kind, number)
+		if err != nil {
+			if len(items) > 0 && errors.Is(err, errOutOfBounds) {
+				break
+			}
+			return nil, err
+		}
+		size += uint64(len(blob))
+		if maxBytes != 0 && size > maxBytes && len(items) > 0 {
+			break
+		}
+		items = append(items, blob)
+	}
+	return items, nil
+}
+
+// Ancients returns the number of the block after the last archived one.
+func (s *Store) Ancients() (uint64, error) {
+	return s.end, nil
+}
+
+// Tail returns the number of the first archived block.
+func (s *Store) Tail() (uint64, error) {
+	return s.start, nil
+}
+
+// AncientSize returns the total size of the era1 files, which contain all the
+// supported kinds together.
+func (s *Store) AncientSize(kind string) (uint64, error) {
+	if !supported(kind) {
+		return 0, errUnsupportedKind
+	}
+	var size uint64
+	for _, e := range s.eras {
+		size += uint64(e.m.length)
+	}
+	return size, nil
+}
+
+// ReadAncients runs the given read operation on the store. The era1 files are
+// immutable, so no further locking is needed.
+func (s *Store) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return fn(s)
+}
+
+// convertReceipts converts the consensus encoding of the receipts of a block
+// into the storage encoding used by the chain freezer.
+func convertReceipts(blob []byte) ([]byte, error) {
+	var receipts []*types.Receipt
+	if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+		return nil, err
+	}
+	stored := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		stored[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	return rlp.EncodeToBytes(stored)
+}
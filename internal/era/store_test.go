@@ -0,0 +1,214 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestArchive writes the given number of era1 files, each containing the
+// given number of blocks, and returns the blocks and receipts written along
+// with the accumulator roots of the files.
+func makeTestArchive(t *testing.T, dir string, files, size int) ([]*types.Block, []types.Receipts, []common.Hash) {
+	t.Helper()
+
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		roots    []common.Hash
+		td       = new(big.Int)
+	)
+	for epoch := 0; epoch < files; epoch++ {
+		f, err := os.Create(filepath.Join(dir, Filename("testnet", epoch, common.Hash{})))
+		if err != nil {
+			t.Fatalf("error creating era1 file: %v", err)
+		}
+		builder := NewBuilder(f)
+		for i := 0; i < size; i++ {
+			number := uint64(epoch*size + i)
+			receipt := &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: number,
+				Logs:              []*types.Log{{Address: common.Address{byte(number)}, Data: []byte{byte(number)}}},
+			}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+			header := &types.Header{
+				Number:      new(big.Int).SetUint64(number),
+				Difficulty:  big.NewInt(1),
+				Extra:       []byte{byte(number)},
+				TxHash:      types.EmptyTxsHash,
+				ReceiptHash: types.DeriveSha(types.Receipts{receipt}, trie.NewStackTrie(nil)),
+			}
+			block := types.NewBlockWithHeader(header)
+
+			td.Add(td, header.Difficulty)
+			if err := builder.Add(block, types.Receipts{receipt}, new(big.Int).Set(td)); err != nil {
+				t.Fatalf("error adding block %d: %v", number, err)
+			}
+			blocks = append(blocks, block)
+			receipts = append(receipts, types.Receipts{receipt})
+		}
+		root, err := builder.Finalize()
+		if err != nil {
+			t.Fatalf("error finalizing era1: %v", err)
+		}
+		roots = append(roots, root)
+		f.Close()
+	}
+	return blocks, receipts, roots
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	blocks, receipts, _ := makeTestArchive(t, dir, 3, 16)
+
+	s, err := OpenStore(dir, "testnet", nil)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	if tail, _ := s.Tail(); tail != 0 {
+		t.Fatalf("unexpected tail: %d", tail)
+	}
+	if head, _ := s.Ancients(); head != uint64(len(blocks)) {
+		t.Fatalf("unexpected ancients: %d", head)
+	}
+	td := new(big.Int)
+	for i, block := range blocks {
+		// Read every item twice, hitting the cache on the second round
+		for round := 0; round < 2; round++ {
+			want, _ := rlp.EncodeToBytes(block.Header())
+			have, err := s.Ancient(rawdb.ChainFreezerHeaderTable, uint64(i))
+			if err != nil || !bytes.Equal(have, want) {
+				t.Fatalf("unexpected header %d: %x, %v", i, have, err)
+			}
+			have, err = s.Ancient(rawdb.ChainFreezerHashTable, uint64(i))
+			if err != nil || common.BytesToHash(have) != block.Hash() {
+				t.Fatalf("unexpected hash %d: %x, %v", i, have, err)
+			}
+			want, _ = rlp.EncodeToBytes(block.Body())
+			have, err = s.Ancient(rawdb.ChainFreezerBodiesTable, uint64(i))
+			if err != nil || !bytes.Equal(have, want) {
+				t.Fatalf("unexpected body %d: %x, %v", i, have, err)
+			}
+			stored := make([]*types.ReceiptForStorage, len(receipts[i]))
+			for j, receipt := range receipts[i] {
+				stored[j] = (*types.ReceiptForStorage)(receipt)
+			}
+			want, _ = rlp.EncodeToBytes(stored)
+			have, err = s.Ancient(rawdb.ChainFreezerReceiptTable, uint64(i))
+			if err != nil || !bytes.Equal(have, want) {
+				t.Fatalf("unexpected receipts %d: %x, %v", i, have, err)
+			}
+			if round == 0 {
+				td.Add(td, block.Difficulty())
+			}
+			want, _ = rlp.EncodeToBytes(td)
+			have, err = s.Ancient(rawdb.ChainFreezerDifficultyTable, uint64(i))
+			if err != nil || !bytes.Equal(have, want) {
+				t.Fatalf("unexpected total difficulty %d: %x, %v", i, have, err)
+			}
+		}
+	}
+	if _, err := s.Ancient(rawdb.ChainFreezerBodiesTable, uint64(len(blocks))); err == nil {
+		t.Fatal("read block beyond the archive")
+	}
+	if _, err := s.Ancient("unknown", 0); err == nil {
+		t.Fatal("read unsupported kind")
+	}
+	// Ranges spanning multiple files must be served, and be capped by the
+	// end of the archive.
+	items, err := s.AncientRange(rawdb.ChainFreezerBodiesTable, 10, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to read range: %v", err)
+	}
+	if len(items) != len(blocks)-10 {
+		t.Fatalf("unexpected range length: %d", len(items))
+	}
+	items, err = s.AncientRange(rawdb.ChainFreezerBodiesTable, 10, 100, 1)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("unexpected size-capped range: %d, %v", len(items), err)
+	}
+	// The store must be usable with the chain accessors of rawdb
+	err = s.ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		blob, err := reader.Ancient(rawdb.ChainFreezerHashTable, 20)
+		if err != nil || common.BytesToHash(blob) != blocks[20].Hash() {
+			t.Fatalf("unexpected hash in read operation: %x, %v", blob, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to run read operation: %v", err)
+	}
+}
+
+func TestStoreVerify(t *testing.T) {
+	dir := t.TempDir()
+	_, _, roots := makeTestArchive(t, dir, 2, 16)
+
+	s, err := OpenStore(dir, "testnet", &StoreConfig{Verify: true, Roots: roots})
+	if err != nil {
+		t.Fatalf("failed to open verified store: %v", err)
+	}
+	s.Close()
+
+	if _, err := OpenStore(dir, "testnet", &StoreConfig{Roots: roots[:1]}); err == nil {
+		t.Fatal("opened store with missing accumulator roots")
+	}
+	if _, err := OpenStore(dir, "testnet", &StoreConfig{Roots: []common.Hash{roots[0], {0x1}}}); err == nil {
+		t.Fatal("opened store with mismatching accumulator root")
+	}
+	// Overwrite the second file with one whose content doesn't match the
+	// block headers, which is only detected by the full verification.
+	f, err := os.Create(filepath.Join(dir, Filename("testnet", 1, common.Hash{})))
+	if err != nil {
+		t.Fatalf("error creating era1 file: %v", err)
+	}
+	builder := NewBuilder(f)
+	for i := 16; i < 32; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1), TxHash: types.EmptyTxsHash}
+		if err := builder.Add(types.NewBlockWithHeader(header), nil, big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("error adding block %d: %v", i, err)
+		}
+	}
+	if _, err := builder.Finalize(); err != nil {
+		t.Fatalf("error finalizing era1: %v", err)
+	}
+	f.Close()
+
+	if s, err := OpenStore(dir, "testnet", nil); err != nil {
+		t.Fatalf("failed to open unverified store: %v", err)
+	} else {
+		s.Close()
+	}
+	if _, err := OpenStore(dir, "testnet", &StoreConfig{Verify: true}); err == nil {
+		t.Fatal("opened store with corrupted era1 file")
+	}
+}
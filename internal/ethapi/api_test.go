@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -606,6 +607,9 @@ func (b testBackend) TxPoolContentFrom(addr common.Address) ([]*types.Transactio
 func (b testBackend) SubscribeNewTxsEvent(events chan<- core.NewTxsEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeTxPoolEvents(events chan<- []txpool.TxEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
 func (b testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
 func (b testBackend) GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -975,6 +979,107 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestSimulateV1(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 10
+		signer    = types.HomesteadSigner{}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, genBlocks, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		// Transfer from account[0] to account[1]
+		//    value: 1000 wei
+		//    fee:   0 wei
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee(), Data: nil}), signer, accounts[0].key)
+		b.AddTx(tx)
+		b.SetPoS()
+	}))
+	var (
+		randomAccounts = newAccounts(2)
+		latest         = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		value          = (*hexutil.Big)(big.NewInt(1000))
+	)
+	// Funds sent to a fresh account in the first block are spent by it in the
+	// second one, which only works if state is carried over between blocks.
+	results, err := api.SimulateV1(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{
+			{Calls: []TransactionArgs{{From: &accounts[0].addr, To: &randomAccounts[0].addr, Value: value}}},
+			{Calls: []TransactionArgs{{From: &randomAccounts[0].addr, To: &randomAccounts[1].addr, Value: value}}},
+		},
+		TraceTransfers: true,
+	}, &latest)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(results))
+	}
+	for i, res := range results {
+		if have, want := res.Block.NumberU64(), uint64(genBlocks+i+1); have != want {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, have, want)
+		}
+		if i > 0 && res.Block.ParentHash() != results[i-1].Block.Hash() {
+			t.Errorf("block %d: parent hash mismatch", i)
+		}
+		if len(res.Calls) != 1 {
+			t.Fatalf("block %d: call count mismatch: have %d, want 1", i, len(res.Calls))
+		}
+		call := res.Calls[0]
+		if call.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+			t.Errorf("block %d: call failed: %v", i, call.Error)
+		}
+		if call.GasUsed != hexutil.Uint64(params.TxGas) {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", i, call.GasUsed, params.TxGas)
+		}
+		if len(call.Logs) != 1 {
+			t.Fatalf("block %d: transfer log missing", i)
+		}
+		if log := call.Logs[0]; log.Address != transferAddress || log.BlockHash != res.Block.Hash() {
+			t.Errorf("block %d: unexpected transfer log: %+v", i, log)
+		}
+	}
+	if _, err := json.Marshal(results); err != nil {
+		t.Fatalf("failed to marshal results: %v", err)
+	}
+	// Gaps in the block numbers are filled with empty blocks.
+	gapNumber := (*hexutil.Big)(big.NewInt(int64(genBlocks + 3)))
+	results, err = api.SimulateV1(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{BlockOverrides: &BlockOverrides{Number: gapNumber}}},
+	}, &latest)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("block count mismatch: have %d, want 3", len(results))
+	}
+	// Block numbers must be increasing.
+	sameNumber := (*hexutil.Big)(big.NewInt(int64(genBlocks)))
+	_, err = api.SimulateV1(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{BlockOverrides: &BlockOverrides{Number: sameNumber}}},
+	}, &latest)
+	var numErr *invalidBlockNumberError
+	if !errors.As(err, &numErr) {
+		t.Fatalf("expected invalid block number error, have %v", err)
+	}
+	// Validation mode enforces nonces.
+	badNonce := hexutil.Uint64(100)
+	_, err = api.SimulateV1(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{Calls: []TransactionArgs{{From: &accounts[0].addr, To: &randomAccounts[0].addr, Nonce: &badNonce, MaxFeePerGas: (*hexutil.Big)(big.NewInt(params.GWei))}}}},
+		Validation:      true,
+	}, &latest)
+	var txErr *invalidTxError
+	if !errors.As(err, &txErr) || txErr.Code != errCodeNonceTooHigh {
+		t.Fatalf("expected nonce too high error, have %v", err)
+	}
+}
+
 func TestSignTransaction(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
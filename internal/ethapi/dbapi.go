@@ -41,3 +41,140 @@ func (api *DebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error
 func (api *DebugAPI) DbAncients() (uint64, error) {
 	return api.b.ChainDb().Ancients()
 }
+
+const (
+	// maxDbIterateItems is the maximum number of entries returned in a page of
+	// database iteration.
+	maxDbIterateItems = 4096
+
+	// maxDbIterateSize is the soft limit of the total size of the entries
+	// returned in a page of database iteration.
+	maxDbIterateSize = 4 * 1024 * 1024
+
+	// maxDbAncientRangeSize is the maximum total size of the ancient items
+	// returned in a range.
+	maxDbAncientRangeSize = 16 * 1024 * 1024
+)
+
+// DbHas reports whether a key is stored in the database.
+func (api *DebugAPI) DbHas(key string) (bool, error) {
+	blob, err := common.ParseHexOrString(key)
+	if err != nil {
+		return false, err
+	}
+	return api.b.ChainDb().Has(blob)
+}
+
+// DbStat returns the statistic data of the database for the given property.
+// It is a mapping to the `KeyValueStater.Stat` method
+func (api *DebugAPI) DbStat(property string) (string, error) {
+	return api.b.ChainDb().Stat(property)
+}
+
+// DbIteratorPage is a page of consecutive database entries.
+type DbIteratorPage struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"` // Start key of the next page without the prefix, nil if exhausted
+}
+
+// DbIterate returns a page of the database entries with the given prefix,
+// starting at the given key (or after, if it does not exist). The start key
+// excludes the prefix. At most limit entries are returned, which is capped by
+// the server both in number and size.
+//
+// The pages are read from independent iterators, so there is no guarantee of
+// consistency across pages if the database is modified in between.
+func (api *DebugAPI) DbIterate(prefix hexutil.Bytes, start hexutil.Bytes, limit int) (*DbIteratorPage, error) {
+	if limit <= 0 || limit > maxDbIterateItems {
+		limit = maxDbIterateItems
+	}
+	it := api.b.ChainDb().NewIterator(prefix, start)
+	defer it.Release()
+
+	var (
+		page = &DbIteratorPage{Keys: []hexutil.Bytes{}, Values: []hexutil.Bytes{}}
+		size int
+	)
+	for it.Next() {
+		if len(page.Keys) >= limit || size >= maxDbIterateSize {
+			next := hexutil.Bytes(common.CopyBytes(it.Key()[len(prefix):]))
+			page.Next = &next
+			break
+		}
+		page.Keys = append(page.Keys, common.CopyBytes(it.Key()))
+		page.Values = append(page.Values, common.CopyBytes(it.Value()))
+		size += len(it.Key()) + len(it.Value())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// DbAncientRange retrieves multiple items in sequence from the append-only
+// immutable files, starting from the index 'start'. It is a mapping to the
+// `AncientReaderOp.AncientRange` method, the total size of the items is capped
+// by the server.
+func (api *DebugAPI) DbAncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	if maxBytes == 0 || maxBytes > maxDbAncientRangeSize {
+		maxBytes = maxDbAncientRangeSize
+	}
+	items, err := api.b.ChainDb().AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]hexutil.Bytes, len(items))
+	for i, item := range items {
+		blobs[i] = item
+	}
+	return blobs, nil
+}
+
+// DbAncientTail returns the number of the first stored item in the ancient
+// store. It is a mapping to the `AncientReaderOp.Tail` method
+func (api *DebugAPI) DbAncientTail() (uint64, error) {
+	return api.b.ChainDb().Tail()
+}
+
+// DbAncientSize returns the ancient size of the specified category. It is a
+// mapping to the `AncientReaderOp.AncientSize` method
+func (api *DebugAPI) DbAncientSize(kind string) (uint64, error) {
+	return api.b.ChainDb().AncientSize(kind)
+}
+
+// DbBatchOp is a single operation of a database write batch.
+type DbBatchOp struct {
+	Key    hexutil.Bytes `json:"key"`
+	Value  hexutil.Bytes `json:"value,omitempty"`
+	Delete bool          `json:"delete,omitempty"`
+}
+
+// DebugDBWriteAPI is the collection of database modifying APIs exposed over
+// the debugging namespace. As it allows arbitrary changes to the database, it
+// is only available behind authentication.
+type DebugDBWriteAPI struct {
+	b Backend
+}
+
+// NewDebugDBWriteAPI creates a new instance of DebugDBWriteAPI.
+func NewDebugDBWriteAPI(b Backend) *DebugDBWriteAPI {
+	return &DebugDBWriteAPI{b: b}
+}
+
+// DbWrite atomically applies the batch of operations to the database.
+func (api *DebugDBWriteAPI) DbWrite(ops []DbBatchOp) error {
+	batch := api.b.ChainDb().NewBatch()
+	for _, op := range ops {
+		if op.Delete {
+			if err := batch.Delete(op.Key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := batch.Put(op.Key, op.Value); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/remotedb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestRemoteDB serves the database over an in-process RPC server, and
+// returns the remote database connected to it.
+func newTestRemoteDB(t *testing.T, db ethdb.Database) ethdb.Database {
+	t.Helper()
+
+	var (
+		server  = rpc.NewServer()
+		backend = &testBackend{db: db}
+	)
+	if err := server.RegisterName("debug", NewDebugAPI(backend)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("debug", NewDebugDBWriteAPI(backend)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return remotedb.New(rpc.DialInProc(server))
+}
+
+func TestRemoteDatabaseKeyValue(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		remote = newTestRemoteDB(t, db)
+		key    = []byte("foo")
+	)
+	defer remote.Close()
+
+	if has, err := remote.Has(key); err != nil || has {
+		t.Fatalf("unexpected key presence: %v, %v", has, err)
+	}
+	if err := remote.Put(key, []byte("bar")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if has, err := remote.Has(key); err != nil || !has {
+		t.Fatalf("unexpected key presence: %v, %v", has, err)
+	}
+	if val, err := remote.Get(key); err != nil || !bytes.Equal(val, []byte("bar")) {
+		t.Fatalf("unexpected value: %x, %v", val, err)
+	}
+	if err := remote.Delete(key); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if has, _ := db.Has(key); has {
+		t.Fatal("key is not deleted")
+	}
+	// Batches must be applied atomically, in order
+	batch := remote.NewBatch()
+	batch.Put([]byte("k1"), []byte("v1"))
+	batch.Put([]byte("k2"), nil)
+	batch.Put([]byte("k3"), []byte("v3"))
+	batch.Delete([]byte("k3"))
+	if has, _ := db.Has([]byte("k1")); has {
+		t.Fatal("batch applied before write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if val, err := db.Get([]byte("k1")); err != nil || !bytes.Equal(val, []byte("v1")) {
+		t.Fatalf("unexpected value: %x, %v", val, err)
+	}
+	if has, _ := db.Has([]byte("k2")); !has {
+		t.Fatal("empty value is not written")
+	}
+	if has, _ := db.Has([]byte("k3")); has {
+		t.Fatal("deleted key is written")
+	}
+	// Replayed batches must yield the same content
+	local := memorydb.New()
+	if err := batch.Replay(local); err != nil {
+		t.Fatalf("failed to replay batch: %v", err)
+	}
+	if local.Len() != 2 {
+		t.Fatalf("unexpected replayed items: %d", local.Len())
+	}
+	if _, err := remote.NewSnapshot(); err == nil {
+		t.Fatal("created remote snapshot")
+	}
+}
+
+func TestRemoteDatabaseIterator(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		remote = newTestRemoteDB(t, db)
+	)
+	defer remote.Close()
+
+	// Fill the database with enough entries to span multiple pages
+	for i := 0; i < 3000; i++ {
+		db.Put([]byte(fmt.Sprintf("a-%05d", i)), []byte(fmt.Sprintf("val-%d", i)))
+		db.Put([]byte(fmt.Sprintf("b-%05d", i)), []byte{})
+	}
+	tests := []struct {
+		prefix string
+		start  string
+	}{
+		{"", ""},
+		{"a-", ""},
+		{"a-", "01500"},
+		{"b-", "02999"},
+		{"c-", ""},
+	}
+	for _, test := range tests {
+		var (
+			want = db.NewIterator([]byte(test.prefix), []byte(test.start))
+			have = remote.NewIterator([]byte(test.prefix), []byte(test.start))
+			n    int
+		)
+		for want.Next() {
+			if !have.Next() {
+				t.Fatalf("prefix %q start %q: remote iterator exhausted after %d entries: %v", test.prefix, test.start, n, have.Error())
+			}
+			if !bytes.Equal(have.Key(), want.Key()) || !bytes.Equal(have.Value(), want.Value()) {
+				t.Fatalf("prefix %q start %q: entry %d mismatch, have %q=%q, want %q=%q", test.prefix, test.start, n, have.Key(), have.Value(), want.Key(), want.Value())
+			}
+			n++
+		}
+		if have.Next() {
+			t.Fatalf("prefix %q start %q: remote iterator not exhausted, extra key %q", test.prefix, test.start, have.Key())
+		}
+		if err := have.Error(); err != nil {
+			t.Fatalf("prefix %q start %q: iterator error: %v", test.prefix, test.start, err)
+		}
+		want.Release()
+		have.Release()
+	}
+}
+
+func TestRemoteDatabaseAncients(t *testing.T) {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+	)
+	for i := 0; i < 10; i++ {
+		blocks = append(blocks, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}))
+		receipts = append(receipts, types.Receipts{})
+	}
+	if _, err := rawdb.WriteAncientBlocks(db, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	remote := newTestRemoteDB(t, db)
+	defer remote.Close()
+
+	if tail, err := remote.Tail(); err != nil || tail != 0 {
+		t.Fatalf("unexpected tail: %d, %v", tail, err)
+	}
+	if head, err := remote.Ancients(); err != nil || head != 10 {
+		t.Fatalf("unexpected ancients: %d, %v", head, err)
+	}
+	want, _ := db.AncientSize(rawdb.ChainFreezerHeaderTable)
+	if size, err := remote.AncientSize(rawdb.ChainFreezerHeaderTable); err != nil || size != want {
+		t.Fatalf("unexpected ancient size: %d, %v", size, err)
+	}
+	items, err := remote.AncientRange(rawdb.ChainFreezerHashTable, 2, 5, 0)
+	if err != nil {
+		t.Fatalf("failed to read ancient range: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("unexpected range length: %d", len(items))
+	}
+	for i, item := range items {
+		if !bytes.Equal(item, blocks[2+i].Hash().Bytes()) {
+			t.Fatalf("unexpected hash %d: %x", 2+i, item)
+		}
+	}
+	if _, err := remote.ModifyAncients(func(ethdb.AncientWriteOp) error { return nil }); err == nil {
+		t.Fatal("modified remote ancients")
+	}
+}
@@ -72,6 +72,9 @@ type TransactionArgs struct {
 	Commitments []kzg4844.Commitment `json:"commitments"`
 	Proofs      []kzg4844.Proof      `json:"proofs"`
 
+	// For SetCodeTxType
+	AuthorizationList []types.SetCodeAuthorization `json:"authorizationList"`
+
 	// This configures whether blobs are allowed to be passed.
 	blobSidecarAllowed bool
 }
@@ -126,11 +129,19 @@ func (args *TransactionArgs) setDefaults(ctx context.Context, b Backend, skipGas
 		return fmt.Errorf(`too many blobs in transaction (have=%d, max=%d)`, len(args.BlobHashes), maxBlobsPerTransaction)
 	}
 
+	// SetCodeTx fields
+	if args.AuthorizationList != nil && args.GasPrice != nil {
+		return errors.New(`gasPrice is not supported in set code transactions, use maxFeePerGas`)
+	}
+
 	// create check
 	if args.To == nil {
 		if args.BlobHashes != nil {
 			return errors.New(`missing "to" in blob transaction`)
 		}
+		if args.AuthorizationList != nil {
+			return errors.New(`missing "to" in set code transaction`)
+		}
 		if len(args.data()) == 0 {
 			return errors.New(`contract creation without any data provided`)
 		}
@@ -158,6 +169,7 @@ func (args *TransactionArgs) setDefaults(ctx context.Context, b Backend, skipGas
 				AccessList:           args.AccessList,
 				BlobFeeCap:           args.BlobFeeCap,
 				BlobHashes:           args.BlobHashes,
+				AuthorizationList:    args.AuthorizationList,
 			}
 			latestBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 			estimated, err := DoEstimateGas(ctx, b, callArgs, latestBlockNr, nil, b.RPCGasCap())
@@ -454,6 +466,7 @@ func (args *TransactionArgs) ToMessage(baseFee *big.Int) *core.Message {
 	return &core.Message{
 		From:              args.from(),
 		To:                args.To,
+		Nonce:             uint64(*args.Nonce),
 		Value:             (*big.Int)(args.Value),
 		GasLimit:          uint64(*args.Gas),
 		GasPrice:          gasPrice,
@@ -464,6 +477,8 @@ func (args *TransactionArgs) ToMessage(baseFee *big.Int) *core.Message {
 		BlobGasFeeCap:     (*big.Int)(args.BlobFeeCap),
 		BlobHashes:        args.BlobHashes,
 		SkipAccountChecks: true,
+
+		SetCodeAuthorizations: args.AuthorizationList,
 	}
 }
 
@@ -472,6 +487,24 @@ func (args *TransactionArgs) ToMessage(baseFee *big.Int) *core.Message {
 func (args *TransactionArgs) ToTransaction() *types.Transaction {
 	var data types.TxData
 	switch {
+	case args.AuthorizationList != nil:
+		al := types.AccessList{}
+		if args.AccessList != nil {
+			al = *args.AccessList
+		}
+		data = &types.SetCodeTx{
+			To:         *args.To,
+			ChainID:    uint256.MustFromBig((*big.Int)(args.ChainID)),
+			Nonce:      uint64(*args.Nonce),
+			Gas:        uint64(*args.Gas),
+			GasFeeCap:  uint256.MustFromBig((*big.Int)(args.MaxFeePerGas)),
+			GasTipCap:  uint256.MustFromBig((*big.Int)(args.MaxPriorityFeePerGas)),
+			Value:      uint256.MustFromBig((*big.Int)(args.Value)),
+			Data:       args.data(),
+			AccessList: al,
+			AuthList:   args.AuthorizationList,
+		}
+
 	case args.BlobHashes != nil:
 		al := types.AccessList{}
 		if args.AccessList != nil {
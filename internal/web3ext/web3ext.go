@@ -153,6 +153,11 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'checkpoint',
+			call: 'admin_checkpoint',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -437,6 +442,63 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'debugTransaction',
+			call: 'debug_debugTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'debugCall',
+			call: 'debug_debugCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'debugStepInto',
+			call: 'debug_debugStepInto',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'debugStepOver',
+			call: 'debug_debugStepOver',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'debugStepOut',
+			call: 'debug_debugStepOut',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'debugContinue',
+			call: 'debug_debugContinue',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'debugSetBreakpoint',
+			call: 'debug_debugSetBreakpoint',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'debugRemoveBreakpoint',
+			call: 'debug_debugRemoveBreakpoint',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'debugMemory',
+			call: 'debug_debugMemory',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'debugStorage',
+			call: 'debug_debugStorage',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'debugEndSession',
+			call: 'debug_debugEndSession',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -491,6 +553,41 @@ web3._extend({
 			call: 'debug_dbAncients',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'dbHas',
+			call: 'debug_dbHas',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'dbStat',
+			call: 'debug_dbStat',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'dbIterate',
+			call: 'debug_dbIterate',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'dbAncientRange',
+			call: 'debug_dbAncientRange',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'dbAncientTail',
+			call: 'debug_dbAncientTail',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'dbAncientSize',
+			call: 'debug_dbAncientSize',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'dbWrite',
+			call: 'debug_dbWrite',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setTrieFlushInterval',
 			call: 'debug_setTrieFlushInterval',
@@ -622,6 +719,21 @@ web3._extend({
 			call: 'eth_getBlockReceipts',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'sendBundle',
+			call: 'eth_sendBundle',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactionConditional',
+			call: 'eth_sendRawTransactionConditional',
+			params: 2,
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -0,0 +1,243 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errBundleReverted is returned if a transaction of a bundle reverted without
+// being allowed to.
+var errBundleReverted = errors.New("bundle transaction reverted")
+
+// BundleTxResult is the outcome of a transaction of an executed bundle.
+type BundleTxResult struct {
+	Hash         common.Hash
+	From         common.Address
+	GasUsed      uint64
+	Err          error    // EVM error of the execution, e.g. revert
+	ReturnData   []byte   // Returned data or revert reason
+	CoinbaseDiff *big.Int // Balance change of the fee recipient
+}
+
+// BundleResult is the outcome of an executed bundle.
+type BundleResult struct {
+	Hash         common.Hash
+	Txs          []*BundleTxResult
+	GasUsed      uint64
+	CoinbaseDiff *big.Int // Balance change of the fee recipient
+	GasPrice     *big.Int // Effective gas price paid to the fee recipient
+}
+
+// check returns an error if a transaction of the bundle reverted without being
+// allowed to.
+func (r *BundleResult) check(bundle *bundlepool.Bundle) error {
+	for _, tx := range r.Txs {
+		if tx.Err != nil && !bundle.CanRevert(tx.Hash) {
+			return fmt.Errorf("%w: %x: %v", errBundleReverted, tx.Hash, tx.Err)
+		}
+	}
+	return nil
+}
+
+// copy returns a deep copy of the environment. The state of the environment
+// can't be reverted across transactions, so bundles are applied on a copy.
+func (env *environment) copy() *environment {
+	cpy := &environment{
+		signer:   env.signer,
+		state:    env.state.Copy(),
+		tcount:   env.tcount,
+		coinbase: env.coinbase,
+		header:   types.CopyHeader(env.header),
+		txs:      slices.Clone(env.txs),
+		receipts: slices.Clone(env.receipts),
+		sidecars: slices.Clone(env.sidecars),
+		blobs:    env.blobs,
+	}
+	if env.gasPool != nil {
+		gasPool := *env.gasPool
+		cpy.gasPool = &gasPool
+	}
+	return cpy
+}
+
+// applyBundle executes the transactions of the bundle in order on top of the
+// environment. If any of them is invalid, an error is returned and the
+// environment must be discarded. Reverted transactions are part of the result.
+func (miner *Miner) applyBundle(env *environment, bundle *bundlepool.Bundle) (*BundleResult, error) {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	var (
+		start  = env.state.GetBalance(env.coinbase).ToBig()
+		result = &BundleResult{Hash: bundle.Hash()}
+	)
+	for _, tx := range bundle.Txs {
+		before := env.state.GetBalance(env.coinbase).ToBig()
+
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+		receipt, exec, err := miner.applyBundleTransaction(env, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+
+		from, _ := types.Sender(env.signer, tx)
+		result.Txs = append(result.Txs, &BundleTxResult{
+			Hash:         tx.Hash(),
+			From:         from,
+			GasUsed:      receipt.GasUsed,
+			Err:          exec.Err,
+			ReturnData:   common.CopyBytes(exec.ReturnData),
+			CoinbaseDiff: new(big.Int).Sub(env.state.GetBalance(env.coinbase).ToBig(), before),
+		})
+		result.GasUsed += receipt.GasUsed
+	}
+	result.CoinbaseDiff = new(big.Int).Sub(env.state.GetBalance(env.coinbase).ToBig(), start)
+	result.GasPrice = new(big.Int)
+	if result.GasUsed > 0 {
+		result.GasPrice.Div(result.CoinbaseDiff, new(big.Int).SetUint64(result.GasUsed))
+	}
+	return result, nil
+}
+
+// applyBundleTransaction runs the transaction like applyTransaction, but also
+// returns the execution result.
+func (miner *Miner) applyBundleTransaction(env *environment, tx *types.Transaction) (*types.Receipt, *core.ExecutionResult, error) {
+	msg, err := core.TransactionToMessage(tx, env.signer, env.header.BaseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		context = core.NewEVMBlockContext(env.header, miner.chain, &env.coinbase)
+		vmenv   = vm.NewEVM(context, core.NewEVMTxContext(msg), env.state, miner.chainConfig, vm.Config{})
+	)
+	result, err := core.ApplyMessage(vmenv, msg, env.gasPool)
+	if err != nil {
+		return nil, nil, err
+	}
+	var root []byte
+	if miner.chainConfig.IsByzantium(env.header.Number) {
+		env.state.Finalise(true)
+	} else {
+		root = env.state.IntermediateRoot(miner.chainConfig.IsEIP158(env.header.Number)).Bytes()
+	}
+	env.header.GasUsed += result.UsedGas
+
+	receipt := core.MakeReceipt(vmenv, result, env.state, env.header.Number, env.header.Hash(), tx, env.header.GasUsed, root)
+	return receipt, result, nil
+}
+
+// commitBundles fills the bundles targeting the sealing block into it, before
+// any pool transaction. Every bundle is first simulated against the untouched
+// environment to rank them by the effective gas price they pay; bundles failing
+// the simulation are dropped from the pool. The bundles are then committed in
+// order, skipping the ones failing or paying less than simulated due to the
+// bundles committed before them.
+func (miner *Miner) commitBundles(env *environment, interrupt *atomic.Int32) error {
+	if miner.bundlePool == nil {
+		return nil
+	}
+	bundles := miner.bundlePool.Pending(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return nil
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	type candidate struct {
+		bundle *bundlepool.Bundle
+		result *BundleResult
+	}
+	var candidates []candidate
+	for _, bundle := range bundles {
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		result, err := miner.applyBundle(env.copy(), bundle)
+		if err == nil {
+			err = result.check(bundle)
+		}
+		if err != nil {
+			log.Debug("Bundle simulation failed, dropping", "hash", bundle.Hash(), "err", err)
+			miner.bundlePool.Drop(bundle.Hash())
+			continue
+		}
+		candidates = append(candidates, candidate{bundle, result})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].result.GasPrice.Cmp(candidates[j].result.GasPrice) > 0
+	})
+	for _, c := range candidates {
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		if env.gasPool.Gas() < c.result.GasUsed {
+			log.Trace("Not enough gas left for bundle", "hash", c.result.Hash, "left", env.gasPool.Gas(), "needed", c.result.GasUsed)
+			continue
+		}
+		work := env.copy()
+		result, err := miner.applyBundle(work, c.bundle)
+		if err == nil {
+			err = result.check(c.bundle)
+		}
+		if err == nil && result.GasPrice.Cmp(c.result.GasPrice) < 0 {
+			err = fmt.Errorf("gas price dropped from %v to %v", c.result.GasPrice, result.GasPrice)
+		}
+		if err != nil {
+			log.Trace("Bundle skipped", "hash", c.result.Hash, "err", err)
+			continue
+		}
+		*env = *work
+		log.Debug("Committed bundle", "hash", result.Hash, "txs", len(result.Txs), "gas", result.GasUsed, "price", result.GasPrice)
+	}
+	return nil
+}
+
+// SimulateBundle executes the bundle on top of the given parent block, in a
+// block with the given timestamp and fee recipient, returning the outcome. The
+// target block and timestamp range of the bundle are not enforced, reverted
+// transactions are reported in the result instead of failing the simulation.
+func (miner *Miner) SimulateBundle(bundle *bundlepool.Bundle, parent common.Hash, timestamp uint64, coinbase common.Address) (*BundleResult, error) {
+	env, err := miner.prepareWork(&generateParams{
+		timestamp:  timestamp,
+		parentHash: parent,
+		coinbase:   coinbase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return miner.applyBundle(env, bundle)
+}
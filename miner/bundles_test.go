@@ -0,0 +1,141 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// revertingCode is the init code of a contract creation reverting right away.
+var revertingCode = common.FromHex("0x60006000fd")
+
+// newBundleTx creates a transaction of the bank account paying the given gas
+// price, creating a contract with the code if the recipient is nil.
+func newBundleTx(nonce uint64, to *common.Address, gasPrice int64, code []byte) *types.Transaction {
+	gas := params.TxGas
+	if to == nil {
+		gas = 100_000
+	}
+	return types.MustSignNewTx(testBankKey, types.LatestSigner(params.TestChainConfig), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Value:    big.NewInt(1),
+		Gas:      gas,
+		GasPrice: big.NewInt(gasPrice * params.InitialBaseFee),
+		Data:     code,
+	})
+}
+
+func TestCommitBundles(t *testing.T) {
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+
+	var (
+		cheap    = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(0, &testUserAddress, 2, nil)}, BlockNumber: 1}
+		rich     = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(0, &testUserAddress, 10, nil), newBundleTx(1, &testUserAddress, 10, nil)}, BlockNumber: 1}
+		reverted = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(0, nil, 20, revertingCode)}, BlockNumber: 1}
+		future   = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(0, &testUserAddress, 30, nil)}, BlockNumber: 2}
+	)
+	for _, bundle := range []*bundlepool.Bundle{cheap, rich, reverted, future} {
+		if err := b.bundlePool.Add(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	result := w.generateWork(&generateParams{
+		timestamp:  uint64(time.Now().Unix()),
+		parentHash: b.chain.CurrentBlock().Hash(),
+		coinbase:   common.Address{0xc0},
+	})
+	if result.err != nil {
+		t.Fatalf("failed to generate work: %v", result.err)
+	}
+	// The richest bundle is included first, the conflicting one is skipped and
+	// the pool transaction with the same nonce is ignored.
+	txs := result.block.Transactions()
+	if len(txs) != 2 || txs[0].Hash() != rich.Txs[0].Hash() || txs[1].Hash() != rich.Txs[1].Hash() {
+		t.Fatalf("unexpected block transactions: %v", txs)
+	}
+	for i, receipt := range result.receipts {
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("transaction %d failed", i)
+		}
+	}
+	// The reverting bundle is dropped, the losing one is kept for later payloads
+	if b.bundlePool.Get(reverted.Hash()) != nil {
+		t.Fatal("reverting bundle not dropped")
+	}
+	if b.bundlePool.Get(cheap.Hash()) == nil || b.bundlePool.Get(future.Hash()) == nil {
+		t.Fatal("valid bundles dropped")
+	}
+}
+
+func TestCommitRevertibleBundle(t *testing.T) {
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+
+	tx := newBundleTx(0, nil, 20, revertingCode)
+	bundle := &bundlepool.Bundle{Txs: types.Transactions{tx}, BlockNumber: 1, RevertingTxHashes: []common.Hash{tx.Hash()}}
+	if err := b.bundlePool.Add(bundle); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	result := w.generateWork(&generateParams{
+		timestamp:  uint64(time.Now().Unix()),
+		parentHash: b.chain.CurrentBlock().Hash(),
+		coinbase:   common.Address{0xc0},
+	})
+	if result.err != nil {
+		t.Fatalf("failed to generate work: %v", result.err)
+	}
+	txs := result.block.Transactions()
+	if len(txs) == 0 || txs[0].Hash() != tx.Hash() || result.receipts[0].Status != types.ReceiptStatusFailed {
+		t.Fatalf("revertible bundle not included: %v", txs)
+	}
+}
+
+func TestSimulateBundle(t *testing.T) {
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+
+	var (
+		coinbase = common.Address{0xc0}
+		bundle   = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(0, &testUserAddress, 10, nil), newBundleTx(1, nil, 10, revertingCode)}}
+	)
+	result, err := w.SimulateBundle(bundle, b.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), coinbase)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if len(result.Txs) != 2 || result.Txs[0].Err != nil || result.Txs[1].Err == nil {
+		t.Fatalf("unexpected simulation result: %+v", result.Txs)
+	}
+	if result.Hash != bundle.Hash() || result.GasUsed != result.Txs[0].GasUsed+result.Txs[1].GasUsed {
+		t.Fatalf("unexpected simulation result: %+v", result)
+	}
+	if new(big.Int).Add(result.Txs[0].CoinbaseDiff, result.Txs[1].CoinbaseDiff).Cmp(result.CoinbaseDiff) != 0 || result.GasPrice.Sign() <= 0 {
+		t.Fatalf("unexpected coinbase payment: %v, gas price %v", result.CoinbaseDiff, result.GasPrice)
+	}
+	// Invalid bundles are rejected
+	bundle = &bundlepool.Bundle{Txs: types.Transactions{newBundleTx(5, &testUserAddress, 10, nil)}}
+	if _, err := w.SimulateBundle(bundle, b.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), coinbase); err == nil {
+		t.Fatal("invalid bundle simulated")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
+	"github.com/ethereum/go-ethereum/core/txpool/conditionalpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the conditions of a conditional transaction are re-checked against
+// the block state right before inclusion, skipping it if a preceding transaction
+// of the same block changed the guarded storage.
+func TestCommitConditionalTransactions(t *testing.T) {
+	var (
+		guarded      = common.Address{0xc0, 0xde}
+		writerKey, _ = crypto.GenerateKey()
+		earlyKey, _  = crypto.GenerateKey()
+		lateKey, _   = crypto.GenerateKey()
+		funds        = big.NewInt(params.Ether)
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			guarded: {Code: common.FromHex("0x600160005500")}, // sstore(0, 1)
+			crypto.PubkeyToAddress(writerKey.PublicKey): {Balance: funds},
+			crypto.PubkeyToAddress(earlyKey.PublicKey):  {Balance: funds},
+			crypto.PubkeyToAddress(lateKey.PublicKey):   {Balance: funds},
+		},
+	}
+	engine := ethash.NewFaker()
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		legacyPool      = legacypool.New(testTxPoolConfig, chain)
+		conditionalPool = conditionalpool.New(conditionalpool.DefaultConfig, chain)
+	)
+	pool, err := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{legacyPool, conditionalPool})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	backend := &testWorkerBackend{
+		chain:      chain,
+		txPool:     pool,
+		bundlePool: bundlepool.New(bundlepool.DefaultConfig, chain),
+		genesis:    gspec,
+	}
+	miner := New(backend, testConfig, engine)
+
+	newTx := func(key *ecdsa.PrivateKey, to common.Address, gas uint64, gasPrice int64) *types.Transaction {
+		return types.MustSignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.LegacyTx{
+			To:       &to,
+			Gas:      gas,
+			GasPrice: big.NewInt(gasPrice * params.InitialBaseFee),
+		})
+	}
+	var (
+		writer = newTx(writerKey, guarded, 100_000, 10)              // Changes the guarded slot
+		early  = newTx(earlyKey, common.Address{}, params.TxGas, 20) // Conditional, precedes the writer
+		late   = newTx(lateKey, common.Address{}, params.TxGas, 5)   // Conditional, follows the writer
+	)
+	if errs := pool.Add([]*types.Transaction{writer}, false, true); errs[0] != nil {
+		t.Fatalf("failed to add writer transaction: %v", errs[0])
+	}
+	// Guard the early transaction by the slot and the late one by the storage root
+	root := types.EmptyRootHash
+	if err := conditionalPool.AddConditional(early, &conditionalpool.Conditions{
+		KnownAccounts: map[common.Address]conditionalpool.KnownAccount{
+			guarded: {StorageSlots: map[common.Hash]common.Hash{{}: {}}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add early conditional transaction: %v", err)
+	}
+	if err := conditionalPool.AddConditional(late, &conditionalpool.Conditions{
+		KnownAccounts: map[common.Address]conditionalpool.KnownAccount{
+			guarded: {StorageRoot: &root},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add late conditional transaction: %v", err)
+	}
+	result := miner.generateWork(&generateParams{
+		timestamp:  uint64(time.Now().Unix()),
+		parentHash: chain.CurrentBlock().Hash(),
+		coinbase:   common.Address{0xc0},
+	})
+	if result.err != nil {
+		t.Fatalf("failed to generate work: %v", result.err)
+	}
+	// The early conditional transaction still sees the untouched storage, while
+	// the late one is skipped since the writer changed it.
+	txs := result.block.Transactions()
+	if len(txs) != 2 || txs[0].Hash() != early.Hash() || txs[1].Hash() != writer.Hash() {
+		t.Fatalf("unexpected block transactions: %v", txs)
+	}
+}
@@ -29,7 +29,9 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -38,6 +40,7 @@ import (
 type Backend interface {
 	BlockChain() *core.BlockChain
 	TxPool() *txpool.TxPool
+	BundlePool() *bundlepool.BundlePool
 }
 
 // Config is the configuration parameters of mining.
@@ -48,6 +51,9 @@ type Config struct {
 	GasCeil             uint64         // Target gas ceiling for mined blocks.
 	GasPrice            *big.Int       // Minimum gas price for mining a transaction
 	Recommit            time.Duration  // The time interval for miner to re-create mining work.
+
+	Ordering        string           `toml:",omitempty"` // Transaction ordering strategy (price, fifo, roundrobin, priority)
+	PrioritySenders []common.Address `toml:",omitempty"` // Senders included first by the priority ordering, in order
 }
 
 // DefaultConfig contains default settings for miner.
@@ -67,9 +73,11 @@ var DefaultConfig = Config{
 type Miner struct {
 	confMu      sync.RWMutex // The lock used to protect the config fields: GasCeil, GasTip and Extradata
 	config      *Config
+	ordering    Ordering // Transaction ordering strategy, fixed at creation
 	chainConfig *params.ChainConfig
 	engine      consensus.Engine
 	txpool      *txpool.TxPool
+	bundlePool  *bundlepool.BundlePool
 	chain       *core.BlockChain
 	pending     *pending
 	pendingMu   sync.Mutex // Lock protects the pending block
@@ -77,11 +85,18 @@ type Miner struct {
 
 // New creates a new miner with provided config.
 func New(eth Backend, config Config, engine consensus.Engine) *Miner {
+	ordering, err := NewOrdering(config.Ordering, config.PrioritySenders)
+	if err != nil {
+		log.Warn("Invalid transaction ordering, using price ordering", "err", err)
+		ordering = priceOrdering{}
+	}
 	return &Miner{
 		config:      &config,
+		ordering:    ordering,
 		chainConfig: eth.BlockChain().Config(),
 		engine:      engine,
 		txpool:      eth.TxPool(),
+		bundlePool:  eth.BundlePool(),
 		chain:       eth.BlockChain(),
 		pending:     &pending{},
 	}
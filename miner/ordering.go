@@ -17,7 +17,9 @@
 package miner
 
 import (
+	"bytes"
 	"container/heap"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -26,11 +28,164 @@ import (
 	"github.com/holiman/uint256"
 )
 
+// Names of the built-in transaction ordering strategies.
+const (
+	OrderingPrice      = "price"      // Highest effective tip first, local senders before remote ones
+	OrderingFIFO       = "fifo"       // Strict arrival time order
+	OrderingRoundRobin = "roundrobin" // One transaction per sender and round, highest tip first within a round
+	OrderingPriority   = "priority"   // Configured senders first in the given order, then highest tip first
+)
+
+// Ordering is a strategy deciding the order in which the pending transactions
+// are included into a block.
+type Ordering interface {
+	// Order creates the ordered set of the pending transactions, grouped by
+	// sender and sorted by nonce. Locals are the senders of local transactions.
+	//
+	// Note, the input map is reowned so the caller should not interact any more
+	// with it after providing it to the ordering.
+	Order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, locals []common.Address, baseFee *big.Int) TransactionSet
+}
+
+// TransactionSet is an ordered set of transactions honouring the nonce order
+// of every sender.
+type TransactionSet interface {
+	// Peek returns the next transaction and its effective miner tip, nil if the
+	// set is exhausted.
+	Peek() (*txpool.LazyTransaction, *uint256.Int)
+
+	// Shift replaces the next transaction with the next one from the same sender.
+	Shift()
+
+	// Pop removes the next transaction, *not* replacing it with the next one from
+	// the same sender. This should be used when a transaction cannot be executed
+	// and hence all subsequent ones should be discarded from the same sender.
+	Pop()
+}
+
+// NewOrdering creates the built-in ordering strategy with the given name. The
+// priority senders are only used by the priority ordering.
+func NewOrdering(name string, prioritySenders []common.Address) (Ordering, error) {
+	switch name {
+	case "", OrderingPrice:
+		return priceOrdering{}, nil
+	case OrderingFIFO:
+		return fifoOrdering{}, nil
+	case OrderingRoundRobin:
+		return roundRobinOrdering{}, nil
+	case OrderingPriority:
+		ranks := make(map[common.Address]int, len(prioritySenders))
+		for i, sender := range prioritySenders {
+			if _, ok := ranks[sender]; !ok {
+				ranks[sender] = i
+			}
+		}
+		return &priorityOrdering{ranks: ranks}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering %q", name)
+	}
+}
+
+// priceOrdering includes the transactions of the local senders first, then the
+// remote ones, each ordered by effective miner tip and arrival time.
+type priceOrdering struct{}
+
+func (priceOrdering) Order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, locals []common.Address, baseFee *big.Int) TransactionSet {
+	localTxs := make(map[common.Address][]*txpool.LazyTransaction)
+	for _, account := range locals {
+		if accTxs := txs[account]; len(accTxs) > 0 {
+			delete(txs, account)
+			localTxs[account] = accTxs
+		}
+	}
+	return transactionSets{
+		newTransactionsByPriceAndNonce(signer, localTxs, baseFee),
+		newTransactionsByPriceAndNonce(signer, txs, baseFee),
+	}
+}
+
+// fifoOrdering includes the transactions in the order they were first seen.
+type fifoOrdering struct{}
+
+func (fifoOrdering) Order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, locals []common.Address, baseFee *big.Int) TransactionSet {
+	return newTransactionsByOrderAndNonce(signer, txs, baseFee, byTime)
+}
+
+// roundRobinOrdering includes one transaction of every sender per round, the
+// senders of a round being ordered by effective miner tip and arrival time.
+type roundRobinOrdering struct{}
+
+func (roundRobinOrdering) Order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, locals []common.Address, baseFee *big.Int) TransactionSet {
+	return newTransactionsByOrderAndNonce(signer, txs, baseFee, func(a, b *txWithMinerFee) bool {
+		if a.round != b.round {
+			return a.round < b.round
+		}
+		return byPriceAndTime(a, b)
+	})
+}
+
+// priorityOrdering includes the transactions of the priority senders first, in
+// the configured order, then the rest ordered by effective miner tip and arrival
+// time.
+type priorityOrdering struct {
+	ranks map[common.Address]int // Position of the priority senders
+}
+
+// rank returns the position of the sender in the priority list.
+func (o *priorityOrdering) rank(sender common.Address) int {
+	if rank, ok := o.ranks[sender]; ok {
+		return rank
+	}
+	return len(o.ranks)
+}
+
+func (o *priorityOrdering) Order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, locals []common.Address, baseFee *big.Int) TransactionSet {
+	return newTransactionsByOrderAndNonce(signer, txs, baseFee, func(a, b *txWithMinerFee) bool {
+		if ra, rb := o.rank(a.from), o.rank(b.from); ra != rb {
+			return ra < rb
+		}
+		return byPriceAndTime(a, b)
+	})
+}
+
+// transactionSets chains multiple transaction sets, exhausting them in order.
+type transactionSets []TransactionSet
+
+// next returns the first set which is not exhausted yet.
+func (sets transactionSets) next() TransactionSet {
+	for _, set := range sets {
+		if tx, _ := set.Peek(); tx != nil {
+			return set
+		}
+	}
+	return nil
+}
+
+func (sets transactionSets) Peek() (*txpool.LazyTransaction, *uint256.Int) {
+	if set := sets.next(); set != nil {
+		return set.Peek()
+	}
+	return nil, nil
+}
+
+func (sets transactionSets) Shift() {
+	if set := sets.next(); set != nil {
+		set.Shift()
+	}
+}
+
+func (sets transactionSets) Pop() {
+	if set := sets.next(); set != nil {
+		set.Pop()
+	}
+}
+
 // txWithMinerFee wraps a transaction with its gas price or effective miner gasTipCap
 type txWithMinerFee struct {
-	tx   *txpool.LazyTransaction
-	from common.Address
-	fees *uint256.Int
+	tx    *txpool.LazyTransaction
+	from  common.Address
+	fees  *uint256.Int
+	round int // Number of transactions of the sender ordered before
 }
 
 // newTxWithMinerFee creates a wrapped transaction, calculating the effective
@@ -54,46 +209,62 @@ func newTxWithMinerFee(tx *txpool.LazyTransaction, from common.Address, baseFee
 	}, nil
 }
 
-// txByPriceAndTime implements both the sort and the heap interface, making it useful
-// for all at once sorting as well as individually adding and removing elements.
-type txByPriceAndTime []*txWithMinerFee
-
-func (s txByPriceAndTime) Len() int { return len(s) }
-func (s txByPriceAndTime) Less(i, j int) bool {
+// byPriceAndTime orders the transactions by effective miner tip, then by the time
+// they were first seen.
+func byPriceAndTime(a, b *txWithMinerFee) bool {
 	// If the prices are equal, use the time the transaction was first seen for
 	// deterministic sorting
-	cmp := s[i].fees.Cmp(s[j].fees)
+	cmp := a.fees.Cmp(b.fees)
 	if cmp == 0 {
-		return s[i].tx.Time.Before(s[j].tx.Time)
+		return a.tx.Time.Before(b.tx.Time)
 	}
 	return cmp > 0
 }
-func (s txByPriceAndTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-func (s *txByPriceAndTime) Push(x interface{}) {
-	*s = append(*s, x.(*txWithMinerFee))
+// byTime orders the transactions by the time they were first seen, falling back
+// to the hash for deterministic sorting.
+func byTime(a, b *txWithMinerFee) bool {
+	if !a.tx.Time.Equal(b.tx.Time) {
+		return a.tx.Time.Before(b.tx.Time)
+	}
+	return bytes.Compare(a.tx.Hash[:], b.tx.Hash[:]) < 0
+}
+
+// txHeads implements both the sort and the heap interface over the next
+// transaction of every account, ordered by the comparison function.
+type txHeads struct {
+	txs  []*txWithMinerFee
+	less func(a, b *txWithMinerFee) bool
+}
+
+func (s *txHeads) Len() int           { return len(s.txs) }
+func (s *txHeads) Less(i, j int) bool { return s.less(s.txs[i], s.txs[j]) }
+func (s *txHeads) Swap(i, j int)      { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
+
+func (s *txHeads) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*txWithMinerFee))
 }
 
-func (s *txByPriceAndTime) Pop() interface{} {
-	old := *s
+func (s *txHeads) Pop() interface{} {
+	old := s.txs
 	n := len(old)
 	x := old[n-1]
 	old[n-1] = nil
-	*s = old[0 : n-1]
+	s.txs = old[0 : n-1]
 	return x
 }
 
-// transactionsByPriceAndNonce represents a set of transactions that can return
-// transactions in a profit-maximizing sorted order, while supporting removing
+// transactionsByOrderAndNonce represents a set of transactions that can return
+// transactions in the order of a comparison function, while supporting removing
 // entire batches of transactions for non-executable accounts.
-type transactionsByPriceAndNonce struct {
+type transactionsByOrderAndNonce struct {
 	txs     map[common.Address][]*txpool.LazyTransaction // Per account nonce-sorted list of transactions
-	heads   txByPriceAndTime                             // Next transaction for each unique account (price heap)
+	heads   *txHeads                                     // Next transaction for each unique account (heap)
 	signer  types.Signer                                 // Signer for the set of transactions
 	baseFee *uint256.Int                                 // Current base fee
 }
 
-func NewTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) *transactionsByPriceAndNonce {
+func NewTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) *transactionsByOrderAndNonce {
 	return newTransactionsByPriceAndNonce(signer, txs, baseFee)
 }
 
@@ -102,27 +273,36 @@ func NewTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address]
 //
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providing it to the constructor.
-func newTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) *transactionsByPriceAndNonce {
+func newTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) *transactionsByOrderAndNonce {
+	return newTransactionsByOrderAndNonce(signer, txs, baseFee, byPriceAndTime)
+}
+
+// newTransactionsByOrderAndNonce creates a transaction set that can retrieve
+// transactions sorted by the comparison function in a nonce-honouring way.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func newTransactionsByOrderAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int, less func(a, b *txWithMinerFee) bool) *transactionsByOrderAndNonce {
 	// Convert the basefee from header format to uint256 format
 	var baseFeeUint *uint256.Int
 	if baseFee != nil {
 		baseFeeUint = uint256.MustFromBig(baseFee)
 	}
-	// Initialize a price and received time based heap with the head transactions
-	heads := make(txByPriceAndTime, 0, len(txs))
+	// Initialize a heap with the head transactions
+	heads := &txHeads{txs: make([]*txWithMinerFee, 0, len(txs)), less: less}
 	for from, accTxs := range txs {
 		wrapped, err := newTxWithMinerFee(accTxs[0], from, baseFeeUint)
 		if err != nil {
 			delete(txs, from)
 			continue
 		}
-		heads = append(heads, wrapped)
+		heads.txs = append(heads.txs, wrapped)
 		txs[from] = accTxs[1:]
 	}
-	heap.Init(&heads)
+	heap.Init(heads)
 
 	// Assemble and return the transaction set
-	return &transactionsByPriceAndNonce{
+	return &transactionsByOrderAndNonce{
 		txs:     txs,
 		heads:   heads,
 		signer:  signer,
@@ -130,41 +310,42 @@ func newTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address]
 	}
 }
 
-// Peek returns the next transaction by price.
-func (t *transactionsByPriceAndNonce) Peek() (*txpool.LazyTransaction, *uint256.Int) {
-	if len(t.heads) == 0 {
+// Peek returns the next transaction in order.
+func (t *transactionsByOrderAndNonce) Peek() (*txpool.LazyTransaction, *uint256.Int) {
+	if len(t.heads.txs) == 0 {
 		return nil, nil
 	}
-	return t.heads[0].tx, t.heads[0].fees
+	return t.heads.txs[0].tx, t.heads.txs[0].fees
 }
 
 // Shift replaces the current best head with the next one from the same account.
-func (t *transactionsByPriceAndNonce) Shift() {
-	acc := t.heads[0].from
-	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
-		if wrapped, err := newTxWithMinerFee(txs[0], acc, t.baseFee); err == nil {
-			t.heads[0], t.txs[acc] = wrapped, txs[1:]
-			heap.Fix(&t.heads, 0)
+func (t *transactionsByOrderAndNonce) Shift() {
+	head := t.heads.txs[0]
+	if txs, ok := t.txs[head.from]; ok && len(txs) > 0 {
+		if wrapped, err := newTxWithMinerFee(txs[0], head.from, t.baseFee); err == nil {
+			wrapped.round = head.round + 1
+			t.heads.txs[0], t.txs[head.from] = wrapped, txs[1:]
+			heap.Fix(t.heads, 0)
 			return
 		}
 	}
-	heap.Pop(&t.heads)
+	heap.Pop(t.heads)
 }
 
 // Pop removes the best transaction, *not* replacing it with the next one from
 // the same account. This should be used when a transaction cannot be executed
 // and hence all subsequent ones should be discarded from the same account.
-func (t *transactionsByPriceAndNonce) Pop() {
-	heap.Pop(&t.heads)
+func (t *transactionsByOrderAndNonce) Pop() {
+	heap.Pop(t.heads)
 }
 
-// Empty returns if the price heap is empty. It can be used to check it simpler
-// than calling peek and checking for nil return.
-func (t *transactionsByPriceAndNonce) Empty() bool {
-	return len(t.heads) == 0
+// Empty returns if the heap is empty. It can be used to check it simpler than
+// calling peek and checking for nil return.
+func (t *transactionsByOrderAndNonce) Empty() bool {
+	return len(t.heads.txs) == 0
 }
 
 // Clear removes the entire content of the heap.
-func (t *transactionsByPriceAndNonce) Clear() {
-	t.heads, t.txs = nil, nil
+func (t *transactionsByOrderAndNonce) Clear() {
+	t.heads.txs, t.txs = nil, nil
 }
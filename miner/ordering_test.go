@@ -18,8 +18,10 @@ package miner
 
 import (
 	"crypto/ecdsa"
+	"fmt"
 	"math/big"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
 
@@ -194,3 +197,102 @@ func TestTransactionTimeSort(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the built-in ordering strategies order the transactions as
+// expected, honouring the nonce order of every sender.
+func TestOrderingStrategies(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a, b, c = common.Address{0xa}, common.Address{0xb}, common.Address{0xc}
+		names   = make(map[common.Hash]string)
+	)
+	// newTxs creates the transaction list of a sender with the given tip and
+	// first seen times.
+	newTxs := func(sender common.Address, name string, tip uint64, times ...int64) []*txpool.LazyTransaction {
+		var txs []*txpool.LazyTransaction
+		for i, seen := range times {
+			hash := common.Hash{sender[0], byte(i)}
+			names[hash] = fmt.Sprintf("%s%d", name, i)
+			txs = append(txs, &txpool.LazyTransaction{
+				Hash:      hash,
+				Time:      time.Unix(seen, 0),
+				GasFeeCap: uint256.NewInt(tip),
+				GasTipCap: uint256.NewInt(tip),
+				Gas:       params.TxGas,
+			})
+		}
+		return txs
+	}
+	for _, tt := range []struct {
+		name    string
+		senders []common.Address
+		locals  []common.Address
+		want    string
+	}{
+		{OrderingPrice, nil, nil, "b0 b1 b2 c0 c1 a0 a1 a2"},
+		{OrderingPrice, nil, []common.Address{a}, "a0 a1 a2 b0 b1 b2 c0 c1"},
+		{OrderingFIFO, nil, []common.Address{a}, "a0 b0 b1 c0 a1 a2 b2 c1"},
+		{OrderingRoundRobin, nil, nil, "b0 c0 a0 b1 c1 a1 b2 a2"},
+		{OrderingPriority, []common.Address{c, a}, nil, "c0 c1 a0 a1 a2 b0 b1 b2"},
+	} {
+		ordering, err := NewOrdering(tt.name, tt.senders)
+		if err != nil {
+			t.Fatalf("failed to create %s ordering: %v", tt.name, err)
+		}
+		txs := map[common.Address][]*txpool.LazyTransaction{
+			a: newTxs(a, "a", 1, 1, 5, 6),
+			b: newTxs(b, "b", 3, 2, 3, 7),
+			c: newTxs(c, "c", 2, 4, 8),
+		}
+		set := ordering.Order(types.HomesteadSigner{}, txs, tt.locals, nil)
+
+		var have []string
+		for tx, _ := set.Peek(); tx != nil; tx, _ = set.Peek() {
+			have = append(have, names[tx.Hash])
+			set.Shift()
+		}
+		if strings.Join(have, " ") != tt.want {
+			t.Errorf("%s ordering (locals %v): have %v, want %v", tt.name, tt.locals, have, tt.want)
+		}
+	}
+	if _, err := NewOrdering("unknown", nil); err == nil {
+		t.Fatal("unknown ordering created")
+	}
+}
+
+// Tests that popping a transaction drops the rest of the sender's transactions
+// with every strategy.
+func TestOrderingPop(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{OrderingPrice, OrderingFIFO, OrderingRoundRobin, OrderingPriority} {
+		ordering, _ := NewOrdering(name, nil)
+		txs := make(map[common.Address][]*txpool.LazyTransaction)
+		for i := 0; i < 3; i++ {
+			sender := common.Address{byte(i)}
+			for j := 0; j < 3; j++ {
+				txs[sender] = append(txs[sender], &txpool.LazyTransaction{
+					Hash:      common.Hash{byte(i), byte(j)},
+					Time:      time.Unix(int64(i*3+j), 0),
+					GasFeeCap: uint256.NewInt(1),
+					GasTipCap: uint256.NewInt(1),
+				})
+			}
+		}
+		set := ordering.Order(types.HomesteadSigner{}, txs, nil, nil)
+
+		var count int
+		for tx, _ := set.Peek(); tx != nil; tx, _ = set.Peek() {
+			count++
+			if tx.Hash[1] == 1 {
+				set.Pop()
+			} else {
+				set.Shift()
+			}
+		}
+		if count != 6 {
+			t.Errorf("%s ordering: have %d transactions, want 6", name, count)
+		}
+	}
+}
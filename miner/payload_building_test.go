@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -99,10 +100,11 @@ func init() {
 
 // testWorkerBackend implements worker.Backend interfaces and wraps all information needed during the testing.
 type testWorkerBackend struct {
-	db      ethdb.Database
-	txPool  *txpool.TxPool
-	chain   *core.BlockChain
-	genesis *core.Genesis
+	db         ethdb.Database
+	txPool     *txpool.TxPool
+	bundlePool *bundlepool.BundlePool
+	chain      *core.BlockChain
+	genesis    *core.Genesis
 }
 
 func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, n int) *testWorkerBackend {
@@ -129,15 +131,17 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 	txpool, _ := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{pool})
 
 	return &testWorkerBackend{
-		db:      db,
-		chain:   chain,
-		txPool:  txpool,
-		genesis: gspec,
+		db:         db,
+		chain:      chain,
+		txPool:     txpool,
+		bundlePool: bundlepool.New(bundlepool.DefaultConfig, chain),
+		genesis:    gspec,
 	}
 }
 
-func (b *testWorkerBackend) BlockChain() *core.BlockChain { return b.chain }
-func (b *testWorkerBackend) TxPool() *txpool.TxPool       { return b.txPool }
+func (b *testWorkerBackend) BlockChain() *core.BlockChain       { return b.chain }
+func (b *testWorkerBackend) TxPool() *txpool.TxPool             { return b.txPool }
+func (b *testWorkerBackend) BundlePool() *bundlepool.BundlePool { return b.bundlePool }
 
 func newTestWorker(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, blocks int) (*Miner, *testWorkerBackend) {
 	backend := newTestWorkerBackend(t, chainConfig, engine, db, blocks)
@@ -40,6 +40,7 @@ var (
 	legacyStateTestDir             = filepath.Join(baseDir, "LegacyTests", "Constantinople", "GeneralStateTests")
 	transactionTestDir             = filepath.Join(baseDir, "TransactionTests")
 	rlpTestDir                     = filepath.Join(baseDir, "RLPTests")
+	eofTestDir                     = filepath.Join(baseDir, "EOFTests")
 	difficultyTestDir              = filepath.Join(baseDir, "BasicTests")
 	executionSpecBlockchainTestDir = filepath.Join(".", "spec-tests", "fixtures", "blockchain_tests")
 	executionSpecStateTestDir      = filepath.Join(".", "spec-tests", "fixtures", "state_tests")
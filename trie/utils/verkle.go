@@ -231,15 +231,18 @@ func storageIndex(bytes []byte) (*uint256.Int, byte) {
 		// and the sub-index is the LSB of the modified storage key.
 		return zero, byte(key[0] & 0xFF)
 	}
+	// The sub-index is the LSB of the original storage key, since mainStorageOffset
+	// doesn't affect this byte, so we can avoid masks or shifts. It must be taken
+	// before the key is shifted.
+	subIndex := byte(key[0] & 0xFF)
+
 	// We first divide by VerkleNodeWidth to create room to avoid an overflow next.
 	key.Rsh(&key, uint(verkleNodeWidthLog2))
 
 	// We add mainStorageOffset/VerkleNodeWidth which can't overflow.
 	key.Add(&key, mainStorageOffsetLshVerkleNodeWidth)
 
-	// The sub-index is the LSB of the original storage key, since mainStorageOffset
-	// doesn't affect this byte, so we can avoid masks or shifts.
-	return &key, byte(key[0] & 0xFF)
+	return &key, subIndex
 }
 
 // StorageSlotKey returns the verkle tree key of the storage slot for the
@@ -62,6 +62,22 @@ func TestTreeKey(t *testing.T) {
 	}
 }
 
+// Tests that the main storage slots within the same group are mapped to the
+// distinct sub-indexes of the same stem.
+func TestStorageSlotKeySubIndex(t *testing.T) {
+	address := []byte{0x01}
+	for _, pair := range [][2]uint64{{64, 128}, {256, 257}, {1000, 1023}} {
+		a := StorageSlotKey(address, uint256.NewInt(pair[0]).Bytes())
+		b := StorageSlotKey(address, uint256.NewInt(pair[1]).Bytes())
+		if !bytes.Equal(a[:31], b[:31]) {
+			t.Fatalf("slots %d and %d in different stems", pair[0], pair[1])
+		}
+		if a[31] != byte(pair[0]) || b[31] != byte(pair[1]) {
+			t.Fatalf("slots %d and %d mapped to sub-indexes %d and %d", pair[0], pair[1], a[31], b[31])
+		}
+	}
+}
+
 // goos: darwin
 // goarch: amd64
 // pkg: github.com/ethereum/go-ethereum/trie/utils
@@ -257,6 +257,18 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// SetTracker installs the tracker notified of the state updates and commits,
+// or removes it if nil is given. It's only supported by hash-based database
+// and will return an error for others.
+func (db *Database) SetTracker(tracker hashdb.Tracker) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetTracker(tracker)
+	return nil
+}
+
 // Recover rollbacks the database to a specified historical point. The state is
 // supported as the rollback destination only if it's canonical state and the
 // corresponding trie histories are existent. It's only supported by path-based
@@ -276,6 +288,17 @@ func (db *Database) Recover(target common.Hash) error {
 	return pdb.Recover(target, loader)
 }
 
+// CheckpointHistory creates a consistent copy of the state history in the given
+// directory, which must not exist yet. It's only supported by path-based database
+// and will return an error for others.
+func (db *Database) CheckpointHistory(dir string) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.CheckpointHistory(dir)
+}
+
 // Recoverable returns the indicator if the specified state is enabled to be
 // recovered. It's only supported by path-based database and will return an
 // error for others.
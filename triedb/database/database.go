@@ -31,6 +31,18 @@ type Reader interface {
 	Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error)
 }
 
+// StateReader wraps the methods of a backing reader of flat states.
+type StateReader interface {
+	// Account retrieves the account with the provided address, in the slim
+	// RLP format. No error will be returned if the account is not found.
+	Account(address common.Address) ([]byte, error)
+
+	// Storage retrieves the storage slot with the provided account address
+	// and the raw slot key, with the prefix-zero trimmed. No error will be
+	// returned if the slot is not found.
+	Storage(address common.Address, key common.Hash) ([]byte, error)
+}
+
 // PreimageStore wraps the methods of a backing store for reading and writing
 // trie node preimages.
 type PreimageStore interface {
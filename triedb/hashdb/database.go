@@ -65,6 +65,24 @@ type ChildResolver interface {
 	ForEach(node []byte, onChild func(common.Hash))
 }
 
+// Tracker is notified of the state updates and commits of the database, which
+// allows tracking the trie nodes that may be persisted from a point in time.
+// The callbacks are invoked with the database lock held, they must not call
+// back into the database.
+type Tracker interface {
+	// OnNode is called with the hash of every trie node inserted by a state
+	// update, including the ones already cached, before any of them can be
+	// written to disk.
+	OnNode(hash common.Hash)
+
+	// OnUpdate is called once all the trie nodes of the state update to the
+	// given root are inserted.
+	OnUpdate(root common.Hash)
+
+	// OnCommit is called once the entire trie of the given root is on disk.
+	OnCommit(root common.Hash)
+}
+
 // Config contains the settings for database.
 type Config struct {
 	CleanCacheSize int // Maximum memory allowance (in bytes) for caching clean nodes
@@ -102,6 +120,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	tracker Tracker // Optional tracker of the state updates and commits
+
 	lock sync.RWMutex
 }
 
@@ -435,6 +455,9 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	}
 	batch.Reset()
 
+	if db.tracker != nil {
+		db.tracker.OnCommit(node)
+	}
 	// Reset the storage counters and bumped metrics
 	memcacheCommitTimeTimer.Update(time.Since(start))
 	memcacheCommitBytesMeter.Mark(int64(storage - db.dirtiesSize))
@@ -540,6 +563,15 @@ func (c *cleaner) Delete(key []byte) error {
 	panic("not implemented")
 }
 
+// SetTracker installs the tracker notified of the state updates and commits,
+// or removes it if nil is given.
+func (db *Database) SetTracker(tracker Tracker) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.tracker = tracker
+}
+
 // Initialized returns an indicator if state data is already initialized
 // in hash-based scheme by checking the presence of genesis state.
 func (db *Database) Initialized(genesisRoot common.Hash) bool {
@@ -580,6 +612,9 @@ func (db *Database) Update(root common.Hash, parent common.Hash, block uint64, n
 			if n.IsDeleted() {
 				return // ignore deletion
 			}
+			if db.tracker != nil {
+				db.tracker.OnNode(n.Hash)
+			}
 			db.insert(n.Hash, n.Blob)
 		})
 	}
@@ -596,6 +631,9 @@ func (db *Database) Update(root common.Hash, parent common.Hash, block uint64, n
 			}
 		}
 	}
+	if db.tracker != nil {
+		db.tracker.OnUpdate(root)
+	}
 	return nil
 }
 
@@ -140,6 +140,7 @@ type Database struct {
 	diskdb     ethdb.Database               // Persistent storage for matured trie nodes
 	tree       *layerTree                   // The group for all known layers
 	freezer    ethdb.ResettableAncientStore // Freezer for storing trie histories, nil possible in tests
+	indexer    *historyIndexer              // Indexer of the state histories, nil if freezer is nil
 	lock       sync.RWMutex                 // Lock to prevent mutations from happening at the same time
 }
 
@@ -168,6 +169,9 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 	if err := db.repairHistory(); err != nil {
 		log.Crit("Failed to repair pathdb", "err", err)
 	}
+	if db.indexer != nil && !db.readOnly {
+		db.indexer.start()
+	}
 	// Disable database in case node is still in the initial state sync stage.
 	if rawdb.ReadSnapSyncStatusFlag(diskdb) == rawdb.StateSyncRunning && !db.readOnly {
 		if err := db.Disable(); err != nil {
@@ -195,6 +199,7 @@ func (db *Database) repairHistory() error {
 		log.Crit("Failed to open state history freezer", "err", err)
 	}
 	db.freezer = freezer
+	db.indexer = newHistoryIndexer(db.diskdb, freezer)
 
 	// Reset the entire state histories if the trie database is not initialized
 	// yet. This action is necessary because these state histories are not
@@ -206,7 +211,7 @@ func (db *Database) repairHistory() error {
 			log.Crit("Failed to retrieve head of state history", "err", err)
 		}
 		if frozen != 0 {
-			err := db.freezer.Reset()
+			err := db.indexer.reset()
 			if err != nil {
 				log.Crit("Failed to reset state histories", "err", err)
 			}
@@ -216,7 +221,7 @@ func (db *Database) repairHistory() error {
 	}
 	// Truncate the extra state histories above in freezer in case it's not
 	// aligned with the disk layer. It might happen after a unclean shutdown.
-	pruned, err := truncateFromHead(db.diskdb, db.freezer, id)
+	pruned, err := db.indexer.truncateFromHead(db.diskdb, id)
 	if err != nil {
 		log.Crit("Failed to truncate extra state histories", "err", err)
 	}
@@ -327,7 +332,7 @@ func (db *Database) Enable(root common.Hash) error {
 	// mappings can be huge and might take a while to clear
 	// them, just leave them in disk and wait for overwriting.
 	if db.freezer != nil {
-		if err := db.freezer.Reset(); err != nil {
+		if err := db.indexer.reset(); err != nil {
 			return err
 		}
 	}
@@ -381,7 +386,7 @@ func (db *Database) Recover(root common.Hash, loader triestate.TrieLoader) error
 		db.tree.reset(dl)
 	}
 	rawdb.DeleteTrieJournal(db.diskdb)
-	_, err := truncateFromHead(db.diskdb, db.freezer, dl.stateID())
+	_, err := db.indexer.truncateFromHead(db.diskdb, dl.stateID())
 	if err != nil {
 		return err
 	}
@@ -389,6 +394,23 @@ func (db *Database) Recover(root common.Hash, loader triestate.TrieLoader) error
 	return nil
 }
 
+// CheckpointHistory creates a consistent copy of the state history freezer in
+// the given directory, which must not exist yet. Nothing is copied if the state
+// history is not kept in a freezer.
+func (db *Database) CheckpointHistory(dir string) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.freezer == nil {
+		return nil
+	}
+	freezer, ok := db.freezer.(ethdb.Checkpointer)
+	if !ok {
+		return errors.New("state history freezer doesn't support checkpoints")
+	}
+	return freezer.Checkpoint(dir)
+}
+
 // Recoverable returns the indicator if the specified state is recoverable.
 func (db *Database) Recoverable(root common.Hash) bool {
 	// Ensure the requested state is a known state.
@@ -434,10 +456,12 @@ func (db *Database) Close() error {
 	// Release the memory held by clean cache.
 	db.tree.bottom().resetCache()
 
-	// Close the attached state history freezer.
+	// Close the attached state history freezer, after terminating the
+	// indexing of the histories.
 	if db.freezer == nil {
 		return nil
 	}
+	db.indexer.close()
 	return db.freezer.Close()
 }
 
@@ -0,0 +1,461 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The state history index maps every account and storage slot to the ids of
+// the state histories in which it was modified. Since a state history holds
+// the values of the state before the transition, the value of an account at
+// state n is found in the first history after n modifying it, or in the disk
+// layer if it was not modified since.
+//
+// The ids of each account and slot are stored in chunks of fixed capacity,
+// keyed by the last id they hold. The chunk being filled up is keyed by the
+// maximum uint64, so the chunk holding the first id after n is always the
+// first chunk keyed at or after n+1.
+
+const (
+	// historyIndexChunkSize is the maximum number of ids stored in a chunk.
+	historyIndexChunkSize = 1024
+
+	// openChunk is the id of the chunk being filled up.
+	openChunk = math.MaxUint64
+)
+
+// stateIdent identifies an account or a storage slot in the history index.
+type stateIdent struct {
+	address common.Address
+	slot    common.Hash // Hash of the storage slot key, only set for slots
+	storage bool
+}
+
+func newAccountIdent(address common.Address) stateIdent {
+	return stateIdent{address: address}
+}
+
+func newStorageIdent(address common.Address, slot common.Hash) stateIdent {
+	return stateIdent{address: address, slot: slot, storage: true}
+}
+
+func (i stateIdent) read(db ethdb.KeyValueReader, chunk uint64) []uint64 {
+	if i.storage {
+		return decodeHistoryIDs(rawdb.ReadStorageHistoryIndex(db, i.address, i.slot, chunk))
+	}
+	return decodeHistoryIDs(rawdb.ReadAccountHistoryIndex(db, i.address, chunk))
+}
+
+func (i stateIdent) write(db ethdb.KeyValueWriter, chunk uint64, ids []uint64) {
+	if len(ids) == 0 {
+		i.delete(db, chunk)
+		return
+	}
+	if i.storage {
+		rawdb.WriteStorageHistoryIndex(db, i.address, i.slot, chunk, encodeHistoryIDs(ids))
+	} else {
+		rawdb.WriteAccountHistoryIndex(db, i.address, chunk, encodeHistoryIDs(ids))
+	}
+}
+
+func (i stateIdent) delete(db ethdb.KeyValueWriter, chunk uint64) {
+	if i.storage {
+		rawdb.DeleteStorageHistoryIndex(db, i.address, i.slot, chunk)
+	} else {
+		rawdb.DeleteAccountHistoryIndex(db, i.address, chunk)
+	}
+}
+
+func (i stateIdent) iterate(db ethdb.Iteratee, from uint64, fn func(chunk uint64, ids []uint64) bool) {
+	callback := func(chunk uint64, data []byte) bool {
+		return fn(chunk, decodeHistoryIDs(data))
+	}
+	if i.storage {
+		rawdb.IterateStorageHistoryIndex(db, i.address, i.slot, from, callback)
+	} else {
+		rawdb.IterateAccountHistoryIndex(db, i.address, from, callback)
+	}
+}
+
+// encodeHistoryIDs packs the list of history ids into byte stream.
+func encodeHistoryIDs(ids []uint64) []byte {
+	blob := make([]byte, 0, 8*len(ids))
+	for _, id := range ids {
+		blob = binary.BigEndian.AppendUint64(blob, id)
+	}
+	return blob
+}
+
+// decodeHistoryIDs unpacks the list of history ids from the byte stream.
+func decodeHistoryIDs(blob []byte) []uint64 {
+	ids := make([]uint64, 0, len(blob)/8)
+	for i := 0; i+8 <= len(blob); i += 8 {
+		ids = append(ids, binary.BigEndian.Uint64(blob[i:]))
+	}
+	return ids
+}
+
+// historyIdents returns the identifiers of all the accounts and storage slots
+// modified in the given state history.
+func historyIdents(h *history) []stateIdent {
+	idents := make([]stateIdent, 0, len(h.accountList))
+	for _, addr := range h.accountList {
+		idents = append(idents, newAccountIdent(addr))
+		for _, slot := range h.storageList[addr] {
+			idents = append(idents, newStorageIdent(addr, slot))
+		}
+	}
+	return idents
+}
+
+// appendHistoryIndex appends the history id to the index of the given state.
+// The ids must be appended in ascending order.
+func appendHistoryIndex(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, ident stateIdent, id uint64) {
+	ids := append(ident.read(db, openChunk), id)
+	if len(ids) < historyIndexChunkSize {
+		ident.write(batch, openChunk, ids)
+		return
+	}
+	// The chunk is full, store it under its last id
+	ident.write(batch, id, ids)
+	ident.delete(batch, openChunk)
+}
+
+// popHistoryIndex removes the history id from the end of the index of the
+// given state.
+func popHistoryIndex(db ethdb.KeyValueStore, batch ethdb.KeyValueWriter, ident stateIdent, id uint64) error {
+	chunk, ids := uint64(openChunk), ident.read(db, openChunk)
+	if len(ids) == 0 {
+		// The open chunk is empty, reopen the last full chunk
+		ident.iterate(db, 0, func(c uint64, list []uint64) bool {
+			if c != openChunk {
+				chunk, ids = c, list
+			}
+			return true
+		})
+	}
+	if len(ids) == 0 || ids[len(ids)-1] != id {
+		return fmt.Errorf("history %d is not the last indexed one", id)
+	}
+	if chunk != openChunk {
+		ident.delete(batch, chunk)
+	}
+	ident.write(batch, openChunk, ids[:len(ids)-1])
+	return nil
+}
+
+// pruneHistoryIndex removes the history ids up to and including the given one
+// from the index of the given state.
+func pruneHistoryIndex(db ethdb.Iteratee, batch ethdb.KeyValueWriter, ident stateIdent, id uint64) {
+	ident.iterate(db, 0, func(chunk uint64, ids []uint64) bool {
+		n := sort.Search(len(ids), func(i int) bool { return ids[i] > id })
+		ident.write(batch, chunk, ids[n:])
+		// Continue with the next chunk only if this one was removed entirely
+		return n == len(ids) && chunk < id
+	})
+}
+
+// nextHistoryIndex returns the id of the first indexed history after the given
+// one in which the state was modified.
+func nextHistoryIndex(db ethdb.Iteratee, ident stateIdent, after uint64) (uint64, bool) {
+	var (
+		next  uint64
+		found bool
+	)
+	ident.iterate(db, after+1, func(chunk uint64, ids []uint64) bool {
+		n := sort.Search(len(ids), func(i int) bool { return ids[i] > after })
+		if n < len(ids) {
+			next, found = ids[n], true
+		}
+		return false
+	})
+	return next, found
+}
+
+// historyIndexer maintains the state history index. The histories are indexed
+// in the background, as the index might have to catch up with a large number
+// of histories, e.g. after the index was enabled on an existing database.
+//
+// All truncations of the state histories must go through the indexer, which
+// keeps the index aligned with the histories.
+type historyIndexer struct {
+	disk    ethdb.KeyValueStore
+	freezer ethdb.ResettableAncientStore
+	head    uint64     // Id of the last indexed history
+	lock    sync.Mutex // Lock protecting the index and the histories against concurrent mutations
+
+	wake   chan struct{}
+	closed chan struct{}
+	done   chan struct{} // Closed when the background indexing terminates, nil if never started
+}
+
+// newHistoryIndexer creates the state history indexer, dropping the index if it
+// is not aligned with the state histories.
+func newHistoryIndexer(disk ethdb.KeyValueStore, freezer ethdb.ResettableAncientStore) *historyIndexer {
+	indexer := &historyIndexer{
+		disk:    disk,
+		freezer: freezer,
+		wake:    make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+	if head := rawdb.ReadStateHistoryIndexHead(disk); head != nil {
+		indexer.head = *head
+	}
+	// Histories before the tail are gone, there is nothing left to index.
+	tail, err := freezer.Tail()
+	if err != nil {
+		log.Crit("Failed to retrieve tail of state history", "err", err)
+	}
+	if indexer.head < tail {
+		indexer.head = tail
+	}
+	// The index can only be ahead of the histories if they were removed
+	// behind its back, start over in that case.
+	head, err := freezer.Ancients()
+	if err != nil {
+		log.Crit("Failed to retrieve head of state history", "err", err)
+	}
+	if indexer.head > head {
+		log.Warn("Dropping dangling state history index", "indexed", indexer.head, "head", head)
+		if err := rawdb.DeleteStateHistoryIndex(disk); err != nil {
+			log.Crit("Failed to drop state history index", "err", err)
+		}
+		indexer.head = tail
+	}
+	return indexer
+}
+
+// start launches the background indexing.
+func (i *historyIndexer) start() {
+	i.done = make(chan struct{})
+	go i.loop()
+}
+
+// loop indexes the state histories which are not yet indexed, each time new
+// ones are added.
+func (i *historyIndexer) loop() {
+	defer close(i.done)
+
+	for {
+		i.catchUp()
+
+		select {
+		case <-i.wake:
+		case <-i.closed:
+			return
+		}
+	}
+}
+
+// catchUp indexes the state histories which are not yet indexed.
+func (i *historyIndexer) catchUp() {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		count  int
+	)
+	for {
+		select {
+		case <-i.closed:
+			return
+		default:
+		}
+		done, err := i.indexNext()
+		if err != nil {
+			log.Error("Failed to index state history", "err", err)
+			return
+		}
+		if done {
+			break
+		}
+		count++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing state histories", "indexed", count, "head", i.indexed(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if count > 0 {
+		log.Debug("Indexed state histories", "count", count, "head", i.indexed(), "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// indexNext indexes the history following the last indexed one. It returns
+// true if there is nothing left to index.
+func (i *historyIndexer) indexNext() (bool, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	head, err := i.freezer.Ancients()
+	if err != nil {
+		return false, err
+	}
+	if i.head >= head {
+		return true, nil
+	}
+	id := i.head + 1
+	h, err := readHistory(i.freezer, id)
+	if err != nil {
+		return false, err
+	}
+	batch := i.disk.NewBatch()
+	for _, ident := range historyIdents(h) {
+		appendHistoryIndex(i.disk, batch, ident, id)
+	}
+	rawdb.WriteStateHistoryIndexHead(batch, id)
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	i.head = id
+	return false, nil
+}
+
+// notify signals the indexer that new histories were added.
+func (i *historyIndexer) notify() {
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// indexed returns the id of the last indexed history.
+func (i *historyIndexer) indexed() uint64 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.head
+}
+
+// truncateFromHead removes the histories after the given id from the index and
+// then from the freezer. It returns the number of histories removed.
+func (i *historyIndexer) truncateFromHead(db ethdb.Batcher, nhead uint64) (int, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for i.head > nhead {
+		h, err := readHistory(i.freezer, i.head)
+		if err != nil {
+			return 0, err
+		}
+		batch := i.disk.NewBatch()
+		for _, ident := range historyIdents(h) {
+			if err := popHistoryIndex(i.disk, batch, ident, i.head); err != nil {
+				return 0, err
+			}
+		}
+		rawdb.WriteStateHistoryIndexHead(batch, i.head-1)
+		if err := batch.Write(); err != nil {
+			return 0, err
+		}
+		i.head--
+	}
+	return truncateFromHead(db, i.freezer, nhead)
+}
+
+// truncateFromTail removes the histories up to and including the given id from
+// the index and then from the freezer. It returns the number of histories
+// removed.
+func (i *historyIndexer) truncateFromTail(db ethdb.Batcher, ntail uint64) (int, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	otail, err := i.freezer.Tail()
+	if err != nil {
+		return 0, err
+	}
+	for id := otail + 1; id <= ntail && id <= i.head; id++ {
+		h, err := readHistory(i.freezer, id)
+		if err != nil {
+			return 0, err
+		}
+		batch := i.disk.NewBatch()
+		for _, ident := range historyIdents(h) {
+			pruneHistoryIndex(i.disk, batch, ident, id)
+		}
+		if err := batch.Write(); err != nil {
+			return 0, err
+		}
+	}
+	// Histories removed before being indexed don't need to be indexed anymore
+	if i.head < ntail {
+		rawdb.WriteStateHistoryIndexHead(i.disk, ntail)
+		i.head = ntail
+	}
+	return truncateFromTail(db, i.freezer, ntail)
+}
+
+// reset drops all the histories along with the entire index.
+func (i *historyIndexer) reset() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if err := i.freezer.Reset(); err != nil {
+		return err
+	}
+	if err := rawdb.DeleteStateHistoryIndex(i.disk); err != nil {
+		return err
+	}
+	i.head = 0
+	return nil
+}
+
+// lookup returns the id of the first history in range (after, last] in which
+// the given state was modified. Histories not yet indexed are searched one by
+// one.
+func (i *historyIndexer) lookup(ident stateIdent, after uint64, last uint64) (uint64, bool, error) {
+	head := i.indexed()
+	if after < head {
+		if id, found := nextHistoryIndex(i.disk, ident, after); found && id <= head {
+			if id > last {
+				return 0, false, nil
+			}
+			return id, true, nil
+		}
+	}
+	for id := max(after, head) + 1; id <= last; id++ {
+		found, err := historyModifies(i.freezer, id, ident)
+		if err != nil {
+			return 0, false, err
+		}
+		if found {
+			return id, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// close terminates the background indexing.
+func (i *historyIndexer) close() {
+	select {
+	case <-i.closed:
+	default:
+		close(i.closed)
+	}
+	if i.done != nil {
+		<-i.done
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/internal/testrand"
+)
+
+func TestHistoryIndexChunks(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		ident = newStorageIdent(testrand.Address(), testrand.Hash())
+	)
+	for id := uint64(1); id <= 2500; id++ {
+		batch := db.NewBatch()
+		appendHistoryIndex(db, batch, ident, id)
+		if err := batch.Write(); err != nil {
+			t.Fatalf("Failed to write index: %v", err)
+		}
+	}
+	check := func(after uint64, want uint64, exist bool) {
+		t.Helper()
+		next, found := nextHistoryIndex(db, ident, after)
+		if found != exist || next != want {
+			t.Fatalf("Unexpected next history after %d, want: %d (%t), got: %d (%t)", after, want, exist, next, found)
+		}
+	}
+	for _, after := range []uint64{0, 1023, 1024, 2047, 2048, 2499} {
+		check(after, after+1, true)
+	}
+	check(2500, 0, false)
+
+	// Pop the histories back across the chunk boundary
+	if err := popHistoryIndex(db, db.NewBatch(), ident, 2499); err == nil {
+		t.Fatal("Popped history which is not the last one")
+	}
+	for id := uint64(2500); id > 2000; id-- {
+		batch := db.NewBatch()
+		if err := popHistoryIndex(db, batch, ident, id); err != nil {
+			t.Fatalf("Failed to pop history %d: %v", id, err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatalf("Failed to write index: %v", err)
+		}
+	}
+	check(1999, 2000, true)
+	check(2000, 0, false)
+
+	// Prune the histories across the chunk boundary
+	batch := db.NewBatch()
+	pruneHistoryIndex(db, batch, ident, 1500)
+	if err := batch.Write(); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	check(0, 1501, true)
+	check(1600, 1601, true)
+}
+
+// waitIndexed waits until the indexer catches up with the given history.
+func waitIndexed(t *testing.T, indexer *historyIndexer, id uint64) {
+	for start := time.Now(); indexer.indexed() < id; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Indexer failed to catch up, indexed: %d, want: %d", indexer.indexed(), id)
+		}
+	}
+}
+
+func TestHistoryIndexer(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to cap database, err: %v", err)
+	}
+	var (
+		db        = tester.db
+		head      = uint64(len(tester.roots))
+		histories []*history
+	)
+	waitIndexed(t, db.indexer, head)
+
+	for id := uint64(1); id <= head; id++ {
+		h, err := readHistory(db.freezer, id)
+		if err != nil {
+			t.Fatalf("Failed to read history %d: %v", id, err)
+		}
+		histories = append(histories, h)
+	}
+	// Every state must be indexed in the histories modifying it, with the
+	// values before the transition.
+	unindexed := &historyIndexer{disk: db.diskdb, freezer: db.freezer}
+	for i, h := range histories {
+		id := uint64(i + 1)
+		for _, addr := range h.accountList {
+			ident := newAccountIdent(addr)
+			if next, found := nextHistoryIndex(db.diskdb, ident, id-1); !found || next != id {
+				t.Fatalf("Account %x is not indexed in history %d", addr, id)
+			}
+			blob, found, err := readHistoryState(db.freezer, id, ident)
+			if err != nil || !found || !bytes.Equal(blob, h.accounts[addr]) {
+				t.Fatalf("Unexpected account %x in history %d: %x, %v", addr, id, blob, err)
+			}
+			for _, slot := range h.storageList[addr] {
+				ident := newStorageIdent(addr, slot)
+				blob, found, err := readHistoryState(db.freezer, id, ident)
+				if err != nil || !found || !bytes.Equal(blob, h.storages[addr][slot]) {
+					t.Fatalf("Unexpected slot %x:%x in history %d: %x, %v", addr, slot, id, blob, err)
+				}
+				// The lookup with the index must match the one scanning
+				// the histories.
+				for after := uint64(0); after < head; after++ {
+					want, wantFound, _ := unindexed.lookup(ident, after, head)
+					have, haveFound, err := db.indexer.lookup(ident, after, head)
+					if err != nil || have != want || haveFound != wantFound {
+						t.Fatalf("Lookup mismatch after %d, want: %d (%t), got: %d (%t)", after, want, wantFound, have, haveFound)
+					}
+				}
+			}
+		}
+	}
+	// Truncating the histories must remove them from the index
+	if _, err := db.indexer.truncateFromHead(db.diskdb, head-2); err != nil {
+		t.Fatalf("Failed to truncate head: %v", err)
+	}
+	if _, err := db.indexer.truncateFromTail(db.diskdb, 2); err != nil {
+		t.Fatalf("Failed to truncate tail: %v", err)
+	}
+	for i, h := range histories {
+		id := uint64(i + 1)
+		for _, ident := range historyIdents(h) {
+			if id > head-2 {
+				if next, found := nextHistoryIndex(db.diskdb, ident, head-2); found {
+					t.Fatalf("Truncated history %d is still indexed", next)
+				}
+			}
+			if id <= 2 {
+				if next, found := nextHistoryIndex(db.diskdb, ident, 0); found && next <= 2 {
+					t.Fatalf("Pruned history %d is still indexed", next)
+				}
+			}
+		}
+	}
+}
+
+func TestHistoryIndexerReset(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to cap database, err: %v", err)
+	}
+	head := uint64(len(tester.roots))
+	waitIndexed(t, tester.db.indexer, head)
+
+	h, err := readHistory(tester.db.freezer, 1)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	// Dropping the histories behind the indexer must discard the index
+	// on the next start.
+	tester.db.Close()
+
+	ancient, err := tester.db.diskdb.AncientDatadir()
+	if err != nil {
+		t.Fatalf("Failed to resolve ancient directory: %v", err)
+	}
+	freezer, err := rawdb.NewStateFreezer(ancient, false)
+	if err != nil {
+		t.Fatalf("Failed to open freezer: %v", err)
+	}
+	defer freezer.Close()
+
+	if err := freezer.Reset(); err != nil {
+		t.Fatalf("Failed to reset freezer: %v", err)
+	}
+	indexer := newHistoryIndexer(tester.db.diskdb, freezer)
+	if indexed := indexer.indexed(); indexed != 0 {
+		t.Fatalf("Unexpected index head: %d", indexed)
+	}
+	if rawdb.ReadStateHistoryIndexHead(tester.db.diskdb) != nil {
+		t.Fatal("Index head is not dropped")
+	}
+	for _, ident := range historyIdents(h) {
+		if _, found := nextHistoryIndex(tester.db.diskdb, ident, 0); found {
+			t.Fatal("Index is not dropped")
+		}
+	}
+}
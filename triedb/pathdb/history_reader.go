@@ -0,0 +1,238 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+// readHistoryState reads the value of the given account or storage slot before
+// the state transition of the specified history, without decoding the entire
+// history. The returned flag reports whether the state was modified in the
+// history at all.
+func readHistoryState(reader ethdb.AncientReader, id uint64, ident stateIdent) ([]byte, bool, error) {
+	accountIndexes := rawdb.ReadStateAccountIndex(reader, id)
+	if len(accountIndexes)%accountIndexSize != 0 || len(accountIndexes) == 0 {
+		return nil, false, fmt.Errorf("invalid account index of state history %d, len: %d", id, len(accountIndexes))
+	}
+	n := len(accountIndexes) / accountIndexSize
+	pos := sort.Search(n, func(i int) bool {
+		return bytes.Compare(accountIndexes[i*accountIndexSize:i*accountIndexSize+common.AddressLength], ident.address.Bytes()) >= 0
+	})
+	if pos == n {
+		return nil, false, nil
+	}
+	var accIndex accountIndex
+	accIndex.decode(accountIndexes[pos*accountIndexSize : (pos+1)*accountIndexSize])
+	if accIndex.address != ident.address {
+		return nil, false, nil
+	}
+	if !ident.storage {
+		data := rawdb.ReadStateAccountHistory(reader, id)
+		last := accIndex.offset + uint32(accIndex.length)
+		if uint32(len(data)) < last {
+			return nil, false, fmt.Errorf("account data of state history %d is corrupted", id)
+		}
+		return data[accIndex.offset:last], true, nil
+	}
+	storageIndexes := rawdb.ReadStateStorageIndex(reader, id)
+	if uint32(len(storageIndexes)) < (accIndex.storageOffset+accIndex.storageSlots)*uint32(slotIndexSize) {
+		return nil, false, fmt.Errorf("storage index of state history %d is corrupted", id)
+	}
+	slots := storageIndexes[accIndex.storageOffset*uint32(slotIndexSize) : (accIndex.storageOffset+accIndex.storageSlots)*uint32(slotIndexSize)]
+	pos = sort.Search(int(accIndex.storageSlots), func(i int) bool {
+		return bytes.Compare(slots[i*slotIndexSize:i*slotIndexSize+common.HashLength], ident.slot.Bytes()) >= 0
+	})
+	if pos == int(accIndex.storageSlots) {
+		return nil, false, nil
+	}
+	var slot slotIndex
+	slot.decode(slots[pos*slotIndexSize : (pos+1)*slotIndexSize])
+	if slot.hash != ident.slot {
+		return nil, false, nil
+	}
+	data := rawdb.ReadStateStorageHistory(reader, id)
+	last := slot.offset + uint32(slot.length)
+	if uint32(len(data)) < last {
+		return nil, false, fmt.Errorf("storage data of state history %d is corrupted", id)
+	}
+	return data[slot.offset:last], true, nil
+}
+
+// historyModifies reports whether the given account or storage slot was
+// modified in the specified history.
+func historyModifies(reader ethdb.AncientReader, id uint64, ident stateIdent) (bool, error) {
+	_, found, err := readHistoryState(reader, id, ident)
+	return found, err
+}
+
+// layerDatabase exposes a single layer as a trie database, for opening the
+// tries of the layer.
+type layerDatabase struct {
+	layer layer
+}
+
+// Reader implements database.Database, returning a reader of the layer.
+func (db *layerDatabase) Reader(root common.Hash) (database.Reader, error) {
+	if root != db.layer.rootHash() {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	return &reader{layer: db.layer}, nil
+}
+
+// Preimage implements database.Database, preimages are not available.
+func (db *layerDatabase) Preimage(hash common.Hash) []byte { return nil }
+
+// InsertPreimage implements database.Database, preimages are discarded.
+func (db *layerDatabase) InsertPreimage(preimages map[common.Hash][]byte) {}
+
+// HistoricalReader reconstructs the accounts and storage slots of a state
+// which is no longer kept in the layer tree, but whose state histories are
+// still retained. The value of a state item is taken from the first history
+// after the requested state in which the item was modified, or from the disk
+// layer if the item was not modified since.
+type HistoricalReader struct {
+	db   *Database
+	id   uint64      // State id of the requested state
+	root common.Hash // State root of the requested state
+}
+
+// HistoricReader returns a reader for the historical state with the given root.
+// The state must still be covered by the retained state histories.
+func (db *Database) HistoricReader(root common.Hash) (*HistoricalReader, error) {
+	if db.isVerkle {
+		return nil, errors.New("historical state is not supported in verkle")
+	}
+	if db.freezer == nil || db.indexer == nil {
+		return nil, errors.New("state histories are not available")
+	}
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	// The states before the tail can't be reconstructed, as the histories
+	// of their successor transitions are already pruned.
+	if *id < tail {
+		return nil, fmt.Errorf("state %#x is pruned, id: %d, tail: %d", root, *id, tail)
+	}
+	return &HistoricalReader{db: db, id: *id, root: root}, nil
+}
+
+// Account returns the account with the given address in the slim RLP format,
+// or nil if the account was not present.
+func (r *HistoricalReader) Account(address common.Address) ([]byte, error) {
+	return r.read(func(dl *diskLayer) ([]byte, error) {
+		return r.account(dl, address)
+	})
+}
+
+// read runs the given read operation against the disk layer, retrying it if
+// the disk layer progressed in the meantime.
+func (r *HistoricalReader) read(fn func(dl *diskLayer) ([]byte, error)) ([]byte, error) {
+	var prev *diskLayer
+	for {
+		dl := r.db.tree.bottom()
+		if dl == prev {
+			// The disk layer is stale without being replaced, the database
+			// is disabled.
+			return nil, errSnapshotStale
+		}
+		if dl.stateID() < r.id {
+			return nil, fmt.Errorf("state %#x is not historical, id: %d, disk: %d", r.root, r.id, dl.stateID())
+		}
+		blob, err := fn(dl)
+		if !errors.Is(err, errSnapshotStale) {
+			return blob, err
+		}
+		prev = dl
+	}
+}
+
+func (r *HistoricalReader) account(dl *diskLayer, address common.Address) ([]byte, error) {
+	ident := newAccountIdent(address)
+	id, found, err := r.db.indexer.lookup(ident, r.id, dl.stateID())
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		blob, _, err := readHistoryState(r.db.freezer, id, ident)
+		return blob, err
+	}
+	acct, err := r.diskAccount(dl, address)
+	if err != nil || acct == nil {
+		return nil, err
+	}
+	return types.SlimAccountRLP(*acct), nil
+}
+
+// Storage returns the value of the given storage slot, with the prefix-zero
+// trimmed, or nil if the slot was not present.
+func (r *HistoricalReader) Storage(address common.Address, key common.Hash) ([]byte, error) {
+	return r.read(func(dl *diskLayer) ([]byte, error) {
+		return r.storage(dl, address, key)
+	})
+}
+
+func (r *HistoricalReader) storage(dl *diskLayer, address common.Address, key common.Hash) ([]byte, error) {
+	ident := newStorageIdent(address, crypto.Keccak256Hash(key.Bytes()))
+	id, found, err := r.db.indexer.lookup(ident, r.id, dl.stateID())
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		blob, _, err := readHistoryState(r.db.freezer, id, ident)
+		if err != nil || len(blob) == 0 {
+			return nil, err
+		}
+		_, content, _, err := rlp.Split(blob)
+		return content, err
+	}
+	acct, err := r.diskAccount(dl, address)
+	if err != nil || acct == nil {
+		return nil, err
+	}
+	tr, err := trie.NewStateTrie(trie.StorageTrieID(dl.rootHash(), crypto.Keccak256Hash(address.Bytes()), acct.Root), &layerDatabase{layer: dl})
+	if err != nil {
+		return nil, err
+	}
+	return tr.GetStorage(address, key.Bytes())
+}
+
+// diskAccount reads the account from the state of the given disk layer.
+func (r *HistoricalReader) diskAccount(dl *diskLayer, address common.Address) (*types.StateAccount, error) {
+	tr, err := trie.NewStateTrie(trie.StateTrieID(dl.rootHash()), &layerDatabase{layer: dl})
+	if err != nil {
+		return nil, err
+	}
+	return tr.GetAccount(address)
+}